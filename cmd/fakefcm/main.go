@@ -0,0 +1,25 @@
+// Command fakefcm runs the local FCM stand-in for development.
+//
+// Start it, then run the server with:
+//
+//	FCM_ENDPOINT=http://127.0.0.1:9099/v1 FCM_PROJECT_ID=remiaq-dev
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"remiaq/internal/fakefcm"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9099", "listen address")
+	flag.Parse()
+
+	log.Printf("fake FCM listening on http://%s (endpoint: http://%s/v1)", *addr, *addr)
+	log.Printf("GET /_fake/messages to list messages, POST /_fake/faults to script errors")
+	if err := http.ListenAndServe(*addr, fakefcm.NewServer()); err != nil {
+		log.Fatalf("fake FCM stopped: %v", err)
+	}
+}
@@ -6,12 +6,15 @@ import (
 	"os"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 
 	"remiaq/config"
 	"remiaq/internal/handlers" // ← Đã sửa từ api/handlers
 	"remiaq/internal/middleware"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
 	pbRepo "remiaq/internal/repository/pocketbase"
 	"remiaq/internal/services"
 	"remiaq/internal/worker"
@@ -37,38 +40,138 @@ func main() {
 	reminderRepo := pbRepo.NewReminderRepo(app)
 	userRepo := pbRepo.NewUserRepo(app)
 	queryRepo := pbRepo.NewQueryRepo(app)
+	deliveryRepo := pbRepo.NewDeliveryRepo(app)
+	templateRepo := pbRepo.NewTemplateRepo(app)
+	groupRepo := pbRepo.NewGroupRepo(app)
 
 	// Initialize services
 	// Note: FCM service is optional, we'll initialize it with a stub for now
 	var fcmService *services.FCMService
 	if _, err := os.Stat(cfg.FCMCredentials); err == nil {
-		fcmService, err = services.NewFCMService(cfg.FCMCredentials)
+		fcmService, err = services.NewFCMService(cfg.FCMCredentials, fcmOptions(cfg)...)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize FCM service: %v", err)
 			// Continue without FCM for development
 		}
+	} else if cfg.FCMEndpoint != "" {
+		// Không có credentials nhưng có endpoint riêng (fakefcm): gửi không xác thực
+		fcmService, err = services.NewFCMService("", fcmOptions(cfg)...)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize FCM service for %s: %v", cfg.FCMEndpoint, err)
+		} else {
+			log.Printf("Using FCM endpoint %s without credentials", cfg.FCMEndpoint)
+		}
 	} else {
 		log.Println("Warning: FCM credentials not found, notifications disabled")
 	}
 
+	// Initialize system status repo (worker toggle + circuit breaker state)
+	sysRepo := pbRepo.NewSystemStatusRepo(app)
+	bgCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Wrap FCM in a circuit breaker; transitions are persisted to system_status
+	var notifier services.Notifier
+	var breaker *services.CircuitBreaker
+	if fcmService != nil {
+		breakerCfg := services.DefaultCircuitBreakerConfig()
+		if cfg.CircuitFailureThreshold > 0 {
+			breakerCfg.FailureThreshold = cfg.CircuitFailureThreshold
+		}
+		if cfg.CircuitOpenSeconds > 0 {
+			breakerCfg.OpenTimeout = time.Duration(cfg.CircuitOpenSeconds) * time.Second
+		}
+		breaker = services.NewCircuitBreaker(fcmService, breakerCfg, func(tr services.CircuitTransition) {
+			if err := sysRepo.UpdateCircuitState(context.Background(), tr.To, tr.Reason, tr.At); err != nil {
+				log.Printf("Warning: Failed to persist circuit state: %v", err)
+			}
+		})
+		notifier = breaker
+	}
+
 	lunarCalendar := services.NewLunarCalendar()
 	schedCalculator := services.NewScheduleCalculator(lunarCalendar)
-	reminderService := services.NewReminderService(reminderRepo, userRepo, fcmService, schedCalculator)
+	retryPolicy := services.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = cfg.FCMSendMaxAttempts
+	retryPolicy.BaseDelay = time.Duration(cfg.FCMRetryBaseMs) * time.Millisecond
+	retryPolicy.MaxDelay = time.Duration(cfg.FCMRetryMaxMs) * time.Millisecond
+	limiter := services.NewRateLimiter(services.RateLimitConfig{
+		Global:      services.RateLimit{Limit: cfg.RateLimitGlobalPerMinute, Window: time.Minute},
+		PerUser:     services.RateLimit{Limit: cfg.RateLimitUserPerMinute, Window: time.Minute},
+		PerReminder: services.RateLimit{Limit: cfg.RateLimitReminderPerHour, Window: time.Hour},
+	})
+	serviceOpts := []services.ReminderServiceOption{
+		services.WithRetryPolicy(retryPolicy),
+		services.WithConcurrency(cfg.WorkerConcurrency),
+		services.WithRateLimiter(limiter),
+		services.WithDeliveryRepo(deliveryRepo),
+		services.WithGroupRepo(groupRepo),
+		services.WithTemplateRenderer(services.NewTemplateRenderer(lunarCalendar, templateRepo)),
+		services.WithTransactor(pbRepo.NewTransactor(app)),
+	}
+	if cfg.SMTPHost != "" {
+		mailer, err := services.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		if err != nil {
+			log.Fatalf("Failed to configure SMTP: %v", err)
+		}
+		serviceOpts = append(serviceOpts, services.WithEmailSender(mailer))
+	} else {
+		log.Println("SMTP_HOST not set, email escalation disabled")
+	}
+	if cfg.WorkerLeaseSec > 0 {
+		instanceID := cfg.WorkerInstanceID
+		if instanceID == "" {
+			instanceID = defaultInstanceID()
+		}
+		leaseTTL := time.Duration(cfg.WorkerLeaseSec) * time.Second
+		serviceOpts = append(serviceOpts, services.WithLeasing(instanceID, leaseTTL, cfg.WorkerClaimBatch))
+		log.Printf("Worker leasing enabled as %s (lease %s)", instanceID, leaseTTL)
+	}
+	// Scheduler mode: the worker sleeps until the next trigger, woken by reminder changes
+	var workerOpts []worker.Option
+	if cfg.WorkerMode == config.WorkerModeScheduler {
+		schedule := worker.NewSchedule()
+		serviceOpts = append(serviceOpts, services.WithScheduleObserver(schedule))
+		workerOpts = append(workerOpts, worker.WithSchedule(schedule, reminderRepo, time.Duration(cfg.WorkerResyncSec)*time.Second))
+	}
+	reminderService := services.NewReminderService(reminderRepo, userRepo, notifier, schedCalculator, serviceOpts...)
+
+	// Group membership is synced with FCM topics only when FCM is configured
+	var topics services.TopicManager
+	if fcmService != nil {
+		topics = fcmService
+	}
+	groupService := services.NewGroupService(groupRepo, userRepo, topics)
 
 	// Initialize handlers
 	reminderHandler := handlers.NewReminderHandler(reminderService)
-	queryHandler := handlers.NewQueryHandler(queryRepo)
+	groupHandler := handlers.NewGroupHandler(groupService)
+	queryHandler := handlers.NewQueryHandler(queryRepo,
+		handlers.WithAPIKeys(apiKeys(cfg)...),
+		handlers.WithAuditLog(pbRepo.NewQueryAuditRepo(app)),
+		handlers.WithSavedQueries(pbRepo.NewSavedQueryRepo(app)),
+		handlers.WithAdHocQueries(cfg.RawQueryAdHoc),
+		handlers.WithExportTimeout(time.Duration(cfg.RawQueryExportTimeoutSec)*time.Second))
+	deliveryHandler := handlers.NewDeliveryHandler(deliveryRepo, reminderService)
 
-	// Initialize system status repo and start background worker
-	sysRepo := pbRepo.NewSystemStatusRepo(app)
-	sysHandler := handlers.NewSystemStatusHandler(sysRepo)
-	bgCtx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	w := worker.NewWorker(sysRepo, reminderService, time.Duration(cfg.WorkerInterval)*time.Second)
+	// Start background worker
+	var sysHandler *handlers.SystemStatusHandler
+	if breaker != nil {
+		sysHandler = handlers.NewSystemStatusHandler(sysRepo, breaker)
+	} else {
+		sysHandler = handlers.NewSystemStatusHandler(sysRepo, nil)
+	}
+	w := worker.NewWorker(sysRepo, reminderService, time.Duration(cfg.WorkerInterval)*time.Second, workerOpts...)
 	w.Start(bgCtx)
 
 	// Setup routes
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		// Khôi phục override của admin và bắt đầu probe khi DB đã sẵn sàng
+		if breaker != nil {
+			restoreCircuitOverride(sysRepo, breaker)
+			go breaker.Run(bgCtx)
+		}
+
 		// Handle preflight OPTIONS requests
 		se.Router.OPTIONS("/*", func(re *core.RequestEvent) error {
 			middleware.SetCORSHeaders(re)
@@ -81,7 +184,7 @@ func main() {
 			return re.String(200, "RemiAq API is running!")
 		})
 
-		// Raw SQL query endpoints (from original main.go)
+		// Raw SQL query endpoints (from original main.go): superusers or API keys, audited
 		se.Router.GET("/api/rquery", queryHandler.HandleSelect)
 		se.Router.POST("/api/rquery", queryHandler.HandleSelect)
 
@@ -94,6 +197,13 @@ func main() {
 		se.Router.GET("/api/rdelete", queryHandler.HandleDelete)
 		se.Router.DELETE("/api/rdelete", queryHandler.HandleDelete)
 
+		se.Router.GET("/api/rquery/audit", queryHandler.HandleAuditLog)
+
+		// Saved queries: vetted SQL with typed parameters, still available when ad-hoc SQL is off
+		se.Router.GET("/api/queries", queryHandler.HandleListSaved)
+		se.Router.GET("/api/queries/{name}/run", queryHandler.HandleRunSaved)
+		se.Router.POST("/api/queries/{name}/run", queryHandler.HandleRunSaved)
+
 		// Reminder CRUD endpoints
 		se.Router.POST("/api/reminders", reminderHandler.CreateReminder)
 		se.Router.GET("/api/reminders/{id}", reminderHandler.GetReminder)
@@ -107,6 +217,19 @@ func main() {
 		se.Router.POST("/api/reminders/{id}/snooze", reminderHandler.SnoozeReminder)
 		se.Router.POST("/api/reminders/{id}/complete", reminderHandler.CompleteReminder)
 
+		// Broadcast groups
+		se.Router.POST("/api/groups", groupHandler.CreateGroup)
+		se.Router.GET("/api/groups/{id}", groupHandler.GetGroup)
+		se.Router.DELETE("/api/groups/{id}", groupHandler.DeleteGroup)
+		se.Router.GET("/api/groups/{id}/members", groupHandler.ListMembers)
+		se.Router.POST("/api/groups/{id}/members", groupHandler.AddMember)
+		se.Router.DELETE("/api/groups/{id}/members/{userId}", groupHandler.RemoveMember)
+
+		// Delivery log
+		se.Router.GET("/api/reminders/{id}/deliveries", deliveryHandler.GetReminderDeliveries)
+		se.Router.GET("/api/users/{userId}/deliveries", deliveryHandler.GetUserDeliveries)
+		se.Router.POST("/api/deliveries/{id}/ack", deliveryHandler.AckDelivery)
+
 		// System status API
 		se.Router.GET("/api/system_status", sysHandler.GetSystemStatus)
 		se.Router.PUT("/api/system_status", sysHandler.PutSystemStatus)
@@ -148,3 +271,49 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// restoreCircuitOverride re-applies the admin circuit override stored in system_status.
+func restoreCircuitOverride(sysRepo repository.SystemStatusRepository, breaker *services.CircuitBreaker) {
+	status, err := sysRepo.Get(context.Background())
+	if err != nil {
+		log.Printf("Warning: Failed to read circuit override: %v", err)
+		return
+	}
+	switch status.CircuitOverride {
+	case models.CircuitStateOpen:
+		breaker.ForceOpen(status.CircuitReason)
+	case models.CircuitStateClosed:
+		breaker.ForceClose()
+	}
+}
+
+// defaultInstanceID names this instance for reminder leases: hostname plus a random
+// suffix, so two processes on one host never share a lease.
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "remiaq"
+	}
+	return host + "-" + uuid.New().String()[:8]
+}
+
+// fcmOptions maps the FCM endpoint/project overrides from config.
+func fcmOptions(cfg *config.Config) []services.FCMOption {
+	var opts []services.FCMOption
+	if cfg.FCMEndpoint != "" {
+		opts = append(opts, services.WithFCMEndpoint(cfg.FCMEndpoint))
+	}
+	if cfg.FCMProjectID != "" {
+		opts = append(opts, services.WithFCMProjectID(cfg.FCMProjectID))
+	}
+	return opts
+}
+
+// apiKeys maps the raw query API keys from config.
+func apiKeys(cfg *config.Config) []handlers.APIKey {
+	keys := make([]handlers.APIKey, len(cfg.RawQueryAPIKeys))
+	for i, key := range cfg.RawQueryAPIKeys {
+		keys[i] = handlers.APIKey{Name: key.Name, Key: key.Key, Write: key.Write, Tables: key.Tables}
+	}
+	return keys
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -9,12 +11,65 @@ import (
 
 // Config holds application configuration
 type Config struct {
-	ServerAddr     string
-	WorkerInterval int    // seconds
-	FCMCredentials string // path to firebase credentials JSON
-	Environment    string // development, production
+	ServerAddr        string
+	WorkerInterval    int    // seconds; in scheduler mode, the wait before retrying reminders left due
+	WorkerMode        string // poll, scheduler
+	WorkerResyncSec   int    // scheduler mode: reload the schedule from the DB this often
+	WorkerConcurrency int    // users processed in parallel per tick
+	WorkerInstanceID  string // lease owner of this instance, empty = hostname + random suffix
+	WorkerLeaseSec    int    // how long claimed reminders are held, 0 = leasing disabled (single instance)
+	WorkerClaimBatch  int    // reminders claimed per batch, 0 = all due reminders at once
+	FCMCredentials    string // path to firebase credentials JSON
+	FCMEndpoint       string // override FCM HTTP v1 endpoint, e.g. the local fakefcm server
+	FCMProjectID      string // Firebase project, required when FCMEndpoint is set without credentials
+	Environment       string // development, production
+
+	// Per-send retry for transient FCM failures
+	FCMSendMaxAttempts int // total attempts per send, including the first
+	FCMRetryBaseMs     int // delay before the first retry (milliseconds)
+	FCMRetryMaxMs      int // maximum delay between retries (milliseconds)
+
+	// Notifier circuit breaker
+	CircuitFailureThreshold int // consecutive FCM failures before the circuit opens
+	CircuitOpenSeconds      int // wait before probing FCM while the circuit is open
+
+	// Send rate limits (0 = unlimited)
+	RateLimitGlobalPerMinute int // FCM messages per minute across all users
+	RateLimitUserPerMinute   int // notifications per minute for one user
+	RateLimitReminderPerHour int // notifications per hour for one reminder
+
+	// SMTP server for email escalation (empty host = email escalation disabled)
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string // sender address, may include a display name
+
+	// API keys for the raw SQL endpoints (superusers always have access)
+	RawQueryAPIKeys []APIKey
+	RawQueryAdHoc   bool // false = only saved queries (/api/queries/{name}/run) can be run
+
+	RawQueryExportTimeoutSec int // bounds a CSV/NDJSON export; 0 = until the client disconnects
+}
+
+// APIKey grants access to the raw SQL endpoints to callers sending Key in the X-API-Key header.
+// RAW_QUERY_API_KEYS lists them as name:key:read|write[:table,table], separated by ";".
+type APIKey struct {
+	Name   string
+	Key    string
+	Write  bool     // INSERT/UPDATE/DELETE besides SELECT
+	Tables []string // empty = every table of the query policy
 }
 
+// minAPIKeyLength is the shortest accepted API key
+const minAPIKeyLength = 16
+
+// Worker modes
+const (
+	WorkerModePoll      = "poll"      // query due reminders every WorkerInterval
+	WorkerModeScheduler = "scheduler" // sleep until the next trigger (in-memory timer heap)
+)
+
 // ValidationError represents configuration validation error
 type ValidationError struct {
 	Field   string
@@ -28,12 +83,46 @@ func (e *ValidationError) Error() string {
 // Load loads configuration from environment variables with validation
 func Load() (*Config, error) {
 	cfg := &Config{
-		ServerAddr:     getEnv("SERVER_ADDR", "127.0.0.1:8888"),
-		WorkerInterval: getEnvInt("WORKER_INTERVAL", 10),
-		FCMCredentials: getEnv("FCM_CREDENTIALS", "./firebase-credentials.json"),
-		Environment:    getEnv("ENVIRONMENT", "development"),
+		ServerAddr:        getEnv("SERVER_ADDR", "127.0.0.1:8888"),
+		WorkerInterval:    getEnvInt("WORKER_INTERVAL", 10),
+		WorkerMode:        getEnv("WORKER_MODE", WorkerModePoll),
+		WorkerResyncSec:   getEnvInt("WORKER_RESYNC_SECONDS", 300),
+		WorkerConcurrency: getEnvInt("WORKER_CONCURRENCY", 8),
+		WorkerInstanceID:  getEnv("WORKER_INSTANCE_ID", ""),
+		WorkerLeaseSec:    getEnvInt("WORKER_LEASE_SECONDS", 300),
+		WorkerClaimBatch:  getEnvInt("WORKER_CLAIM_BATCH", 500),
+		FCMCredentials:    getEnv("FCM_CREDENTIALS", "./firebase-credentials.json"),
+		FCMEndpoint:       getEnv("FCM_ENDPOINT", ""),
+		FCMProjectID:      getEnv("FCM_PROJECT_ID", ""),
+		Environment:       getEnv("ENVIRONMENT", "development"),
+
+		FCMSendMaxAttempts: getEnvInt("FCM_SEND_MAX_ATTEMPTS", 3),
+		FCMRetryBaseMs:     getEnvInt("FCM_RETRY_BASE_MS", 500),
+		FCMRetryMaxMs:      getEnvInt("FCM_RETRY_MAX_MS", 5000),
+
+		CircuitFailureThreshold: getEnvInt("CIRCUIT_FAILURE_THRESHOLD", 5),
+		CircuitOpenSeconds:      getEnvInt("CIRCUIT_OPEN_SECONDS", 60),
+
+		RateLimitGlobalPerMinute: getEnvInt("RATE_LIMIT_GLOBAL_PER_MINUTE", 1000),
+		RateLimitUserPerMinute:   getEnvInt("RATE_LIMIT_USER_PER_MINUTE", 30),
+		RateLimitReminderPerHour: getEnvInt("RATE_LIMIT_REMINDER_PER_HOUR", 10),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		RawQueryAdHoc:            getEnvBool("RAW_QUERY_ADHOC", true),
+		RawQueryExportTimeoutSec: getEnvInt("RAW_QUERY_EXPORT_TIMEOUT_SECONDS", 0),
 	}
 
+	apiKeys, err := parseAPIKeys(getEnv("RAW_QUERY_API_KEYS", ""))
+	if err != nil {
+		return nil, &ValidationError{Field: "RawQueryAPIKeys", Message: err.Error()}
+	}
+	cfg.RawQueryAPIKeys = apiKeys
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -59,11 +148,102 @@ func (c *Config) Validate() error {
 		return &ValidationError{Field: "WorkerInterval", Message: "cannot exceed 3600 seconds (1 hour)"}
 	}
 
+	// Validate worker mode (empty = poll)
+	if c.WorkerMode != "" && c.WorkerMode != WorkerModePoll && c.WorkerMode != WorkerModeScheduler {
+		return &ValidationError{Field: "WorkerMode", Message: "must be poll or scheduler"}
+	}
+	if c.WorkerMode == WorkerModeScheduler && (c.WorkerResyncSec < 10 || c.WorkerResyncSec > 86400) {
+		return &ValidationError{Field: "WorkerResyncSec", Message: "must be between 10 and 86400"}
+	}
+
+	// Validate WorkerConcurrency (0 = use default)
+	if c.WorkerConcurrency < 0 || c.WorkerConcurrency > 256 {
+		return &ValidationError{Field: "WorkerConcurrency", Message: "must be between 0 and 256"}
+	}
+
+	// Validate leasing (0 = disabled); the lease must outlive a tick, including FCM retries
+	if c.WorkerLeaseSec != 0 && (c.WorkerLeaseSec < 30 || c.WorkerLeaseSec > 86400) {
+		return &ValidationError{Field: "WorkerLeaseSec", Message: "must be 0 or between 30 and 86400"}
+	}
+	if c.WorkerClaimBatch < 0 {
+		return &ValidationError{Field: "WorkerClaimBatch", Message: "cannot be negative"}
+	}
+
 	// Validate FCMCredentials
 	if c.FCMCredentials == "" {
 		return &ValidationError{Field: "FCMCredentials", Message: "cannot be empty"}
 	}
 
+	// Validate FCM endpoint override
+	if c.FCMEndpoint != "" {
+		u, err := url.Parse(c.FCMEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ValidationError{Field: "FCMEndpoint", Message: "must be an http(s) URL"}
+		}
+	}
+
+	// Validate FCM retry settings (0 attempts = send once, no retry)
+	if c.FCMSendMaxAttempts < 0 || c.FCMSendMaxAttempts > 10 {
+		return &ValidationError{Field: "FCMSendMaxAttempts", Message: "must be between 0 and 10"}
+	}
+	if c.FCMRetryBaseMs < 0 {
+		return &ValidationError{Field: "FCMRetryBaseMs", Message: "cannot be negative"}
+	}
+	if c.FCMRetryMaxMs < 0 {
+		return &ValidationError{Field: "FCMRetryMaxMs", Message: "cannot be negative"}
+	}
+	if c.FCMRetryMaxMs > 0 && c.FCMRetryMaxMs < c.FCMRetryBaseMs {
+		return &ValidationError{Field: "FCMRetryMaxMs", Message: "cannot be less than FCMRetryBaseMs"}
+	}
+
+	// Validate circuit breaker settings (0 = use default)
+	if c.CircuitFailureThreshold < 0 || c.CircuitFailureThreshold > 100 {
+		return &ValidationError{Field: "CircuitFailureThreshold", Message: "must be between 0 and 100"}
+	}
+	if c.CircuitOpenSeconds < 0 || c.CircuitOpenSeconds > 3600 {
+		return &ValidationError{Field: "CircuitOpenSeconds", Message: "must be between 0 and 3600"}
+	}
+
+	// Validate rate limits (0 = unlimited)
+	if c.RateLimitGlobalPerMinute < 0 {
+		return &ValidationError{Field: "RateLimitGlobalPerMinute", Message: "cannot be negative"}
+	}
+	if c.RateLimitUserPerMinute < 0 {
+		return &ValidationError{Field: "RateLimitUserPerMinute", Message: "cannot be negative"}
+	}
+	if c.RateLimitReminderPerHour < 0 {
+		return &ValidationError{Field: "RateLimitReminderPerHour", Message: "cannot be negative"}
+	}
+
+	// Validate SMTP settings (only when email escalation is enabled)
+	if c.SMTPHost != "" {
+		if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+			return &ValidationError{Field: "SMTPPort", Message: "must be between 1 and 65535"}
+		}
+		if _, err := mail.ParseAddress(c.SMTPFrom); err != nil {
+			return &ValidationError{Field: "SMTPFrom", Message: "must be a valid email address"}
+		}
+	}
+
+	if c.RawQueryExportTimeoutSec < 0 {
+		return &ValidationError{Field: "RawQueryExportTimeoutSec", Message: "cannot be negative"}
+	}
+
+	// Validate raw query API keys
+	names := make(map[string]bool, len(c.RawQueryAPIKeys))
+	for _, key := range c.RawQueryAPIKeys {
+		if key.Name == "" {
+			return &ValidationError{Field: "RawQueryAPIKeys", Message: "key name cannot be empty"}
+		}
+		if names[key.Name] {
+			return &ValidationError{Field: "RawQueryAPIKeys", Message: fmt.Sprintf("duplicate key name %q", key.Name)}
+		}
+		names[key.Name] = true
+		if len(key.Key) < minAPIKeyLength {
+			return &ValidationError{Field: "RawQueryAPIKeys", Message: fmt.Sprintf("key %q must be at least %d characters", key.Name, minAPIKeyLength)}
+		}
+	}
+
 	// Validate Environment
 	validEnvs := []string{"development", "production", "testing"}
 	if !contains(validEnvs, c.Environment) {
@@ -106,6 +286,49 @@ func getEnvInt(key string, fallback int) int {
 	return fallback
 }
 
+// getEnvBool gets environment variable as boolean with fallback
+func getEnvBool(key string, fallback bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return fallback
+}
+
+// parseAPIKeys parses name:key:read|write[:table,table] entries separated by ";"
+func parseAPIKeys(value string) ([]APIKey, error) {
+	var keys []APIKey
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid entry %q, expected name:key:read|write[:tables]", parts[0])
+		}
+
+		key := APIKey{Name: parts[0], Key: parts[1]}
+		switch parts[2] {
+		case "read":
+		case "write":
+			key.Write = true
+		default:
+			return nil, fmt.Errorf("key %q: scope must be read or write", key.Name)
+		}
+		if len(parts) == 4 {
+			for _, table := range strings.Split(parts[3], ",") {
+				if table = strings.TrimSpace(table); table != "" {
+					key.Tables = append(key.Tables, table)
+				}
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 // contains checks if slice contains string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -41,8 +41,13 @@ func TestLoad_Defaults(t *testing.T) {
 	assert.NotNil(t, cfg)
 	assert.Equal(t, "127.0.0.1:8888", cfg.ServerAddr)
 	assert.Equal(t, 10, cfg.WorkerInterval)
+	assert.Equal(t, WorkerModePoll, cfg.WorkerMode)
+	assert.Equal(t, 300, cfg.WorkerLeaseSec)
+	assert.Equal(t, 500, cfg.WorkerClaimBatch)
 	assert.Equal(t, "./firebase-credentials.json", cfg.FCMCredentials)
 	assert.Equal(t, "development", cfg.Environment)
+	assert.True(t, cfg.RawQueryAdHoc)
+	assert.Equal(t, 0, cfg.RawQueryExportTimeoutSec)
 }
 
 func TestValidate_Success(t *testing.T) {
@@ -141,6 +146,143 @@ func TestValidate_InvalidEnvironment(t *testing.T) {
 	assert.Contains(t, err.Error(), "must be one of")
 }
 
+func TestValidate_InvalidFCMRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(c *Config)
+		field    string
+		expected string
+	}{
+		{"negative attempts", func(c *Config) { c.FCMSendMaxAttempts = -1 }, "FCMSendMaxAttempts", "must be between 0 and 10"},
+		{"too many attempts", func(c *Config) { c.FCMSendMaxAttempts = 11 }, "FCMSendMaxAttempts", "must be between 0 and 10"},
+		{"negative base delay", func(c *Config) { c.FCMRetryBaseMs = -1 }, "FCMRetryBaseMs", "cannot be negative"},
+		{"max below base", func(c *Config) { c.FCMRetryBaseMs = 1000; c.FCMRetryMaxMs = 500 }, "FCMRetryMaxMs", "cannot be less than"},
+		{"negative circuit threshold", func(c *Config) { c.CircuitFailureThreshold = -1 }, "CircuitFailureThreshold", "must be between 0 and 100"},
+		{"circuit open too long", func(c *Config) { c.CircuitOpenSeconds = 3601 }, "CircuitOpenSeconds", "must be between 0 and 3600"},
+		{"negative worker concurrency", func(c *Config) { c.WorkerConcurrency = -1 }, "WorkerConcurrency", "must be between 0 and 256"},
+		{"worker concurrency too high", func(c *Config) { c.WorkerConcurrency = 257 }, "WorkerConcurrency", "must be between 0 and 256"},
+		{"unknown worker mode", func(c *Config) { c.WorkerMode = "cron" }, "WorkerMode", "must be poll or scheduler"},
+		{"scheduler resync too short", func(c *Config) { c.WorkerMode = WorkerModeScheduler; c.WorkerResyncSec = 1 }, "WorkerResyncSec", "must be between 10 and 86400"},
+		{"worker lease too short", func(c *Config) { c.WorkerLeaseSec = 10 }, "WorkerLeaseSec", "must be 0 or between 30 and 86400"},
+		{"negative claim batch", func(c *Config) { c.WorkerClaimBatch = -1 }, "WorkerClaimBatch", "cannot be negative"},
+		{"negative global rate limit", func(c *Config) { c.RateLimitGlobalPerMinute = -1 }, "RateLimitGlobalPerMinute", "cannot be negative"},
+		{"negative user rate limit", func(c *Config) { c.RateLimitUserPerMinute = -1 }, "RateLimitUserPerMinute", "cannot be negative"},
+		{"negative reminder rate limit", func(c *Config) { c.RateLimitReminderPerHour = -1 }, "RateLimitReminderPerHour", "cannot be negative"},
+		{"negative export timeout", func(c *Config) { c.RawQueryExportTimeoutSec = -1 }, "RawQueryExportTimeoutSec", "cannot be negative"},
+		{"invalid SMTP port", func(c *Config) { c.SMTPHost = "smtp.example.com"; c.SMTPFrom = "noreply@example.com" }, "SMTPPort", "must be between 1 and 65535"},
+		{"invalid SMTP sender", func(c *Config) { c.SMTPHost = "smtp.example.com"; c.SMTPPort = 587; c.SMTPFrom = "remiaq" }, "SMTPFrom", "must be a valid email address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ServerAddr:     "localhost:8080",
+				WorkerInterval: 60,
+				FCMCredentials: "./credentials.json",
+				Environment:    "development",
+			}
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.field)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestValidate_FCMEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		valid    bool
+	}{
+		{"empty uses default", "", true},
+		{"local fake", "http://127.0.0.1:9099/v1", true},
+		{"https", "https://fcm.example.com/v1", true},
+		{"missing scheme", "127.0.0.1:9099", false},
+		{"unsupported scheme", "ftp://example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ServerAddr:     "localhost:8080",
+				WorkerInterval: 60,
+				FCMCredentials: "./credentials.json",
+				FCMEndpoint:    tt.endpoint,
+				Environment:    "development",
+			}
+
+			err := cfg.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "FCMEndpoint")
+			}
+		})
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	t.Run("should parse scopes and tables", func(t *testing.T) {
+		keys, err := parseAPIKeys(" reporting:0123456789abcdef:read:reminders, deliveries ; ops:fedcba9876543210:write ;")
+		assert.NoError(t, err)
+		assert.Equal(t, []APIKey{
+			{Name: "reporting", Key: "0123456789abcdef", Tables: []string{"reminders", "deliveries"}},
+			{Name: "ops", Key: "fedcba9876543210", Write: true},
+		}, keys)
+	})
+
+	t.Run("should reject malformed entries", func(t *testing.T) {
+		for _, value := range []string{"ops", "ops:key", "ops:key:admin", "ops:key:read:t:extra"} {
+			_, err := parseAPIKeys(value)
+			assert.Error(t, err, value)
+		}
+	})
+
+	t.Run("should fail Load", func(t *testing.T) {
+		os.Setenv("RAW_QUERY_API_KEYS", "ops:0123456789abcdef:admin")
+		defer os.Unsetenv("RAW_QUERY_API_KEYS")
+
+		_, err := Load()
+		assert.EqualError(t, err, `config validation error for RawQueryAPIKeys: key "ops": scope must be read or write`)
+	})
+}
+
+func TestValidate_RawQueryAPIKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []APIKey
+		expected string
+	}{
+		{"valid", []APIKey{{Name: "a", Key: "0123456789abcdef"}, {Name: "b", Key: "fedcba9876543210", Write: true}}, ""},
+		{"empty name", []APIKey{{Key: "0123456789abcdef"}}, "key name cannot be empty"},
+		{"duplicate name", []APIKey{{Name: "a", Key: "0123456789abcdef"}, {Name: "a", Key: "fedcba9876543210"}}, `duplicate key name "a"`},
+		{"short key", []APIKey{{Name: "a", Key: "secret"}}, `key "a" must be at least 16 characters`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ServerAddr:      "localhost:8080",
+				WorkerInterval:  60,
+				FCMCredentials:  "./credentials.json",
+				Environment:     "development",
+				RawQueryAPIKeys: tt.keys,
+			}
+
+			err := cfg.Validate()
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, "config validation error for RawQueryAPIKeys: "+tt.expected)
+			}
+		})
+	}
+}
+
 func TestEnvironmentCheckers(t *testing.T) {
 	tests := []struct {
 		env           string
@@ -191,6 +333,35 @@ func TestGetEnvInt(t *testing.T) {
 	}
 }
 
+func TestGetEnvBool(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		fallback bool
+		expected bool
+	}{
+		{"false", "false", true, false},
+		{"true", "1", false, true},
+		{"invalid boolean", "maybe", true, true},
+		{"empty value", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "TEST_BOOL_VAR"
+			if tt.envValue != "" {
+				os.Setenv(key, tt.envValue)
+				defer os.Unsetenv(key)
+			} else {
+				os.Unsetenv(key)
+			}
+
+			result := getEnvBool(key, tt.fallback)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
 func TestContains(t *testing.T) {
 	slice := []string{"apple", "banana", "cherry"}
 
@@ -9,6 +9,7 @@ require (
 	github.com/pocketbase/pocketbase v0.29.3
 	github.com/stretchr/testify v1.11.1
 	google.golang.org/api v0.170.0
+	modernc.org/sqlite v1.38.2
 )
 
 require (
@@ -73,5 +74,4 @@ require (
 	modernc.org/libc v1.66.3 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.11.0 // indirect
-	modernc.org/sqlite v1.38.2 // indirect
 )
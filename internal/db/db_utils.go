@@ -2,37 +2,82 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/pocketbase/dbx"
-	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
 )
 
 // DBHelperInterface defines the interface for database operations
-// This allows for easy mocking in tests
+// This allows for easy mocking in tests.
+// Every call runs with ctx: cancelling it aborts the query, and a transaction started by
+// InTransaction is carried in ctx, so calls made with the transaction's ctx join it.
 type DBHelperInterface interface {
-	GetOneRow(query string, params dbx.Params) (dbx.NullStringMap, error)
-	GetAllRows(query string, params dbx.Params) ([]dbx.NullStringMap, error)
-	Exec(query string, params dbx.Params) error
-	Count(query string, params dbx.Params) (int, error)
-	Exists(query string, params dbx.Params) (bool, error)
+	GetOneRow(ctx context.Context, query string, params dbx.Params) (dbx.NullStringMap, error)
+	GetAllRows(ctx context.Context, query string, params dbx.Params) ([]dbx.NullStringMap, error)
+	Exec(ctx context.Context, query string, params dbx.Params) error
+	ExecResult(ctx context.Context, query string, params dbx.Params) (sql.Result, error)
+	Count(ctx context.Context, query string, params dbx.Params) (int, error)
+	Exists(ctx context.Context, query string, params dbx.Params) (bool, error)
+
+	// QueryRows runs a query and passes its rows to handler as they are read from the cursor
+	QueryRows(ctx context.Context, query string, params dbx.Params, handler RowHandler) error
+
+	// InTransaction runs fn in a transaction carried by the ctx passed to fn, committing when
+	// fn returns nil and rolling back otherwise. Inside a transaction it joins the outer one.
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
+// RowHandler receives the rows of QueryRows without buffering the whole result
+type RowHandler interface {
+	Columns(names, declTypes []string) error // once, before the first row; declTypes are "" for expressions
+	Row(values []any) error                  // driver values (int64, float64, string, []byte, time.Time, nil), reused between rows
+}
+
+// DefaultTimeout bounds each query whose ctx has no deadline of its own.
+const DefaultTimeout = 30 * time.Second
+
 type DBHelper struct {
-	App *pocketbase.PocketBase
+	App     core.App
+	Timeout time.Duration // per-query timeout when ctx has no deadline; <= 0 = none
+}
+
+// NewDBHelper returns a helper bound to the current PocketBase app, with DefaultTimeout
+func NewDBHelper(app core.App) *DBHelper {
+	return &DBHelper{App: app, Timeout: DefaultTimeout}
+}
+
+type noTimeoutKey struct{}
+
+// WithoutTimeout returns a ctx whose queries aren't bounded by the helper Timeout, for
+// long-running reads such as streamed exports. Cancelling ctx still stops them.
+func WithoutTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
 }
 
-// NewDBHelper returns a helper bound to the current PocketBase app
-func NewDBHelper(app *pocketbase.PocketBase) *DBHelper {
-	return &DBHelper{App: app}
+// query builds a query bound to ctx, on the transaction carried by ctx if any. The returned
+// cancel func releases the per-query timeout and must be called once the query is done.
+func (h *DBHelper) query(ctx context.Context, statement string, params dbx.Params) (*dbx.Query, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && h.Timeout > 0 && ctx.Value(noTimeoutKey{}) == nil {
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+	}
+	app := h.App
+	if tx, ok := txFromContext(ctx); ok {
+		app = tx
+	}
+	return app.DB().NewQuery(statement).Bind(params).WithContext(ctx), cancel
 }
 
 // GetOneRow runs a query and returns a single row as raw map.
 // Returns error if no row found or query fails.
-func (h *DBHelper) GetOneRow(query string, params dbx.Params) (dbx.NullStringMap, error) {
+func (h *DBHelper) GetOneRow(ctx context.Context, query string, params dbx.Params) (dbx.NullStringMap, error) {
 	var result dbx.NullStringMap
-	q := h.App.DB().NewQuery(query).Bind(params)
+	q, cancel := h.query(ctx, query, params)
+	defer cancel()
 	err := q.One(&result)
 	if err != nil {
 		log.Printf("[DBHelper] GetOneRow failed (query=%s): %v", query, err)
@@ -44,9 +89,9 @@ func (h *DBHelper) GetOneRow(query string, params dbx.Params) (dbx.NullStringMap
 // GetOne is a generic function that runs a query and returns a single row mapped to struct T.
 // Go doesn't support generic methods, so this is implemented as a function.
 // Accepts both *DBHelper and DBHelperInterface for flexibility.
-// Usage: user, err := db.GetOne[User](helper, "SELECT * FROM users WHERE id = {:id}", dbx.Params{"id": 1})
-func GetOne[T any](h DBHelperInterface, query string, params dbx.Params) (*T, error) {
-	raw, err := h.GetOneRow(query, params)
+// Usage: user, err := db.GetOne[User](ctx, helper, "SELECT * FROM users WHERE id = {:id}", dbx.Params{"id": 1})
+func GetOne[T any](ctx context.Context, h DBHelperInterface, query string, params dbx.Params) (*T, error) {
+	raw, err := h.GetOneRow(ctx, query, params)
 	if err != nil {
 		return nil, err
 	}
@@ -55,9 +100,9 @@ func GetOne[T any](h DBHelperInterface, query string, params dbx.Params) (*T, er
 
 // GetOneWithConfig is a generic function that runs a query and returns a single row mapped to struct T with config.
 // Supports custom mappers and required field validation.
-// Usage: user, err := db.GetOneWithConfig[User](helper, query, params, &db.MapperConfig{RequiredFields: []string{"ID"}})
-func GetOneWithConfig[T any](h DBHelperInterface, query string, params dbx.Params, cfg *MapperConfig) (*T, error) {
-	raw, err := h.GetOneRow(query, params)
+// Usage: user, err := db.GetOneWithConfig[User](ctx, helper, query, params, &db.MapperConfig{RequiredFields: []string{"ID"}})
+func GetOneWithConfig[T any](ctx context.Context, h DBHelperInterface, query string, params dbx.Params, cfg *MapperConfig) (*T, error) {
+	raw, err := h.GetOneRow(ctx, query, params)
 	if err != nil {
 		return nil, err
 	}
@@ -66,9 +111,10 @@ func GetOneWithConfig[T any](h DBHelperInterface, query string, params dbx.Param
 
 // GetAllRows runs a query and returns all rows as raw maps.
 // Returns error if query fails.
-func (h *DBHelper) GetAllRows(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+func (h *DBHelper) GetAllRows(ctx context.Context, query string, params dbx.Params) ([]dbx.NullStringMap, error) {
 	var results []dbx.NullStringMap
-	q := h.App.DB().NewQuery(query).Bind(params)
+	q, cancel := h.query(ctx, query, params)
+	defer cancel()
 	err := q.All(&results)
 	if err != nil {
 		log.Printf("[DBHelper] GetAllRows failed (query=%s): %v", query, err)
@@ -78,9 +124,9 @@ func (h *DBHelper) GetAllRows(query string, params dbx.Params) ([]dbx.NullString
 }
 
 // GetAll is a generic function that runs a query and returns all rows mapped to slice of struct T.
-// Usage: users, err := db.GetAll[User](helper, "SELECT * FROM users", dbx.Params{})
-func GetAll[T any](h DBHelperInterface, query string, params dbx.Params) ([]T, error) {
-	rows, err := h.GetAllRows(query, params)
+// Usage: users, err := db.GetAll[User](ctx, helper, "SELECT * FROM users", dbx.Params{})
+func GetAll[T any](ctx context.Context, h DBHelperInterface, query string, params dbx.Params) ([]T, error) {
+	rows, err := h.GetAllRows(ctx, query, params)
 	if err != nil {
 		return nil, err
 	}
@@ -98,9 +144,9 @@ func GetAll[T any](h DBHelperInterface, query string, params dbx.Params) ([]T, e
 }
 
 // GetAllWithConfig is a generic function that runs a query and returns all rows mapped to slice of struct T with config.
-// Usage: users, err := db.GetAllWithConfig[User](helper, query, params, &db.MapperConfig{RequiredFields: []string{"ID"}})
-func GetAllWithConfig[T any](h DBHelperInterface, query string, params dbx.Params, cfg *MapperConfig) ([]T, error) {
-	rows, err := h.GetAllRows(query, params)
+// Usage: users, err := db.GetAllWithConfig[User](ctx, helper, query, params, &db.MapperConfig{RequiredFields: []string{"ID"}})
+func GetAllWithConfig[T any](ctx context.Context, h DBHelperInterface, query string, params dbx.Params, cfg *MapperConfig) ([]T, error) {
+	rows, err := h.GetAllRows(ctx, query, params)
 	if err != nil {
 		return nil, err
 	}
@@ -119,35 +165,38 @@ func GetAllWithConfig[T any](h DBHelperInterface, query string, params dbx.Param
 
 // Exec runs INSERT, UPDATE, DELETE queries.
 // Returns error if execution fails.
-func (h *DBHelper) Exec(query string, params dbx.Params) error {
-	q := h.App.DB().NewQuery(query).Bind(params)
-	_, err := q.Execute()
+func (h *DBHelper) Exec(ctx context.Context, query string, params dbx.Params) error {
+	_, err := h.ExecResult(ctx, query, params)
+	return err
+}
+
+// ExecResult runs INSERT, UPDATE, DELETE queries and returns the driver result
+// (rows affected, last insert id).
+func (h *DBHelper) ExecResult(ctx context.Context, query string, params dbx.Params) (sql.Result, error) {
+	q, cancel := h.query(ctx, query, params)
+	defer cancel()
+	result, err := q.Execute()
 	if err != nil {
 		log.Printf("[DBHelper] Exec failed (query=%s): %v", query, err)
-		return err
+		return nil, err
 	}
-	return nil
+	return result, nil
 }
 
-// ExecWithContext runs INSERT, UPDATE, DELETE with context support.
-// Returns error if context is cancelled or execution fails.
-func (h *DBHelper) ExecWithContext(ctx context.Context, query string, params dbx.Params) error {
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-		return h.Exec(query, params)
-	}
+// InTransaction runs fn in a transaction of the helper's app. See the package-level InTransaction.
+func (h *DBHelper) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return InTransaction(ctx, h.App, fn)
 }
 
 // Count runs a COUNT query and returns the row count.
 // Query should use "SELECT COUNT(*) AS count" pattern for predictable parsing.
 // Example: "SELECT COUNT(*) AS count FROM users WHERE status = {:status}"
-func (h *DBHelper) Count(query string, params dbx.Params) (int, error) {
+func (h *DBHelper) Count(ctx context.Context, query string, params dbx.Params) (int, error) {
 	var result struct {
 		Count int `db:"count"`
 	}
-	q := h.App.DB().NewQuery(query).Bind(params)
+	q, cancel := h.query(ctx, query, params)
+	defer cancel()
 	err := q.One(&result)
 	if err != nil {
 		log.Printf("[DBHelper] Count failed (query=%s): %v", query, err)
@@ -159,13 +208,14 @@ func (h *DBHelper) Count(query string, params dbx.Params) (int, error) {
 // Exists checks if a query returns any rows using SQL EXISTS for better performance.
 // Much faster than Count for large datasets since it stops after finding the first match.
 // Example: "SELECT * FROM users WHERE email = {:email}"
-func (h *DBHelper) Exists(query string, params dbx.Params) (bool, error) {
+func (h *DBHelper) Exists(ctx context.Context, query string, params dbx.Params) (bool, error) {
 	// Wrap query with EXISTS to get true/false
 	existsQuery := fmt.Sprintf("SELECT EXISTS(%s) AS ok", query)
 	var result struct {
 		Ok bool `db:"ok"`
 	}
-	q := h.App.DB().NewQuery(existsQuery).Bind(params)
+	q, cancel := h.query(ctx, existsQuery, params)
+	defer cancel()
 	err := q.One(&result)
 	if err != nil {
 		log.Printf("[DBHelper] Exists failed (query=%s): %v", query, err)
@@ -173,3 +223,45 @@ func (h *DBHelper) Exists(query string, params dbx.Params) (bool, error) {
 	}
 	return result.Ok, nil
 }
+
+// QueryRows runs a query and hands each row to handler as soon as it is scanned, so large
+// results are never held in memory. An error returned by handler stops the iteration.
+func (h *DBHelper) QueryRows(ctx context.Context, query string, params dbx.Params, handler RowHandler) error {
+	q, cancel := h.query(ctx, query, params)
+	defer cancel()
+	rows, err := q.Rows()
+	if err != nil {
+		log.Printf("[DBHelper] QueryRows failed (query=%s): %v", query, err)
+		return err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	columns := make([]string, len(columnTypes))
+	declTypes := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = columnType.Name()
+		declTypes[i] = columnType.DatabaseTypeName()
+	}
+	if err := handler.Columns(columns, declTypes); err != nil {
+		return err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if err := handler.Row(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"testing"
 	"time"
@@ -28,7 +30,7 @@ func TestGetOne_SuccessMapping(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.User](mockHelper, "SELECT * FROM users WHERE id = ?", dbx.Params{})
+		result, err := GetOne[models.User](context.Background(), mockHelper, "SELECT * FROM users WHERE id = ?", dbx.Params{})
 		require.NoError(t, err)
 		require.NotNil(t, result)
 		assert.Equal(t, "user123", result.ID)
@@ -47,12 +49,32 @@ func TestGetOne_SuccessMapping(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.SystemStatus](mockHelper, "SELECT * FROM system_status", dbx.Params{})
+		result, err := GetOne[models.SystemStatus](context.Background(), mockHelper, "SELECT * FROM system_status", dbx.Params{})
 		require.NoError(t, err)
 		require.NotNil(t, result)
 		assert.Equal(t, 1, result.ID)
 		assert.True(t, result.WorkerEnabled)
 	})
+
+	t.Run("should map pointer fields and leave empty ones nil", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+				return dbx.NullStringMap{
+					"mid":                {String: "1", Valid: true},
+					"circuit_state":      {String: "open", Valid: true},
+					"error_at":           {String: "2025-10-18 09:00:00.000Z", Valid: true},
+					"circuit_changed_at": {String: "", Valid: true},
+				}, nil
+			},
+		}
+
+		result, err := GetOne[models.SystemStatus](context.Background(), mockHelper, "SELECT * FROM system_status", dbx.Params{})
+		require.NoError(t, err)
+		require.NotNil(t, result.ErrorAt)
+		assert.Equal(t, time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC), result.ErrorAt.UTC())
+		assert.Nil(t, result.CircuitChangedAt)
+		assert.Equal(t, "open", result.CircuitState)
+	})
 }
 
 // TestGetOne_MappingErrors tests mapping error cases
@@ -71,7 +93,7 @@ func TestGetOne_MappingErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.User](mockHelper, "SELECT * FROM users", dbx.Params{})
+		result, err := GetOne[models.User](context.Background(), mockHelper, "SELECT * FROM users", dbx.Params{})
 		require.NoError(t, err, "mapper allows missing fields - uses zero values")
 		require.NotNil(t, result)
 		assert.Equal(t, "", result.ID) // Empty string for missing string field
@@ -90,7 +112,7 @@ func TestGetOne_MappingErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.SystemStatus](mockHelper, "SELECT * FROM system_status", dbx.Params{})
+		result, err := GetOne[models.SystemStatus](context.Background(), mockHelper, "SELECT * FROM system_status", dbx.Params{})
 		assert.Error(t, err, "should error when 'mid' cannot convert to int")
 		assert.Nil(t, result)
 		// Error message uses field name not db tag
@@ -109,7 +131,7 @@ func TestGetOne_MappingErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.SystemStatus](mockHelper, "SELECT * FROM system_status", dbx.Params{})
+		result, err := GetOne[models.SystemStatus](context.Background(), mockHelper, "SELECT * FROM system_status", dbx.Params{})
 		assert.Error(t, err, "should error on invalid boolean value")
 		assert.Nil(t, result)
 	})
@@ -128,7 +150,7 @@ func TestGetOne_MappingErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.User](mockHelper, "SELECT * FROM users", dbx.Params{})
+		result, err := GetOne[models.User](context.Background(), mockHelper, "SELECT * FROM users", dbx.Params{})
 		assert.Error(t, err, "should error on invalid time format")
 		assert.Nil(t, result)
 		// Error message uses field name not db tag
@@ -147,7 +169,7 @@ func TestGetOne_MappingErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.SystemStatus](mockHelper, "SELECT * FROM system_status", dbx.Params{})
+		result, err := GetOne[models.SystemStatus](context.Background(), mockHelper, "SELECT * FROM system_status", dbx.Params{})
 		require.NoError(t, err, "mapper allows NULL fields - uses zero values")
 		require.NotNil(t, result)
 		assert.Equal(t, 0, result.ID) // Zero value for missing int field
@@ -181,7 +203,7 @@ func TestGetAll_MappingErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetAll[models.User](mockHelper, "SELECT * FROM users", dbx.Params{})
+		result, err := GetAll[models.User](context.Background(), mockHelper, "SELECT * FROM users", dbx.Params{})
 		assert.Error(t, err, "should error when any row fails mapping")
 		assert.Nil(t, result)
 		assert.Contains(t, err.Error(), "row 1") // Error on row 2 (0-indexed = row 1)
@@ -211,7 +233,7 @@ func TestGetAll_MappingErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetAll[models.User](mockHelper, "SELECT * FROM users", dbx.Params{})
+		result, err := GetAll[models.User](context.Background(), mockHelper, "SELECT * FROM users", dbx.Params{})
 		require.NoError(t, err)
 		require.Len(t, result, 2)
 		assert.Equal(t, "user1", result[0].ID)
@@ -228,7 +250,7 @@ func TestGetOne_DatabaseErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.User](mockHelper, "SELECT * FROM users", dbx.Params{})
+		result, err := GetOne[models.User](context.Background(), mockHelper, "SELECT * FROM users", dbx.Params{})
 		require.Error(t, err)
 		assert.Nil(t, result)
 		assert.Contains(t, err.Error(), "database connection lost")
@@ -241,52 +263,79 @@ func TestGetOne_DatabaseErrors(t *testing.T) {
 			},
 		}
 
-		result, err := GetOne[models.User](mockHelper, "SELECT * FROM users WHERE id = ?", dbx.Params{})
+		result, err := GetOne[models.User](context.Background(), mockHelper, "SELECT * FROM users WHERE id = ?", dbx.Params{})
 		require.Error(t, err)
 		assert.Nil(t, result)
 	})
 }
 
+// mockResult is a fixed sql.Result
+type mockResult struct{}
+
+func (mockResult) LastInsertId() (int64, error) { return 0, nil }
+func (mockResult) RowsAffected() (int64, error) { return 0, nil }
+
 // MockDBHelper for testing
 type MockDBHelper struct {
 	GetOneRowFn  func(query string, params dbx.Params) (dbx.NullStringMap, error)
 	GetAllRowsFn func(query string, params dbx.Params) ([]dbx.NullStringMap, error)
 	ExecFn       func(query string, params dbx.Params) error
+	ExecResultFn func(query string, params dbx.Params) (sql.Result, error)
 	CountFn      func(query string, params dbx.Params) (int, error)
 	ExistsFn     func(query string, params dbx.Params) (bool, error)
+	QueryRowsFn  func(query string, params dbx.Params, handler RowHandler) error
 }
 
-func (m *MockDBHelper) GetOneRow(query string, params dbx.Params) (dbx.NullStringMap, error) {
+func (m *MockDBHelper) GetOneRow(ctx context.Context, query string, params dbx.Params) (dbx.NullStringMap, error) {
 	if m.GetOneRowFn != nil {
 		return m.GetOneRowFn(query, params)
 	}
 	return nil, nil
 }
 
-func (m *MockDBHelper) GetAllRows(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+func (m *MockDBHelper) GetAllRows(ctx context.Context, query string, params dbx.Params) ([]dbx.NullStringMap, error) {
 	if m.GetAllRowsFn != nil {
 		return m.GetAllRowsFn(query, params)
 	}
 	return nil, nil
 }
 
-func (m *MockDBHelper) Exec(query string, params dbx.Params) error {
+func (m *MockDBHelper) Exec(ctx context.Context, query string, params dbx.Params) error {
 	if m.ExecFn != nil {
 		return m.ExecFn(query, params)
 	}
 	return nil
 }
 
-func (m *MockDBHelper) Count(query string, params dbx.Params) (int, error) {
+// ExecResult uses ExecResultFn, falling back to ExecFn with an empty result
+func (m *MockDBHelper) ExecResult(ctx context.Context, query string, params dbx.Params) (sql.Result, error) {
+	if m.ExecResultFn != nil {
+		return m.ExecResultFn(query, params)
+	}
+	return mockResult{}, m.Exec(ctx, query, params)
+}
+
+func (m *MockDBHelper) Count(ctx context.Context, query string, params dbx.Params) (int, error) {
 	if m.CountFn != nil {
 		return m.CountFn(query, params)
 	}
 	return 0, nil
 }
 
-func (m *MockDBHelper) Exists(query string, params dbx.Params) (bool, error) {
+func (m *MockDBHelper) Exists(ctx context.Context, query string, params dbx.Params) (bool, error) {
 	if m.ExistsFn != nil {
 		return m.ExistsFn(query, params)
 	}
 	return false, nil
 }
+
+func (m *MockDBHelper) QueryRows(ctx context.Context, query string, params dbx.Params, handler RowHandler) error {
+	if m.QueryRowsFn != nil {
+		return m.QueryRowsFn(query, params, handler)
+	}
+	return handler.Columns(nil, nil)
+}
+
+func (m *MockDBHelper) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -38,7 +38,8 @@ type FieldInfo struct {
 
 // MapNullStringMapToStruct maps dbx.NullStringMap to any struct T.
 // Uses reflection to automatically parse field types based on db struct tags.
-// Supports: bool, int*, uint*, float*, string, time.Time, structs (JSON), slices, maps.
+// Supports: bool, int*, uint*, float*, string, time.Time, structs (JSON), slices, maps
+// and pointers to any of these (nil when the column is empty).
 func MapNullStringMapToStruct[T any](m dbx.NullStringMap) (*T, error) {
 	return MapNullStringMapToStructWithConfig[T](m, &MapperConfig{})
 }
@@ -145,7 +146,22 @@ func mapFieldValue(fieldVal reflect.Value, fieldType reflect.Type, value string,
 			}
 		}
 
+	case reflect.Ptr:
+		// Optional fields: empty/null leaves the pointer nil
+		if value == "" || value == "null" {
+			return nil
+		}
+		elem := reflect.New(fieldType.Elem())
+		if err := mapFieldValue(elem.Elem(), fieldType.Elem(), value, fieldName); err != nil {
+			return err
+		}
+		fieldVal.Set(elem)
+
 	case reflect.Slice, reflect.Map:
+		// Cột JSON chưa có dữ liệu: giữ giá trị rỗng
+		if value == "" {
+			return nil
+		}
 		// Try JSON unmarshal for complex types
 		if err := json.Unmarshal([]byte(value), fieldVal.Addr().Interface()); err != nil {
 			return fmt.Errorf("invalid JSON for field %s: %w", fieldName, err)
@@ -1,29 +1,43 @@
 package db
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 )
 
+type txKey struct{}
+
 // InTransaction runs a function in a database transaction.
 // It automatically commits on nil error, or rolls back if fn returns error.
+// The transaction is carried in the ctx passed to fn: helper calls made with that ctx
+// run inside it, and an InTransaction call made with it joins it instead of nesting.
 // Example:
 //
-//	err := db.InTransaction(app, func(tx *db.DBHelper) error {
-//	    return tx.Exec("UPDATE users SET name={:n} WHERE id={:id}", dbx.Params{
+//	err := db.InTransaction(ctx, app, func(ctx context.Context) error {
+//	    return helper.Exec(ctx, "UPDATE users SET name={:n} WHERE id={:id}", dbx.Params{
 //	        "n": "John", "id": 1,
 //	    })
 //	})
-func InTransaction(app *pocketbase.PocketBase, fn func(tx *DBHelper) error) error {
+func InTransaction(ctx context.Context, app core.App, fn func(ctx context.Context) error) error {
 	if app == nil {
 		return fmt.Errorf("app cannot be nil")
 	}
+	if _, ok := txFromContext(ctx); ok {
+		return fn(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	return app.RunInTransaction(func(txApp core.App) error {
-		// Convert the transaction app to DBHelper
-		txHelper := &DBHelper{App: txApp.(*pocketbase.PocketBase)}
-		return fn(txHelper)
+		return fn(context.WithValue(ctx, txKey{}, txApp))
 	})
 }
+
+// txFromContext returns the transaction app carried by ctx.
+func txFromContext(ctx context.Context) (core.App, bool) {
+	tx, ok := ctx.Value(txKey{}).(core.App)
+	return tx, ok
+}
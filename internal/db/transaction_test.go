@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	_ "github.com/pocketbase/pocketbase/migrations" // system tables needed by Bootstrap
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHelper returns a helper on a fresh SQLite database with a notes table.
+func newTestHelper(t *testing.T) *DBHelper {
+	t.Helper()
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	require.NoError(t, app.Bootstrap())
+	t.Cleanup(func() { _ = app.ResetBootstrapState() })
+
+	h := NewDBHelper(app)
+	require.NoError(t, h.Exec(context.Background(), "CREATE TABLE notes (id TEXT PRIMARY KEY, body TEXT)", nil))
+	return h
+}
+
+func countNotes(t *testing.T, h *DBHelper) int {
+	t.Helper()
+	n, err := h.Count(context.Background(), "SELECT COUNT(*) AS count FROM notes", nil)
+	require.NoError(t, err)
+	return n
+}
+
+func TestInTransaction(t *testing.T) {
+	insert := func(ctx context.Context, h *DBHelper, id string) error {
+		return h.Exec(ctx, "INSERT INTO notes (id, body) VALUES ({:id}, 'x')", dbx.Params{"id": id})
+	}
+
+	t.Run("should commit calls made with the transaction ctx", func(t *testing.T) {
+		h := newTestHelper(t)
+
+		err := h.InTransaction(context.Background(), func(ctx context.Context) error {
+			if err := insert(ctx, h, "a"); err != nil {
+				return err
+			}
+			// Đọc trong transaction thấy dòng chưa commit
+			n, err := h.Count(ctx, "SELECT COUNT(*) AS count FROM notes", nil)
+			assert.Equal(t, 1, n)
+			return err
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, countNotes(t, h))
+	})
+
+	t.Run("should roll back every call when fn fails", func(t *testing.T) {
+		h := newTestHelper(t)
+
+		err := h.InTransaction(context.Background(), func(ctx context.Context) error {
+			require.NoError(t, insert(ctx, h, "a"))
+			require.NoError(t, insert(ctx, h, "b"))
+			return errors.New("boom")
+		})
+
+		assert.EqualError(t, err, "boom")
+		assert.Equal(t, 0, countNotes(t, h))
+	})
+
+	t.Run("should join an outer transaction", func(t *testing.T) {
+		h := newTestHelper(t)
+
+		err := InTransaction(context.Background(), h.App, func(ctx context.Context) error {
+			require.NoError(t, h.InTransaction(ctx, func(ctx context.Context) error {
+				return insert(ctx, h, "inner")
+			}))
+			return errors.New("outer fails")
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, countNotes(t, h), "inner work belongs to the outer transaction")
+	})
+
+	t.Run("should not start when ctx is already cancelled", func(t *testing.T) {
+		h := newTestHelper(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := false
+		err := h.InTransaction(ctx, func(ctx context.Context) error {
+			called = true
+			return nil
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, called)
+	})
+}
+
+func TestDBHelper_Context(t *testing.T) {
+	t.Run("should abort queries when ctx is cancelled", func(t *testing.T) {
+		h := newTestHelper(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := h.GetAllRows(ctx, "SELECT * FROM notes", nil)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Error(t, h.Exec(ctx, "INSERT INTO notes (id) VALUES ('a')", nil))
+		assert.Equal(t, 0, countNotes(t, h))
+	})
+
+	t.Run("should apply the default timeout when ctx has no deadline", func(t *testing.T) {
+		h := newTestHelper(t)
+		h.Timeout = time.Nanosecond
+
+		_, err := h.GetAllRows(context.Background(), "SELECT * FROM notes", nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should keep the deadline of ctx", func(t *testing.T) {
+		h := newTestHelper(t)
+		h.Timeout = time.Nanosecond
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := h.GetAllRows(ctx, "SELECT * FROM notes", nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should skip the default timeout WithoutTimeout", func(t *testing.T) {
+		h := newTestHelper(t)
+		h.Timeout = time.Nanosecond
+
+		var c rowCollector
+		assert.NoError(t, h.QueryRows(WithoutTimeout(context.Background()), "SELECT * FROM notes", nil, &c))
+		assert.ErrorIs(t, h.QueryRows(context.Background(), "SELECT * FROM notes", nil, &c), context.DeadlineExceeded)
+	})
+}
+
+func TestDBHelper_ExecResult(t *testing.T) {
+	t.Run("should report rows affected and last insert id", func(t *testing.T) {
+		h := newTestHelper(t)
+		ctx := context.Background()
+
+		result, err := h.ExecResult(ctx, "INSERT INTO notes (id, body) VALUES ('a', 'x'), ('b', 'x')", nil)
+		require.NoError(t, err)
+		rows, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), rows)
+		lastID, err := result.LastInsertId()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), lastID, "rowid of the last inserted row")
+
+		result, err = h.ExecResult(ctx, "UPDATE notes SET body = 'y' WHERE id = {:id}", dbx.Params{"id": "a"})
+		require.NoError(t, err)
+		rows, err = result.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), rows)
+	})
+}
+
+// rowCollector is a RowHandler keeping copies of the rows, stopping after limit rows if set
+type rowCollector struct {
+	columns, declTypes []string
+	rows               [][]any
+	limit              int
+}
+
+func (c *rowCollector) Columns(names, declTypes []string) error {
+	c.columns, c.declTypes = names, declTypes
+	return nil
+}
+
+func (c *rowCollector) Row(values []any) error {
+	if c.limit > 0 && len(c.rows) == c.limit {
+		return errors.New("limit reached")
+	}
+	c.rows = append(c.rows, append([]any(nil), values...))
+	return nil
+}
+
+func TestDBHelper_QueryRows(t *testing.T) {
+	t.Run("should pass typed rows in order", func(t *testing.T) {
+		h := newTestHelper(t)
+		ctx := context.Background()
+		require.NoError(t, h.Exec(ctx, "INSERT INTO notes (id, body) VALUES ('a', 'x'), ('b', NULL)", nil))
+
+		var c rowCollector
+		require.NoError(t, h.QueryRows(ctx, "SELECT id, body, length(id) AS n, 1.5 AS f FROM notes ORDER BY id", nil, &c))
+
+		assert.Equal(t, []string{"id", "body", "n", "f"}, c.columns)
+		assert.Equal(t, []string{"TEXT", "TEXT", "", ""}, c.declTypes)
+		assert.Equal(t, [][]any{{"a", "x", int64(1), 1.5}, {"b", nil, int64(1), 1.5}}, c.rows)
+	})
+
+	t.Run("should report columns of empty results", func(t *testing.T) {
+		h := newTestHelper(t)
+
+		var c rowCollector
+		require.NoError(t, h.QueryRows(context.Background(), "SELECT id FROM notes", nil, &c))
+
+		assert.Equal(t, []string{"id"}, c.columns)
+		assert.Empty(t, c.rows)
+	})
+
+	t.Run("should stop when the handler fails", func(t *testing.T) {
+		h := newTestHelper(t)
+		ctx := context.Background()
+		require.NoError(t, h.Exec(ctx, "INSERT INTO notes (id) VALUES ('a'), ('b'), ('c')", nil))
+
+		c := rowCollector{limit: 1}
+		err := h.QueryRows(ctx, "SELECT id FROM notes", nil, &c)
+
+		assert.EqualError(t, err, "limit reached")
+		assert.Len(t, c.rows, 1)
+	})
+
+	t.Run("should return query errors before any callback", func(t *testing.T) {
+		h := newTestHelper(t)
+
+		var c rowCollector
+		assert.Error(t, h.QueryRows(context.Background(), "SELECT nope FROM notes", nil, &c))
+		assert.Nil(t, c.columns)
+	})
+}
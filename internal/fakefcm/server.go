@@ -0,0 +1,314 @@
+// Package fakefcm is a local stand-in for the FCM HTTP v1 API.
+//
+// It accepts messages:send requests from the Firebase messaging SDK, records
+// every message and can be scripted to fail for specific tokens (or topics)
+// with the same error bodies FCM returns. Point the SDK at it with
+// FCM_ENDPOINT=<server URL>/v1 and any FCM_PROJECT_ID.
+package fakefcm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a message accepted by the fake server.
+type Message struct {
+	ID           string            `json:"id"` // full resource name: projects/<project>/messages/<n>
+	Project      string            `json:"project"`
+	Token        string            `json:"token,omitempty"`
+	Topic        string            `json:"topic,omitempty"`
+	Title        string            `json:"title,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      json.RawMessage   `json:"android,omitempty"`
+	APNS         json.RawMessage   `json:"apns,omitempty"`
+	ValidateOnly bool              `json:"validate_only,omitempty"`
+	ReceivedAt   time.Time         `json:"received_at"`
+}
+
+// Fault is a scripted failure returned instead of accepting a message.
+type Fault struct {
+	HTTPStatus int    `json:"http_status"`
+	Status     string `json:"status"`               // google.rpc status, vd. NOT_FOUND
+	ErrorCode  string `json:"error_code,omitempty"` // FcmError code, vd. UNREGISTERED
+	Message    string `json:"message,omitempty"`
+	// RetryAfter is sent as the Retry-After header when > 0.
+	// The SDK gives up instead of retrying 503s when it exceeds two minutes.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+}
+
+// Predefined faults matching real FCM responses.
+var (
+	// Unregistered: token đã bị gỡ khỏi thiết bị
+	Unregistered = Fault{HTTPStatus: http.StatusNotFound, Status: "NOT_FOUND", ErrorCode: "UNREGISTERED", Message: "Requested entity was not found."}
+	// QuotaExceeded: vượt giới hạn gửi
+	QuotaExceeded = Fault{HTTPStatus: http.StatusTooManyRequests, Status: "RESOURCE_EXHAUSTED", ErrorCode: "QUOTA_EXCEEDED", Message: "Quota exceeded."}
+	// Unavailable: FCM quá tải (503); SDK gửi lại ngay lần đầu, sau đó chờ 1s, 2s, 4s
+	Unavailable = Fault{HTTPStatus: http.StatusServiceUnavailable, Status: "UNAVAILABLE", ErrorCode: "UNAVAILABLE", Message: "The service is currently unavailable."}
+)
+
+// script is a fault applied to the next times requests for a target (0 = until cleared).
+type script struct {
+	fault Fault
+	times int
+}
+
+// Server is the fake FCM HTTP v1 API. It implements http.Handler.
+type Server struct {
+	mu       sync.Mutex
+	messages []Message
+	faults   map[string]*script
+	attempts map[string]int
+	nextID   int
+}
+
+// NewServer creates an empty fake server.
+func NewServer() *Server {
+	return &Server{
+		faults:   make(map[string]*script),
+		attempts: make(map[string]int),
+	}
+}
+
+// Fail makes requests for target (a token or topic) fail with fault.
+// times limits how many requests fail; 0 means until Clear or Reset.
+func (s *Server) Fail(target string, fault Fault, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[target] = &script{fault: fault, times: times}
+}
+
+// Clear removes the scripted fault for target.
+func (s *Server) Clear(target string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.faults, target)
+}
+
+// Reset drops all recorded messages, attempts and faults.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	s.faults = make(map[string]*script)
+	s.attempts = make(map[string]int)
+}
+
+// Messages returns the accepted messages, excluding validate-only (dry-run) requests.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Message
+	for _, msg := range s.messages {
+		if !msg.ValidateOnly {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// MessagesTo returns the accepted messages addressed to token.
+func (s *Server) MessagesTo(token string) []Message {
+	var out []Message
+	for _, msg := range s.Messages() {
+		if msg.Token == token {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// Attempts returns how many send requests (accepted or failed) were made for target.
+func (s *Server) Attempts(target string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[target]
+}
+
+// ServeHTTP handles POST /v1/projects/{project}/messages:send and the /_fake admin endpoints.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/_fake/"):
+		s.serveAdmin(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/messages:send"):
+		s.serveSend(w, r)
+	default:
+		writeError(w, Fault{HTTPStatus: http.StatusNotFound, Status: "NOT_FOUND", Message: "unknown path " + r.URL.Path})
+	}
+}
+
+// sendRequest is the messages:send request body.
+type sendRequest struct {
+	ValidateOnly bool `json:"validate_only"`
+	Message      struct {
+		Token        string            `json:"token"`
+		Topic        string            `json:"topic"`
+		Condition    string            `json:"condition"`
+		Data         map[string]string `json:"data"`
+		Notification *struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		} `json:"notification"`
+		Android json.RawMessage `json:"android"`
+		APNS    json.RawMessage `json:"apns"`
+	} `json:"message"`
+}
+
+func (s *Server) serveSend(w http.ResponseWriter, r *http.Request) {
+	project, ok := projectFromPath(r.URL.Path)
+	if !ok {
+		writeError(w, Fault{HTTPStatus: http.StatusNotFound, Status: "NOT_FOUND", Message: "unknown path " + r.URL.Path})
+		return
+	}
+
+	var req sendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, invalidArgument("invalid JSON payload: "+err.Error()))
+		return
+	}
+	target := req.Message.Token
+	if target == "" {
+		target = req.Message.Topic
+	}
+	if target == "" && req.Message.Condition == "" {
+		writeError(w, invalidArgument("message must have exactly one of token, topic or condition"))
+		return
+	}
+
+	s.mu.Lock()
+	s.attempts[target]++
+	if sc, ok := s.faults[target]; ok {
+		fault := sc.fault
+		if sc.times > 0 {
+			sc.times--
+			if sc.times == 0 {
+				delete(s.faults, target)
+			}
+		}
+		s.mu.Unlock()
+		writeError(w, fault)
+		return
+	}
+
+	s.nextID++
+	msg := Message{
+		ID:           fmt.Sprintf("projects/%s/messages/%d", project, s.nextID),
+		Project:      project,
+		Token:        req.Message.Token,
+		Topic:        req.Message.Topic,
+		Data:         req.Message.Data,
+		Android:      req.Message.Android,
+		APNS:         req.Message.APNS,
+		ValidateOnly: req.ValidateOnly,
+		ReceivedAt:   time.Now(),
+	}
+	if n := req.Message.Notification; n != nil {
+		msg.Title, msg.Body = n.Title, n.Body
+	}
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"name": msg.ID})
+}
+
+// faultRequest is the body of POST /_fake/faults.
+type faultRequest struct {
+	Target string `json:"target"`
+	Error  string `json:"error"` // UNREGISTERED, QUOTA_EXCEEDED, UNAVAILABLE, hoặc rỗng để xóa
+	Times  int    `json:"times"`
+}
+
+// serveAdmin exposes recorded messages and fault scripting for manual testing:
+//
+//	GET    /_fake/messages  list accepted messages
+//	DELETE /_fake/messages  reset messages and faults
+//	POST   /_fake/faults    {"target": "<token>", "error": "UNREGISTERED", "times": 1}
+func (s *Server) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/_fake/messages" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Messages())
+	case r.URL.Path == "/_fake/messages" && r.Method == http.MethodDelete:
+		s.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/_fake/faults" && r.Method == http.MethodPost:
+		var req faultRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+			writeError(w, invalidArgument("body must be {\"target\", \"error\", \"times\"}"))
+			return
+		}
+		if req.Error == "" {
+			s.Clear(req.Target)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fault, ok := FaultByCode(req.Error)
+		if !ok {
+			writeError(w, invalidArgument("unknown error "+req.Error))
+			return
+		}
+		s.Fail(req.Target, fault, req.Times)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, Fault{HTTPStatus: http.StatusNotFound, Status: "NOT_FOUND", Message: "unknown path " + r.URL.Path})
+	}
+}
+
+// FaultByCode returns the predefined fault for an FCM error code.
+func FaultByCode(code string) (Fault, bool) {
+	switch strings.ToUpper(code) {
+	case Unregistered.ErrorCode:
+		return Unregistered, true
+	case QuotaExceeded.ErrorCode:
+		return QuotaExceeded, true
+	case Unavailable.ErrorCode:
+		return Unavailable, true
+	default:
+		return Fault{}, false
+	}
+}
+
+// projectFromPath extracts the project from /v1/projects/{project}/messages:send.
+func projectFromPath(path string) (string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "projects" && parts[i+2] == "messages:send" {
+			return parts[i+1], parts[i+1] != ""
+		}
+	}
+	return "", false
+}
+
+func invalidArgument(message string) Fault {
+	return Fault{HTTPStatus: http.StatusBadRequest, Status: "INVALID_ARGUMENT", ErrorCode: "INVALID_ARGUMENT", Message: message}
+}
+
+// writeError writes fault in the google.rpc.Status format the messaging SDK parses.
+func writeError(w http.ResponseWriter, fault Fault) {
+	body := map[string]interface{}{
+		"code":    fault.HTTPStatus,
+		"message": fault.Message,
+		"status":  fault.Status,
+	}
+	if fault.ErrorCode != "" {
+		body["details"] = []map[string]string{{
+			"@type":     "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+			"errorCode": fault.ErrorCode,
+		}}
+	}
+	if fault.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(fault.RetryAfter/time.Second)))
+	}
+	writeJSON(w, fault.HTTPStatus, map[string]interface{}{"error": body})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
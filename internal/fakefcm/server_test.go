@@ -0,0 +1,120 @@
+package fakefcm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func send(t *testing.T, s *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/projects/demo/messages:send", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_Send(t *testing.T) {
+	t.Run("should record messages and return their name", func(t *testing.T) {
+		s := NewServer()
+
+		rec := send(t, s, `{"message":{"token":"tok","notification":{"title":"T","body":"B"},"data":{"k":"v"}}}`)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "projects/demo/messages/1", resp["name"])
+
+		msgs := s.Messages()
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "tok", msgs[0].Token)
+		assert.Equal(t, "T", msgs[0].Title)
+		assert.Equal(t, "B", msgs[0].Body)
+		assert.Equal(t, "v", msgs[0].Data["k"])
+	})
+
+	t.Run("should not list validate-only requests", func(t *testing.T) {
+		s := NewServer()
+
+		rec := send(t, s, `{"validate_only":true,"message":{"topic":"probe"}}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, s.Messages())
+		assert.Equal(t, 1, s.Attempts("probe"))
+	})
+
+	t.Run("should reject messages without a target", func(t *testing.T) {
+		rec := send(t, NewServer(), `{"message":{}}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestServer_Fail(t *testing.T) {
+	t.Run("should return FCM error bodies for scripted tokens", func(t *testing.T) {
+		s := NewServer()
+		s.Fail("tok", Unregistered, 0)
+
+		rec := send(t, s, `{"message":{"token":"tok"}}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"errorCode":"UNREGISTERED"`)
+		assert.Contains(t, rec.Body.String(), `"status":"NOT_FOUND"`)
+		assert.Empty(t, s.Messages())
+	})
+
+	t.Run("should fail only the given number of times", func(t *testing.T) {
+		s := NewServer()
+		s.Fail("tok", QuotaExceeded, 1)
+
+		assert.Equal(t, http.StatusTooManyRequests, send(t, s, `{"message":{"token":"tok"}}`).Code)
+		assert.Equal(t, http.StatusOK, send(t, s, `{"message":{"token":"tok"}}`).Code)
+		assert.Equal(t, 2, s.Attempts("tok"))
+		assert.Len(t, s.MessagesTo("tok"), 1)
+	})
+
+	t.Run("should not affect other tokens", func(t *testing.T) {
+		s := NewServer()
+		s.Fail("bad", Unavailable, 0)
+
+		assert.Equal(t, http.StatusOK, send(t, s, `{"message":{"token":"good"}}`).Code)
+	})
+}
+
+func TestServer_Admin(t *testing.T) {
+	s := NewServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/_fake/faults", strings.NewReader(`{"target":"tok","error":"unregistered"}`))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, http.StatusNotFound, send(t, s, `{"message":{"token":"tok"}}`).Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/_fake/faults", strings.NewReader(`{"target":"tok","error":"BOGUS"}`))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/_fake/faults", strings.NewReader(`{"target":"tok"}`))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, http.StatusOK, send(t, s, `{"message":{"token":"tok"}}`).Code)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_fake/messages", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var msgs []Message
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &msgs))
+	assert.Len(t, msgs, 1)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/_fake/messages", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, s.Messages())
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"remiaq/internal/middleware"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+	"remiaq/internal/services"
+	"remiaq/internal/utils"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// DeliveryAcknowledger records client acknowledgements of sent notifications
+type DeliveryAcknowledger interface {
+	AcknowledgeDelivery(ctx context.Context, deliveryID, userID, event string) ([]*models.Delivery, error)
+}
+
+// DeliveryHandler exposes the delivery log (lịch sử gửi thông báo)
+type DeliveryHandler struct {
+	repo  repository.DeliveryRepository
+	acker DeliveryAcknowledger
+}
+
+// NewDeliveryHandler creates a new delivery handler. acker may be nil when acknowledgements are not served.
+func NewDeliveryHandler(repo repository.DeliveryRepository, acker DeliveryAcknowledger) *DeliveryHandler {
+	return &DeliveryHandler{repo: repo, acker: acker}
+}
+
+// AckDelivery handles POST /api/deliveries/:id/ack with {"event": "received"|"opened", "user_id": "..."}.
+// :id is the "delivery_id" from the notification data payload.
+func (h *DeliveryHandler) AckDelivery(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	if h.acker == nil {
+		return utils.SendError(re, 503, "Delivery acknowledgements are not enabled", nil)
+	}
+
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.SendError(re, 400, "Delivery ID is required", nil)
+	}
+
+	var req struct {
+		Event  string `json:"event"`
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&req); err != nil {
+		return utils.SendError(re, 400, "Invalid request body", err)
+	}
+
+	deliveries, err := h.acker.AcknowledgeDelivery(re.Request.Context(), id, req.UserID, req.Event)
+	switch {
+	case errors.Is(err, services.ErrInvalidAckEvent):
+		return utils.SendError(re, 400, "Invalid ack event", err)
+	case errors.Is(err, sql.ErrNoRows):
+		return utils.SendError(re, 404, "Delivery not found", err)
+	case err != nil:
+		return utils.SendError(re, 500, "Failed to acknowledge delivery", err)
+	}
+
+	return utils.SendSuccess(re, "Delivery acknowledged", deliveries)
+}
+
+// GetReminderDeliveries handles GET /api/reminders/:id/deliveries?page=&perPage=
+func (h *DeliveryHandler) GetReminderDeliveries(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.SendError(re, 400, "Reminder ID is required", nil)
+	}
+
+	page, perPage, err := utils.ParsePagination(re)
+	if err != nil {
+		return utils.SendError(re, 400, "Invalid pagination", err)
+	}
+
+	deliveries, total, err := h.repo.ListByReminder(re.Request.Context(), id, page, perPage)
+	if err != nil {
+		return utils.SendError(re, 500, "Failed to get deliveries", err)
+	}
+
+	return utils.SendPagedResponse(re, page, perPage, total, deliveries)
+}
+
+// GetUserDeliveries handles GET /api/users/:userId/deliveries?page=&perPage=
+func (h *DeliveryHandler) GetUserDeliveries(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	userID := re.Request.PathValue("userId")
+	if userID == "" {
+		return utils.SendError(re, 400, "User ID is required", nil)
+	}
+
+	page, perPage, err := utils.ParsePagination(re)
+	if err != nil {
+		return utils.SendError(re, 400, "Invalid pagination", err)
+	}
+
+	deliveries, total, err := h.repo.ListByUser(re.Request.Context(), userID, page, perPage)
+	if err != nil {
+		return utils.SendError(re, 500, "Failed to get deliveries", err)
+	}
+
+	return utils.SendPagedResponse(re, page, perPage, total, deliveries)
+}
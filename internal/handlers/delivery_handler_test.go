@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+	"remiaq/internal/services"
+)
+
+// Mock DeliveryRepository
+type MockDeliveryRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryRepository) Create(ctx context.Context, delivery *models.Delivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func (m *MockDeliveryRepository) ListByReminder(ctx context.Context, reminderID string, page, perPage int) ([]*models.Delivery, int, error) {
+	args := m.Called(ctx, reminderID, page, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*models.Delivery), args.Int(1), args.Error(2)
+}
+
+func (m *MockDeliveryRepository) ListByUser(ctx context.Context, userID string, page, perPage int) ([]*models.Delivery, int, error) {
+	args := m.Called(ctx, userID, page, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*models.Delivery), args.Int(1), args.Error(2)
+}
+
+func (m *MockDeliveryRepository) Acknowledge(ctx context.Context, dispatchID, userID, event string, at time.Time) ([]*models.Delivery, error) {
+	args := m.Called(ctx, dispatchID, userID, event, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Delivery), args.Error(1)
+}
+
+// createDeliveryRequestEvent builds a GET request event with one path value
+func createDeliveryRequestEvent(target, pathKey, pathValue string) (*core.RequestEvent, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest("GET", target, nil)
+	req.SetPathValue(pathKey, pathValue)
+	recorder := httptest.NewRecorder()
+	return &core.RequestEvent{
+		Event: router.Event{
+			Request:  req,
+			Response: recorder,
+		},
+	}, recorder
+}
+
+func TestDeliveryHandler_GetReminderDeliveries(t *testing.T) {
+	t.Run("returns requested page with totals", func(t *testing.T) {
+		mockRepo := &MockDeliveryRepository{}
+		handler := NewDeliveryHandler(mockRepo, nil)
+
+		deliveries := []*models.Delivery{
+			{ID: "d2", ReminderID: "rem1", Outcome: models.DeliveryOutcomeFailed, ErrorClass: "unavailable", Attempt: 2},
+			{ID: "d1", ReminderID: "rem1", Outcome: models.DeliveryOutcomeFailed, ErrorClass: "unavailable", Attempt: 1},
+		}
+		mockRepo.On("ListByReminder", mock.Anything, "rem1", 2, 2).Return(deliveries, 5, nil)
+
+		re, recorder := createDeliveryRequestEvent("/api/reminders/rem1/deliveries?page=2&perPage=2", "id", "rem1")
+		err := handler.GetReminderDeliveries(re)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var body struct {
+			Page       int               `json:"page"`
+			PerPage    int               `json:"perPage"`
+			TotalItems int               `json:"totalItems"`
+			TotalPages int               `json:"totalPages"`
+			Items      []models.Delivery `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, 2, body.Page)
+		assert.Equal(t, 2, body.PerPage)
+		assert.Equal(t, 5, body.TotalItems)
+		assert.Equal(t, 3, body.TotalPages)
+		assert.Len(t, body.Items, 2)
+		assert.Equal(t, "d2", body.Items[0].ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("uses default pagination", func(t *testing.T) {
+		mockRepo := &MockDeliveryRepository{}
+		handler := NewDeliveryHandler(mockRepo, nil)
+		mockRepo.On("ListByReminder", mock.Anything, "rem1", 1, 30).Return([]*models.Delivery{}, 0, nil)
+
+		re, recorder := createDeliveryRequestEvent("/api/reminders/rem1/deliveries", "id", "rem1")
+		err := handler.GetReminderDeliveries(re)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("caps perPage", func(t *testing.T) {
+		mockRepo := &MockDeliveryRepository{}
+		handler := NewDeliveryHandler(mockRepo, nil)
+		mockRepo.On("ListByReminder", mock.Anything, "rem1", 1, 500).Return([]*models.Delivery{}, 0, nil)
+
+		re, _ := createDeliveryRequestEvent("/api/reminders/rem1/deliveries?perPage=10000", "id", "rem1")
+		require.NoError(t, handler.GetReminderDeliveries(re))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects invalid page", func(t *testing.T) {
+		mockRepo := &MockDeliveryRepository{}
+		handler := NewDeliveryHandler(mockRepo, nil)
+
+		re, recorder := createDeliveryRequestEvent("/api/reminders/rem1/deliveries?page=0", "id", "rem1")
+		require.NoError(t, handler.GetReminderDeliveries(re))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		mockRepo.AssertNotCalled(t, "ListByReminder", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("missing reminder id", func(t *testing.T) {
+		handler := NewDeliveryHandler(&MockDeliveryRepository{}, nil)
+
+		re, recorder := createDeliveryRequestEvent("/api/reminders//deliveries", "id", "")
+		require.NoError(t, handler.GetReminderDeliveries(re))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := &MockDeliveryRepository{}
+		handler := NewDeliveryHandler(mockRepo, nil)
+		mockRepo.On("ListByReminder", mock.Anything, "rem1", 1, 30).Return(nil, 0, errors.New("db down"))
+
+		re, recorder := createDeliveryRequestEvent("/api/reminders/rem1/deliveries", "id", "rem1")
+		require.NoError(t, handler.GetReminderDeliveries(re))
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
+
+func TestDeliveryHandler_GetUserDeliveries(t *testing.T) {
+	t.Run("lists deliveries for user", func(t *testing.T) {
+		mockRepo := &MockDeliveryRepository{}
+		handler := NewDeliveryHandler(mockRepo, nil)
+		mockRepo.On("ListByUser", mock.Anything, "user1", 1, 10).
+			Return([]*models.Delivery{{ID: "d1", UserID: "user1", Outcome: models.DeliveryOutcomeSent}}, 1, nil)
+
+		re, recorder := createDeliveryRequestEvent("/api/users/user1/deliveries?perPage=10", "userId", "user1")
+		require.NoError(t, handler.GetUserDeliveries(re))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"totalItems":1`)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("missing user id", func(t *testing.T) {
+		handler := NewDeliveryHandler(&MockDeliveryRepository{}, nil)
+
+		re, recorder := createDeliveryRequestEvent("/api/users//deliveries", "userId", "")
+		require.NoError(t, handler.GetUserDeliveries(re))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+// Mock DeliveryAcknowledger
+type MockDeliveryAcknowledger struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryAcknowledger) AcknowledgeDelivery(ctx context.Context, deliveryID, userID, event string) ([]*models.Delivery, error) {
+	args := m.Called(ctx, deliveryID, userID, event)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Delivery), args.Error(1)
+}
+
+// createAckRequestEvent builds a POST ack request event for a delivery ID
+func createAckRequestEvent(deliveryID, body string) (*core.RequestEvent, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest("POST", "/api/deliveries/"+deliveryID+"/ack", strings.NewReader(body))
+	req.SetPathValue("id", deliveryID)
+	recorder := httptest.NewRecorder()
+	return &core.RequestEvent{
+		Event: router.Event{
+			Request:  req,
+			Response: recorder,
+		},
+	}, recorder
+}
+
+func TestDeliveryHandler_AckDelivery(t *testing.T) {
+	t.Run("acknowledges opened notification", func(t *testing.T) {
+		acker := &MockDeliveryAcknowledger{}
+		handler := NewDeliveryHandler(&MockDeliveryRepository{}, acker)
+		acker.On("AcknowledgeDelivery", mock.Anything, "dispatch-1", "user1", models.AckEventOpened).
+			Return([]*models.Delivery{{ID: "d1", DispatchID: "dispatch-1"}}, nil)
+
+		re, recorder := createAckRequestEvent("dispatch-1", `{"event":"opened","user_id":"user1"}`)
+		err := handler.AckDelivery(re)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		acker.AssertExpectations(t)
+	})
+
+	t.Run("rejects invalid event", func(t *testing.T) {
+		acker := &MockDeliveryAcknowledger{}
+		handler := NewDeliveryHandler(&MockDeliveryRepository{}, acker)
+		acker.On("AcknowledgeDelivery", mock.Anything, "dispatch-1", "", "clicked").Return(nil, services.ErrInvalidAckEvent)
+
+		re, recorder := createAckRequestEvent("dispatch-1", `{"event":"clicked"}`)
+		_ = handler.AckDelivery(re)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("returns not found for unknown delivery", func(t *testing.T) {
+		acker := &MockDeliveryAcknowledger{}
+		handler := NewDeliveryHandler(&MockDeliveryRepository{}, acker)
+		acker.On("AcknowledgeDelivery", mock.Anything, "missing", "", models.AckEventReceived).Return(nil, sql.ErrNoRows)
+
+		re, recorder := createAckRequestEvent("missing", `{"event":"received"}`)
+		_ = handler.AckDelivery(re)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("returns unavailable without acknowledger", func(t *testing.T) {
+		handler := NewDeliveryHandler(&MockDeliveryRepository{}, nil)
+
+		re, recorder := createAckRequestEvent("dispatch-1", `{"event":"received"}`)
+		_ = handler.AckDelivery(re)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"remiaq/internal/middleware"
+	"remiaq/internal/models"
+	"remiaq/internal/services"
+	"remiaq/internal/utils"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// GroupServiceInterface defines the interface for group service
+type GroupServiceInterface interface {
+	CreateGroup(ctx context.Context, group *models.Group) error
+	GetGroup(ctx context.Context, id string) (*models.Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+	ListMembers(ctx context.Context, groupID string) ([]*models.User, error)
+	AddMember(ctx context.Context, groupID, userID string) error
+	RemoveMember(ctx context.Context, groupID, userID string) error
+}
+
+// GroupHandler handles broadcast group HTTP requests
+type GroupHandler struct {
+	groupService GroupServiceInterface
+}
+
+// NewGroupHandler creates a new group handler
+func NewGroupHandler(groupService GroupServiceInterface) *GroupHandler {
+	return &GroupHandler{groupService: groupService}
+}
+
+// CreateGroup handles POST /api/groups
+func (h *GroupHandler) CreateGroup(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	var group models.Group
+	if err := json.NewDecoder(re.Request.Body).Decode(&group); err != nil {
+		return utils.SendError(re, 400, "Invalid request body", err)
+	}
+
+	if err := h.groupService.CreateGroup(re.Request.Context(), &group); err != nil {
+		return utils.SendError(re, 400, "Failed to create group", err)
+	}
+
+	return utils.SendSuccess(re, "Group created successfully", group)
+}
+
+// GetGroup handles GET /api/groups/:id
+func (h *GroupHandler) GetGroup(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.SendError(re, 400, "Group ID is required", nil)
+	}
+
+	group, err := h.groupService.GetGroup(re.Request.Context(), id)
+	if err != nil {
+		return utils.SendError(re, 404, "Group not found", err)
+	}
+
+	return utils.SendSuccess(re, "", group)
+}
+
+// DeleteGroup handles DELETE /api/groups/:id
+func (h *GroupHandler) DeleteGroup(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.SendError(re, 400, "Group ID is required", nil)
+	}
+
+	if err := h.groupService.DeleteGroup(re.Request.Context(), id); err != nil {
+		return utils.SendError(re, groupErrorStatus(err), "Failed to delete group", err)
+	}
+
+	return utils.SendSuccess(re, "Group deleted successfully", nil)
+}
+
+// ListMembers handles GET /api/groups/:id/members
+func (h *GroupHandler) ListMembers(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.SendError(re, 400, "Group ID is required", nil)
+	}
+
+	members, err := h.groupService.ListMembers(re.Request.Context(), id)
+	if err != nil {
+		return utils.SendError(re, groupErrorStatus(err), "Failed to list members", err)
+	}
+
+	return utils.SendSuccess(re, "", members)
+}
+
+// AddMember handles POST /api/groups/:id/members with {"user_id": "..."}.
+// The user's device is subscribed to the group topic.
+func (h *GroupHandler) AddMember(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.SendError(re, 400, "Group ID is required", nil)
+	}
+
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&req); err != nil {
+		return utils.SendError(re, 400, "Invalid request body", err)
+	}
+	if req.UserID == "" {
+		return utils.SendError(re, 400, "user_id is required", nil)
+	}
+
+	if err := h.groupService.AddMember(re.Request.Context(), id, req.UserID); err != nil {
+		return utils.SendError(re, groupErrorStatus(err), "Failed to add member", err)
+	}
+
+	return utils.SendSuccess(re, "Member added successfully", nil)
+}
+
+// RemoveMember handles DELETE /api/groups/:id/members/:userId.
+// The user's device is unsubscribed from the group topic.
+func (h *GroupHandler) RemoveMember(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	id := re.Request.PathValue("id")
+	userID := re.Request.PathValue("userId")
+	if id == "" || userID == "" {
+		return utils.SendError(re, 400, "Group ID and user ID are required", nil)
+	}
+
+	if err := h.groupService.RemoveMember(re.Request.Context(), id, userID); err != nil {
+		return utils.SendError(re, groupErrorStatus(err), "Failed to remove member", err)
+	}
+
+	return utils.SendSuccess(re, "Member removed successfully", nil)
+}
+
+// groupErrorStatus maps group service errors to HTTP status codes
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, services.ErrNotMember):
+		return 404
+	case errors.Is(err, services.ErrAlreadyMember):
+		return 409
+	default:
+		return 400
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+	"remiaq/internal/services"
+)
+
+// Mock GroupService
+type MockGroupService struct {
+	mock.Mock
+}
+
+func (m *MockGroupService) CreateGroup(ctx context.Context, group *models.Group) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *MockGroupService) GetGroup(ctx context.Context, id string) (*models.Group, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Group), args.Error(1)
+}
+
+func (m *MockGroupService) DeleteGroup(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockGroupService) ListMembers(ctx context.Context, groupID string) ([]*models.User, error) {
+	args := m.Called(ctx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockGroupService) AddMember(ctx context.Context, groupID, userID string) error {
+	args := m.Called(ctx, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *MockGroupService) RemoveMember(ctx context.Context, groupID, userID string) error {
+	args := m.Called(ctx, groupID, userID)
+	return args.Error(0)
+}
+
+// createGroupRequestEvent builds a request event with the given path values
+func createGroupRequestEvent(method, target, body string, pathValues map[string]string) (*core.RequestEvent, *httptest.ResponseRecorder) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	for key, value := range pathValues {
+		req.SetPathValue(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	return &core.RequestEvent{
+		Event: router.Event{
+			Request:  req,
+			Response: recorder,
+		},
+	}, recorder
+}
+
+func TestGroupHandler_CreateGroup(t *testing.T) {
+	t.Run("creates group from body", func(t *testing.T) {
+		mockService := &MockGroupService{}
+		handler := NewGroupHandler(mockService)
+		mockService.On("CreateGroup", mock.Anything, mock.MatchedBy(func(g *models.Group) bool {
+			return g.Name == "Team" && g.OwnerID == "user-1"
+		})).Return(nil)
+
+		re, recorder := createGroupRequestEvent("POST", "/api/groups", `{"name":"Team","owner_id":"user-1"}`, nil)
+		err := handler.CreateGroup(re)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects invalid body", func(t *testing.T) {
+		handler := NewGroupHandler(&MockGroupService{})
+
+		re, recorder := createGroupRequestEvent("POST", "/api/groups", `{`, nil)
+		_ = handler.CreateGroup(re)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestGroupHandler_AddMember(t *testing.T) {
+	t.Run("adds member", func(t *testing.T) {
+		mockService := &MockGroupService{}
+		handler := NewGroupHandler(mockService)
+		mockService.On("AddMember", mock.Anything, "g1", "user-2").Return(nil)
+
+		re, recorder := createGroupRequestEvent("POST", "/api/groups/g1/members", `{"user_id":"user-2"}`,
+			map[string]string{"id": "g1"})
+		err := handler.AddMember(re)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("requires user_id", func(t *testing.T) {
+		handler := NewGroupHandler(&MockGroupService{})
+
+		re, recorder := createGroupRequestEvent("POST", "/api/groups/g1/members", `{}`, map[string]string{"id": "g1"})
+		_ = handler.AddMember(re)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("returns conflict for existing member", func(t *testing.T) {
+		mockService := &MockGroupService{}
+		handler := NewGroupHandler(mockService)
+		mockService.On("AddMember", mock.Anything, "g1", "user-2").Return(services.ErrAlreadyMember)
+
+		re, recorder := createGroupRequestEvent("POST", "/api/groups/g1/members", `{"user_id":"user-2"}`,
+			map[string]string{"id": "g1"})
+		_ = handler.AddMember(re)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+}
+
+func TestGroupHandler_RemoveMember(t *testing.T) {
+	t.Run("removes member", func(t *testing.T) {
+		mockService := &MockGroupService{}
+		handler := NewGroupHandler(mockService)
+		mockService.On("RemoveMember", mock.Anything, "g1", "user-2").Return(nil)
+
+		re, recorder := createGroupRequestEvent("DELETE", "/api/groups/g1/members/user-2", "",
+			map[string]string{"id": "g1", "userId": "user-2"})
+		err := handler.RemoveMember(re)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("returns not found for non-member", func(t *testing.T) {
+		mockService := &MockGroupService{}
+		handler := NewGroupHandler(mockService)
+		mockService.On("RemoveMember", mock.Anything, "g1", "user-2").Return(services.ErrNotMember)
+
+		re, recorder := createGroupRequestEvent("DELETE", "/api/groups/g1/members/user-2", "",
+			map[string]string{"id": "g1", "userId": "user-2"})
+		_ = handler.RemoveMember(re)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestGroupHandler_DeleteGroup(t *testing.T) {
+	t.Run("returns not found for missing group", func(t *testing.T) {
+		mockService := &MockGroupService{}
+		handler := NewGroupHandler(mockService)
+		mockService.On("DeleteGroup", mock.Anything, "missing").Return(sql.ErrNoRows)
+
+		re, recorder := createGroupRequestEvent("DELETE", "/api/groups/missing", "", map[string]string{"id": "missing"})
+		_ = handler.DeleteGroup(re)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
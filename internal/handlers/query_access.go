@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"time"
+
+	"remiaq/internal/middleware"
+	"remiaq/internal/models"
+	"remiaq/internal/utils"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// APIKeyHeader is the request header carrying an API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKey grants access to the raw SQL endpoints to callers sending Key in the X-API-Key header
+type APIKey struct {
+	Name   string
+	Key    string
+	Write  bool     // INSERT/UPDATE/DELETE besides SELECT
+	Tables []string // empty = every table of the handler's policy
+}
+
+var (
+	errAuthRequired  = errors.New("superuser or API key required")
+	errInvalidAPIKey = errors.New("invalid API key")
+	errReadOnlyKey   = errors.New("API key is read-only")
+	errAdHocDisabled = errors.New("ad-hoc queries are disabled, run a saved query")
+)
+
+// queryAccess is what the caller of a raw query may do
+type queryAccess struct {
+	caller    string // "superuser:<email>" or "apikey:<name>"
+	superuser bool
+	write     bool // may run INSERT/UPDATE/DELETE
+	policy    middleware.QueryPolicy
+}
+
+// authenticate identifies the caller: superusers have full access, API keys their scope.
+// The returned status goes with the error.
+func (h *QueryHandler) authenticate(re *core.RequestEvent) (*queryAccess, int, error) {
+	if re.HasSuperuserAuth() {
+		name := re.Auth.Email()
+		if name == "" {
+			name = re.Auth.Id
+		}
+		return &queryAccess{caller: "superuser:" + name, superuser: true, write: true, policy: h.policy}, 0, nil
+	}
+
+	key := re.Request.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, 401, errAuthRequired
+	}
+	for _, apiKey := range h.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey.Key)) != 1 {
+			continue
+		}
+		access := &queryAccess{caller: "apikey:" + apiKey.Name, write: apiKey.Write, policy: h.policy}
+		if len(apiKey.Tables) > 0 {
+			access.policy = h.policy.Only(apiKey.Tables...)
+		}
+		return access, 0, nil
+	}
+	return nil, 401, errInvalidAPIKey
+}
+
+// authorize authenticates the caller of a query of type stmt. A caller that is known but
+// not allowed is returned along with the error, for the audit log.
+func (h *QueryHandler) authorize(re *core.RequestEvent, stmt middleware.StatementType) (*queryAccess, int, error) {
+	access, status, err := h.authenticate(re)
+	if err != nil {
+		return nil, status, err
+	}
+	if stmt != middleware.StatementSelect && !access.write {
+		return access, 403, errReadOnlyKey
+	}
+	return access, 0, nil
+}
+
+// authorizeAdHoc authorizes an ad-hoc query of type stmt, like authorize
+func (h *QueryHandler) authorizeAdHoc(re *core.RequestEvent, stmt middleware.StatementType) (*queryAccess, int, error) {
+	access, status, err := h.authorize(re, stmt)
+	if err != nil {
+		return access, status, err
+	}
+	if h.noAdHoc {
+		return access, 403, errAdHocDisabled
+	}
+	return access, 0, nil
+}
+
+// auditRecord collects the audit log entry of one raw query request, denied ones included
+type auditRecord struct {
+	entry models.QueryAudit
+	start time.Time
+}
+
+func newAuditRecord(stmt middleware.StatementType) *auditRecord {
+	return &auditRecord{
+		entry: models.QueryAudit{Statement: string(stmt)},
+		start: time.Now(),
+	}
+}
+
+// allow records the caller of an authorized request
+func (a *auditRecord) allow(access *queryAccess) {
+	a.entry.Caller = access.caller
+}
+
+// deny records a request refused by authorization, with the caller when it is known, and sends the error
+func (a *auditRecord) deny(re *core.RequestEvent, access *queryAccess, status int, err error) error {
+	if access != nil {
+		a.entry.Caller = access.caller
+	}
+	a.entry.Outcome = models.QueryOutcomeDenied
+	return a.fail(re, status, "Access denied", err)
+}
+
+// request records the query of req
+func (a *auditRecord) request(req *QueryRequest) {
+	a.entry.Query = req.Query
+	a.entry.Params = req.Params
+	a.entry.DryRun = req.DryRun
+}
+
+// fail records the error and sends it
+func (a *auditRecord) fail(re *core.RequestEvent, status int, message string, err error) error {
+	if a.entry.Outcome == "" {
+		a.entry.Outcome = models.QueryOutcomeFailed
+	}
+	a.entry.Status = status
+	a.entry.Error = message
+	if err != nil {
+		a.entry.Error += ": " + err.Error()
+	}
+	return utils.SendError(re, status, message, err)
+}
+
+// finish stores the audit entry; the duration covers the whole request. A failed write is
+// only logged: the response does not depend on it.
+func (h *QueryHandler) finish(ctx context.Context, a *auditRecord) {
+	if h.auditRepo == nil {
+		return
+	}
+	// Export lỗi giữa chừng chỉ ghi Error: status 200 đã gửi đi
+	if a.entry.Outcome == "" {
+		a.entry.Outcome = models.QueryOutcomeOK
+		if a.entry.Error != "" {
+			a.entry.Outcome = models.QueryOutcomeFailed
+		}
+	}
+	if a.entry.Status == 0 {
+		a.entry.Status = 200
+	}
+	a.entry.DurationMs = time.Since(a.start).Milliseconds()
+	if err := h.auditRepo.Create(context.WithoutCancel(ctx), &a.entry); err != nil {
+		log.Printf("Warning: Failed to record raw query audit for %s: %v", a.entry.Caller, err)
+	}
+}
+
+// HandleAuditLog handles GET /api/rquery/audit?page=&perPage=&caller= (superusers only)
+func (h *QueryHandler) HandleAuditLog(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	if !re.HasSuperuserAuth() {
+		return utils.SendError(re, 403, "Access denied", errors.New("superuser required"))
+	}
+	if h.auditRepo == nil {
+		return utils.SendError(re, 404, "Audit log is not enabled", nil)
+	}
+
+	page, perPage, err := utils.ParsePagination(re)
+	if err != nil {
+		return utils.SendError(re, 400, "Invalid pagination", err)
+	}
+
+	entries, total, err := h.auditRepo.List(re.Request.Context(), re.Request.URL.Query().Get("caller"), page, perPage)
+	if err != nil {
+		return utils.SendError(re, 500, "Failed to get audit log", err)
+	}
+
+	return utils.SendPagedResponse(re, page, perPage, total, entries)
+}
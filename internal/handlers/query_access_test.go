@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock QueryAuditRepository
+type MockQueryAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockQueryAuditRepository) Create(ctx context.Context, entry *models.QueryAudit) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockQueryAuditRepository) List(ctx context.Context, caller string, page, perPage int) ([]*models.QueryAudit, int, error) {
+	args := m.Called(ctx, caller, page, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*models.QueryAudit), args.Int(1), args.Error(2)
+}
+
+var testAPIKeys = []APIKey{
+	{Name: "reporting", Key: "read-key-0123456789", Tables: []string{"reminders"}},
+	{Name: "ops", Key: "write-key-0123456789", Write: true},
+}
+
+// createAPIKeyRequestEvent creates a request authenticated with an API key only
+func createAPIKeyRequestEvent(method, path string, body interface{}, key string) *core.RequestEvent {
+	re := createMockRequestEvent(method, path, body)
+	re.Auth = nil
+	if key != "" {
+		re.Request.Header.Set(APIKeyHeader, key)
+	}
+	return re
+}
+
+func TestQueryHandler_Authorize(t *testing.T) {
+	endpoints := map[string]func(*QueryHandler, *core.RequestEvent) error{
+		"SELECT * FROM reminders":                 (*QueryHandler).HandleSelect,
+		"INSERT INTO reminders (id) VALUES ('r')": (*QueryHandler).HandleInsert,
+		"UPDATE reminders SET id = 'r' WHERE 1":   (*QueryHandler).HandleUpdate,
+		"DELETE FROM reminders WHERE id = 'r'":    (*QueryHandler).HandleDelete,
+	}
+
+	t.Run("should require a superuser or an API key", func(t *testing.T) {
+		user := core.NewRecord(core.NewAuthCollection("musers"))
+		for query, handle := range endpoints {
+			for name, re := range map[string]*core.RequestEvent{
+				"anonymous":   createAPIKeyRequestEvent("POST", "/query", QueryRequest{Query: query}, ""),
+				"invalid key": createAPIKeyRequestEvent("POST", "/query", QueryRequest{Query: query}, "read-key-012345678"),
+				"app user":    createAPIKeyRequestEvent("POST", "/query", QueryRequest{Query: query}, ""),
+			} {
+				if name == "app user" {
+					re.Auth = user
+				}
+				mockRepo := &MockQueryRepository{}
+				handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...))
+
+				require.NoError(t, handle(handler, re))
+
+				assert.Equal(t, http.StatusUnauthorized, re.Response.(*httptest.ResponseRecorder).Code, name+": "+query)
+				assert.Empty(t, mockRepo.Calls, "query must not run")
+			}
+		}
+	})
+
+	t.Run("should only let write keys mutate", func(t *testing.T) {
+		for query, handle := range endpoints {
+			if strings.HasPrefix(query, "SELECT") {
+				continue
+			}
+			mockRepo := &MockQueryRepository{}
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...))
+
+			re := createAPIKeyRequestEvent("POST", "/query", QueryRequest{Query: query, DryRun: true}, "read-key-0123456789")
+			require.NoError(t, handle(handler, re))
+
+			recorder := re.Response.(*httptest.ResponseRecorder)
+			assert.Equal(t, http.StatusForbidden, recorder.Code, query)
+			assert.Contains(t, recorder.Body.String(), "API key is read-only")
+			assert.Empty(t, mockRepo.Calls)
+		}
+
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteDelete", mock.Anything, "DELETE FROM users WHERE id = 'u1'", mock.Anything).Return(int64(1), nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...))
+
+		re := createAPIKeyRequestEvent("DELETE", "/query", QueryRequest{Query: "DELETE FROM users WHERE id = 'u1'"}, "write-key-0123456789")
+		require.NoError(t, handler.HandleDelete(re))
+
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should restrict keys to their tables", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{}, Total: 0}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...))
+
+		re := createAPIKeyRequestEvent("POST", "/query", QueryRequest{Query: "SELECT * FROM reminders"}, "read-key-0123456789")
+		require.NoError(t, handler.HandleSelect(re))
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+
+		re = createAPIKeyRequestEvent("POST", "/query", QueryRequest{Query: "SELECT * FROM users"}, "read-key-0123456789")
+		require.NoError(t, handler.HandleSelect(re))
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `table \"users\" is not allowed`)
+
+		mockRepo.AssertNumberOfCalls(t, "ExecuteSelectPage", 1)
+	})
+}
+
+func TestQueryHandler_Audit(t *testing.T) {
+	t.Run("should record the caller, query, params and rows", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{{"id": "r1"}, {"id": "r2"}}, Total: 2}, nil)
+		auditRepo := &MockQueryAuditRepository{}
+		auditRepo.On("Create", mock.Anything, mock.MatchedBy(func(entry *models.QueryAudit) bool {
+			return entry.Caller == "superuser:admin@example.com" &&
+				entry.Statement == "SELECT" &&
+				entry.Query == "SELECT * FROM reminders WHERE id IN ({:a}, {:b})" &&
+				entry.Params["a"] == json.Number("1") &&
+				entry.RowsAffected == 2 &&
+				entry.Outcome == models.QueryOutcomeOK &&
+				entry.Status == http.StatusOK &&
+				entry.Error == "" &&
+				entry.DurationMs >= 0
+		})).Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAuditLog(auditRepo))
+
+		re := createMockRequestEvent("POST", "/query", map[string]any{
+			"query":  "SELECT * FROM reminders WHERE id IN ({:a}, {:b})",
+			"params": map[string]any{"a": 1, "b": "x"},
+		})
+		require.NoError(t, handler.HandleSelect(re))
+
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("should record failures and dry runs", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), assert.AnError)
+		mockRepo.On("ExecuteDryRun", mock.Anything, mock.Anything, mock.Anything).Return(int64(4), nil)
+		var entries []*models.QueryAudit
+		auditRepo := &MockQueryAuditRepository{}
+		auditRepo.On("Create", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { entries = append(entries, args.Get(1).(*models.QueryAudit)) }).
+			Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...), WithAuditLog(auditRepo))
+
+		for _, req := range []QueryRequest{
+			{Query: "UPDATE users SET name = 'x' WHERE id = 1"},
+			{Query: "UPDATE users SET name = 'x' WHERE id = 1", DryRun: true},
+			{Query: "UPDATE users SET name = 'x'"},
+		} {
+			re := createAPIKeyRequestEvent("PUT", "/query", req, "write-key-0123456789")
+			require.NoError(t, handler.HandleUpdate(re))
+		}
+
+		require.Len(t, entries, 3)
+		for _, entry := range entries {
+			assert.Equal(t, "apikey:ops", entry.Caller)
+			assert.Equal(t, "UPDATE", entry.Statement)
+		}
+		assert.Equal(t, "Update execution failed: "+assert.AnError.Error(), entries[0].Error)
+		assert.Equal(t, models.QueryOutcomeFailed, entries[0].Outcome)
+		assert.Equal(t, http.StatusBadRequest, entries[0].Status)
+		assert.True(t, entries[1].DryRun)
+		assert.Equal(t, int64(4), entries[1].RowsAffected)
+		assert.Empty(t, entries[1].Error)
+		assert.Contains(t, entries[2].Error, "Query validation failed: UPDATE statements must include a WHERE clause")
+	})
+
+	t.Run("should record denied requests", func(t *testing.T) {
+		var entries []*models.QueryAudit
+		auditRepo := &MockQueryAuditRepository{}
+		auditRepo.On("Create", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { entries = append(entries, args.Get(1).(*models.QueryAudit)) }).
+			Return(nil)
+		mockRepo := &MockQueryRepository{}
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...), WithAuditLog(auditRepo))
+
+		re := createAPIKeyRequestEvent("POST", "/query", QueryRequest{Query: "SELECT * FROM reminders"}, "")
+		require.NoError(t, handler.HandleSelect(re))
+		re = createAPIKeyRequestEvent("DELETE", "/query", QueryRequest{Query: "DELETE FROM reminders WHERE id = 'r'"}, "read-key-0123456789")
+		require.NoError(t, handler.HandleDelete(re))
+
+		assert.Empty(t, mockRepo.Calls, "query must not run")
+		require.Len(t, entries, 2)
+		assert.Empty(t, entries[0].Caller, "caller is unknown without credentials")
+		assert.Equal(t, "SELECT", entries[0].Statement)
+		assert.Equal(t, models.QueryOutcomeDenied, entries[0].Outcome)
+		assert.Equal(t, http.StatusUnauthorized, entries[0].Status)
+		assert.Equal(t, "Access denied: "+errAuthRequired.Error(), entries[0].Error)
+		assert.Equal(t, "apikey:reporting", entries[1].Caller)
+		assert.Equal(t, "DELETE", entries[1].Statement)
+		assert.Equal(t, models.QueryOutcomeDenied, entries[1].Outcome)
+		assert.Equal(t, http.StatusForbidden, entries[1].Status)
+		assert.Equal(t, "Access denied: "+errReadOnlyKey.Error(), entries[1].Error)
+	})
+
+	t.Run("should answer even when the audit log fails", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteInsert", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), int64(7), nil)
+		auditRepo := &MockQueryAuditRepository{}
+		auditRepo.On("Create", mock.Anything, mock.Anything).Return(assert.AnError)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAuditLog(auditRepo))
+
+		re := createMockRequestEvent("POST", "/query", QueryRequest{Query: "INSERT INTO users (id) VALUES ('u')"})
+		require.NoError(t, handler.HandleInsert(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"lastInsertId":7`)
+		auditRepo.AssertExpectations(t)
+	})
+}
+
+func TestHandleAuditLog(t *testing.T) {
+	t.Run("should list entries for superusers", func(t *testing.T) {
+		auditRepo := &MockQueryAuditRepository{}
+		auditRepo.On("List", mock.Anything, "apikey:ops", 2, 10).
+			Return([]*models.QueryAudit{{ID: "a1", Caller: "apikey:ops", Statement: "DELETE"}}, 11, nil)
+		handler := NewQueryHandler(&MockQueryRepository{}, WithAuditLog(auditRepo))
+
+		re := createMockRequestEvent("GET", "/api/rquery/audit?caller=apikey:ops&page=2&perPage=10", nil)
+		require.NoError(t, handler.HandleAuditLog(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, float64(11), body["totalItems"])
+		assert.Equal(t, float64(2), body["totalPages"])
+		assert.Len(t, body["items"], 1)
+		auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("should deny API keys and anonymous callers", func(t *testing.T) {
+		auditRepo := &MockQueryAuditRepository{}
+		handler := NewQueryHandler(&MockQueryRepository{}, WithAPIKeys(testAPIKeys...), WithAuditLog(auditRepo))
+
+		for _, key := range []string{"", "write-key-0123456789"} {
+			re := createAPIKeyRequestEvent("GET", "/api/rquery/audit", nil, key)
+			require.NoError(t, handler.HandleAuditLog(re))
+			assert.Equal(t, http.StatusForbidden, re.Response.(*httptest.ResponseRecorder).Code)
+		}
+		assert.Empty(t, auditRepo.Calls)
+	})
+
+	t.Run("should report a disabled audit log", func(t *testing.T) {
+		handler := NewQueryHandler(&MockQueryRepository{})
+
+		re := createMockRequestEvent("GET", "/api/rquery/audit", nil)
+		require.NoError(t, handler.HandleAuditLog(re))
+
+		assert.Equal(t, http.StatusNotFound, re.Response.(*httptest.ResponseRecorder).Code)
+	})
+}
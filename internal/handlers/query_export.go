@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remiaq/internal/middleware"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Streaming export formats of SELECT results
+const (
+	exportCSV    = "csv"
+	exportNDJSON = "ndjson"
+)
+
+// exportFormat returns the export format asked by ?format= (json, csv, ndjson) or, without
+// it, by the Accept header (text/csv, application/x-ndjson). "" = paginated JSON.
+func exportFormat(re *core.RequestEvent) (string, error) {
+	switch format := strings.ToLower(re.Request.URL.Query().Get("format")); format {
+	case "":
+	case "json":
+		return "", nil
+	case exportCSV, exportNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, expected json, csv or ndjson", format)
+	}
+
+	for _, part := range strings.Split(re.Request.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return exportCSV, nil
+		case "application/x-ndjson", "application/ndjson":
+			return exportNDJSON, nil
+		}
+	}
+	return "", nil
+}
+
+// runExport streams every row of a SELECT as CSV or NDJSON; pagination parameters don't
+// apply. Once the first byte is sent the status can't change anymore, so an error after it
+// is audited and the client sees a truncated export: NDJSON ends with an {"error": ...}
+// line, a CSV response is aborted before its end.
+func (h *QueryHandler) runExport(re *core.RequestEvent, audit *auditRecord, query string, params map[string]any, format string) error {
+	ctx := re.Request.Context()
+	if h.exportTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.exportTimeout)
+		defer cancel()
+	}
+
+	stream := &exportStream{w: re.Response, format: format}
+	err := h.queryRepo.StreamSelect(ctx, middleware.TrimStatement(query), params, stream)
+	audit.entry.RowsAffected = stream.rows
+
+	if stream.out == nil {
+		if err == nil {
+			err = errors.New("query returned no columns")
+		}
+		return audit.fail(re, 400, "Query execution failed", err)
+	}
+	// Vẫn gửi các dòng đã đệm khi lỗi giữa chừng
+	if flushErr := stream.flush(); err == nil {
+		err = flushErr
+	}
+	if err == nil {
+		return nil
+	}
+
+	audit.entry.Error = "Export failed: " + err.Error()
+	log.Printf("Warning: %s export interrupted after %d rows: %v", format, stream.rows, err)
+	if format == exportNDJSON && stream.trailer(err) == nil {
+		return nil
+	}
+	// CSV không có chỗ báo lỗi: cắt kết nối để client không nhận nhầm file thiếu dòng là đủ
+	panic(http.ErrAbortHandler)
+}
+
+// exportStream is a repository.RowWriter encoding rows straight to the response.
+// The response starts with the columns, i.e. once the query is known to run.
+type exportStream struct {
+	w      http.ResponseWriter
+	format string
+	out    *bufio.Writer // nil until Columns
+	csv    *csv.Writer
+	keys   [][]byte // JSON-encoded column names
+	record []string // reused CSV record
+	rows   int64
+}
+
+func (s *exportStream) Columns(names []string) error {
+	header := s.w.Header()
+	switch s.format {
+	case exportCSV:
+		header.Set("Content-Type", "text/csv; charset=utf-8")
+		header.Set("Content-Disposition", `attachment; filename="query.csv"`)
+	default:
+		header.Set("Content-Type", "application/x-ndjson")
+	}
+	s.w.WriteHeader(http.StatusOK)
+	s.out = bufio.NewWriterSize(s.w, 32*1024)
+
+	if s.format == exportCSV {
+		s.csv = csv.NewWriter(s.out)
+		s.record = make([]string, len(names))
+		return s.csv.Write(names)
+	}
+	s.keys = make([][]byte, len(names))
+	for i, name := range names {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		s.keys[i] = key
+	}
+	return nil
+}
+
+func (s *exportStream) Row(values []any) error {
+	s.rows++
+	if s.format == exportCSV {
+		for i, value := range values {
+			s.record[i] = csvValue(value)
+		}
+		return s.csv.Write(s.record)
+	}
+
+	// Ghi object theo đúng thứ tự cột thay vì map (bị sắp xếp lại theo key)
+	s.out.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			s.out.WriteByte(',')
+		}
+		s.out.Write(s.keys[i])
+		s.out.WriteByte(':')
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		s.out.Write(encoded)
+	}
+	s.out.WriteByte('}')
+	return s.out.WriteByte('\n')
+}
+
+// trailer ends an interrupted NDJSON export with an error line
+func (s *exportStream) trailer(cause error) error {
+	line, err := json.Marshal(map[string]any{"error": "Export failed: " + cause.Error(), "rows": s.rows})
+	if err != nil {
+		return err
+	}
+	s.out.Write(line)
+	s.out.WriteByte('\n')
+	return s.out.Flush()
+}
+
+// flush writes what is still buffered to the response
+func (s *exportStream) flush() error {
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return s.out.Flush()
+}
+
+// csvValue formats a driver value as a CSV field; NULL is an empty field
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case json.RawMessage:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// streamRows makes a StreamSelect mock write columns and rows to its RowWriter
+func streamRows(columns []string, rows [][]any) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		w := args.Get(3).(repository.RowWriter)
+		if columns == nil {
+			return
+		}
+		if w.Columns(columns) != nil {
+			return
+		}
+		for _, row := range rows {
+			if w.Row(row) != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		expected string
+		wantErr  bool
+	}{
+		{"default", "/query", "", "", false},
+		{"browser accept", "/query", "text/html,application/xhtml+xml,*/*;q=0.8", "", false},
+		{"csv param", "/query?format=csv", "", exportCSV, false},
+		{"ndjson param", "/query?format=NDJSON", "", exportNDJSON, false},
+		{"json param wins over accept", "/query?format=json", "text/csv", "", false},
+		{"csv accept", "/query", "text/csv; charset=utf-8", exportCSV, false},
+		{"ndjson accept", "/query", "application/json;q=0.5, application/x-ndjson", exportNDJSON, false},
+		{"unknown format", "/query?format=xml", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := createMockRequestEvent("GET", tt.path, nil)
+			if tt.accept != "" {
+				re.Request.Header.Set("Accept", tt.accept)
+			}
+
+			format, err := exportFormat(re)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestHandleSelectExport(t *testing.T) {
+	columns := []string{"id", "count", "score", "note", "due"}
+	rows := [][]any{
+		{"r1", int64(3), 0.25, "a, \"quoted\" note", time.Date(2025, 10, 18, 8, 30, 0, 0, time.UTC)},
+		{"r2", int64(0), nil, nil, nil},
+	}
+	query := "SELECT * FROM reminders"
+
+	t.Run("should stream CSV with typed values", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("StreamSelect", mock.Anything, query, mock.Anything, mock.Anything).
+			Run(streamRows(columns, rows)).Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query?format=csv&page=2", QueryRequest{Query: query + ";"})
+		require.NoError(t, handler.HandleSelect(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "id,count,score,note,due\n"+
+			"r1,3,0.25,\"a, \"\"quoted\"\" note\",2025-10-18T08:30:00Z\n"+
+			"r2,0,,,\n", recorder.Body.String())
+		mockRepo.AssertNotCalled(t, "ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should stream NDJSON in column order", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("StreamSelect", mock.Anything, query, mock.Anything, mock.Anything).
+			Run(streamRows(columns, rows)).Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query", QueryRequest{Query: query})
+		re.Request.Header.Set("Accept", "application/x-ndjson")
+		require.NoError(t, handler.HandleSelect(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, `{"id":"r1","count":3,"score":0.25,"note":"a, \"quoted\" note","due":"2025-10-18T08:30:00Z"}`+"\n"+
+			`{"id":"r2","count":0,"score":null,"note":null,"due":null}`+"\n", recorder.Body.String())
+	})
+
+	t.Run("should write booleans and JSON columns", func(t *testing.T) {
+		for format, expected := range map[string]string{
+			exportCSV:    "done,meta\ntrue,\"{\"\"a\"\":[1]}\"\n",
+			exportNDJSON: `{"done":true,"meta":{"a":[1]}}` + "\n",
+		} {
+			mockRepo := &MockQueryRepository{}
+			mockRepo.On("StreamSelect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Run(streamRows([]string{"done", "meta"}, [][]any{{true, json.RawMessage(`{"a":[1]}`)}})).Return(nil)
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+			re := createMockRequestEvent("POST", "/query?format="+format, QueryRequest{Query: query})
+			require.NoError(t, handler.HandleSelect(re))
+
+			assert.Equal(t, expected, re.Response.(*httptest.ResponseRecorder).Body.String(), format)
+		}
+	})
+
+	t.Run("should send an empty CSV with its header", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("StreamSelect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(streamRows([]string{"id"}, nil)).Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query?format=csv", QueryRequest{Query: query})
+		require.NoError(t, handler.HandleSelect(re))
+
+		assert.Equal(t, "id\n", re.Response.(*httptest.ResponseRecorder).Body.String())
+	})
+
+	t.Run("should send JSON errors when the query fails to start", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("StreamSelect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(streamRows(nil, nil)).Return(assert.AnError)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query?format=ndjson", QueryRequest{Query: query})
+		require.NoError(t, handler.HandleSelect(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Query execution failed")
+	})
+
+	t.Run("should abort a CSV export and audit errors after the first row", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("StreamSelect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(streamRows(columns, rows[:1])).Return(assert.AnError)
+		auditRepo := &MockQueryAuditRepository{}
+		auditRepo.On("Create", mock.Anything, mock.MatchedBy(func(entry *models.QueryAudit) bool {
+			return entry.RowsAffected == 1 && entry.Error == "Export failed: "+assert.AnError.Error()
+		})).Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAuditLog(auditRepo))
+
+		re := createMockRequestEvent("POST", "/query?format=csv", QueryRequest{Query: query})
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() { handler.HandleSelect(re) })
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "r1,3,")
+		auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("should end a truncated NDJSON export with an error line", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("StreamSelect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(streamRows([]string{"id"}, [][]any{{"r1"}})).Return(context.DeadlineExceeded)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query?format=ndjson", QueryRequest{Query: query})
+		require.NoError(t, handler.HandleSelect(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"id":"r1"}`+"\n"+`{"error":"Export failed: context deadline exceeded","rows":1}`+"\n", recorder.Body.String())
+	})
+
+	t.Run("should bound exports by the export timeout only", func(t *testing.T) {
+		for timeout, bounded := range map[time.Duration]bool{0: false, time.Hour: true} {
+			mockRepo := &MockQueryRepository{}
+			mockRepo.On("StreamSelect", mock.MatchedBy(func(ctx context.Context) bool {
+				deadline, ok := ctx.Deadline()
+				return ok == bounded && (!ok || time.Until(deadline) > time.Minute)
+			}), mock.Anything, mock.Anything, mock.Anything).
+				Run(streamRows([]string{"id"}, nil)).Return(nil)
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithExportTimeout(timeout))
+
+			re := createMockRequestEvent("POST", "/query?format=csv", QueryRequest{Query: query})
+			require.NoError(t, handler.HandleSelect(re))
+
+			mockRepo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("should reject unknown formats", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query?format=xlsx", QueryRequest{Query: query})
+		require.NoError(t, handler.HandleSelect(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Invalid format")
+		assert.Empty(t, mockRepo.Calls)
+	})
+
+	t.Run("should export saved queries", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("StreamSelect", mock.Anything, testSavedQueries["due_reminders"].SQL,
+			map[string]any{"user": "u1", "min": int64(1)}, mock.Anything).
+			Run(streamRows([]string{"id"}, [][]any{{"r1"}})).Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithSavedQueries(newSavedQueryRepo()))
+
+		re := createSavedQueryRequestEvent("POST", "due_reminders", map[string]any{"user": "u1"}, "")
+		re.Request.URL.RawQuery = "format=csv"
+		require.NoError(t, handler.HandleRunSaved(re))
+
+		assert.Equal(t, "id\nr1\n", re.Response.(*httptest.ResponseRecorder).Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+}
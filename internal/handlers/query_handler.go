@@ -1,135 +1,400 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"remiaq/internal/middleware"
+	"remiaq/internal/models"
 	"remiaq/internal/repository"
 	"remiaq/internal/utils"
 
 	"github.com/pocketbase/pocketbase/core"
 )
 
-// QueryHandler handles raw SQL query requests
+// QueryHandler handles raw SQL query requests. Callers must be superusers or send one of
+// the handler's API keys.
 type QueryHandler struct {
 	queryRepo repository.QueryRepository
+	policy    middleware.QueryPolicy
+	apiKeys   []APIKey
+	auditRepo repository.QueryAuditRepository // nil = no audit log
+	savedRepo repository.SavedQueryRepository // nil = no saved queries
+	noAdHoc   bool                            // only saved queries may run
+
+	exportTimeout time.Duration // bounds a streamed export; <= 0 = until it ends or the client leaves
+}
+
+// QueryHandlerOption configures optional QueryHandler behaviour
+type QueryHandlerOption func(*QueryHandler)
+
+// WithQueryPolicy sets the table/column allowlist (default middleware.DefaultQueryPolicy)
+func WithQueryPolicy(policy middleware.QueryPolicy) QueryHandlerOption {
+	return func(h *QueryHandler) {
+		h.policy = policy
+	}
+}
+
+// WithAPIKeys sets the API keys accepted besides superuser auth
+func WithAPIKeys(keys ...APIKey) QueryHandlerOption {
+	return func(h *QueryHandler) {
+		h.apiKeys = keys
+	}
+}
+
+// WithAuditLog records every ad-hoc request in repo, denied ones included, and every authorized saved query run
+func WithAuditLog(repo repository.QueryAuditRepository) QueryHandlerOption {
+	return func(h *QueryHandler) {
+		h.auditRepo = repo
+	}
+}
+
+// WithSavedQueries serves the saved queries of repo under /api/queries
+func WithSavedQueries(repo repository.SavedQueryRepository) QueryHandlerOption {
+	return func(h *QueryHandler) {
+		h.savedRepo = repo
+	}
+}
+
+// WithAdHocQueries enables or disables the ad-hoc SQL endpoints (enabled by default);
+// saved queries keep working when they are disabled
+func WithAdHocQueries(enabled bool) QueryHandlerOption {
+	return func(h *QueryHandler) {
+		h.noAdHoc = !enabled
+	}
+}
+
+// WithExportTimeout bounds each CSV/NDJSON export (default none: an export runs until
+// its last row or until the client disconnects)
+func WithExportTimeout(timeout time.Duration) QueryHandlerOption {
+	return func(h *QueryHandler) {
+		h.exportTimeout = timeout
+	}
 }
 
 // NewQueryHandler creates a new query handler
-func NewQueryHandler(queryRepo repository.QueryRepository) *QueryHandler {
-	return &QueryHandler{
+func NewQueryHandler(queryRepo repository.QueryRepository, opts ...QueryHandlerOption) *QueryHandler {
+	h := &QueryHandler{
 		queryRepo: queryRepo,
+		policy:    middleware.DefaultQueryPolicy,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // QueryRequest represents a raw SQL query request
 type QueryRequest struct {
-	Query string `json:"query"`
+	Query  string         `json:"query"`
+	Params map[string]any `json:"params,omitempty"` // values of the {:name} placeholders
+	DryRun bool           `json:"dryRun"`           // mutations only: run in a rolled-back transaction
 }
 
 // parseRequest parses query from request (GET or POST)
 func parseRequest(re *core.RequestEvent) (*QueryRequest, error) {
 	var req QueryRequest
 
+	// UseNumber: số nguyên lớn không bị làm tròn qua float64
 	if re.Request.Method == "GET" {
 		req.Query = re.Request.URL.Query().Get("q")
+		if params := re.Request.URL.Query().Get("params"); params != "" {
+			decoder := json.NewDecoder(strings.NewReader(params))
+			decoder.UseNumber()
+			if err := decoder.Decode(&req.Params); err != nil {
+				return nil, fmt.Errorf("invalid params: %w", err)
+			}
+		}
 	} else {
-		if err := json.NewDecoder(re.Request.Body).Decode(&req); err != nil {
+		decoder := json.NewDecoder(re.Request.Body)
+		decoder.UseNumber()
+		// Body rỗng hợp lệ: truy vấn đã lưu không cần tham số
+		if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
 			return nil, err
 		}
 	}
 
+	// ?dry_run=true works for every method
+	if dryRun := re.Request.URL.Query().Get("dry_run"); dryRun != "" {
+		parsed, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dry_run: %w", err)
+		}
+		req.DryRun = req.DryRun || parsed
+	}
+
 	return &req, nil
 }
 
-// HandleSelect handles SELECT queries
+// validate checks the query against the caller's policy and that its placeholders are bound
+func validate(access *queryAccess, req *QueryRequest, stmt middleware.StatementType) error {
+	if err := access.policy.Validate(req.Query, stmt); err != nil {
+		return err
+	}
+	return middleware.ValidateParams(req.Query, req.Params)
+}
+
+// HandleSelect handles SELECT queries, returning one page of the result (see parsePage) or,
+// with ?format=csv|ndjson or a matching Accept header, streaming every row (see runExport)
 func (h *QueryHandler) HandleSelect(re *core.RequestEvent) error {
 	middleware.SetCORSHeaders(re)
 
+	audit := newAuditRecord(middleware.StatementSelect)
+	defer h.finish(re.Request.Context(), audit)
+
+	access, status, err := h.authorizeAdHoc(re, middleware.StatementSelect)
+	if err != nil {
+		return audit.deny(re, access, status, err)
+	}
+	audit.allow(access)
+
 	req, err := parseRequest(re)
 	if err != nil {
-		return utils.SendError(re, 400, "Invalid request format", err)
+		return audit.fail(re, 400, "Invalid request format", err)
 	}
+	audit.request(req)
 
 	// Validate query
-	if err := middleware.ValidateSelectQuery(req.Query); err != nil {
-		return utils.SendError(re, 400, "Query validation failed", err)
+	if err := validate(access, req, middleware.StatementSelect); err != nil {
+		return audit.fail(re, 400, "Query validation failed", err)
 	}
 
-	// Execute query
-	result, err := h.queryRepo.ExecuteSelect(re.Request.Context(), req.Query)
+	return h.runSelect(re, audit, req.Query, req.Params)
+}
+
+// runSelect executes a validated SELECT and sends the requested page of it, or exports it
+func (h *QueryHandler) runSelect(re *core.RequestEvent, audit *auditRecord, query string, params map[string]any) error {
+	format, err := exportFormat(re)
 	if err != nil {
-		return utils.SendError(re, 400, "Query execution failed", err)
+		return audit.fail(re, 400, "Invalid format", err)
+	}
+	if format != "" {
+		return h.runExport(re, audit, query, params, format)
 	}
 
-	return utils.SendQueryResponse(re, result)
-}
-
-// HandleInsert handles INSERT queries
-func (h *QueryHandler) HandleInsert(re *core.RequestEvent) error {
-	middleware.SetCORSHeaders(re)
+	pg, err := parsePage(re)
+	if err != nil {
+		return audit.fail(re, 400, "Invalid pagination", err)
+	}
 
-	req, err := parseRequest(re)
+	// Lấy dư 1 dòng để biết còn trang sau hay không
+	result, err := h.queryRepo.ExecuteSelectPage(re.Request.Context(), middleware.TrimStatement(query), params,
+		repository.SelectPage{
+			Limit:     pg.perPage + 1,
+			Offset:    pg.offset,
+			Sort:      pg.fields,
+			After:     pg.after,
+			SkipTotal: pg.skipTotal,
+			Schema:    pg.schema,
+		})
 	if err != nil {
-		return utils.SendError(re, 400, "Invalid request format", err)
+		return audit.fail(re, 400, "Query execution failed", err)
 	}
+	items := result.Items
 
-	// Validate query
-	if err := middleware.ValidateInsertQuery(req.Query); err != nil {
-		return utils.SendError(re, 400, "Query validation failed", err)
+	nextCursor := ""
+	if len(items) > pg.perPage {
+		items = items[:pg.perPage]
+		if len(pg.fields) > 0 {
+			if nextCursor, err = encodeCursor(pg.sort, pg.fields, items[len(items)-1]); err != nil {
+				return audit.fail(re, 400, "Query execution failed", err)
+			}
+		}
 	}
+	audit.entry.RowsAffected = int64(len(items))
 
-	// Execute query
-	rowsAffected, lastInsertId, err := h.queryRepo.ExecuteInsert(re.Request.Context(), req.Query)
-	if err != nil {
-		return utils.SendError(re, 400, "Insert execution failed", err)
+	var schema []models.QueryColumn
+	if pg.schema {
+		schema = result.Columns
 	}
+	return utils.SendQueryResponse(re, pg.page, pg.perPage, result.Total, items, nextCursor, schema)
+}
 
-	return utils.SendMutationResponse(re, rowsAffected, lastInsertId)
+// pageRequest is the pagination of a SELECT request
+type pageRequest struct {
+	page, perPage int
+	offset        int
+	sort          string // raw sort parameter, recorded in cursors
+	fields        []repository.SortField
+	after         []any // keyset values from the cursor
+	skipTotal     bool
+	schema        bool // describe the result columns
 }
 
-// HandleUpdate handles UPDATE queries
-func (h *QueryHandler) HandleUpdate(re *core.RequestEvent) error {
-	middleware.SetCORSHeaders(re)
+// sortColumnPattern matches the result column names accepted by sort
+var sortColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
-	req, err := parseRequest(re)
+// parsePage reads page, perPage, sort ("-created,id"), skipTotal, schema and cursor from the
+// query string. A cursor selects the keyset page after the row it was made from: page is then
+// reported as 0 and the total isn't counted.
+func parsePage(re *core.RequestEvent) (*pageRequest, error) {
+	page, perPage, err := utils.ParsePagination(re)
 	if err != nil {
-		return utils.SendError(re, 400, "Invalid request format", err)
+		return nil, err
 	}
+	query := re.Request.URL.Query()
+	pg := &pageRequest{page: page, perPage: perPage, offset: (page - 1) * perPage, sort: query.Get("sort")}
 
-	// Validate query
-	if err := middleware.ValidateUpdateQuery(req.Query); err != nil {
-		return utils.SendError(re, 400, "Query validation failed", err)
+	if pg.sort != "" {
+		for _, column := range strings.Split(pg.sort, ",") {
+			column = strings.TrimSpace(column)
+			desc := strings.HasPrefix(column, "-")
+			column = strings.TrimPrefix(strings.TrimPrefix(column, "-"), "+")
+			if !sortColumnPattern.MatchString(column) {
+				return nil, fmt.Errorf("invalid sort column %q", column)
+			}
+			pg.fields = append(pg.fields, repository.SortField{Column: column, Desc: desc})
+		}
 	}
 
-	// Execute query
-	rowsAffected, err := h.queryRepo.ExecuteUpdate(re.Request.Context(), req.Query)
+	if skipTotal := query.Get("skipTotal"); skipTotal != "" {
+		if pg.skipTotal, err = strconv.ParseBool(skipTotal); err != nil {
+			return nil, fmt.Errorf("invalid skipTotal: %w", err)
+		}
+	}
+	if schema := query.Get("schema"); schema != "" {
+		if pg.schema, err = strconv.ParseBool(schema); err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		if len(pg.fields) == 0 {
+			return nil, errors.New("cursor requires sort")
+		}
+		if pg.after, err = decodeCursor(cursor, pg.sort); err != nil {
+			return nil, err
+		}
+		pg.page, pg.offset = 0, 0
+		pg.skipTotal = true
+	}
+
+	return pg, nil
+}
+
+// queryCursor is the JSON content of a keyset cursor
+type queryCursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// encodeCursor makes the cursor of the page following row
+func encodeCursor(sort string, fields []repository.SortField, row map[string]interface{}) (string, error) {
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		value, ok := row[field.Column]
+		if !ok {
+			return "", fmt.Errorf("sort column %q is not in the result", field.Column)
+		}
+		values[i] = value
+	}
+	data, err := json.Marshal(queryCursor{Sort: sort, Values: values})
 	if err != nil {
-		return utils.SendError(re, 400, "Update execution failed", err)
+		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
 
-	return utils.SendMutationResponse(re, rowsAffected)
+// decodeCursor returns the keyset values of cursor, which must have been made for sort
+func decodeCursor(cursor, sort string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	// UseNumber: giá trị số nguyên lớn của cột không bị làm tròn qua float64
+	var c queryCursor
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.Sort != sort || len(c.Values) != strings.Count(sort, ",")+1 {
+		return nil, errors.New("cursor does not match sort")
+	}
+	return c.Values, nil
+}
+
+// HandleInsert handles INSERT queries
+func (h *QueryHandler) HandleInsert(re *core.RequestEvent) error {
+	return h.handleMutation(re, middleware.StatementInsert)
+}
+
+// HandleUpdate handles UPDATE queries
+func (h *QueryHandler) HandleUpdate(re *core.RequestEvent) error {
+	return h.handleMutation(re, middleware.StatementUpdate)
 }
 
 // HandleDelete handles DELETE queries
 func (h *QueryHandler) HandleDelete(re *core.RequestEvent) error {
+	return h.handleMutation(re, middleware.StatementDelete)
+}
+
+// handleMutation handles an ad-hoc INSERT, UPDATE or DELETE request
+func (h *QueryHandler) handleMutation(re *core.RequestEvent, stmt middleware.StatementType) error {
 	middleware.SetCORSHeaders(re)
 
+	audit := newAuditRecord(stmt)
+	defer h.finish(re.Request.Context(), audit)
+
+	access, status, err := h.authorizeAdHoc(re, stmt)
+	if err != nil {
+		return audit.deny(re, access, status, err)
+	}
+	audit.allow(access)
+
 	req, err := parseRequest(re)
 	if err != nil {
-		return utils.SendError(re, 400, "Invalid request format", err)
+		return audit.fail(re, 400, "Invalid request format", err)
 	}
+	audit.request(req)
 
 	// Validate query
-	if err := middleware.ValidateDeleteQuery(req.Query); err != nil {
-		return utils.SendError(re, 400, "Query validation failed", err)
+	if err := validate(access, req, stmt); err != nil {
+		return audit.fail(re, 400, "Query validation failed", err)
 	}
 
-	// Execute query
-	rowsAffected, err := h.queryRepo.ExecuteDelete(re.Request.Context(), req.Query)
-	if err != nil {
-		return utils.SendError(re, 400, "Delete execution failed", err)
+	return h.runMutation(re, audit, stmt, req.Query, req.Params, req.DryRun)
+}
+
+// runMutation executes a validated mutation, or with dryRun in a rolled-back transaction
+// that reports how many rows it would affect
+func (h *QueryHandler) runMutation(re *core.RequestEvent, audit *auditRecord, stmt middleware.StatementType, query string, params map[string]any, dryRun bool) error {
+	ctx := re.Request.Context()
+	if dryRun {
+		rowsAffected, err := h.queryRepo.ExecuteDryRun(ctx, query, params)
+		if err != nil {
+			return audit.fail(re, 400, "Dry run failed", err)
+		}
+		audit.entry.RowsAffected = rowsAffected
+		return utils.SendDryRunResponse(re, rowsAffected)
+	}
+
+	var rowsAffected, lastInsertId int64
+	var err error
+	switch stmt {
+	case middleware.StatementInsert:
+		if rowsAffected, lastInsertId, err = h.queryRepo.ExecuteInsert(ctx, query, params); err != nil {
+			return audit.fail(re, 400, "Insert execution failed", err)
+		}
+		audit.entry.RowsAffected = rowsAffected
+		return utils.SendMutationResponse(re, rowsAffected, lastInsertId)
+	case middleware.StatementUpdate:
+		if rowsAffected, err = h.queryRepo.ExecuteUpdate(ctx, query, params); err != nil {
+			return audit.fail(re, 400, "Update execution failed", err)
+		}
+	default:
+		if rowsAffected, err = h.queryRepo.ExecuteDelete(ctx, query, params); err != nil {
+			return audit.fail(re, 400, "Delete execution failed", err)
+		}
 	}
+	audit.entry.RowsAffected = rowsAffected
 
 	return utils.SendMutationResponse(re, rowsAffected)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,11 @@ import (
 	"strings"
 	"testing"
 
+	"remiaq/internal/middleware"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+	"remiaq/internal/utils"
+
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/router"
 	"github.com/stretchr/testify/assert"
@@ -23,29 +29,44 @@ type MockQueryRepository struct {
 	mock.Mock
 }
 
-func (m *MockQueryRepository) ExecuteSelect(ctx context.Context, query string) ([]map[string]interface{}, error) {
-	args := m.Called(ctx, query)
+func (m *MockQueryRepository) ExecuteSelectPage(ctx context.Context, query string, params map[string]any, page repository.SelectPage) (*repository.SelectResult, error) {
+	args := m.Called(ctx, query, params, page)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]map[string]interface{}), args.Error(1)
+	return args.Get(0).(*repository.SelectResult), args.Error(1)
 }
 
-func (m *MockQueryRepository) ExecuteInsert(ctx context.Context, query string) (int64, int64, error) {
-	args := m.Called(ctx, query)
+func (m *MockQueryRepository) ExecuteInsert(ctx context.Context, query string, params map[string]any) (int64, int64, error) {
+	args := m.Called(ctx, query, params)
 	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockQueryRepository) ExecuteUpdate(ctx context.Context, query string) (int64, error) {
-	args := m.Called(ctx, query)
+func (m *MockQueryRepository) ExecuteUpdate(ctx context.Context, query string, params map[string]any) (int64, error) {
+	args := m.Called(ctx, query, params)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockQueryRepository) ExecuteDelete(ctx context.Context, query string) (int64, error) {
-	args := m.Called(ctx, query)
+func (m *MockQueryRepository) ExecuteDelete(ctx context.Context, query string, params map[string]any) (int64, error) {
+	args := m.Called(ctx, query, params)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockQueryRepository) StreamSelect(ctx context.Context, query string, params map[string]any, w repository.RowWriter) error {
+	args := m.Called(ctx, query, params, w)
+	return args.Error(0)
+}
+
+func (m *MockQueryRepository) ExecuteDryRun(ctx context.Context, query string, params map[string]any) (int64, error) {
+	args := m.Called(ctx, query, params)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// testQueryPolicy allows the tables used by the queries below
+var testQueryPolicy = middleware.QueryPolicy{
+	Tables: map[string][]string{"users": nil, "reminders": nil, "verifications": nil},
+}
+
 // Helper: Create mock RequestEvent with proper Body handling
 func createMockRequestEvent(method, path string, body interface{}) *core.RequestEvent {
 	var bodyReader io.Reader
@@ -67,10 +88,19 @@ func createMockRequestEvent(method, path string, body interface{}) *core.Request
 			Response: recorder,
 		},
 	}
+	re.Auth = testSuperuser()
 
 	return re
 }
 
+// testSuperuser returns a superuser auth record
+func testSuperuser() *core.Record {
+	record := core.NewRecord(core.NewAuthCollection(core.CollectionNameSuperusers))
+	record.Id = "su1"
+	record.SetEmail("admin@example.com")
+	return record
+}
+
 // ============= TestNewQueryHandler =============
 func TestNewQueryHandler(t *testing.T) {
 	mockRepo := &MockQueryRepository{}
@@ -78,6 +108,17 @@ func TestNewQueryHandler(t *testing.T) {
 
 	assert.NotNil(t, handler)
 	assert.Equal(t, mockRepo, handler.queryRepo)
+	assert.Equal(t, middleware.DefaultQueryPolicy, handler.policy)
+
+	t.Run("should reject tables outside the default policy", func(t *testing.T) {
+		re := createMockRequestEvent("POST", "/query", QueryRequest{Query: "SELECT * FROM _superusers"})
+		_ = handler.HandleSelect(re)
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `table \"_superusers\" is not allowed (line 1, column 15)`)
+		mockRepo.AssertNotCalled(t, "ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 // ============= TestParseRequest =============
@@ -130,6 +171,14 @@ func TestParseRequest(t *testing.T) {
 			expected:    "",
 			expectError: false,
 		},
+		{
+			name:        "POST request without body",
+			method:      "POST",
+			path:        "/query",
+			body:        nil,
+			expected:    "",
+			expectError: false,
+		},
 		{
 			name:        "GET request with special characters",
 			method:      "GET",
@@ -184,11 +233,11 @@ func TestHandleSelect(t *testing.T) {
 			name:  "successful SELECT query",
 			query: "SELECT * FROM users",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteSelect", mock.Anything, "SELECT * FROM users").
-					Return([]map[string]interface{}{
+				m.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM users", mock.Anything, mock.Anything).
+					Return(&repository.SelectResult{Items: []map[string]interface{}{
 						{"id": "1", "name": "John"},
 						{"id": "2", "name": "Jane"},
-					}, nil)
+					}, Total: 2}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedRows:   2,
@@ -197,10 +246,10 @@ func TestHandleSelect(t *testing.T) {
 			name:  "SELECT with WHERE clause",
 			query: "SELECT * FROM users WHERE id = 1",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteSelect", mock.Anything, "SELECT * FROM users WHERE id = 1").
-					Return([]map[string]interface{}{
+				m.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM users WHERE id = 1", mock.Anything, mock.Anything).
+					Return(&repository.SelectResult{Items: []map[string]interface{}{
 						{"id": "1", "name": "John"},
-					}, nil)
+					}, Total: 1}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedRows:   1,
@@ -209,17 +258,17 @@ func TestHandleSelect(t *testing.T) {
 			name:  "SELECT with empty result",
 			query: "SELECT * FROM users WHERE id = 999",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteSelect", mock.Anything, "SELECT * FROM users WHERE id = 999").
-					Return([]map[string]interface{}{}, nil)
+				m.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM users WHERE id = 999", mock.Anything, mock.Anything).
+					Return(&repository.SelectResult{Items: []map[string]interface{}{}, Total: 0}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedRows:   0,
 		},
 		{
 			name:  "SELECT query execution failed",
-			query: "SELECT * FROM nonexistent_table",
+			query: "SELECT * FROM reminders WHERE nonexistent_column = 1",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteSelect", mock.Anything, "SELECT * FROM nonexistent_table").
+				m.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders WHERE nonexistent_column = 1", mock.Anything, mock.Anything).
 					Return(nil, assert.AnError)
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -228,10 +277,10 @@ func TestHandleSelect(t *testing.T) {
 			name:  "SELECT via GET with query parameter",
 			query: "",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteSelect", mock.Anything, "SELECT * FROM users").
-					Return([]map[string]interface{}{
+				m.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM users", mock.Anything, mock.Anything).
+					Return(&repository.SelectResult{Items: []map[string]interface{}{
 						{"id": "1", "name": "John"},
-					}, nil)
+					}, Total: 1}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedRows:   1,
@@ -241,7 +290,7 @@ func TestHandleSelect(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &MockQueryRepository{}
-			handler := NewQueryHandler(mockRepo)
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 			tt.setupMock(mockRepo)
 
 			var re *core.RequestEvent
@@ -254,6 +303,7 @@ func TestHandleSelect(t *testing.T) {
 						Response: recorder,
 					},
 				}
+				re.Auth = testSuperuser()
 			} else {
 				re = createMockRequestEvent("GET", "/query?q="+url.QueryEscape(tt.query), nil)
 				if tt.query != "" {
@@ -272,6 +322,169 @@ func TestHandleSelect(t *testing.T) {
 	}
 }
 
+// ============= TestHandleSelectPagination =============
+func TestHandleSelectPagination(t *testing.T) {
+	rows := func(n int) []map[string]interface{} {
+		items := make([]map[string]interface{}, n)
+		for i := range items {
+			items[i] = map[string]interface{}{"id": fmt.Sprintf("r%d", i), "created": "2025-10-18 09:00:00.000Z"}
+		}
+		return items
+	}
+	get := func(handler *QueryHandler, query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		re := createMockRequestEvent("GET", "/query?q="+url.QueryEscape("SELECT * FROM reminders")+query, nil)
+		require.NoError(t, handler.HandleSelect(re))
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return recorder, body
+	}
+
+	t.Run("should default to the first page and count the total", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything,
+			repository.SelectPage{Limit: 31, Offset: 0}).Return(&repository.SelectResult{Items: rows(31), Total: 65}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		recorder, body := get(handler, "")
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, float64(1), body["page"])
+		assert.Equal(t, float64(30), body["perPage"])
+		assert.Equal(t, float64(65), body["totalItems"])
+		assert.Equal(t, float64(3), body["totalPages"])
+		assert.Len(t, body["items"], 30)
+		assert.NotContains(t, body, "nextCursor", "no cursor without sort")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should page, sort and cap perPage", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything,
+			repository.SelectPage{
+				Limit:  utils.MaxPerPage + 1,
+				Offset: utils.MaxPerPage,
+				Sort:   []repository.SortField{{Column: "created", Desc: true}, {Column: "id"}},
+			}).Return(&repository.SelectResult{Items: rows(3), Total: utils.MaxPerPage + 3}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		_, body := get(handler, "&page=2&perPage=10000&sort=-created,%2Bid")
+
+		assert.Equal(t, float64(2), body["page"])
+		assert.Equal(t, float64(utils.MaxPerPage), body["perPage"])
+		assert.Equal(t, float64(2), body["totalPages"])
+		assert.NotContains(t, body, "nextCursor", "last page")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should follow keyset cursors", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything,
+			repository.SelectPage{Limit: 3, Offset: 0, Sort: []repository.SortField{{Column: "created"}, {Column: "id"}}, SkipTotal: true}).
+			Return(&repository.SelectResult{Items: rows(3), Total: -1}, nil).Once()
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		_, body := get(handler, "&perPage=2&sort=created,id&skipTotal=1")
+		assert.Equal(t, float64(-1), body["totalItems"])
+		assert.Equal(t, float64(-1), body["totalPages"])
+		cursor, ok := body["nextCursor"].(string)
+		require.True(t, ok)
+
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything,
+			repository.SelectPage{
+				Limit:     3,
+				Sort:      []repository.SortField{{Column: "created"}, {Column: "id"}},
+				After:     []any{"2025-10-18 09:00:00.000Z", "r1"},
+				SkipTotal: true,
+			}).Return(&repository.SelectResult{Items: rows(1), Total: -1}, nil).Once()
+
+		_, body = get(handler, "&perPage=2&page=5&sort=created,id&cursor="+cursor)
+		assert.Equal(t, float64(0), body["page"])
+		assert.Equal(t, float64(-1), body["totalItems"])
+		assert.NotContains(t, body, "nextCursor")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should keep typed cursor values", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{
+				{"n": int64(12345678901234567), "done": true},
+				{"n": int64(1), "done": false},
+			}, Total: -1}, nil).Once()
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		_, body := get(handler, "&perPage=1&sort=-n,done")
+		cursor := body["nextCursor"].(string)
+
+		after, err := decodeCursor(cursor, "-n,done")
+		require.NoError(t, err)
+		assert.Equal(t, []any{json.Number("12345678901234567"), true}, after)
+	})
+
+	t.Run("should describe the columns with schema", func(t *testing.T) {
+		columns := []models.QueryColumn{{Name: "id", Type: "text", DeclType: "TEXT"}, {Name: "done", Type: "boolean", DeclType: "BOOLEAN"}}
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything,
+			repository.SelectPage{Limit: 31, Schema: true}).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{}, Columns: columns, Total: 0}, nil)
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders", mock.Anything,
+			repository.SelectPage{Limit: 31}).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{{"id": "r1", "done": true}}, Columns: columns, Total: 1}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		recorder, body := get(handler, "&schema=true")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"name": "id", "type": "text", "declType": "TEXT"},
+			map[string]interface{}{"name": "done", "type": "boolean", "declType": "BOOLEAN"},
+		}, body["schema"])
+
+		_, body = get(handler, "")
+		assert.NotContains(t, body, "schema")
+		assert.Equal(t, true, body["items"].([]interface{})[0].(map[string]interface{})["done"])
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should trim the terminating semicolon", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM reminders ", mock.Anything, mock.Anything).
+			Return(&repository.SelectResult{Items: rows(0), Total: 0}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query", QueryRequest{Query: "SELECT * FROM reminders ; -- done"})
+		require.NoError(t, handler.HandleSelect(re))
+
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject invalid pagination", func(t *testing.T) {
+		tests := map[string]string{
+			"&page=0":            "invalid page",
+			"&perPage=abc":       "invalid perPage",
+			"&sort=id%3BDROP":    `invalid sort column \"id;DROP\"`,
+			"&sort=,id":          `invalid sort column \"\"`,
+			"&skipTotal=maybe":   "invalid skipTotal",
+			"&schema=maybe":      "invalid schema",
+			"&cursor=abc":        "cursor requires sort",
+			"&sort=id&cursor=!!": "invalid cursor",
+			"&sort=id&cursor=eyJzIjoiLWlkIiwidiI6WyJyMSJdfQ": "cursor does not match sort", // {"s":"-id","v":["r1"]}
+		}
+
+		for query, message := range tests {
+			mockRepo := &MockQueryRepository{}
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+			recorder, _ := get(handler, query)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+			assert.Contains(t, recorder.Body.String(), message, query)
+			assert.Empty(t, mockRepo.Calls, query)
+		}
+	})
+}
+
 // ============= TestHandleInsert =============
 func TestHandleInsert(t *testing.T) {
 	tests := []struct {
@@ -285,7 +498,7 @@ func TestHandleInsert(t *testing.T) {
 			name:  "successful INSERT query",
 			query: "INSERT INTO users (name) VALUES ('John')",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteInsert", mock.Anything, "INSERT INTO users (name) VALUES ('John')").
+				m.On("ExecuteInsert", mock.Anything, "INSERT INTO users (name) VALUES ('John')", mock.Anything).
 					Return(int64(1), int64(123), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -295,7 +508,7 @@ func TestHandleInsert(t *testing.T) {
 			name:  "INSERT with multiple rows",
 			query: "INSERT INTO users (name, email) VALUES ('John', 'john@example.com')",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteInsert", mock.Anything, mock.Anything).
+				m.On("ExecuteInsert", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(1), int64(124), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -305,7 +518,7 @@ func TestHandleInsert(t *testing.T) {
 			name:  "INSERT execution failed",
 			query: "INSERT INTO users (name) VALUES ('John')",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteInsert", mock.Anything, "INSERT INTO users (name) VALUES ('John')").
+				m.On("ExecuteInsert", mock.Anything, "INSERT INTO users (name) VALUES ('John')", mock.Anything).
 					Return(int64(0), int64(0), assert.AnError)
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -315,7 +528,7 @@ func TestHandleInsert(t *testing.T) {
 			name:  "INSERT with duplicate key",
 			query: "INSERT INTO users (id, name) VALUES (1, 'John')",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteInsert", mock.Anything, mock.Anything).
+				m.On("ExecuteInsert", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(0), int64(0), assert.AnError)
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -326,7 +539,7 @@ func TestHandleInsert(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &MockQueryRepository{}
-			handler := NewQueryHandler(mockRepo)
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 			tt.setupMock(mockRepo)
 
 			body := QueryRequest{Query: tt.query}
@@ -355,7 +568,7 @@ func TestHandleUpdate(t *testing.T) {
 			name:  "successful UPDATE query",
 			query: "UPDATE users SET name='Jane' WHERE id=1",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteUpdate", mock.Anything, "UPDATE users SET name='Jane' WHERE id=1").
+				m.On("ExecuteUpdate", mock.Anything, "UPDATE users SET name='Jane' WHERE id=1", mock.Anything).
 					Return(int64(1), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -365,7 +578,7 @@ func TestHandleUpdate(t *testing.T) {
 			name:  "UPDATE with no matching rows",
 			query: "UPDATE users SET name='Jane' WHERE id=999",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteUpdate", mock.Anything, mock.Anything).
+				m.On("ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(0), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -375,7 +588,7 @@ func TestHandleUpdate(t *testing.T) {
 			name:  "UPDATE multiple rows",
 			query: "UPDATE users SET status='active' WHERE role='admin'",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteUpdate", mock.Anything, mock.Anything).
+				m.On("ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(5), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -385,7 +598,7 @@ func TestHandleUpdate(t *testing.T) {
 			name:  "UPDATE execution failed",
 			query: "UPDATE users SET name='Jane' WHERE id=1",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteUpdate", mock.Anything, "UPDATE users SET name='Jane' WHERE id=1").
+				m.On("ExecuteUpdate", mock.Anything, "UPDATE users SET name='Jane' WHERE id=1", mock.Anything).
 					Return(int64(0), assert.AnError)
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -395,7 +608,7 @@ func TestHandleUpdate(t *testing.T) {
 			name:  "UPDATE with JOIN",
 			query: "UPDATE users SET status='verified' WHERE id IN (SELECT user_id FROM verifications)",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteUpdate", mock.Anything, mock.Anything).
+				m.On("ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(3), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -406,7 +619,7 @@ func TestHandleUpdate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &MockQueryRepository{}
-			handler := NewQueryHandler(mockRepo)
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 			tt.setupMock(mockRepo)
 
 			body := QueryRequest{Query: tt.query}
@@ -435,7 +648,7 @@ func TestHandleDelete(t *testing.T) {
 			name:  "successful DELETE query",
 			query: "DELETE FROM users WHERE id=1",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteDelete", mock.Anything, "DELETE FROM users WHERE id=1").
+				m.On("ExecuteDelete", mock.Anything, "DELETE FROM users WHERE id=1", mock.Anything).
 					Return(int64(1), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -445,7 +658,7 @@ func TestHandleDelete(t *testing.T) {
 			name:  "DELETE with no matching rows",
 			query: "DELETE FROM users WHERE id=999",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteDelete", mock.Anything, mock.Anything).
+				m.On("ExecuteDelete", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(0), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -455,7 +668,7 @@ func TestHandleDelete(t *testing.T) {
 			name:  "DELETE multiple rows",
 			query: "DELETE FROM users WHERE created_at < '2020-01-01'",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteDelete", mock.Anything, mock.Anything).
+				m.On("ExecuteDelete", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(100), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -465,7 +678,7 @@ func TestHandleDelete(t *testing.T) {
 			name:  "DELETE execution failed",
 			query: "DELETE FROM users WHERE id=1",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteDelete", mock.Anything, "DELETE FROM users WHERE id=1").
+				m.On("ExecuteDelete", mock.Anything, "DELETE FROM users WHERE id=1", mock.Anything).
 					Return(int64(0), assert.AnError)
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -475,7 +688,7 @@ func TestHandleDelete(t *testing.T) {
 			name:  "DELETE with complex WHERE",
 			query: "DELETE FROM reminders WHERE user_id IN (SELECT id FROM users WHERE status='inactive')",
 			setupMock: func(m *MockQueryRepository) {
-				m.On("ExecuteDelete", mock.Anything, mock.Anything).
+				m.On("ExecuteDelete", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(50), nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -486,7 +699,7 @@ func TestHandleDelete(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &MockQueryRepository{}
-			handler := NewQueryHandler(mockRepo)
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 			tt.setupMock(mockRepo)
 
 			body := QueryRequest{Query: tt.query}
@@ -502,6 +715,156 @@ func TestHandleDelete(t *testing.T) {
 	}
 }
 
+// ============= TestHandleDryRun =============
+func TestHandleDryRun(t *testing.T) {
+	handlers := map[string]func(*QueryHandler, *core.RequestEvent) error{
+		"INSERT INTO users (name) VALUES ('John')":    (*QueryHandler).HandleInsert,
+		"UPDATE users SET name = 'Jane' WHERE id = 1": (*QueryHandler).HandleUpdate,
+		"DELETE FROM users WHERE id = 1":              (*QueryHandler).HandleDelete,
+	}
+
+	for query, handle := range handlers {
+		t.Run("dryRun in body: "+query, func(t *testing.T) {
+			mockRepo := &MockQueryRepository{}
+			mockRepo.On("ExecuteDryRun", mock.Anything, query, mock.Anything).Return(int64(2), nil)
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+			re := createMockRequestEvent("POST", "/query", QueryRequest{Query: query, DryRun: true})
+			require.NoError(t, handle(handler, re))
+
+			recorder := re.Response.(*httptest.ResponseRecorder)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.JSONEq(t, `{"rowsAffected":2,"dryRun":true}`, recorder.Body.String())
+			mockRepo.AssertExpectations(t)
+			mockRepo.AssertNotCalled(t, "ExecuteInsert", mock.Anything, mock.Anything, mock.Anything)
+			mockRepo.AssertNotCalled(t, "ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything)
+			mockRepo.AssertNotCalled(t, "ExecuteDelete", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("dry_run query parameter", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteDryRun", mock.Anything, "DELETE FROM users WHERE verified = 0", mock.Anything).Return(int64(10), nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query?dry_run=true", QueryRequest{Query: "DELETE FROM users WHERE verified = 0"})
+		require.NoError(t, handler.HandleDelete(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"rowsAffected":10,"dryRun":true}`, recorder.Body.String())
+	})
+
+	t.Run("invalid dry_run query parameter", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query?dry_run=maybe", QueryRequest{Query: "DELETE FROM users WHERE verified = 0"})
+		_ = handler.HandleDelete(re)
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		mockRepo.AssertNotCalled(t, "ExecuteDryRun", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("dry run failed", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteDryRun", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), assert.AnError)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("POST", "/query", QueryRequest{Query: "DELETE FROM users WHERE verified = 0", DryRun: true})
+		_ = handler.HandleDelete(re)
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+// ============= TestHandleParams =============
+func TestHandleParams(t *testing.T) {
+	t.Run("should pass bound params to the repository", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		query := "SELECT * FROM reminders WHERE user_id = {:uid} AND retry_count > {:n}"
+		mockRepo.On("ExecuteSelectPage", mock.Anything, query, map[string]any{"uid": "u1", "n": json.Number("2")}, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{}, Total: 0}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		body := map[string]any{"query": query, "params": map[string]any{"uid": "u1", "n": 2}}
+		re := createMockRequestEvent("POST", "/query", body)
+		require.NoError(t, handler.HandleSelect(re))
+
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should read params of GET requests from JSON", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, "SELECT * FROM users WHERE id = {:id}", map[string]any{"id": json.Number("12345678901234567")}, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{}, Total: 0}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+		path := "/query?q=" + url.QueryEscape("SELECT * FROM users WHERE id = {:id}") +
+			"&params=" + url.QueryEscape(`{"id": 12345678901234567}`)
+		re := createMockRequestEvent("GET", path, nil)
+		require.NoError(t, handler.HandleSelect(re))
+
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject unbound placeholders", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			body   QueryRequest
+			handle func(*QueryHandler, *core.RequestEvent) error
+			errMsg string
+		}{
+			{
+				name:   "missing param",
+				body:   QueryRequest{Query: "DELETE FROM users WHERE id = {:id}", Params: map[string]any{"uid": "u1"}},
+				handle: (*QueryHandler).HandleDelete,
+				errMsg: "placeholder {:id} is not bound (line 1, column 30)",
+			},
+			{
+				name:   "positional placeholder",
+				body:   QueryRequest{Query: "SELECT * FROM users WHERE id = ?"},
+				handle: (*QueryHandler).HandleSelect,
+				errMsg: `unsupported placeholder \"?\", use {:name}`,
+			},
+			{
+				name:   "dry run",
+				body:   QueryRequest{Query: "UPDATE users SET name = {:name} WHERE id = 1", DryRun: true},
+				handle: (*QueryHandler).HandleUpdate,
+				errMsg: "placeholder {:name} is not bound",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockRepo := &MockQueryRepository{}
+				handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
+
+				re := createMockRequestEvent("POST", "/query", tt.body)
+				_ = tt.handle(handler, re)
+
+				recorder := re.Response.(*httptest.ResponseRecorder)
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), tt.errMsg)
+				assert.Empty(t, mockRepo.Calls)
+			})
+		}
+	})
+
+	t.Run("should reject invalid params JSON", func(t *testing.T) {
+		handler := NewQueryHandler(&MockQueryRepository{}, WithQueryPolicy(testQueryPolicy))
+
+		re := createMockRequestEvent("GET", "/query?q=SELECT+1&params=%7Bbad", nil)
+		_ = handler.HandleSelect(re)
+
+		assert.Equal(t, http.StatusBadRequest, re.Response.(*httptest.ResponseRecorder).Code)
+	})
+}
+
 // ============= TestRequestValidation =============
 func TestRequestValidation(t *testing.T) {
 	tests := []struct {
@@ -568,9 +931,9 @@ func TestErrorHandling(t *testing.T) {
 		{
 			name: "SELECT error handling",
 			handler: func(m *MockQueryRepository) *QueryHandler {
-				m.On("ExecuteSelect", mock.Anything, mock.Anything).
+				m.On("ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 					Return(nil, assert.AnError)
-				return NewQueryHandler(m)
+				return NewQueryHandler(m, WithQueryPolicy(testQueryPolicy))
 			},
 			requestMethod:  "GET",
 			expectedStatus: http.StatusBadRequest,
@@ -578,9 +941,9 @@ func TestErrorHandling(t *testing.T) {
 		{
 			name: "INSERT error handling",
 			handler: func(m *MockQueryRepository) *QueryHandler {
-				m.On("ExecuteInsert", mock.Anything, mock.Anything).
+				m.On("ExecuteInsert", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(0), int64(0), assert.AnError)
-				return NewQueryHandler(m)
+				return NewQueryHandler(m, WithQueryPolicy(testQueryPolicy))
 			},
 			requestMethod:  "POST",
 			expectedStatus: http.StatusBadRequest,
@@ -588,9 +951,9 @@ func TestErrorHandling(t *testing.T) {
 		{
 			name: "UPDATE error handling",
 			handler: func(m *MockQueryRepository) *QueryHandler {
-				m.On("ExecuteUpdate", mock.Anything, mock.Anything).
+				m.On("ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(0), assert.AnError)
-				return NewQueryHandler(m)
+				return NewQueryHandler(m, WithQueryPolicy(testQueryPolicy))
 			},
 			requestMethod:  "POST",
 			expectedStatus: http.StatusBadRequest,
@@ -598,9 +961,9 @@ func TestErrorHandling(t *testing.T) {
 		{
 			name: "DELETE error handling",
 			handler: func(m *MockQueryRepository) *QueryHandler {
-				m.On("ExecuteDelete", mock.Anything, mock.Anything).
+				m.On("ExecuteDelete", mock.Anything, mock.Anything, mock.Anything).
 					Return(int64(0), assert.AnError)
-				return NewQueryHandler(m)
+				return NewQueryHandler(m, WithQueryPolicy(testQueryPolicy))
 			},
 			requestMethod:  "POST",
 			expectedStatus: http.StatusBadRequest,
@@ -640,18 +1003,18 @@ func TestErrorHandling(t *testing.T) {
 // ============= TestConcurrency =============
 func TestConcurrency(t *testing.T) {
 	mockRepo := &MockQueryRepository{}
-	handler := NewQueryHandler(mockRepo)
+	handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 
-	mockRepo.On("ExecuteSelect", mock.Anything, mock.Anything).
-		Return([]map[string]interface{}{{"id": "1"}}, nil).
+	mockRepo.On("ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&repository.SelectResult{Items: []map[string]interface{}{{"id": "1"}}, Total: 1}, nil).
 		Maybe()
-	mockRepo.On("ExecuteInsert", mock.Anything, mock.Anything).
+	mockRepo.On("ExecuteInsert", mock.Anything, mock.Anything, mock.Anything).
 		Return(int64(1), int64(100), nil).
 		Maybe()
-	mockRepo.On("ExecuteUpdate", mock.Anything, mock.Anything).
+	mockRepo.On("ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything).
 		Return(int64(1), nil).
 		Maybe()
-	mockRepo.On("ExecuteDelete", mock.Anything, mock.Anything).
+	mockRepo.On("ExecuteDelete", mock.Anything, mock.Anything, mock.Anything).
 		Return(int64(1), nil).
 		Maybe()
 
@@ -702,10 +1065,10 @@ func TestConcurrency(t *testing.T) {
 // ============= Benchmarks =============
 func BenchmarkHandleSelect(b *testing.B) {
 	mockRepo := &MockQueryRepository{}
-	handler := NewQueryHandler(mockRepo)
+	handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 
-	mockRepo.On("ExecuteSelect", mock.Anything, mock.Anything).
-		Return([]map[string]interface{}{{"id": "1"}}, nil).
+	mockRepo.On("ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&repository.SelectResult{Items: []map[string]interface{}{{"id": "1"}}, Total: 1}, nil).
 		Maybe()
 
 	b.ResetTimer()
@@ -717,9 +1080,9 @@ func BenchmarkHandleSelect(b *testing.B) {
 
 func BenchmarkHandleInsert(b *testing.B) {
 	mockRepo := &MockQueryRepository{}
-	handler := NewQueryHandler(mockRepo)
+	handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 
-	mockRepo.On("ExecuteInsert", mock.Anything, mock.Anything).
+	mockRepo.On("ExecuteInsert", mock.Anything, mock.Anything, mock.Anything).
 		Return(int64(1), int64(100), nil).
 		Maybe()
 
@@ -733,9 +1096,9 @@ func BenchmarkHandleInsert(b *testing.B) {
 
 func BenchmarkHandleUpdate(b *testing.B) {
 	mockRepo := &MockQueryRepository{}
-	handler := NewQueryHandler(mockRepo)
+	handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 
-	mockRepo.On("ExecuteUpdate", mock.Anything, mock.Anything).
+	mockRepo.On("ExecuteUpdate", mock.Anything, mock.Anything, mock.Anything).
 		Return(int64(1), nil).
 		Maybe()
 
@@ -749,9 +1112,9 @@ func BenchmarkHandleUpdate(b *testing.B) {
 
 func BenchmarkHandleDelete(b *testing.B) {
 	mockRepo := &MockQueryRepository{}
-	handler := NewQueryHandler(mockRepo)
+	handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy))
 
-	mockRepo.On("ExecuteDelete", mock.Anything, mock.Anything).
+	mockRepo.On("ExecuteDelete", mock.Anything, mock.Anything, mock.Anything).
 		Return(int64(1), nil).
 		Maybe()
 
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"remiaq/internal/middleware"
+	"remiaq/internal/models"
+	"remiaq/internal/utils"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var allStatements = []middleware.StatementType{
+	middleware.StatementSelect, middleware.StatementInsert, middleware.StatementUpdate, middleware.StatementDelete,
+}
+
+// canRun reports whether the caller has the role of the saved query
+func canRun(access *queryAccess, saved *models.SavedQuery) bool {
+	switch saved.Role {
+	case models.SavedQueryRoleSuperuser:
+		return access.superuser
+	case models.SavedQueryRoleWrite:
+		return access.write
+	case models.SavedQueryRoleRead:
+		return true
+	}
+	return false
+}
+
+// HandleListSaved handles GET /api/queries: the saved queries the caller may run
+func (h *QueryHandler) HandleListSaved(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	access, status, err := h.authenticate(re)
+	if err != nil {
+		return utils.SendError(re, status, "Access denied", err)
+	}
+	if h.savedRepo == nil {
+		return utils.SendError(re, 404, "Saved queries are not enabled", nil)
+	}
+
+	queries, err := h.savedRepo.List(re.Request.Context())
+	if err != nil {
+		return utils.SendError(re, 500, "Failed to get saved queries", err)
+	}
+
+	visible := make([]*models.SavedQuery, 0, len(queries))
+	for _, saved := range queries {
+		if canRun(access, saved) {
+			visible = append(visible, saved)
+		}
+	}
+	return utils.SendSuccess(re, "", visible)
+}
+
+// HandleRunSaved handles GET/POST /api/queries/{name}/run. Parameters are sent like those of
+// the raw query endpoints (params JSON, dryRun) and checked against the saved schema; SELECT
+// results are paginated the same way. Mutations also need a write API key.
+func (h *QueryHandler) HandleRunSaved(re *core.RequestEvent) error {
+	middleware.SetCORSHeaders(re)
+
+	access, status, err := h.authenticate(re)
+	if err != nil {
+		return utils.SendError(re, status, "Access denied", err)
+	}
+	if h.savedRepo == nil {
+		return utils.SendError(re, 404, "Saved queries are not enabled", nil)
+	}
+
+	name := re.Request.PathValue("name")
+	saved, err := h.savedRepo.GetByName(re.Request.Context(), name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return utils.SendError(re, 404, "Saved query not found", err)
+	case err != nil:
+		return utils.SendError(re, 500, "Failed to get saved query", err)
+	}
+	if !canRun(access, saved) {
+		return utils.SendError(re, 403, "Access denied", fmt.Errorf("saved query %q requires role %s", name, saved.Role))
+	}
+
+	// Câu SQL do admin soạn: sai thì là lỗi cấu hình, không phải lỗi của người gọi
+	if err := saved.Validate(); err != nil {
+		return utils.SendError(re, 500, "Saved query is invalid", err)
+	}
+	stmt, err := h.policy.ValidateStatement(saved.SQL, allStatements...)
+	if err != nil {
+		return utils.SendError(re, 500, "Saved query is invalid", err)
+	}
+	if stmt != middleware.StatementSelect && !access.write {
+		return utils.SendError(re, 403, "Access denied", errReadOnlyKey)
+	}
+
+	audit := newAuditRecord(stmt)
+	audit.allow(access)
+	audit.entry.SavedQuery = saved.Name
+	audit.entry.Query = saved.SQL
+	defer h.finish(re.Request.Context(), audit)
+
+	req, err := parseRequest(re)
+	if err != nil {
+		return audit.fail(re, 400, "Invalid request format", err)
+	}
+	if req.Query != "" {
+		return audit.fail(re, 400, "Invalid request format", errors.New("query cannot be set when running a saved query"))
+	}
+	audit.entry.Params = req.Params
+	audit.entry.DryRun = req.DryRun
+
+	params, err := saved.BindParams(req.Params)
+	if err != nil {
+		return audit.fail(re, 400, "Invalid parameters", err)
+	}
+	audit.entry.Params = params
+
+	// API key giới hạn bảng vẫn áp dụng cho truy vấn đã lưu
+	if err := access.policy.Validate(saved.SQL, stmt); err != nil {
+		return audit.fail(re, 400, "Query validation failed", err)
+	}
+	if err := middleware.ValidateParams(saved.SQL, params); err != nil {
+		return audit.fail(re, 500, "Saved query is invalid", err)
+	}
+
+	if stmt == middleware.StatementSelect {
+		return h.runSelect(re, audit, saved.SQL, params)
+	}
+	return h.runMutation(re, audit, stmt, saved.SQL, params, req.DryRun)
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock SavedQueryRepository
+type MockSavedQueryRepository struct {
+	mock.Mock
+}
+
+func (m *MockSavedQueryRepository) GetByName(ctx context.Context, name string) (*models.SavedQuery, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SavedQuery), args.Error(1)
+}
+
+func (m *MockSavedQueryRepository) List(ctx context.Context) ([]*models.SavedQuery, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SavedQuery), args.Error(1)
+}
+
+// testSavedQueries are the saved queries used by the tests below
+var testSavedQueries = map[string]*models.SavedQuery{
+	"due_reminders": {
+		Name: "due_reminders",
+		SQL:  "SELECT * FROM reminders WHERE user_id = {:user} AND priority >= {:min}",
+		Params: map[string]models.SavedQueryParam{
+			"user": {Type: models.ParamTypeString, Required: true},
+			"min":  {Type: models.ParamTypeInteger, Default: json.Number("1")},
+		},
+		Role: models.SavedQueryRoleRead,
+	},
+	"purge_user": {
+		Name:   "purge_user",
+		SQL:    "DELETE FROM users WHERE id = {:id}",
+		Params: map[string]models.SavedQueryParam{"id": {Type: models.ParamTypeString, Required: true}},
+		Role:   models.SavedQueryRoleRead,
+	},
+	"all_users": {
+		Name: "all_users",
+		SQL:  "SELECT * FROM users",
+		Role: models.SavedQueryRoleSuperuser,
+	},
+}
+
+// newSavedQueryRepo returns a mock serving testSavedQueries
+func newSavedQueryRepo() *MockSavedQueryRepository {
+	savedRepo := &MockSavedQueryRepository{}
+	for name, saved := range testSavedQueries {
+		savedRepo.On("GetByName", mock.Anything, name).Return(saved, nil)
+	}
+	savedRepo.On("GetByName", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+	return savedRepo
+}
+
+// createSavedQueryRequestEvent creates a run request for the saved query name
+func createSavedQueryRequestEvent(method, name string, params map[string]any, key string) *core.RequestEvent {
+	path := "/api/queries/" + name + "/run"
+	var body interface{}
+	if method == "GET" && params != nil {
+		encoded, _ := json.Marshal(params)
+		path += "?params=" + url.QueryEscape(string(encoded))
+	} else if params != nil {
+		body = map[string]any{"params": params}
+	}
+
+	re := createMockRequestEvent(method, path, body)
+	if key != "" {
+		re = createAPIKeyRequestEvent(method, path, body, key)
+	}
+	re.Request.SetPathValue("name", name)
+	return re
+}
+
+func TestHandleRunSaved(t *testing.T) {
+	t.Run("should bind typed params and defaults", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, testSavedQueries["due_reminders"].SQL,
+			map[string]any{"user": "u1", "min": int64(1)}, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{{"id": "r1"}}, Total: 1}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithSavedQueries(newSavedQueryRepo()))
+
+		for _, method := range []string{"GET", "POST"} {
+			re := createSavedQueryRequestEvent(method, "due_reminders", map[string]any{"user": "u1"}, "")
+			require.NoError(t, handler.HandleRunSaved(re))
+
+			recorder := re.Response.(*httptest.ResponseRecorder)
+			assert.Equal(t, http.StatusOK, recorder.Code, method)
+			assert.Contains(t, recorder.Body.String(), `"totalItems":1`)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject params not matching the schema", func(t *testing.T) {
+		tests := map[string]map[string]any{
+			"missing required": {"min": 2},
+			"wrong type":       {"user": "u1", "min": "high"},
+			"fraction":         {"user": "u1", "min": 1.5},
+			"unknown":          {"user": "u1", "max": 3},
+		}
+
+		for name, params := range tests {
+			mockRepo := &MockQueryRepository{}
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithSavedQueries(newSavedQueryRepo()))
+
+			re := createSavedQueryRequestEvent("POST", "due_reminders", params, "")
+			require.NoError(t, handler.HandleRunSaved(re))
+
+			recorder := re.Response.(*httptest.ResponseRecorder)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, name)
+			assert.Contains(t, recorder.Body.String(), "Invalid parameters", name)
+			assert.Empty(t, mockRepo.Calls, name)
+		}
+	})
+
+	t.Run("should reject ad-hoc SQL in the request", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithSavedQueries(newSavedQueryRepo()))
+
+		re := createMockRequestEvent("POST", "/api/queries/due_reminders/run", QueryRequest{Query: "SELECT * FROM users"})
+		re.Request.SetPathValue("name", "due_reminders")
+		require.NoError(t, handler.HandleRunSaved(re))
+
+		assert.Equal(t, http.StatusBadRequest, re.Response.(*httptest.ResponseRecorder).Code)
+		assert.Empty(t, mockRepo.Calls)
+	})
+
+	t.Run("should return 404 for unknown queries", func(t *testing.T) {
+		handler := NewQueryHandler(&MockQueryRepository{}, WithQueryPolicy(testQueryPolicy), WithSavedQueries(newSavedQueryRepo()))
+
+		re := createSavedQueryRequestEvent("POST", "missing", nil, "")
+		require.NoError(t, handler.HandleRunSaved(re))
+
+		assert.Equal(t, http.StatusNotFound, re.Response.(*httptest.ResponseRecorder).Code)
+	})
+
+	t.Run("should return 404 without a saved query repository", func(t *testing.T) {
+		handler := NewQueryHandler(&MockQueryRepository{}, WithQueryPolicy(testQueryPolicy))
+
+		re := createSavedQueryRequestEvent("POST", "due_reminders", nil, "")
+		require.NoError(t, handler.HandleRunSaved(re))
+
+		assert.Equal(t, http.StatusNotFound, re.Response.(*httptest.ResponseRecorder).Code)
+	})
+
+	t.Run("should enforce the role and the API key scope", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			params   map[string]any
+			key      string
+			expected int
+		}{
+			{"anonymous", "due_reminders", map[string]any{"user": "u1"}, "invalid-key-0123456789", http.StatusUnauthorized},
+			{"superuser role with a key", "all_users", nil, "write-key-0123456789", http.StatusForbidden},
+			{"mutation with a read key", "purge_user", map[string]any{"id": "u1"}, "read-key-0123456789", http.StatusForbidden},
+		}
+
+		for _, tt := range tests {
+			mockRepo := &MockQueryRepository{}
+			handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...), WithSavedQueries(newSavedQueryRepo()))
+
+			re := createSavedQueryRequestEvent("POST", tt.query, tt.params, tt.key)
+			require.NoError(t, handler.HandleRunSaved(re))
+
+			assert.Equal(t, tt.expected, re.Response.(*httptest.ResponseRecorder).Code, tt.name)
+			assert.Empty(t, mockRepo.Calls, tt.name)
+		}
+	})
+
+	t.Run("should apply the table scope of read keys", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		savedRepo := &MockSavedQueryRepository{}
+		savedRepo.On("GetByName", mock.Anything, "user_count").
+			Return(&models.SavedQuery{Name: "user_count", SQL: "SELECT COUNT(*) FROM users", Role: models.SavedQueryRoleRead}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...), WithSavedQueries(savedRepo))
+
+		re := createSavedQueryRequestEvent("POST", "user_count", nil, "read-key-0123456789")
+		require.NoError(t, handler.HandleRunSaved(re))
+
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Query validation failed")
+		assert.Empty(t, mockRepo.Calls)
+	})
+
+	t.Run("should run mutations and dry runs with a write key", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteDryRun", mock.Anything, "DELETE FROM users WHERE id = {:id}", map[string]any{"id": "u1"}).Return(int64(1), nil)
+		mockRepo.On("ExecuteDelete", mock.Anything, "DELETE FROM users WHERE id = {:id}", map[string]any{"id": "u1"}).Return(int64(1), nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...), WithSavedQueries(newSavedQueryRepo()))
+
+		re := createSavedQueryRequestEvent("POST", "purge_user", map[string]any{"id": "u1"}, "write-key-0123456789")
+		re.Request.URL.RawQuery = "dry_run=true"
+		require.NoError(t, handler.HandleRunSaved(re))
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertNotCalled(t, "ExecuteDelete", mock.Anything, mock.Anything, mock.Anything)
+
+		re = createSavedQueryRequestEvent("POST", "purge_user", map[string]any{"id": "u1"}, "write-key-0123456789")
+		require.NoError(t, handler.HandleRunSaved(re))
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should keep working when ad-hoc SQL is disabled", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{}, Total: 0}, nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithSavedQueries(newSavedQueryRepo()), WithAdHocQueries(false))
+
+		re := createMockRequestEvent("POST", "/api/rquery", QueryRequest{Query: "SELECT * FROM users"})
+		require.NoError(t, handler.HandleSelect(re))
+		recorder := re.Response.(*httptest.ResponseRecorder)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Access denied")
+
+		re = createSavedQueryRequestEvent("POST", "all_users", nil, "")
+		require.NoError(t, handler.HandleRunSaved(re))
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertNumberOfCalls(t, "ExecuteSelectPage", 1)
+	})
+
+	t.Run("should record the saved query in the audit log", func(t *testing.T) {
+		mockRepo := &MockQueryRepository{}
+		mockRepo.On("ExecuteSelectPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&repository.SelectResult{Items: []map[string]interface{}{{"id": "r1"}, {"id": "r2"}}, Total: 2}, nil)
+		auditRepo := &MockQueryAuditRepository{}
+		auditRepo.On("Create", mock.Anything, mock.MatchedBy(func(entry *models.QueryAudit) bool {
+			return entry.Caller == "apikey:reporting" &&
+				entry.SavedQuery == "due_reminders" &&
+				entry.Statement == "SELECT" &&
+				entry.Query == testSavedQueries["due_reminders"].SQL &&
+				entry.Params["min"] == int64(1) &&
+				entry.RowsAffected == 2 &&
+				entry.Error == ""
+		})).Return(nil)
+		handler := NewQueryHandler(mockRepo, WithQueryPolicy(testQueryPolicy), WithAPIKeys(testAPIKeys...),
+			WithAuditLog(auditRepo), WithSavedQueries(newSavedQueryRepo()))
+
+		re := createSavedQueryRequestEvent("GET", "due_reminders", map[string]any{"user": "u1"}, "read-key-0123456789")
+		require.NoError(t, handler.HandleRunSaved(re))
+
+		assert.Equal(t, http.StatusOK, re.Response.(*httptest.ResponseRecorder).Code)
+		auditRepo.AssertExpectations(t)
+	})
+}
+
+func TestHandleListSaved(t *testing.T) {
+	savedRepo := &MockSavedQueryRepository{}
+	savedRepo.On("List", mock.Anything).Return([]*models.SavedQuery{
+		testSavedQueries["all_users"], testSavedQueries["due_reminders"], testSavedQueries["purge_user"],
+	}, nil)
+	handler := NewQueryHandler(&MockQueryRepository{}, WithAPIKeys(testAPIKeys...), WithSavedQueries(savedRepo))
+
+	tests := map[string]struct {
+		key      string
+		expected int
+	}{
+		"superuser": {"", 3},
+		"API key":   {"read-key-0123456789", 2},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			re := createMockRequestEvent("GET", "/api/queries", nil)
+			if tt.key != "" {
+				re = createAPIKeyRequestEvent("GET", "/api/queries", nil, tt.key)
+			}
+			require.NoError(t, handler.HandleListSaved(re))
+
+			recorder := re.Response.(*httptest.ResponseRecorder)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Len(t, body["data"], tt.expected)
+		})
+	}
+
+	t.Run("should deny anonymous callers", func(t *testing.T) {
+		re := createAPIKeyRequestEvent("GET", "/api/queries", nil, "")
+		require.NoError(t, handler.HandleListSaved(re))
+
+		assert.Equal(t, http.StatusUnauthorized, re.Response.(*httptest.ResponseRecorder).Code)
+	})
+}
@@ -774,6 +774,149 @@ func TestReminderValidation(t *testing.T) {
 			},
 			expectValid: false,
 		},
+		{
+			name: "interval below minimum",
+			reminder: &models.Reminder{
+				Title:             "Test",
+				Type:              "recurring",
+				CalendarType:      "solar",
+				RecurrencePattern: &models.RecurrencePattern{IntervalSeconds: 1},
+			},
+			expectValid: false,
+		},
+		{
+			name: "interval at minimum",
+			reminder: &models.Reminder{
+				Title:             "Test",
+				Type:              "recurring",
+				CalendarType:      "solar",
+				RecurrencePattern: &models.RecurrencePattern{IntervalSeconds: models.MinIntervalSeconds},
+			},
+			expectValid: true,
+		},
+		{
+			name: "retry interval below minimum",
+			reminder: &models.Reminder{
+				Title:            "Test",
+				Type:             "one_time",
+				CalendarType:     "solar",
+				RepeatStrategy:   "retry_until_complete",
+				RetryIntervalSec: 5,
+				MaxRetries:       3,
+			},
+			expectValid: false,
+		},
+		{
+			name: "valid escalation policy",
+			reminder: &models.Reminder{
+				Title:            "Test",
+				Type:             "one_time",
+				CalendarType:     "solar",
+				RepeatStrategy:   "retry_until_complete",
+				RetryIntervalSec: 300,
+				MaxRetries:       3,
+				EscalationPolicy: &models.EscalationPolicy{Steps: []models.EscalationStep{
+					{DelaySeconds: 600, Channel: models.EscalationChannelPush, UserID: "family-1"},
+					{DelaySeconds: 1800, Channel: models.EscalationChannelEmail, Email: "son@example.com"},
+				}},
+			},
+			expectValid: true,
+		},
+		{
+			name: "escalation without retry_until_complete",
+			reminder: &models.Reminder{
+				Title:        "Test",
+				Type:         "one_time",
+				CalendarType: "solar",
+				EscalationPolicy: &models.EscalationPolicy{Steps: []models.EscalationStep{
+					{Channel: models.EscalationChannelEmail, Email: "son@example.com"},
+				}},
+			},
+			expectValid: false,
+		},
+		{
+			name: "escalation email step with invalid address",
+			reminder: &models.Reminder{
+				Title:            "Test",
+				Type:             "one_time",
+				CalendarType:     "solar",
+				RepeatStrategy:   "retry_until_complete",
+				RetryIntervalSec: 300,
+				EscalationPolicy: &models.EscalationPolicy{Steps: []models.EscalationStep{
+					{Channel: models.EscalationChannelEmail, Email: "son"},
+				}},
+			},
+			expectValid: false,
+		},
+		{
+			name: "escalation push step without user",
+			reminder: &models.Reminder{
+				Title:            "Test",
+				Type:             "one_time",
+				CalendarType:     "solar",
+				RepeatStrategy:   "retry_until_complete",
+				RetryIntervalSec: 300,
+				EscalationPolicy: &models.EscalationPolicy{Steps: []models.EscalationStep{
+					{Channel: models.EscalationChannelPush},
+				}},
+			},
+			expectValid: false,
+		},
+		{
+			name: "seen retry interval below minimum",
+			reminder: &models.Reminder{
+				Title:                "Test",
+				Type:                 "one_time",
+				CalendarType:         "solar",
+				SeenRetryIntervalSec: 30,
+			},
+			expectValid: false,
+		},
+		{
+			name: "invalid delivery priority",
+			reminder: &models.Reminder{
+				Title:           "Test",
+				Type:            "one_time",
+				CalendarType:    "solar",
+				DeliveryOptions: &models.DeliveryOptions{Priority: "urgent"},
+			},
+			expectValid: false,
+		},
+		{
+			name: "delivery TTL above FCM maximum",
+			reminder: &models.Reminder{
+				Title:           "Test",
+				Type:            "one_time",
+				CalendarType:    "solar",
+				DeliveryOptions: &models.DeliveryOptions{TTLSeconds: models.MaxTTLSeconds + 1},
+			},
+			expectValid: false,
+		},
+		{
+			name: "valid delivery options",
+			reminder: &models.Reminder{
+				Title:        "Test",
+				Type:         "one_time",
+				CalendarType: "solar",
+				DeliveryOptions: &models.DeliveryOptions{
+					Priority:    "normal",
+					TTLSeconds:  600,
+					CollapseKey: "pill",
+					ChannelID:   "pills",
+				},
+			},
+			expectValid: true,
+		},
+		{
+			name: "retry interval ignored without retry strategy",
+			reminder: &models.Reminder{
+				Title:          "Test",
+				Type:           "one_time",
+				CalendarType:   "solar",
+				RepeatStrategy: "none",
+			},
+			expectValid: true,
+		},
 	}
 
 	for _, tt := range tests {
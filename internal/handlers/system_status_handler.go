@@ -3,6 +3,8 @@ package handlers
 import (
     "encoding/json"
 
+    "remiaq/internal/models"
+
     "remiaq/internal/middleware"
     "remiaq/internal/repository"
     "remiaq/internal/utils"
@@ -10,14 +12,22 @@ import (
     "github.com/pocketbase/pocketbase/core"
 )
 
+// CircuitController cho phép admin ép trạng thái circuit breaker của notifier
+type CircuitController interface {
+    ForceOpen(reason string)
+    ForceClose()
+    ResetOverride()
+}
+
 // SystemStatusHandler cung cấp API GET/PUT cho system_status (singleton mid=1)
 type SystemStatusHandler struct {
-    repo repository.SystemStatusRepository
+    repo    repository.SystemStatusRepository
+    breaker CircuitController
 }
 
-// NewSystemStatusHandler khởi tạo handler
-func NewSystemStatusHandler(repo repository.SystemStatusRepository) *SystemStatusHandler {
-    return &SystemStatusHandler{repo: repo}
+// NewSystemStatusHandler khởi tạo handler. breaker có thể nil khi FCM bị tắt.
+func NewSystemStatusHandler(repo repository.SystemStatusRepository, breaker CircuitController) *SystemStatusHandler {
+    return &SystemStatusHandler{repo: repo, breaker: breaker}
 }
 
 // GetSystemStatus xử lý GET /api/system_status
@@ -32,17 +42,19 @@ func (h *SystemStatusHandler) GetSystemStatus(re *core.RequestEvent) error {
 }
 
 // PutSystemStatus xử lý PUT /api/system_status
-// Body cho phép cập nhật worker_enabled và/hoặc last_error
+// Body cho phép cập nhật worker_enabled, last_error và/hoặc circuit_override
 // {
 //   "worker_enabled": true|false?,
-//   "last_error": "..."?
+//   "last_error": "..."?,
+//   "circuit_override": "open"|"closed"|"auto"?
 // }
 func (h *SystemStatusHandler) PutSystemStatus(re *core.RequestEvent) error {
     middleware.SetCORSHeaders(re)
 
     var req struct {
-        WorkerEnabled *bool   `json:"worker_enabled"`
-        LastError     *string `json:"last_error"`
+        WorkerEnabled   *bool   `json:"worker_enabled"`
+        LastError       *string `json:"last_error"`
+        CircuitOverride *string `json:"circuit_override"`
     }
 
     if err := json.NewDecoder(re.Request.Body).Decode(&req); err != nil {
@@ -51,6 +63,38 @@ func (h *SystemStatusHandler) PutSystemStatus(re *core.RequestEvent) error {
 
     ctx := re.Request.Context()
 
+    // Xử lý circuit_override trước để lỗi validate không làm thay đổi trạng thái khác.
+    // "auto" xoá override, breaker tự đóng/mở theo lỗi FCM.
+    if req.CircuitOverride != nil {
+        override := *req.CircuitOverride
+        switch override {
+        case models.CircuitStateOpen, models.CircuitStateClosed:
+        case "auto":
+            override = ""
+        default:
+            return utils.SendError(re, 400, "circuit_override must be open, closed or auto", nil)
+        }
+        if h.breaker == nil {
+            return utils.SendError(re, 409, "Circuit breaker is not available", nil)
+        }
+        if err := h.repo.SetCircuitOverride(ctx, override); err != nil {
+            return utils.SendError(re, 500, "Failed to update circuit override", err)
+        }
+
+        switch override {
+        case models.CircuitStateOpen:
+            reason := ""
+            if req.LastError != nil {
+                reason = *req.LastError
+            }
+            h.breaker.ForceOpen(reason)
+        case models.CircuitStateClosed:
+            h.breaker.ForceClose()
+        default:
+            h.breaker.ResetOverride()
+        }
+    }
+
     // Xử lý worker_enabled
     if req.WorkerEnabled != nil {
         if *req.WorkerEnabled {
@@ -82,7 +126,7 @@ func (h *SystemStatusHandler) PutSystemStatus(re *core.RequestEvent) error {
         if err := h.repo.UpdateError(ctx, *req.LastError); err != nil {
             return utils.SendError(re, 500, "Failed to update error", err)
         }
-    } else {
+    } else if req.CircuitOverride == nil {
         return utils.SendError(re, 400, "No fields to update", nil)
     }
 
@@ -91,4 +135,5 @@ func (h *SystemStatusHandler) PutSystemStatus(re *core.RequestEvent) error {
         return utils.SendError(re, 500, "Failed to read system status", err)
     }
     return utils.SendSuccess(re, "System status updated", status)
-}
\ No newline at end of file
+}
+
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/router"
@@ -48,11 +49,38 @@ func (m *MockSystemStatusRepository) ClearError(ctx context.Context) error {
 	return args.Error(0)
 }
 
+func (m *MockSystemStatusRepository) UpdateCircuitState(ctx context.Context, state, reason string, changedAt time.Time) error {
+	args := m.Called(ctx, state, reason, changedAt)
+	return args.Error(0)
+}
+
+func (m *MockSystemStatusRepository) SetCircuitOverride(ctx context.Context, override string) error {
+	args := m.Called(ctx, override)
+	return args.Error(0)
+}
+
 func (m *MockSystemStatusRepository) IsWorkerEnabled(ctx context.Context) (bool, error) {
 	args := m.Called(ctx)
 	return args.Bool(0), args.Error(1)
 }
 
+// Mock CircuitController
+type MockCircuitController struct {
+	mock.Mock
+}
+
+func (m *MockCircuitController) ForceOpen(reason string) {
+	m.Called(reason)
+}
+
+func (m *MockCircuitController) ForceClose() {
+	m.Called()
+}
+
+func (m *MockCircuitController) ResetOverride() {
+	m.Called()
+}
+
 // Helper function to create mock RequestEvent for system status handler
 func createSystemStatusRequestEvent(method, path string, body interface{}) *core.RequestEvent {
 	var bodyReader *bytes.Reader
@@ -79,7 +107,7 @@ func createSystemStatusRequestEvent(method, path string, body interface{}) *core
 
 func TestNewSystemStatusHandler(t *testing.T) {
 	mockRepo := &MockSystemStatusRepository{}
-	handler := NewSystemStatusHandler(mockRepo)
+	handler := NewSystemStatusHandler(mockRepo, nil)
 	
 	assert.NotNil(t, handler)
 	assert.Equal(t, mockRepo, handler.repo)
@@ -88,7 +116,7 @@ func TestNewSystemStatusHandler(t *testing.T) {
 func TestSystemStatusHandler_GetSystemStatus(t *testing.T) {
 	t.Run("successful system status retrieval", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		expectedStatus := &models.SystemStatus{
 			ID:            1,
@@ -107,7 +135,7 @@ func TestSystemStatusHandler_GetSystemStatus(t *testing.T) {
 	
 	t.Run("repository error", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		mockRepo.On("Get", mock.Anything).Return(nil, assert.AnError)
 		
@@ -123,7 +151,7 @@ func TestSystemStatusHandler_GetSystemStatus(t *testing.T) {
 func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	t.Run("enable worker successfully", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		requestBody := map[string]interface{}{
 			"worker_enabled": true,
@@ -148,7 +176,7 @@ func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	
 	t.Run("disable worker successfully", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		requestBody := map[string]interface{}{
 			"worker_enabled": false,
@@ -172,7 +200,7 @@ func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	
 	t.Run("disable worker with custom error message", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		requestBody := map[string]interface{}{
 			"worker_enabled": false,
@@ -197,7 +225,7 @@ func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	
 	t.Run("update error message only", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		requestBody := map[string]interface{}{
 			"last_error": "New error message",
@@ -221,7 +249,7 @@ func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	
 	t.Run("invalid request body", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		req := httptest.NewRequest("PUT", "/api/system_status", bytes.NewReader([]byte("invalid json")))
 		req.Header.Set("Content-Type", "application/json")
@@ -241,7 +269,7 @@ func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	
 	t.Run("no fields to update", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		requestBody := map[string]interface{}{}
 		
@@ -254,7 +282,7 @@ func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	
 	t.Run("enable worker with error message", func(t *testing.T) {
 		mockRepo := &MockSystemStatusRepository{}
-		handler := NewSystemStatusHandler(mockRepo)
+		handler := NewSystemStatusHandler(mockRepo, nil)
 		
 		requestBody := map[string]interface{}{
 			"worker_enabled": true,
@@ -279,10 +307,107 @@ func TestSystemStatusHandler_PutSystemStatus(t *testing.T) {
 	})
 }
 
+func TestSystemStatusHandler_PutSystemStatus_CircuitOverride(t *testing.T) {
+	status := &models.SystemStatus{ID: 1, WorkerEnabled: true, CircuitState: models.CircuitStateOpen}
+
+	t.Run("force open with reason", func(t *testing.T) {
+		mockRepo := &MockSystemStatusRepository{}
+		breaker := &MockCircuitController{}
+		handler := NewSystemStatusHandler(mockRepo, breaker)
+
+		mockRepo.On("SetCircuitOverride", mock.Anything, "open").Return(nil)
+		mockRepo.On("UpdateError", mock.Anything, "maintenance").Return(nil)
+		mockRepo.On("Get", mock.Anything).Return(status, nil)
+		breaker.On("ForceOpen", "maintenance").Return()
+
+		re := createSystemStatusRequestEvent("PUT", "/api/system_status", map[string]interface{}{
+			"circuit_override": "open",
+			"last_error":       "maintenance",
+		})
+
+		err := handler.PutSystemStatus(re)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 200, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertExpectations(t)
+		breaker.AssertExpectations(t)
+	})
+
+	t.Run("force closed", func(t *testing.T) {
+		mockRepo := &MockSystemStatusRepository{}
+		breaker := &MockCircuitController{}
+		handler := NewSystemStatusHandler(mockRepo, breaker)
+
+		mockRepo.On("SetCircuitOverride", mock.Anything, "closed").Return(nil)
+		mockRepo.On("Get", mock.Anything).Return(status, nil)
+		breaker.On("ForceClose").Return()
+
+		re := createSystemStatusRequestEvent("PUT", "/api/system_status", map[string]interface{}{
+			"circuit_override": "closed",
+		})
+
+		err := handler.PutSystemStatus(re)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		breaker.AssertExpectations(t)
+	})
+
+	t.Run("auto clears override", func(t *testing.T) {
+		mockRepo := &MockSystemStatusRepository{}
+		breaker := &MockCircuitController{}
+		handler := NewSystemStatusHandler(mockRepo, breaker)
+
+		mockRepo.On("SetCircuitOverride", mock.Anything, "").Return(nil)
+		mockRepo.On("Get", mock.Anything).Return(status, nil)
+		breaker.On("ResetOverride").Return()
+
+		re := createSystemStatusRequestEvent("PUT", "/api/system_status", map[string]interface{}{
+			"circuit_override": "auto",
+		})
+
+		err := handler.PutSystemStatus(re)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		breaker.AssertExpectations(t)
+	})
+
+	t.Run("invalid override", func(t *testing.T) {
+		mockRepo := &MockSystemStatusRepository{}
+		breaker := &MockCircuitController{}
+		handler := NewSystemStatusHandler(mockRepo, breaker)
+
+		re := createSystemStatusRequestEvent("PUT", "/api/system_status", map[string]interface{}{
+			"circuit_override": "half_open",
+		})
+
+		err := handler.PutSystemStatus(re)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 400, re.Response.(*httptest.ResponseRecorder).Code)
+		mockRepo.AssertNotCalled(t, "SetCircuitOverride", mock.Anything, mock.Anything)
+	})
+
+	t.Run("breaker not available", func(t *testing.T) {
+		mockRepo := &MockSystemStatusRepository{}
+		handler := NewSystemStatusHandler(mockRepo, nil)
+
+		re := createSystemStatusRequestEvent("PUT", "/api/system_status", map[string]interface{}{
+			"circuit_override": "open",
+		})
+
+		err := handler.PutSystemStatus(re)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 409, re.Response.(*httptest.ResponseRecorder).Code)
+	})
+}
+
 // Benchmark tests
 func BenchmarkSystemStatusHandler_GetSystemStatus(b *testing.B) {
 	mockRepo := &MockSystemStatusRepository{}
-	handler := NewSystemStatusHandler(mockRepo)
+	handler := NewSystemStatusHandler(mockRepo, nil)
 	
 	expectedStatus := &models.SystemStatus{
 		ID:            1,
@@ -300,7 +425,7 @@ func BenchmarkSystemStatusHandler_GetSystemStatus(b *testing.B) {
 
 func BenchmarkSystemStatusHandler_PutSystemStatus(b *testing.B) {
 	mockRepo := &MockSystemStatusRepository{}
-	handler := NewSystemStatusHandler(mockRepo)
+	handler := NewSystemStatusHandler(mockRepo, nil)
 	
 	requestBody := map[string]interface{}{
 		"worker_enabled": true,
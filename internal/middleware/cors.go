@@ -8,7 +8,7 @@ import (
 func SetCORSHeaders(re *core.RequestEvent) {
 	re.Response.Header().Set("Access-Control-Allow-Origin", "*")
 	re.Response.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
-	re.Response.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Accept, Origin, Cache-Control, X-File-Name")
+	re.Response.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Accept, Origin, Cache-Control, X-File-Name, X-API-Key")
 	re.Response.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range")
 	re.Response.Header().Set("Access-Control-Allow-Credentials", "true")
 	re.Response.Header().Set("Access-Control-Max-Age", "86400")
@@ -34,7 +34,7 @@ func TestSetCORSHeaders(t *testing.T) {
 		
 		assert.Equal(t, "*", headers.Get("Access-Control-Allow-Origin"))
 		assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH", headers.Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, "Content-Type, Authorization, X-Requested-With, Accept, Origin, Cache-Control, X-File-Name", headers.Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "Content-Type, Authorization, X-Requested-With, Accept, Origin, Cache-Control, X-File-Name, X-API-Key", headers.Get("Access-Control-Allow-Headers"))
 		assert.Equal(t, "Content-Length, Content-Range", headers.Get("Access-Control-Expose-Headers"))
 		assert.Equal(t, "true", headers.Get("Access-Control-Allow-Credentials"))
 		assert.Equal(t, "86400", headers.Get("Access-Control-Max-Age"))
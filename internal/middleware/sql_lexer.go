@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// QueryError is a query validation error at a position in the query.
+type QueryError struct {
+	Message string
+	Offset  int // byte offset in the query
+	Line    int // 1-based
+	Column  int // 1-based, in characters
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Line, e.Column)
+}
+
+// newQueryError creates a QueryError at byte offset of query.
+func newQueryError(query string, offset int, format string, args ...any) *QueryError {
+	before := query[:offset]
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return &QueryError{
+		Message: fmt.Sprintf(format, args...),
+		Offset:  offset,
+		Line:    strings.Count(before, "\n") + 1,
+		Column:  utf8.RuneCountInString(before[lineStart:]) + 1,
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenKeyword
+	tokenIdent // bare or quoted identifier
+	tokenString
+	tokenNumber
+	tokenBlob
+	tokenParam
+	tokenOp // operators and punctuation
+)
+
+type token struct {
+	kind  tokenKind
+	value string // keywords upper-cased, identifiers unquoted, raw text otherwise
+	pos   int    // byte offset in the query
+}
+
+// is reports whether t is the keyword or operator value.
+func (t token) is(value string) bool {
+	return (t.kind == tokenKeyword || t.kind == tokenOp) && t.value == value
+}
+
+// keywords are the SQLite keywords the validator needs to tell apart from identifiers.
+// Other words (KEY, TYPE, ...) are read as identifiers, as SQLite does for most keywords.
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BETWEEN": true, "BY": true,
+	"CASE": true, "CAST": true, "COLLATE": true, "CONFLICT": true, "CROSS": true,
+	"CURRENT": true, "CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
+	"DEFAULT": true, "DELETE": true, "DESC": true, "DISTINCT": true, "DO": true, "ELSE": true,
+	"END": true, "ESCAPE": true, "EXCEPT": true, "EXCLUDE": true, "EXISTS": true,
+	"FALSE": true, "FILTER": true, "FIRST": true, "FOLLOWING": true, "FROM": true,
+	"FULL": true, "GLOB": true, "GROUP": true, "GROUPS": true, "HAVING": true, "IN": true,
+	"INDEXED": true, "INNER": true, "INSERT": true, "INTERSECT": true, "INTO": true,
+	"IS": true, "ISNULL": true, "JOIN": true, "LAST": true, "LEFT": true, "LIKE": true,
+	"LIMIT": true, "MATCH": true, "MATERIALIZED": true, "NATURAL": true, "NO": true,
+	"NOT": true, "NOTHING": true, "NOTNULL": true, "NULL": true, "NULLS": true,
+	"OFFSET": true, "ON": true, "OR": true, "ORDER": true, "OTHERS": true, "OUTER": true,
+	"OVER": true, "PARTITION": true, "PRECEDING": true, "RANGE": true, "RECURSIVE": true,
+	"REGEXP": true, "REPLACE": true, "RETURNING": true, "RIGHT": true, "ROW": true,
+	"ROWS": true, "SELECT": true, "SET": true, "THEN": true, "TIES": true, "TRUE": true,
+	"UNBOUNDED": true, "UNION": true, "UPDATE": true, "USING": true, "VALUES": true,
+	"WHEN": true, "WHERE": true, "WINDOW": true, "WITH": true,
+}
+
+// operators lists multi-character operators before their prefixes.
+var operators = []string{
+	"->>", "->", "||", "<=", ">=", "<>", "!=", "==", "<<", ">>",
+	"(", ")", ",", ";", ".", "+", "-", "*", "/", "%", "<", ">", "=", "&", "|", "~",
+}
+
+// tokenize splits query into SQLite tokens, dropping whitespace and comments. The last
+// token is always tokenEOF.
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	i := 0
+	peek := func(j int) byte {
+		if j < len(query) {
+			return query[j]
+		}
+		return 0
+	}
+
+	for i < len(query) {
+		c := query[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+
+		case c == '-' && peek(i+1) == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && peek(i+1) == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, newQueryError(query, start, "unterminated comment")
+			}
+			i += end + 4
+
+		case c == '\'':
+			end, ok := scanQuoted(query, i, '\'')
+			if !ok {
+				return nil, newQueryError(query, start, "unterminated string literal")
+			}
+			tokens = append(tokens, token{kind: tokenString, value: query[start:end], pos: start})
+			i = end
+
+		case c == '"' || c == '`':
+			end, ok := scanQuoted(query, i, c)
+			if !ok {
+				return nil, newQueryError(query, start, "unterminated quoted identifier")
+			}
+			quote := string(c)
+			value := strings.ReplaceAll(query[i+1:end-1], quote+quote, quote)
+			tokens = append(tokens, token{kind: tokenIdent, value: value, pos: start})
+			i = end
+
+		case c == '[':
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				return nil, newQueryError(query, start, "unterminated quoted identifier")
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: query[i+1 : i+end], pos: start})
+			i += end + 1
+
+		case (c == 'x' || c == 'X') && peek(i+1) == '\'':
+			end, ok := scanQuoted(query, i+1, '\'')
+			if !ok {
+				return nil, newQueryError(query, start, "unterminated blob literal")
+			}
+			tokens = append(tokens, token{kind: tokenBlob, value: query[start:end], pos: start})
+			i = end
+
+		case isDigit(c) || (c == '.' && isDigit(peek(i+1))):
+			i = scanNumber(query, i)
+			tokens = append(tokens, token{kind: tokenNumber, value: query[start:i], pos: start})
+
+		case isIdentStart(c):
+			for i < len(query) && isIdentChar(query[i]) {
+				i++
+			}
+			word := query[start:i]
+			if upper := strings.ToUpper(word); keywords[upper] {
+				tokens = append(tokens, token{kind: tokenKeyword, value: upper, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, value: word, pos: start})
+			}
+
+		case c == '{' && peek(i+1) == ':':
+			// Placeholder của dbx: {:name}
+			for i += 2; i < len(query) && (isDigit(query[i]) || isWordChar(query[i])); i++ {
+			}
+			if i == start+2 || peek(i) != '}' {
+				return nil, newQueryError(query, start, "invalid placeholder, expected {:name}")
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenParam, value: query[start:i], pos: start})
+
+		case c == '?':
+			for i++; i < len(query) && isDigit(query[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokenParam, value: query[start:i], pos: start})
+
+		case c == ':' || c == '@' || c == '$':
+			for i++; i < len(query) && isIdentChar(query[i]); i++ {
+			}
+			if i == start+1 {
+				return nil, newQueryError(query, start, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokenParam, value: query[start:i], pos: start})
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(query[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(query[i:])
+				return nil, newQueryError(query, start, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokenOp, value: op, pos: start})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(query)}), nil
+}
+
+// scanQuoted returns the offset after the quote closing the one at i; a doubled quote
+// is an escaped quote.
+func scanQuoted(query string, i int, quote byte) (int, bool) {
+	j := i + 1
+	for {
+		k := strings.IndexByte(query[j:], quote)
+		if k < 0 {
+			return 0, false
+		}
+		j += k + 1
+		if j < len(query) && query[j] == quote {
+			j++
+			continue
+		}
+		return j, true
+	}
+}
+
+// scanNumber returns the offset after the numeric literal at i.
+func scanNumber(query string, i int) int {
+	if query[i] == '0' && i+2 < len(query) && (query[i+1] == 'x' || query[i+1] == 'X') && isHexDigit(query[i+2]) {
+		for i += 2; i < len(query) && isHexDigit(query[i]); i++ {
+		}
+		return i
+	}
+
+	for ; i < len(query) && isDigit(query[i]); i++ {
+	}
+	if i < len(query) && query[i] == '.' {
+		for i++; i < len(query) && isDigit(query[i]); i++ {
+		}
+	}
+	if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < len(query) && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < len(query) && isDigit(query[j]) {
+			for i = j; i < len(query) && isDigit(query[i]); i++ {
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool    { return c >= '0' && c <= '9' }
+func isHexDigit(c byte) bool { return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') }
+
+// isIdentStart accepts ASCII letters, '_' and any UTF-8 byte of a non-ASCII character.
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool { return isIdentStart(c) || isDigit(c) || c == '$' }
+
+// isWordChar matches the name characters of dbx placeholders besides digits.
+func isWordChar(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' }
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Run("should split tokens and drop comments", func(t *testing.T) {
+		tokens, err := tokenize("SELECT \"my \"\"col\"\"\", [b], `c` -- x\nFROM t /* y */ WHERE a >= 1.5e3 AND b = 'it''s' AND c = X'0F' AND d = :p")
+		require.NoError(t, err)
+
+		var kinds []tokenKind
+		var values []string
+		for _, tok := range tokens {
+			kinds = append(kinds, tok.kind)
+			values = append(values, tok.value)
+		}
+		assert.Equal(t, []string{
+			"SELECT", `my "col"`, ",", "b", ",", "c", "FROM", "t", "WHERE", "a", ">=", "1.5e3",
+			"AND", "b", "=", "'it''s'", "AND", "c", "=", "X'0F'", "AND", "d", "=", ":p", "",
+		}, values)
+		assert.Equal(t, []tokenKind{
+			tokenKeyword, tokenIdent, tokenOp, tokenIdent, tokenOp, tokenIdent, tokenKeyword, tokenIdent,
+			tokenKeyword, tokenIdent, tokenOp, tokenNumber, tokenKeyword, tokenIdent, tokenOp, tokenString,
+			tokenKeyword, tokenIdent, tokenOp, tokenBlob, tokenKeyword, tokenIdent, tokenOp, tokenParam, tokenEOF,
+		}, kinds)
+	})
+
+	t.Run("should read unlisted keywords as identifiers", func(t *testing.T) {
+		tokens, err := tokenize("key type")
+		require.NoError(t, err)
+		assert.Equal(t, tokenIdent, tokens[0].kind)
+		assert.Equal(t, tokenIdent, tokens[1].kind)
+	})
+
+	t.Run("should read dbx placeholders", func(t *testing.T) {
+		tokens, err := tokenize("id = {:user_id2}")
+		require.NoError(t, err)
+		assert.Equal(t, token{kind: tokenParam, value: "{:user_id2}", pos: 5}, tokens[2])
+
+		_, err = tokenize("id = {:}")
+		assert.EqualError(t, err, "invalid placeholder, expected {:name} (line 1, column 6)")
+	})
+
+	t.Run("should report unterminated tokens with their position", func(t *testing.T) {
+		tests := map[string]string{
+			"SELECT 'abc":       "unterminated string literal (line 1, column 8)",
+			"SELECT \"abc":      "unterminated quoted identifier (line 1, column 8)",
+			"SELECT [abc":       "unterminated quoted identifier (line 1, column 8)",
+			"SELECT 1\n/* x":    "unterminated comment (line 2, column 1)",
+			"SELECT 1 # 2":      `unexpected character '#' (line 1, column 10)`,
+			"SELECT 'ngày' ^ 1": `unexpected character '^' (line 1, column 15)`,
+			"SELECT\n  x'0F":    "unterminated blob literal (line 2, column 3)",
+		}
+
+		for query, message := range tests {
+			_, err := tokenize(query)
+			require.Error(t, err, query)
+			assert.Equal(t, message, err.Error(), query)
+		}
+	})
+}
@@ -0,0 +1,852 @@
+package middleware
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// tableFunctions are the table-valued functions a FROM clause may use; they only read
+// their arguments.
+var tableFunctions = map[string]bool{"json_each": true, "json_tree": true}
+
+// deniedFunctions reach outside the database.
+var deniedFunctions = map[string]bool{
+	"load_extension": true, "readfile": true, "writefile": true, "edit": true, "fts3_tokenizer": true,
+}
+
+// exprStop are the keywords that end an expression at its own level.
+var exprStop = map[string]bool{
+	"FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "UNION": true, "INTERSECT": true, "EXCEPT": true, "WINDOW": true, "ON": true,
+	"USING": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "NATURAL": true, "SET": true, "RETURNING": true, "AS": true, "ASC": true,
+	"DESC": true, "NULLS": true, "DO": true,
+}
+
+// scope is one SELECT (or the target of an INSERT/UPDATE/DELETE) with the tables it reads.
+// Unqualified columns may come from any table of the scope or of an enclosing scope.
+type scope struct {
+	parent  *scope
+	sources []*source
+	ctes    map[string]bool // names defined by a WITH clause of this scope
+	aliases map[string]bool // result column aliases, usable in ORDER BY
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent}
+}
+
+// lookup finds every source named by the table or alias name in sc and its enclosing scopes.
+func (sc *scope) lookup(name string) []*source {
+	var found []*source
+	for ; sc != nil; sc = sc.parent {
+		for _, src := range sc.sources {
+			if src.name == name {
+				found = append(found, src)
+			}
+		}
+	}
+	return found
+}
+
+func (sc *scope) isCTE(name string) bool {
+	for ; sc != nil; sc = sc.parent {
+		if sc.ctes[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// source is a table, subquery, CTE or table-valued function read by a scope.
+type source struct {
+	name    string          // alias or table name, lower-case
+	table   string          // table name, "" when the rows don't come from a table
+	columns map[string]bool // allowed columns, nil = every column
+}
+
+type columnRef struct {
+	scope     *scope
+	target    *source // column written by INSERT/UPDATE, checked against this table only
+	qualifier string
+	name      string
+	pos       int
+	orderBy   bool // a whole ORDER BY term, may name a result column alias
+}
+
+type naturalJoin struct {
+	scope *scope
+	pos   int
+}
+
+type starRef struct {
+	scope     *scope
+	qualifier string
+	pos       int
+}
+
+// parser walks the tokens of one statement. Columns are checked after the whole statement
+// is read, when every scope knows its tables. Errors are raised as *QueryError panics and
+// recovered by QueryPolicy.Validate.
+type parser struct {
+	query    string
+	tokens   []token
+	i        int
+	tables   map[string]map[string]bool
+	columns  []columnRef
+	stars    []starRef
+	naturals []naturalJoin
+	hasWhere bool          // the UPDATE/DELETE has a WHERE clause
+	stmt     StatementType // type of the parsed statement
+}
+
+func (p *parser) peek() token { return p.peekAt(0) }
+
+func (p *parser) peekAt(n int) token {
+	if p.i+n < len(p.tokens) {
+		return p.tokens[p.i+n]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() token {
+	tok := p.peek()
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) accept(value string) bool {
+	if p.peek().is(value) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(value string) token {
+	tok := p.peek()
+	if !tok.is(value) {
+		p.fail(tok.pos, "expected %s, got %s", value, describe(tok))
+	}
+	p.i++
+	return tok
+}
+
+func (p *parser) ident() token {
+	tok := p.peek()
+	if tok.kind != tokenIdent {
+		p.fail(tok.pos, "expected a name, got %s", describe(tok))
+	}
+	p.i++
+	return tok
+}
+
+func (p *parser) fail(pos int, format string, args ...any) {
+	panic(newQueryError(p.query, pos, format, args...))
+}
+
+func describe(tok token) string {
+	if tok.kind == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", tok.value)
+}
+
+// statement parses the whole query as one statement of the given types.
+func (p *parser) statement(types []StatementType) {
+	root := newScope(nil)
+	if p.peek().is("WITH") {
+		p.with(root)
+	}
+
+	first := p.peek()
+	var stmt StatementType
+	switch {
+	case first.is("SELECT"):
+		stmt = StatementSelect
+	case first.is("INSERT"), first.is("REPLACE"):
+		stmt = StatementInsert
+	case first.is("UPDATE"):
+		stmt = StatementUpdate
+	case first.is("DELETE"):
+		stmt = StatementDelete
+	}
+	p.stmt = stmt
+	if !slices.Contains(types, stmt) {
+		if len(types) == 1 {
+			p.fail(first.pos, "only %s statements are allowed", types[0])
+		}
+		p.fail(first.pos, "%s statements are not allowed", strings.ToUpper(first.value))
+	}
+
+	switch stmt {
+	case StatementSelect:
+		p.selectStmt(root)
+	case StatementInsert:
+		p.insert(root)
+	case StatementUpdate:
+		p.update(root)
+	case StatementDelete:
+		p.delete(root)
+	}
+
+	semicolon := p.accept(";")
+	if tok := p.peek(); tok.kind != tokenEOF {
+		if semicolon {
+			p.fail(tok.pos, "only one statement is allowed")
+		}
+		p.fail(tok.pos, "unexpected %s", describe(tok))
+	}
+	if (stmt == StatementUpdate || stmt == StatementDelete) && !p.hasWhere {
+		p.fail(first.pos, "%s statements must include a WHERE clause for safety", stmt)
+	}
+}
+
+// with parses WITH [RECURSIVE] name [(columns)] AS [[NOT] MATERIALIZED] (select), ...
+func (p *parser) with(sc *scope) {
+	p.expect("WITH")
+	p.accept("RECURSIVE")
+	sc.ctes = make(map[string]bool)
+	for {
+		sc.ctes[strings.ToLower(p.ident().value)] = true
+		if p.accept("(") {
+			for {
+				p.ident()
+				if !p.accept(",") {
+					break
+				}
+			}
+			p.expect(")")
+		}
+		p.expect("AS")
+		if p.accept("NOT") {
+			p.expect("MATERIALIZED")
+		} else {
+			p.accept("MATERIALIZED")
+		}
+		p.expect("(")
+		p.selectStmt(sc)
+		p.expect(")")
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+func (p *parser) startsSelect() bool {
+	tok := p.peek()
+	return tok.is("SELECT") || tok.is("WITH") || tok.is("VALUES")
+}
+
+// selectStmt parses a (compound) SELECT with its ORDER BY and LIMIT.
+func (p *parser) selectStmt(parent *scope) {
+	if p.peek().is("WITH") {
+		parent = newScope(parent)
+		p.with(parent)
+	}
+
+	aliases := make(map[string]bool)
+	sc := p.selectCore(parent, aliases)
+	for {
+		if p.accept("UNION") {
+			p.accept("ALL")
+		} else if !p.accept("INTERSECT") && !p.accept("EXCEPT") {
+			break
+		}
+		sc = p.selectCore(parent, aliases)
+	}
+
+	if p.accept("ORDER") {
+		p.expect("BY")
+		p.orderingTerms(sc)
+	}
+	if p.accept("LIMIT") {
+		p.expr(sc)
+		if p.accept("OFFSET") || p.accept(",") {
+			p.expr(sc)
+		}
+	}
+}
+
+// selectCore parses one SELECT ... [FROM] [WHERE] [GROUP BY] [HAVING] [WINDOW], or VALUES.
+func (p *parser) selectCore(parent *scope, aliases map[string]bool) *scope {
+	sc := newScope(parent)
+	sc.aliases = aliases
+
+	if p.accept("VALUES") {
+		for {
+			p.expect("(")
+			p.exprList(sc)
+			p.expect(")")
+			if !p.accept(",") {
+				return sc
+			}
+		}
+	}
+
+	p.expect("SELECT")
+	if !p.accept("DISTINCT") {
+		p.accept("ALL")
+	}
+	p.resultColumns(sc)
+	if p.accept("FROM") {
+		p.from(sc)
+	}
+	if p.accept("WHERE") {
+		p.expr(sc)
+	}
+	if p.accept("GROUP") {
+		p.expect("BY")
+		p.exprList(sc)
+	}
+	if p.accept("HAVING") {
+		p.expr(sc)
+	}
+	if p.accept("WINDOW") {
+		for {
+			p.ident()
+			p.expect("AS")
+			p.expr(sc)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	return sc
+}
+
+// resultColumns parses the columns of SELECT or RETURNING.
+func (p *parser) resultColumns(sc *scope) {
+	for {
+		tok := p.peek()
+		switch {
+		case tok.is("*"):
+			p.next()
+			p.stars = append(p.stars, starRef{scope: sc, pos: tok.pos})
+		case tok.kind == tokenIdent && p.peekAt(1).is(".") && p.peekAt(2).is("*"):
+			p.i += 3
+			p.stars = append(p.stars, starRef{scope: sc, qualifier: strings.ToLower(tok.value), pos: tok.pos})
+		default:
+			p.expr(sc)
+			if alias := p.alias(); alias != "" && sc.aliases != nil {
+				sc.aliases[alias] = true
+			}
+		}
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+// alias parses an optional [AS] alias and returns it lower-cased.
+func (p *parser) alias() string {
+	if p.accept("AS") {
+		tok := p.next()
+		if tok.kind != tokenIdent && tok.kind != tokenString {
+			p.fail(tok.pos, "expected an alias, got %s", describe(tok))
+		}
+		return strings.ToLower(strings.Trim(tok.value, "'"))
+	}
+	if tok := p.peek(); tok.kind == tokenIdent || tok.kind == tokenString {
+		p.next()
+		return strings.ToLower(strings.Trim(tok.value, "'"))
+	}
+	return ""
+}
+
+func (p *parser) orderingTerms(sc *scope) {
+	for {
+		tok := p.peek()
+		if after := p.peekAt(1); tok.kind == tokenIdent && (after.kind == tokenEOF || after.is(",") ||
+			after.is(")") || after.is(";") || after.is("ASC") || after.is("DESC") || after.is("NULLS") ||
+			after.is("COLLATE") || after.is("LIMIT")) {
+			p.next()
+			p.columns = append(p.columns, columnRef{scope: sc, name: strings.ToLower(tok.value), pos: tok.pos, orderBy: true})
+		} else {
+			p.expr(sc)
+		}
+		if p.accept("COLLATE") {
+			p.ident()
+		}
+		if !p.accept("ASC") {
+			p.accept("DESC")
+		}
+		if p.accept("NULLS") && !p.accept("FIRST") {
+			p.expect("LAST")
+		}
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+// from parses a FROM clause: tables, subqueries and joins.
+func (p *parser) from(sc *scope) {
+	p.tableOrSubquery(sc)
+	for {
+		if p.accept(",") {
+			p.tableOrSubquery(sc)
+			continue
+		}
+		if !p.joinOperator(sc) {
+			return
+		}
+		p.tableOrSubquery(sc)
+		if p.accept("ON") {
+			p.expr(sc)
+		} else if p.accept("USING") {
+			p.expect("(")
+			for {
+				tok := p.ident()
+				p.columns = append(p.columns, columnRef{scope: sc, name: strings.ToLower(tok.value), pos: tok.pos})
+				if !p.accept(",") {
+					break
+				}
+			}
+			p.expect(")")
+		}
+	}
+}
+
+func (p *parser) joinOperator(sc *scope) bool {
+	start := p.peek()
+	if p.accept("NATURAL") {
+		p.naturals = append(p.naturals, naturalJoin{scope: sc, pos: start.pos})
+	}
+	if p.accept("LEFT") || p.accept("RIGHT") || p.accept("FULL") {
+		p.accept("OUTER")
+	} else if !p.accept("INNER") {
+		p.accept("CROSS")
+	}
+	if p.accept("JOIN") {
+		return true
+	}
+	if p.peek().pos != start.pos {
+		p.fail(p.peek().pos, "expected JOIN, got %s", describe(p.peek()))
+	}
+	return false
+}
+
+func (p *parser) tableOrSubquery(sc *scope) {
+	if p.accept("(") {
+		if !p.startsSelect() {
+			p.from(sc) // join in parentheses
+			p.expect(")")
+			return
+		}
+		p.selectStmt(sc)
+		p.expect(")")
+		pos := p.peek().pos
+		p.addSource(sc, &source{name: p.alias()}, pos)
+		return
+	}
+
+	pos := p.peek().pos
+	src := p.table(sc)
+	if tok := p.peek(); tok.kind == tokenIdent || tok.kind == tokenString || tok.is("AS") {
+		pos = tok.pos
+	}
+	if alias := p.alias(); alias != "" {
+		src.name = alias
+	}
+	if p.accept("INDEXED") {
+		p.expect("BY")
+		p.ident()
+	} else if p.peek().is("NOT") && p.peekAt(1).is("INDEXED") {
+		p.i += 2
+	}
+	p.addSource(sc, src, pos)
+}
+
+// addSource adds src to sc. Like SQLite, two sources of one scope can't share a name:
+// the alias would be ambiguous and could hide a column-restricted table.
+func (p *parser) addSource(sc *scope, src *source, pos int) {
+	if src.name != "" {
+		for _, other := range sc.sources {
+			if other.name == src.name {
+				p.fail(pos, "ambiguous table or alias %q", src.name)
+			}
+		}
+	}
+	sc.sources = append(sc.sources, src)
+}
+
+// table parses a table name (or table-valued function call) and checks it against the policy.
+func (p *parser) table(sc *scope) *source {
+	tok := p.ident()
+	name := strings.ToLower(tok.value)
+	if p.peek().is(".") {
+		p.fail(tok.pos, "schema-qualified table names are not allowed")
+	}
+
+	if p.peek().is("(") {
+		if !tableFunctions[name] {
+			p.fail(tok.pos, "table-valued function %q is not allowed", tok.value)
+		}
+		p.expect("(")
+		if !p.peek().is(")") {
+			p.exprList(sc)
+		}
+		p.expect(")")
+		return &source{name: name}
+	}
+
+	if sc.isCTE(name) {
+		return &source{name: name}
+	}
+	return p.policyTable(tok)
+}
+
+// policyTable returns the source of the table named by tok if the policy allows it.
+func (p *parser) policyTable(tok token) *source {
+	name := strings.ToLower(tok.value)
+	columns, ok := p.tables[name]
+	if !ok || strings.HasPrefix(name, "_") || strings.HasPrefix(name, "sqlite_") {
+		p.fail(tok.pos, "table %q is not allowed", tok.value)
+	}
+	return &source{name: name, table: name, columns: columns}
+}
+
+// target parses the table written by INSERT/UPDATE/DELETE with its optional AS alias.
+func (p *parser) target(sc *scope) *source {
+	tok := p.ident()
+	if p.peek().is(".") {
+		p.fail(tok.pos, "schema-qualified table names are not allowed")
+	}
+	src := p.policyTable(tok)
+	pos := tok.pos
+	if p.accept("AS") {
+		alias := p.ident()
+		src.name, pos = strings.ToLower(alias.value), alias.pos
+	}
+	p.addSource(sc, src, pos)
+	return src
+}
+
+func (p *parser) targetColumn(target *source) {
+	tok := p.ident()
+	p.columns = append(p.columns, columnRef{target: target, name: strings.ToLower(tok.value), pos: tok.pos})
+}
+
+func (p *parser) insert(parent *scope) {
+	if !p.accept("REPLACE") {
+		p.expect("INSERT")
+		if p.accept("OR") {
+			p.next() // ROLLBACK, ABORT, REPLACE, FAIL or IGNORE
+		}
+	}
+	p.expect("INTO")
+	sc := newScope(parent)
+	target := p.target(sc)
+
+	if p.accept("(") {
+		for {
+			p.targetColumn(target)
+			if !p.accept(",") {
+				break
+			}
+		}
+		p.expect(")")
+	} else if target.columns != nil {
+		// Không có danh sách cột thì mọi cột đều bị ghi, kể cả cột bị ẩn
+		p.fail(p.peek().pos, "a column list is required for table %q", target.table)
+	}
+
+	if p.accept("DEFAULT") {
+		p.expect("VALUES")
+	} else {
+		p.selectStmt(sc)
+	}
+
+	for p.peek().is("ON") && p.peekAt(1).is("CONFLICT") {
+		p.i += 2
+		if p.accept("(") {
+			p.orderingTerms(sc)
+			p.expect(")")
+			if p.accept("WHERE") {
+				p.expr(sc)
+			}
+		}
+		p.expect("DO")
+		if p.accept("NOTHING") {
+			continue
+		}
+		p.expect("UPDATE")
+		p.expect("SET")
+		// excluded là dòng định chèn, cùng quyền cột với bảng đích
+		upsert := newScope(sc)
+		upsert.sources = []*source{{name: "excluded", table: target.table, columns: target.columns}}
+		p.assignments(upsert, target)
+		if p.accept("WHERE") {
+			p.expr(upsert)
+		}
+	}
+	p.returning(sc)
+}
+
+func (p *parser) update(parent *scope) {
+	p.expect("UPDATE")
+	if p.accept("OR") {
+		p.next()
+	}
+	sc := newScope(parent)
+	target := p.target(sc)
+	if p.accept("INDEXED") {
+		p.expect("BY")
+		p.ident()
+	} else if p.peek().is("NOT") && p.peekAt(1).is("INDEXED") {
+		p.i += 2
+	}
+
+	p.expect("SET")
+	p.assignments(sc, target)
+	if p.accept("FROM") {
+		p.from(sc)
+	}
+	if p.accept("WHERE") {
+		p.hasWhere = true
+		p.expr(sc)
+	}
+	p.returning(sc)
+}
+
+func (p *parser) delete(parent *scope) {
+	p.expect("DELETE")
+	p.expect("FROM")
+	sc := newScope(parent)
+	p.target(sc)
+	if p.accept("INDEXED") {
+		p.expect("BY")
+		p.ident()
+	} else if p.peek().is("NOT") && p.peekAt(1).is("INDEXED") {
+		p.i += 2
+	}
+
+	if p.accept("WHERE") {
+		p.hasWhere = true
+		p.expr(sc)
+	}
+	p.returning(sc)
+}
+
+// assignments parses col = expr, (col, ...) = expr, ... of UPDATE and upsert.
+func (p *parser) assignments(sc *scope, target *source) {
+	for {
+		if p.accept("(") {
+			for {
+				p.targetColumn(target)
+				if !p.accept(",") {
+					break
+				}
+			}
+			p.expect(")")
+		} else {
+			p.targetColumn(target)
+		}
+		p.expect("=")
+		p.expr(sc)
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+func (p *parser) returning(sc *scope) {
+	if p.accept("RETURNING") {
+		p.resultColumns(sc)
+	}
+}
+
+func (p *parser) exprList(sc *scope) {
+	for {
+		p.expr(sc)
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+// expr scans one expression up to a ',', ')', clause keyword or alias at its own level,
+// recording column references and parsing subqueries as child scopes.
+func (p *parser) expr(sc *scope) {
+	start := p.i
+	depth := 0
+	for {
+		tok := p.peek()
+		if depth == 0 && (tok.kind == tokenEOF || tok.is(",") || tok.is(")") || tok.is(";") ||
+			(tok.kind == tokenKeyword && exprStop[tok.value]) || (p.i > start && p.isAlias(tok))) {
+			if p.i == start {
+				p.fail(tok.pos, "expected an expression, got %s", describe(tok))
+			}
+			return
+		}
+
+		switch {
+		case tok.kind == tokenEOF || tok.is(";"):
+			p.fail(tok.pos, "unexpected %s, missing \")\"", describe(tok))
+
+		case tok.is("("):
+			p.next()
+			if p.startsSelect() {
+				p.selectStmt(sc)
+				p.expect(")")
+				continue
+			}
+			depth++
+
+		case tok.is(")"):
+			p.next()
+			depth--
+
+		case tok.is("AS"):
+			// Chỉ gặp trong CAST(expr AS type): bỏ qua tên kiểu
+			p.next()
+			for nested := 0; ; p.next() {
+				t := p.peek()
+				if t.kind == tokenEOF {
+					p.fail(t.pos, "unexpected end of query, missing \")\"")
+				}
+				if t.is("(") {
+					nested++
+				} else if t.is(")") {
+					if nested == 0 {
+						break
+					}
+					nested--
+				}
+			}
+
+		case tok.is("COLLATE"):
+			p.next()
+			p.ident()
+
+		case tok.is("OVER"):
+			p.next()
+			if p.peek().kind == tokenIdent {
+				p.next() // window name
+			}
+
+		case tok.is("IN") && p.peekAt(1).kind == tokenIdent:
+			// x IN table đọc cả bảng
+			p.next()
+			p.table(sc)
+
+		case tok.kind == tokenIdent:
+			p.next()
+			switch {
+			case p.peek().is("("):
+				if deniedFunctions[strings.ToLower(tok.value)] {
+					p.fail(tok.pos, "function %q is not allowed", tok.value)
+				}
+			case p.peek().is("."):
+				p.next()
+				column := p.peek()
+				if column.kind != tokenIdent {
+					p.fail(column.pos, "expected a column name, got %s", describe(column))
+				}
+				p.next()
+				if p.peek().is(".") {
+					p.fail(tok.pos, "schema-qualified names are not allowed")
+				}
+				p.columns = append(p.columns, columnRef{
+					scope: sc, qualifier: strings.ToLower(tok.value), name: strings.ToLower(column.value), pos: column.pos,
+				})
+			default:
+				p.columns = append(p.columns, columnRef{scope: sc, name: strings.ToLower(tok.value), pos: tok.pos})
+			}
+
+		default:
+			p.next()
+		}
+	}
+}
+
+// isAlias reports whether tok, read right after an operand, starts an implicit alias
+// (SELECT title t): two operands are never adjacent otherwise.
+func (p *parser) isAlias(tok token) bool {
+	if tok.kind != tokenIdent && tok.kind != tokenString {
+		return false
+	}
+	prev := p.tokens[p.i-1]
+	switch prev.kind {
+	case tokenIdent, tokenString, tokenNumber, tokenBlob, tokenParam:
+		return true
+	case tokenKeyword:
+		return prev.value == "NULL" || prev.value == "TRUE" || prev.value == "FALSE" || prev.value == "END" ||
+			strings.HasPrefix(prev.value, "CURRENT_")
+	}
+	return prev.is(")")
+}
+
+// check resolves the recorded columns and stars against the tables of their scopes.
+func (p *parser) check() {
+	for _, ref := range p.columns {
+		switch {
+		case ref.target != nil:
+			p.checkColumn(ref.target, ref.name, ref.pos, "")
+		case ref.qualifier != "":
+			sources := ref.scope.lookup(ref.qualifier)
+			if len(sources) == 0 {
+				p.fail(ref.pos, "unknown table or alias %q", ref.qualifier)
+			}
+			// Tên trùng ở scope ngoài vẫn được kiểm tra để không lọt cột bị ẩn
+			for _, src := range sources {
+				p.checkColumn(src, ref.name, ref.pos, "")
+			}
+		case ref.orderBy && ref.scope.aliases[ref.name]:
+		default:
+			// Cột không ghi bảng có thể thuộc bất kỳ bảng nào trong scope hoặc scope ngoài
+			visible := 0
+			for sc := ref.scope; sc != nil; sc = sc.parent {
+				visible += len(sc.sources)
+			}
+			hint := ""
+			if visible > 1 {
+				hint = "; qualify it if it belongs to another table"
+			}
+			for sc := ref.scope; sc != nil; sc = sc.parent {
+				for _, src := range sc.sources {
+					p.checkColumn(src, ref.name, ref.pos, hint)
+				}
+			}
+		}
+	}
+
+	for _, star := range p.stars {
+		sources := star.scope.sources
+		if star.qualifier != "" {
+			sources = star.scope.lookup(star.qualifier)
+			if len(sources) == 0 {
+				p.fail(star.pos, "unknown table or alias %q", star.qualifier)
+			}
+		}
+		for _, src := range sources {
+			if src.columns != nil {
+				p.fail(star.pos, "* is not allowed on table %q, list its columns", src.table)
+			}
+		}
+	}
+
+	p.checkNatural()
+}
+
+func (p *parser) checkColumn(src *source, name string, pos int, hint string) {
+	if src.columns != nil && !src.columns[name] {
+		p.fail(pos, "column %q is not allowed on table %q%s", name, src.table, hint)
+	}
+}
+
+// checkNatural rejects NATURAL JOIN with column-restricted tables: it would compare their
+// hidden columns.
+func (p *parser) checkNatural() {
+	for _, join := range p.naturals {
+		for _, src := range join.scope.sources {
+			if src.columns != nil {
+				p.fail(join.pos, "NATURAL JOIN is not allowed with table %q", src.table)
+			}
+		}
+	}
+}
@@ -2,113 +2,165 @@ package middleware
 
 import (
 	"errors"
-	"regexp"
 	"strings"
 )
 
-// SQL validation patterns
-var (
-	dangerousPatterns = []string{
-		`(?i)\bDROP\b`,
-		`(?i)\bTRUNCATE\b`,
-		`(?i)\bALTER\b`,
-		`(?i)\bCREATE\b`,
-		`(?i)--`,
-		`(?i)/\*`,
-		`(?i)\*/`,
-		`(?i)\bEXEC\b`,
-		`(?i)\bEVAL\b`,
-		`(?i)\bSYSTEM\b`,
-		`(?i)\bSHUTDOWN\b`,
-		`(?i)\bGRANT\b`,
-		`(?i)\bREVOKE\b`,
-		`(?i)\bLOAD_FILE\b`,
-		`(?i)\bINTO\s+OUTFILE\b`,
-		`(?i)\bINTO\s+DUMPFILE\b`,
-	}
+// StatementType is a kind of statement accepted by the raw query endpoints
+type StatementType string
 
-	updateWherePattern = regexp.MustCompile(`(?i)UPDATE\s+.*\s+WHERE\s+`)
-	deleteWherePattern = regexp.MustCompile(`(?i)DELETE\s+FROM\s+.*\s+WHERE\s+`)
+const (
+	StatementSelect StatementType = "SELECT"
+	StatementInsert StatementType = "INSERT"
+	StatementUpdate StatementType = "UPDATE"
+	StatementDelete StatementType = "DELETE"
 )
 
-// ValidateQuery validates a SQL query for safety
-func ValidateQuery(query string) error {
-	if strings.TrimSpace(query) == "" {
-		return errors.New("query cannot be empty")
-	}
+// QueryPolicy is the table/column allowlist applied to raw queries
+type QueryPolicy struct {
+	// Tables maps each table a query may read or write to its allowed columns; nil allows
+	// every column. PocketBase internal tables (_*) and sqlite_* are never allowed.
+	Tables map[string][]string
+}
 
-	// Check for dangerous patterns
-	for _, pattern := range dangerousPatterns {
-		if matched, _ := regexp.MatchString(pattern, query); matched {
-			return errors.New("query contains potentially dangerous operations")
-		}
-	}
+// DefaultQueryPolicy exposes the application collections. musers hides password and tokenKey.
+var DefaultQueryPolicy = QueryPolicy{
+	Tables: map[string][]string{
+		"musers": {
+			"id", "email", "emailVisibility", "verified", "fcm_token", "is_fcm_active",
+			"locale", "digest_enabled", "digest_window_sec",
+		},
+		"reminders":              nil,
+		"deliveries":             nil,
+		"notification_templates": nil,
+		"mgroups":                nil,
+		"mgroup_members":         nil,
+		"system_status":          nil,
+	},
+}
 
-	return nil
+// Validate parses query and checks that it is a single statement of one of types that
+// only uses allowed tables and columns. UPDATE and DELETE must have a WHERE clause.
+// Syntax and policy errors are *QueryError with the position of the offending token.
+func (p QueryPolicy) Validate(query string, types ...StatementType) error {
+	_, err := p.ValidateStatement(query, types...)
+	return err
 }
 
-// ValidateSelectQuery validates a SELECT query
-func ValidateSelectQuery(query string) error {
-	if err := ValidateQuery(query); err != nil {
-		return err
+// ValidateStatement is Validate that also returns the type of the statement.
+func (p QueryPolicy) ValidateStatement(query string, types ...StatementType) (stmt StatementType, err error) {
+	if strings.TrimSpace(query) == "" {
+		return "", errors.New("query cannot be empty")
 	}
-
-	trimmedQuery := strings.ToUpper(strings.TrimSpace(query))
-	if !strings.HasPrefix(trimmedQuery, "SELECT") {
-		return errors.New("only SELECT statements are allowed")
+	tokens, err := tokenize(query)
+	if err != nil {
+		return "", err
+	}
+	if tokens[0].kind == tokenEOF {
+		return "", errors.New("query cannot be empty")
 	}
 
-	return nil
+	ps := &parser{query: query, tokens: tokens, tables: p.compile()}
+	defer func() {
+		if r := recover(); r != nil {
+			qerr, ok := r.(*QueryError)
+			if !ok {
+				panic(r)
+			}
+			stmt, err = "", qerr
+		}
+	}()
+	ps.statement(types)
+	ps.check()
+	return ps.stmt, nil
 }
 
-// ValidateInsertQuery validates an INSERT query
-func ValidateInsertQuery(query string) error {
-	if err := ValidateQuery(query); err != nil {
-		return err
+// Only returns the policy restricted to tables; tables p doesn't allow stay denied.
+func (p QueryPolicy) Only(tables ...string) QueryPolicy {
+	restricted := QueryPolicy{Tables: make(map[string][]string, len(tables))}
+	for _, table := range tables {
+		for name, columns := range p.Tables {
+			if strings.EqualFold(name, table) {
+				restricted.Tables[name] = columns
+			}
+		}
 	}
+	return restricted
+}
 
-	trimmedQuery := strings.ToUpper(strings.TrimSpace(query))
-	if !strings.HasPrefix(trimmedQuery, "INSERT") {
-		return errors.New("only INSERT statements are allowed")
+// compile lower-cases the allowlist into lookup maps (SQLite names are case-insensitive)
+func (p QueryPolicy) compile() map[string]map[string]bool {
+	tables := make(map[string]map[string]bool, len(p.Tables))
+	for table, columns := range p.Tables {
+		var allowed map[string]bool
+		if columns != nil {
+			allowed = make(map[string]bool, len(columns))
+			for _, column := range columns {
+				allowed[strings.ToLower(column)] = true
+			}
+		}
+		tables[strings.ToLower(table)] = allowed
 	}
-
-	return nil
+	return tables
 }
 
-// ValidateUpdateQuery validates an UPDATE query
-func ValidateUpdateQuery(query string) error {
-	if err := ValidateQuery(query); err != nil {
+// ValidateParams checks that every {:name} placeholder of query is bound in params. Other
+// placeholder styles (?, :name, @name, $name) are rejected: they can't be bound and SQLite
+// would silently use NULL.
+func ValidateParams(query string, params map[string]any) error {
+	tokens, err := tokenize(query)
+	if err != nil {
 		return err
 	}
-
-	trimmedQuery := strings.ToUpper(strings.TrimSpace(query))
-	if !strings.HasPrefix(trimmedQuery, "UPDATE") {
-		return errors.New("only UPDATE statements are allowed")
+	for _, tok := range tokens {
+		if tok.kind != tokenParam {
+			continue
+		}
+		if !strings.HasPrefix(tok.value, "{:") {
+			return newQueryError(query, tok.pos, "unsupported placeholder %q, use {:name}", tok.value)
+		}
+		if _, ok := params[tok.value[2:len(tok.value)-1]]; !ok {
+			return newQueryError(query, tok.pos, "placeholder %s is not bound", tok.value)
+		}
 	}
+	return nil
+}
 
-	// Require WHERE clause for UPDATE
-	if !updateWherePattern.MatchString(query) {
-		return errors.New("UPDATE statements must include a WHERE clause for safety")
+// TrimStatement cuts the terminating semicolon (and what follows it) off a validated query
+// so that it can be wrapped in a subquery.
+func TrimStatement(query string) string {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return query
+	}
+	for _, tok := range tokens {
+		if tok.is(";") {
+			return query[:tok.pos]
+		}
 	}
+	return query
+}
 
-	return nil
+// ValidateQuery validates a SELECT, INSERT, UPDATE or DELETE query against DefaultQueryPolicy
+func ValidateQuery(query string) error {
+	return DefaultQueryPolicy.Validate(query, StatementSelect, StatementInsert, StatementUpdate, StatementDelete)
 }
 
-// ValidateDeleteQuery validates a DELETE query
-func ValidateDeleteQuery(query string) error {
-	if err := ValidateQuery(query); err != nil {
-		return err
-	}
+// ValidateSelectQuery validates a SELECT query
+func ValidateSelectQuery(query string) error {
+	return DefaultQueryPolicy.Validate(query, StatementSelect)
+}
 
-	trimmedQuery := strings.ToUpper(strings.TrimSpace(query))
-	if !strings.HasPrefix(trimmedQuery, "DELETE") {
-		return errors.New("only DELETE statements are allowed")
-	}
+// ValidateInsertQuery validates an INSERT query
+func ValidateInsertQuery(query string) error {
+	return DefaultQueryPolicy.Validate(query, StatementInsert)
+}
 
-	// Require WHERE clause for DELETE
-	if !deleteWherePattern.MatchString(query) {
-		return errors.New("DELETE statements must include a WHERE clause for safety")
-	}
+// ValidateUpdateQuery validates an UPDATE query
+func ValidateUpdateQuery(query string) error {
+	return DefaultQueryPolicy.Validate(query, StatementUpdate)
+}
 
-	return nil
+// ValidateDeleteQuery validates a DELETE query
+func ValidateDeleteQuery(query string) error {
+	return DefaultQueryPolicy.Validate(query, StatementDelete)
 }
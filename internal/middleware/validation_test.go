@@ -4,17 +4,18 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateQuery(t *testing.T) {
 	t.Run("should accept valid queries", func(t *testing.T) {
 		validQueries := []string{
-			"SELECT * FROM users WHERE id = 1",
-			"INSERT INTO users (name) VALUES ('John')",
-			"UPDATE users SET name = 'Jane' WHERE id = 1",
-			"DELETE FROM users WHERE id = 1",
+			"SELECT * FROM reminders WHERE id = 1",
+			"INSERT INTO reminders (title) VALUES ('John')",
+			"UPDATE reminders SET title = 'Jane' WHERE id = 1",
+			"DELETE FROM reminders WHERE id = 1",
 		}
-		
+
 		for _, query := range validQueries {
 			err := ValidateQuery(query)
 			assert.NoError(t, err, "Query should be valid: %s", query)
@@ -22,45 +23,75 @@ func TestValidateQuery(t *testing.T) {
 	})
 
 	t.Run("should reject empty query", func(t *testing.T) {
-		err := ValidateQuery("")
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "query cannot be empty")
+		for _, query := range []string{"", "   ", "-- only a comment"} {
+			err := ValidateQuery(query)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "query cannot be empty")
+		}
 	})
 
-	t.Run("should reject dangerous patterns", func(t *testing.T) {
-		dangerousQueries := []string{
-			"DROP TABLE users",
-			"SELECT * FROM users -- comment",
-			"SELECT * FROM users /* comment */",
+	t.Run("should reject other statements", func(t *testing.T) {
+		dangerousQueries := map[string]string{
+			"DROP TABLE reminders":                 "DROP statements are not allowed",
+			"ATTACH DATABASE 'other.db' AS other":  "ATTACH statements are not allowed",
+			"PRAGMA table_info(reminders)":         "PRAGMA statements are not allowed",
+			"CREATE TABLE x (id TEXT)":             "CREATE statements are not allowed",
+			"VACUUM":                               "VACUUM statements are not allowed",
+			"EXPLAIN SELECT * FROM reminders":      "EXPLAIN statements are not allowed",
+			"BEGIN; DELETE FROM reminders; COMMIT": "BEGIN statements are not allowed",
 		}
-		
-		for _, query := range dangerousQueries {
+
+		for query, message := range dangerousQueries {
 			err := ValidateQuery(query)
-			assert.Error(t, err, "Query should be rejected: %s", query)
-			assert.Contains(t, err.Error(), "potentially dangerous operations")
+			require.Error(t, err, "Query should be rejected: %s", query)
+			assert.Contains(t, err.Error(), message)
+		}
+	})
+
+	t.Run("should allow comments and keywords inside literals", func(t *testing.T) {
+		validQueries := []string{
+			"SELECT * FROM reminders -- comment",
+			"SELECT * FROM reminders /* comment */ WHERE id = 1",
+			"SELECT * FROM reminders WHERE title = 'Create a backup; DROP the old one'",
+			"UPDATE reminders SET description = 'created -- by admin' WHERE id = 'a'",
+		}
+
+		for _, query := range validQueries {
+			assert.NoError(t, ValidateQuery(query), "Query should be valid: %s", query)
 		}
 	})
+
+	t.Run("should reject multiple statements", func(t *testing.T) {
+		err := ValidateQuery("SELECT * FROM reminders; DELETE FROM reminders WHERE 1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only one statement is allowed")
+
+		assert.NoError(t, ValidateQuery("SELECT * FROM reminders;"), "a trailing semicolon is fine")
+	})
 }
 
 func TestValidateSelectQuery(t *testing.T) {
 	t.Run("should accept valid SELECT queries", func(t *testing.T) {
 		validQueries := []string{
-			"SELECT * FROM users WHERE id = 1",
-			"SELECT name FROM users WHERE active = true",
+			"SELECT * FROM reminders WHERE id = 1",
+			"SELECT title FROM reminders WHERE status = 'active'",
+			"select id, email from musers where verified = true order by email limit 10",
+			"WITH due AS (SELECT id, user_id FROM reminders WHERE status = 'active') SELECT count(*) FROM due",
 		}
-		
+
 		for _, query := range validQueries {
 			err := ValidateSelectQuery(query)
-			assert.NoError(t, err)
+			assert.NoError(t, err, "Query should be valid: %s", query)
 		}
 	})
 
 	t.Run("should reject non-SELECT queries", func(t *testing.T) {
 		invalidQueries := []string{
-			"INSERT INTO users (name) VALUES ('John')",
-			"UPDATE users SET name = 'Jane' WHERE id = 1",
+			"INSERT INTO reminders (title) VALUES ('John')",
+			"UPDATE reminders SET title = 'Jane' WHERE id = 1",
+			"WITH x AS (SELECT 1) DELETE FROM reminders WHERE id = 1",
 		}
-		
+
 		for _, query := range invalidQueries {
 			err := ValidateSelectQuery(query)
 			assert.Error(t, err)
@@ -69,13 +100,33 @@ func TestValidateSelectQuery(t *testing.T) {
 	})
 }
 
+func TestValidateInsertQuery(t *testing.T) {
+	t.Run("should accept valid INSERT queries", func(t *testing.T) {
+		validQueries := []string{
+			"INSERT INTO reminders (title, user_id) VALUES ('Pay rent', 'u1')",
+			"INSERT OR IGNORE INTO mgroup_members (group_id, user_id) SELECT 'g1', id FROM musers WHERE verified = 1",
+			"INSERT INTO musers (id, email) VALUES ('u1', 'a@b.c') ON CONFLICT (id) DO UPDATE SET email = excluded.email",
+		}
+
+		for _, query := range validQueries {
+			assert.NoError(t, ValidateInsertQuery(query), "Query should be valid: %s", query)
+		}
+	})
+
+	t.Run("should reject non-INSERT queries", func(t *testing.T) {
+		err := ValidateInsertQuery("DELETE FROM reminders WHERE id = 1")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "only INSERT statements are allowed")
+	})
+}
+
 func TestValidateUpdateQuery(t *testing.T) {
 	t.Run("should accept valid UPDATE queries with WHERE clause", func(t *testing.T) {
 		validQueries := []string{
-			"UPDATE users SET name = 'Jane' WHERE id = 1",
-			"UPDATE users SET active = false WHERE created < '2023-01-01'",
+			"UPDATE reminders SET title = 'Jane' WHERE id = 1",
+			"UPDATE reminders SET status = 'paused' WHERE created < '2023-01-01'",
 		}
-		
+
 		for _, query := range validQueries {
 			err := ValidateUpdateQuery(query)
 			assert.NoError(t, err)
@@ -83,18 +134,25 @@ func TestValidateUpdateQuery(t *testing.T) {
 	})
 
 	t.Run("should reject UPDATE queries without WHERE clause", func(t *testing.T) {
-		invalidQuery := "UPDATE users SET name = 'Jane'"
-		err := ValidateUpdateQuery(invalidQuery)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "UPDATE statements must include a WHERE clause")
+		invalidQueries := []string{
+			"UPDATE reminders SET title = 'Jane'",
+			// WHERE của subquery không giới hạn UPDATE
+			"UPDATE reminders SET title = (SELECT email FROM musers WHERE id = 'u1')",
+		}
+
+		for _, query := range invalidQueries {
+			err := ValidateUpdateQuery(query)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "UPDATE statements must include a WHERE clause")
+		}
 	})
 
 	t.Run("should reject non-UPDATE queries", func(t *testing.T) {
 		invalidQueries := []string{
-			"SELECT * FROM users WHERE id = 1",
-			"INSERT INTO users (name) VALUES ('John')",
+			"SELECT * FROM reminders WHERE id = 1",
+			"INSERT INTO reminders (title) VALUES ('John')",
 		}
-		
+
 		for _, query := range invalidQueries {
 			err := ValidateUpdateQuery(query)
 			assert.Error(t, err)
@@ -106,10 +164,10 @@ func TestValidateUpdateQuery(t *testing.T) {
 func TestValidateDeleteQuery(t *testing.T) {
 	t.Run("should accept valid DELETE queries with WHERE clause", func(t *testing.T) {
 		validQueries := []string{
-			"DELETE FROM users WHERE id = 1",
-			"DELETE FROM users WHERE active = false",
+			"DELETE FROM reminders WHERE id = 1",
+			"DELETE FROM deliveries WHERE outcome = 'failed' AND created < '2024-01-01'",
 		}
-		
+
 		for _, query := range validQueries {
 			err := ValidateDeleteQuery(query)
 			assert.NoError(t, err)
@@ -117,7 +175,7 @@ func TestValidateDeleteQuery(t *testing.T) {
 	})
 
 	t.Run("should reject DELETE queries without WHERE clause", func(t *testing.T) {
-		invalidQuery := "DELETE FROM users"
+		invalidQuery := "DELETE FROM reminders"
 		err := ValidateDeleteQuery(invalidQuery)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "DELETE statements must include a WHERE clause")
@@ -125,14 +183,248 @@ func TestValidateDeleteQuery(t *testing.T) {
 
 	t.Run("should reject non-DELETE queries", func(t *testing.T) {
 		invalidQueries := []string{
-			"SELECT * FROM users WHERE id = 1",
-			"INSERT INTO users (name) VALUES ('John')",
+			"SELECT * FROM reminders WHERE id = 1",
+			"INSERT INTO reminders (title) VALUES ('John')",
 		}
-		
+
 		for _, query := range invalidQueries {
 			err := ValidateDeleteQuery(query)
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "only DELETE statements are allowed")
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestQueryPolicy_Validate(t *testing.T) {
+	policy := QueryPolicy{Tables: map[string][]string{
+		"reminders": nil,
+		"musers":    {"id", "email"},
+	}}
+	all := []StatementType{StatementSelect, StatementInsert, StatementUpdate, StatementDelete}
+
+	t.Run("should reject tables outside the allowlist", func(t *testing.T) {
+		rejected := map[string]string{
+			"SELECT * FROM deliveries":                                         `table "deliveries" is not allowed`,
+			"SELECT * FROM _superusers":                                        `table "_superusers" is not allowed`,
+			"SELECT * FROM _params":                                            `table "_params" is not allowed`,
+			"SELECT name FROM sqlite_master":                                   `table "sqlite_master" is not allowed`,
+			"SELECT id FROM reminders WHERE id IN _superusers":                 `table "_superusers" is not allowed`,
+			"SELECT id FROM main.reminders":                                    "schema-qualified table names are not allowed",
+			"SELECT * FROM pragma_table_info('musers')":                        `table-valued function "pragma_table_info" is not allowed`,
+			"DELETE FROM _superusers WHERE 1":                                  `table "_superusers" is not allowed`,
+			"SELECT id FROM reminders WHERE user_id IN (SELECT id FROM _mfas)": `table "_mfas" is not allowed`,
+		}
+
+		for query, message := range rejected {
+			err := policy.Validate(query, all...)
+			require.Error(t, err, query)
+			assert.Contains(t, err.Error(), message, query)
+		}
+	})
+
+	t.Run("should never allow internal tables", func(t *testing.T) {
+		internal := QueryPolicy{Tables: map[string][]string{"_superusers": nil}}
+		assert.Error(t, internal.Validate("SELECT * FROM _superusers", StatementSelect))
+	})
+
+	t.Run("should only allow listed columns of restricted tables", func(t *testing.T) {
+		rejected := map[string]string{
+			"SELECT password FROM musers":                                                            `column "password" is not allowed on table "musers"`,
+			"SELECT u.tokenKey FROM musers u":                                                        `column "tokenkey" is not allowed on table "musers"`,
+			`SELECT "password" FROM musers`:                                                          `column "password" is not allowed on table "musers"`,
+			"SELECT id FROM musers WHERE password LIKE 'a%'":                                         `column "password" is not allowed on table "musers"`,
+			"SELECT * FROM musers":                                                                   `* is not allowed on table "musers"`,
+			"SELECT u.* FROM musers u":                                                               `* is not allowed on table "musers"`,
+			"SELECT count(password) FROM musers":                                                     `column "password" is not allowed`,
+			"UPDATE musers SET password = 'x' WHERE id = 'u1'":                                       `column "password" is not allowed`,
+			"INSERT INTO musers (id, tokenKey) VALUES ('u1', 'k')":                                   `column "tokenkey" is not allowed`,
+			"INSERT INTO musers VALUES ('u1', 'a@b.c')":                                              `a column list is required for table "musers"`,
+			"DELETE FROM musers WHERE id = 'u1' RETURNING *":                                         `* is not allowed on table "musers"`,
+			"SELECT email FROM musers NATURAL JOIN reminders":                                        `NATURAL JOIN is not allowed with table "musers"`,
+			"SELECT id FROM reminders WHERE user_id IN (SELECT id FROM musers WHERE tokenKey = 'k')": `column "tokenkey" is not allowed`,
+			// Cột không ghi bảng trong subquery có thể thuộc bảng ngoài
+			"SELECT id FROM musers WHERE EXISTS (SELECT 1 FROM reminders WHERE password = 'x')": `column "password" is not allowed`,
+		}
+
+		for query, message := range rejected {
+			err := policy.Validate(query, all...)
+			require.Error(t, err, query)
+			assert.Contains(t, err.Error(), message, query)
+		}
+	})
+
+	t.Run("should reject duplicate table names and aliases", func(t *testing.T) {
+		rejected := []string{
+			"SELECT m.password FROM reminders m JOIN musers m ON 1",
+			"SELECT m.password FROM (SELECT 1 AS x) m, musers m",
+			"SELECT m.tokenKey FROM reminders m JOIN musers m ON 1",
+			"SELECT m.tokenKey FROM (SELECT 1 AS x) m, musers m",
+			"SELECT m.id FROM musers m, musers AS m",
+			"SELECT id FROM reminders, reminders",
+			"UPDATE reminders AS r SET title = 'x' FROM musers r WHERE r.id = 'u1'",
+		}
+
+		for _, query := range rejected {
+			err := policy.Validate(query, all...)
+			require.Error(t, err, query)
+			assert.Contains(t, err.Error(), "ambiguous table or alias", query)
+		}
+	})
+
+	t.Run("should check qualified columns against every table with the name", func(t *testing.T) {
+		rejected := []string{
+			"SELECT (SELECT m.password FROM reminders m) FROM musers m",
+			"SELECT (SELECT m.tokenKey FROM reminders m) FROM musers m",
+		}
+
+		for _, query := range rejected {
+			err := policy.Validate(query, StatementSelect)
+			require.Error(t, err, query)
+			assert.Contains(t, err.Error(), "is not allowed on table \"musers\"", query)
+		}
+
+		assert.NoError(t, policy.Validate("SELECT m.id, (SELECT m.id FROM reminders m) FROM musers m", StatementSelect))
+		assert.NoError(t, policy.Validate("SELECT r.id FROM reminders r JOIN (SELECT id FROM reminders) r2 ON r2.id = r.id", StatementSelect))
+	})
+
+	t.Run("should ask to qualify ambiguous columns", func(t *testing.T) {
+		err := policy.Validate("SELECT title FROM reminders r JOIN musers u ON u.id = r.user_id", StatementSelect)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "qualify it if it belongs to another table")
+
+		assert.NoError(t, policy.Validate("SELECT r.title, u.email FROM reminders r JOIN musers u ON u.id = r.user_id", StatementSelect))
+	})
+
+	t.Run("should accept queries within the allowlist", func(t *testing.T) {
+		accepted := []string{
+			"SELECT id, email FROM musers",
+			"SELECT email AS address FROM musers ORDER BY address",
+			"SELECT r.* FROM reminders r",
+			"SELECT * FROM (SELECT id, email FROM musers) u",
+			"SELECT id FROM reminders WHERE user_id IN (SELECT id FROM musers WHERE email = 'a@b.c')",
+			"SELECT CAST(retry_count AS INTEGER) AS n, count(*) FROM reminders GROUP BY n HAVING count(*) > 1",
+			"SELECT j.value FROM reminders, json_each(reminders.delivery_options) j",
+			"SELECT row_number() OVER (PARTITION BY user_id ORDER BY created ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) FROM reminders",
+			"WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 5) SELECT x FROM n",
+			"UPDATE reminders SET (title, status) = ('a', 'paused') WHERE id = 'r1' RETURNING id",
+			"INSERT INTO musers (id, email) VALUES ('u1', 'a@b.c') ON CONFLICT (id) DO UPDATE SET email = excluded.email",
+			`SELECT "key" FROM reminders WHERE [key] = 'x'`,
+		}
+
+		for _, query := range accepted {
+			assert.NoError(t, policy.Validate(query, all...), query)
+		}
+	})
+
+	t.Run("should reject denied functions", func(t *testing.T) {
+		err := policy.Validate("SELECT load_extension('/tmp/evil.so')", StatementSelect)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `function "load_extension" is not allowed`)
+	})
+
+	t.Run("should report the error position", func(t *testing.T) {
+		err := policy.Validate("SELECT id\nFROM reminders\nWHERE user_id IN (SELECT password FROM musers)", StatementSelect)
+
+		var qerr *QueryError
+		require.ErrorAs(t, err, &qerr)
+		assert.Equal(t, 3, qerr.Line)
+		assert.Equal(t, 26, qerr.Column)
+		assert.Equal(t, `column "password" is not allowed on table "musers"; qualify it if it belongs to another table (line 3, column 26)`, err.Error())
+	})
+
+	t.Run("should report syntax errors", func(t *testing.T) {
+		rejected := map[string]string{
+			"SELECT id FROM reminders WHERE (status = 'a'": `unexpected end of query, missing ")" (line 1, column 45)`,
+			"SELECT id FROM reminders WHERE":               "expected an expression, got end of query (line 1, column 31)",
+			"SELECT id FROM reminders r WHERE x.id = 1":    `unknown table or alias "x" (line 1, column 36)`,
+			"SELECT id FROM reminders LEFT musers":         `expected JOIN, got "musers" (line 1, column 31)`,
+		}
+
+		for query, message := range rejected {
+			err := policy.Validate(query, all...)
+			require.Error(t, err, query)
+			assert.Equal(t, message, err.Error(), query)
+		}
+	})
+}
+
+func TestValidateParams(t *testing.T) {
+	t.Run("should accept bound placeholders", func(t *testing.T) {
+		query := "SELECT * FROM reminders WHERE user_id = {:uid} AND status = {:status} AND title = '{:ignored}'"
+		assert.NoError(t, ValidateParams(query, map[string]any{"uid": "u1", "status": nil}))
+		assert.NoError(t, ValidateQuery(query), "placeholders are operands for the parser")
+	})
+
+	t.Run("should reject unbound placeholders", func(t *testing.T) {
+		err := ValidateParams("SELECT * FROM reminders\nWHERE user_id = {:uid}", map[string]any{"user": "u1"})
+		assert.EqualError(t, err, "placeholder {:uid} is not bound (line 2, column 17)")
+	})
+
+	t.Run("should reject other placeholder styles", func(t *testing.T) {
+		for _, query := range []string{
+			"SELECT * FROM reminders WHERE id = ?",
+			"SELECT * FROM reminders WHERE id = ?1",
+			"SELECT * FROM reminders WHERE id = :id",
+			"SELECT * FROM reminders WHERE id = @id",
+			"SELECT * FROM reminders WHERE id = $id",
+		} {
+			err := ValidateParams(query, map[string]any{"id": "r1"})
+			require.Error(t, err, query)
+			assert.Contains(t, err.Error(), "use {:name}", query)
+		}
+	})
+
+	t.Run("should reject malformed placeholders", func(t *testing.T) {
+		err := ValidateParams("SELECT * FROM reminders WHERE id = {:id", nil)
+		assert.EqualError(t, err, "invalid placeholder, expected {:name} (line 1, column 36)")
+	})
+}
+
+func TestTrimStatement(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":                     "SELECT 1",
+		"SELECT 1;":                    "SELECT 1",
+		"SELECT ';' AS s ; -- done":    "SELECT ';' AS s ",
+		"SELECT 1 -- no semicolon; ok": "SELECT 1 -- no semicolon; ok",
+	}
+
+	for query, expected := range tests {
+		assert.Equal(t, expected, TrimStatement(query), query)
+	}
+}
+
+func TestQueryPolicy_Only(t *testing.T) {
+	policy := QueryPolicy{Tables: map[string][]string{
+		"reminders": nil,
+		"musers":    {"id", "email"},
+	}}
+
+	restricted := policy.Only("MUSERS", "deliveries")
+
+	assert.Equal(t, QueryPolicy{Tables: map[string][]string{"musers": {"id", "email"}}}, restricted)
+	assert.NoError(t, restricted.Validate("SELECT email FROM musers", StatementSelect))
+	assert.EqualError(t, restricted.Validate("SELECT * FROM reminders", StatementSelect), `table "reminders" is not allowed (line 1, column 15)`)
+	assert.Error(t, restricted.Validate("SELECT * FROM deliveries", StatementSelect), "tables outside the policy stay denied")
+}
+
+func TestQueryPolicy_ValidateStatement(t *testing.T) {
+	policy := QueryPolicy{Tables: map[string][]string{"reminders": nil}}
+	all := []StatementType{StatementSelect, StatementInsert, StatementUpdate, StatementDelete}
+
+	tests := map[string]StatementType{
+		"SELECT * FROM reminders":                                       StatementSelect,
+		"WITH r AS (SELECT id FROM reminders) SELECT * FROM r":          StatementSelect,
+		"REPLACE INTO reminders (id) VALUES ('r1')":                     StatementInsert,
+		"UPDATE reminders SET title = 'x' WHERE id = 'r1'":              StatementUpdate,
+		"WITH r AS (SELECT 'r1' AS id) DELETE FROM reminders WHERE 1=1": StatementDelete,
+	}
+	for query, expected := range tests {
+		stmt, err := policy.ValidateStatement(query, all...)
+		require.NoError(t, err, query)
+		assert.Equal(t, expected, stmt, query)
+	}
+
+	stmt, err := policy.ValidateStatement("DELETE FROM reminders", all...)
+	assert.Error(t, err)
+	assert.Empty(t, stmt)
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Delivery records one attempt to deliver a reminder to a user's device
+type Delivery struct {
+	ID                string     `json:"id" db:"id"`
+	ReminderID        string     `json:"reminder_id" db:"reminder_id"`
+	UserID            string     `json:"user_id" db:"user_id"`
+	Channel           string     `json:"channel" db:"channel"` // fcm, email
+	Device            string     `json:"device" db:"device"`   // token đã che (chỉ giữ vài ký tự cuối), "topic:<tên topic>" hoặc email đã che
+	ScheduledFor      time.Time  `json:"scheduled_for" db:"scheduled_for"`
+	SentAt            *time.Time `json:"sent_at" db:"sent_at"`
+	ProviderMessageID string     `json:"provider_message_id" db:"provider_message_id"`
+	Outcome           string     `json:"outcome" db:"outcome"`         // sent, failed, deferred, expired
+	ErrorClass        string     `json:"error_class" db:"error_class"` // FCMErrorClass khi thất bại
+	ErrorMessage      string     `json:"error_message" db:"error_message"`
+	Attempt           int        `json:"attempt" db:"attempt"`         // lần gửi thứ mấy trong cùng một tick (bắt đầu từ 1)
+	DispatchID        string     `json:"dispatch_id" db:"dispatch_id"` // "delivery_id" trong data payload, chung cho các bản ghi của cùng một tin
+	ReceivedAt        *time.Time `json:"received_at" db:"received_at"` // thiết bị báo đã hiển thị
+	OpenedAt          *time.Time `json:"opened_at" db:"opened_at"`     // user đã mở thông báo
+	Created           time.Time  `json:"created" db:"created"`
+}
+
+// Constants for delivery channels
+const (
+	DeliveryChannelFCM   = "fcm"
+	DeliveryChannelEmail = "email" // escalation qua email
+)
+
+// Constants for delivery outcomes
+const (
+	DeliveryOutcomeSent     = "sent"
+	DeliveryOutcomeFailed   = "failed"
+	DeliveryOutcomeDeferred = "deferred" // vượt rate limit, chưa gửi tới FCM; reminder vẫn due
+	DeliveryOutcomeExpired  = "expired"  // quá TTL trước khi gửi được, bỏ lượt này
+)
+
+// Constants for delivery acknowledgement events sent by the client
+const (
+	AckEventReceived = "received"
+	AckEventOpened   = "opened"
+)
+
+// Error classes of deliveries that did not go through FCM
+const (
+	DeliveryErrorRateLimited = "rate_limited" // deferred
+	DeliveryErrorEmail       = "email"        // gửi email escalation thất bại
+)
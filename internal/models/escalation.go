@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+)
+
+// EscalationPolicy hands a retry_until_complete reminder to other contacts or channels
+// when the owner still hasn't completed it after all retries.
+type EscalationPolicy struct {
+	Steps []EscalationStep `json:"steps"` // chạy lần lượt, dừng khi reminder được hoàn thành
+}
+
+// EscalationStep notifies one target after a delay.
+type EscalationStep struct {
+	DelaySeconds int    `json:"delay_seconds"`     // chờ sau lần nhắc cuối (hoặc bước trước)
+	Channel      string `json:"channel"`           // push, email
+	UserID       string `json:"user_id,omitempty"` // push: người nhận, vd. người nhà
+	Email        string `json:"email,omitempty"`   // email: địa chỉ nhận
+}
+
+// Constants for escalation channels
+const (
+	EscalationChannelPush  = "push"
+	EscalationChannelEmail = "email"
+)
+
+// MaxEscalationSteps caps the length of an escalation chain
+const MaxEscalationSteps = 5
+
+// HasSteps reports whether the policy escalates at all. Nil policies have no steps.
+func (p *EscalationPolicy) HasSteps() bool {
+	return p != nil && len(p.Steps) > 0
+}
+
+// Validate checks the escalation steps. Nil policies are valid.
+func (p *EscalationPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if len(p.Steps) > MaxEscalationSteps {
+		return &ValidationError{Field: "escalation_policy.steps", Message: fmt.Sprintf("At most %d escalation steps are allowed", MaxEscalationSteps)}
+	}
+	for i, step := range p.Steps {
+		field := fmt.Sprintf("escalation_policy.steps[%d]", i)
+		if step.DelaySeconds < 0 {
+			return &ValidationError{Field: field + ".delay_seconds", Message: "Delay cannot be negative"}
+		}
+		switch step.Channel {
+		case EscalationChannelPush:
+			if step.UserID == "" {
+				return &ValidationError{Field: field + ".user_id", Message: "User is required for push escalation"}
+			}
+		case EscalationChannelEmail:
+			if _, err := mail.ParseAddress(step.Email); err != nil {
+				return &ValidationError{Field: field + ".email", Message: "A valid email is required for email escalation"}
+			}
+		default:
+			return &ValidationError{Field: field + ".channel", Message: "Channel must be push or email"}
+		}
+	}
+	return nil
+}
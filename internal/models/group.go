@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Group is a named set of users that share broadcast reminders.
+// Members are subscribed to the group's FCM topic.
+type Group struct {
+	ID      string    `json:"id" db:"id"`
+	Name    string    `json:"name" db:"name"`
+	Topic   string    `json:"topic" db:"topic"` // FCM topic, sinh từ ID khi tạo
+	OwnerID string    `json:"owner_id" db:"owner_id"`
+	Created time.Time `json:"created" db:"created"`
+	Updated time.Time `json:"updated" db:"updated"`
+}
+
+// GroupMember links a user to a group.
+type GroupMember struct {
+	ID      string    `json:"id" db:"id"`
+	GroupID string    `json:"group_id" db:"group_id"`
+	UserID  string    `json:"user_id" db:"user_id"`
+	Created time.Time `json:"created" db:"created"`
+}
+
+// GroupTopicPrefix prefixes group IDs to form FCM topic names.
+const GroupTopicPrefix = "remiaq-group-"
+
+// Validate checks if group data is valid
+func (g *Group) Validate() error {
+	if g.Name == "" {
+		return &ValidationError{Field: "name", Message: "Name is required"}
+	}
+	return nil
+}
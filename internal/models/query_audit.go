@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// QueryAudit records one call to the raw SQL endpoints
+type QueryAudit struct {
+	ID           string         `json:"id" db:"id"`
+	Caller       string         `json:"caller" db:"caller"`       // "superuser:<email>" hoặc "apikey:<tên key>", rỗng khi không xác thực được
+	Statement    string         `json:"statement" db:"statement"` // SELECT, INSERT, UPDATE, DELETE
+	Query        string         `json:"query" db:"query"`
+	SavedQuery   string         `json:"saved_query" db:"saved_query"` // tên truy vấn đã lưu, rỗng với SQL tự do
+	Params       map[string]any `json:"params" db:"params"`
+	DryRun       bool           `json:"dry_run" db:"dry_run"`
+	RowsAffected int64          `json:"rows_affected" db:"rows_affected"` // số dòng trả về với SELECT
+	DurationMs   int64          `json:"duration_ms" db:"duration_ms"`
+	Outcome      string         `json:"outcome" db:"outcome"` // ok, failed, denied
+	Status       int            `json:"status" db:"status"`   // HTTP status trả về
+	Error        string         `json:"error" db:"error"`     // rỗng khi thành công
+	Created      time.Time      `json:"created" db:"created"`
+}
+
+// Constants for query audit outcomes
+const (
+	QueryOutcomeOK     = "ok"
+	QueryOutcomeFailed = "failed"
+	QueryOutcomeDenied = "denied" // không xác thực được hoặc không có quyền, truy vấn không chạy
+)
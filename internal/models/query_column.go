@@ -0,0 +1,19 @@
+package models
+
+// QueryColumn describes a result column of a raw SELECT
+type QueryColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`               // one of the ColumnType values, "" if only NULLs were seen
+	DeclType string `json:"declType,omitempty"` // type declared in the table, empty for expressions
+}
+
+// Types of raw SELECT result columns, i.e. of the JSON values they hold
+const (
+	ColumnTypeInteger  = "integer"
+	ColumnTypeNumber   = "number"
+	ColumnTypeBoolean  = "boolean"
+	ColumnTypeText     = "text"
+	ColumnTypeJSON     = "json"     // object, array or other JSON value
+	ColumnTypeDatetime = "datetime" // RFC 3339 string
+	ColumnTypeBlob     = "blob"     // base64 string
+)
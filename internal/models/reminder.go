@@ -1,30 +1,62 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
 // Reminder represents a notification reminder
 type Reminder struct {
-	ID                string             `json:"id" db:"id"`
-	UserID            string             `json:"user_id" db:"user_id"`
-	Title             string             `json:"title" db:"title"`
-	Description       string             `json:"description" db:"description"`
-	Type              string             `json:"type" db:"type"`                   // one_time, recurring
-	CalendarType      string             `json:"calendar_type" db:"calendar_type"` // solar, lunar
-	NextTriggerAt     time.Time          `json:"next_trigger_at" db:"next_trigger_at"`
-	TriggerTimeOfDay  string             `json:"trigger_time_of_day" db:"trigger_time_of_day"` // HH:MM format
-	RecurrencePattern *RecurrencePattern `json:"recurrence_pattern" db:"recurrence_pattern"`   // JSON field
-	RepeatStrategy    string             `json:"repeat_strategy" db:"repeat_strategy"`         // none, retry_until_complete
-	RetryIntervalSec  int                `json:"retry_interval_sec" db:"retry_interval_sec"`
-	MaxRetries        int                `json:"max_retries" db:"max_retries"`
-	RetryCount        int                `json:"retry_count" db:"retry_count"`
-	Status            string             `json:"status" db:"status"` // active, completed, paused
-	SnoozeUntil       *time.Time         `json:"snooze_until" db:"snooze_until"`
-	LastCompletedAt   *time.Time         `json:"last_completed_at" db:"last_completed_at"`
-	LastSentAt        *time.Time         `json:"last_sent_at" db:"last_sent_at"`
-	Created           time.Time          `json:"created" db:"created"`
-	Updated           time.Time          `json:"updated" db:"updated"`
+	ID                   string             `json:"id" db:"id"`
+	UserID               string             `json:"user_id" db:"user_id"`
+	Title                string             `json:"title" db:"title"`
+	Description          string             `json:"description" db:"description"`
+	Type                 string             `json:"type" db:"type"`                   // one_time, recurring
+	CalendarType         string             `json:"calendar_type" db:"calendar_type"` // solar, lunar
+	NextTriggerAt        time.Time          `json:"next_trigger_at" db:"next_trigger_at"`
+	TriggerTimeOfDay     string             `json:"trigger_time_of_day" db:"trigger_time_of_day"` // HH:MM format
+	RecurrencePattern    *RecurrencePattern `json:"recurrence_pattern" db:"recurrence_pattern"`   // JSON field
+	RepeatStrategy       string             `json:"repeat_strategy" db:"repeat_strategy"`         // none, retry_until_complete
+	RetryIntervalSec     int                `json:"retry_interval_sec" db:"retry_interval_sec"`
+	SeenRetryIntervalSec int                `json:"seen_retry_interval_sec" db:"seen_retry_interval_sec"` // nhắc lại khi đã mở mà chưa hoàn thành, 0 = như retry_interval_sec
+	MaxRetries           int                `json:"max_retries" db:"max_retries"`
+	RetryCount           int                `json:"retry_count" db:"retry_count"`
+	Status               string             `json:"status" db:"status"` // active, completed, paused
+	SnoozeUntil          *time.Time         `json:"snooze_until" db:"snooze_until"`
+	LastCompletedAt      *time.Time         `json:"last_completed_at" db:"last_completed_at"`
+	LastSentAt           *time.Time         `json:"last_sent_at" db:"last_sent_at"`
+	Template             string             `json:"template" db:"template"`                   // khóa template thông báo, rỗng = default
+	DueAt                *time.Time         `json:"due_at" db:"due_at"`                       // hạn của sự kiện, dùng cho {until_due}
+	OccurrenceCount      int                `json:"occurrence_count" db:"occurrence_count"`   // số lần đã gửi thành công
+	AudienceType         string             `json:"audience_type" db:"audience_type"`         // user, users, group (rỗng = user)
+	AudienceUserIDs      []string           `json:"audience_user_ids" db:"audience_user_ids"` // JSON field, dùng khi audience_type = users
+	GroupID              string             `json:"group_id" db:"group_id"`                   // dùng khi audience_type = group
+	DeliveryOptions      *DeliveryOptions   `json:"delivery_options" db:"delivery_options"`   // JSON field, rỗng = mặc định
+	EscalationPolicy     *EscalationPolicy  `json:"escalation_policy" db:"escalation_policy"` // JSON field, chuyển tiếp khi hết lượt nhắc mà chưa hoàn thành
+	EscalationStep       int                `json:"escalation_step" db:"escalation_step"`     // bước chuyển tiếp đang chờ (từ 1), 0 = chưa chuyển tiếp
+	ClaimedBy            string             `json:"-" db:"claimed_by"`                        // worker instance đang giữ lease, rỗng = chưa ai nhận
+	ClaimedUntil         *time.Time         `json:"-" db:"claimed_until"`                     // lease hết hạn thì instance khác được nhận lại
+	Created              time.Time          `json:"created" db:"created"`
+	Updated              time.Time          `json:"updated" db:"updated"`
+}
+
+// ScheduledTrigger is when an active reminder is next due (snooze included), used by the scheduler
+type ScheduledTrigger struct {
+	ReminderID string    `json:"reminder_id" db:"id"`
+	TriggerAt  time.Time `json:"trigger_at" db:"trigger_at"`
+}
+
+// ReminderTransition is the state change the worker applies to a reminder after processing it.
+// It is applied as a whole, and only while next_trigger_at still equals PrevTriggerAt, so a
+// transition computed twice for the same occurrence (crash, lease takeover) applies once.
+type ReminderTransition struct {
+	ReminderID     string
+	PrevTriggerAt  time.Time  // next_trigger_at the transition was computed from
+	SentAt         *time.Time // set last_sent_at and count the occurrence
+	IncrementRetry bool
+	NextTriggerAt  time.Time  // zero keeps next_trigger_at
+	EscalationStep *int       // set escalation_step
+	CompletedAt    *time.Time // mark completed
 }
 
 // RecurrencePattern defines how a reminder repeats
@@ -36,22 +68,41 @@ type RecurrencePattern struct {
 	BaseOn          string `json:"base_on,omitempty"`          // creation, completion
 }
 
+// DeliveryOptions controls how FCM delivers a reminder's notification (Android and APNs)
+type DeliveryOptions struct {
+	Priority    string `json:"priority,omitempty"`     // high, normal (rỗng = high)
+	TTLSeconds  int    `json:"ttl_seconds,omitempty"`  // hết hạn sau N giây kể từ giờ hẹn, 0 = mặc định FCM (28 ngày)
+	CollapseKey string `json:"collapse_key,omitempty"` // tin mới thay tin cũ cùng key thay vì xếp chồng
+	Sound       string `json:"sound,omitempty"`        // rỗng = default
+	ChannelID   string `json:"channel_id,omitempty"`   // Android notification channel
+	Badge       *int   `json:"badge,omitempty"`        // số trên icon app, nil = không đổi
+}
+
 // User represents a user with FCM token
 type User struct {
-	ID          string    `json:"id" db:"id"`
-	Email       string    `json:"email" db:"email"`
-	FCMToken    string    `json:"fcm_token" db:"fcm_token"`
-	IsFCMActive bool      `json:"is_fcm_active" db:"is_fcm_active"`
-	Created     time.Time `json:"created" db:"created"`
-	Updated     time.Time `json:"updated" db:"updated"`
+	ID          string `json:"id" db:"id"`
+	Email       string `json:"email" db:"email"`
+	FCMToken    string `json:"fcm_token" db:"fcm_token"`
+	IsFCMActive bool   `json:"is_fcm_active" db:"is_fcm_active"`
+	Locale      string `json:"locale" db:"locale"` // vi, en (rỗng = vi)
+	// Digest: gộp các reminder due cùng tick (hoặc trong cửa sổ) thành một thông báo
+	DigestEnabled   bool      `json:"digest_enabled" db:"digest_enabled"`
+	DigestWindowSec int       `json:"digest_window_sec" db:"digest_window_sec"`
+	Created         time.Time `json:"created" db:"created"`
+	Updated         time.Time `json:"updated" db:"updated"`
 }
 
 // SystemStatus represents system configuration (singleton)
 type SystemStatus struct {
-    ID            int       `json:"mid" db:"mid"` // Always 1
-    WorkerEnabled bool      `json:"worker_enabled" db:"worker_enabled"`
-    LastError     string    `json:"last_error" db:"last_error"`
-    Updated       time.Time `json:"updated" db:"updated"`
+	ID               int        `json:"mid" db:"mid"` // Always 1
+	WorkerEnabled    bool       `json:"worker_enabled" db:"worker_enabled"`
+	LastError        string     `json:"last_error" db:"last_error"`
+	ErrorAt          *time.Time `json:"error_at" db:"error_at"`
+	CircuitState     string     `json:"circuit_state" db:"circuit_state"`   // closed, open, half_open
+	CircuitReason    string     `json:"circuit_reason" db:"circuit_reason"` // lý do chuyển trạng thái gần nhất
+	CircuitChangedAt *time.Time `json:"circuit_changed_at" db:"circuit_changed_at"`
+	CircuitOverride  string     `json:"circuit_override" db:"circuit_override"` // "", open, closed (admin)
+	Updated          time.Time  `json:"updated" db:"updated"`
 }
 
 // Constants for reminder types
@@ -79,6 +130,45 @@ const (
 	ReminderStatusPaused    = "paused"
 )
 
+// Minimum spacing between sends of one reminder, so a misconfigured reminder can't spam the user
+const (
+	MinIntervalSeconds  = 60 // recurrence_pattern.interval_seconds
+	MinRetryIntervalSec = 60 // retry_interval_sec khi repeat_strategy = retry_until_complete
+)
+
+// Constants for delivery priorities
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+)
+
+// Limits of delivery options imposed by FCM/APNs
+const (
+	MaxTTLSeconds     = 28 * 24 * 60 * 60 // FCM giữ tin tối đa 28 ngày
+	MaxCollapseKeyLen = 64                // apns-collapse-id tối đa 64 byte
+)
+
+// Constants for reminder audiences
+const (
+	AudienceUser  = "user"  // chỉ user_id (mặc định)
+	AudienceUsers = "users" // danh sách user, gửi multicast
+	AudienceGroup = "group" // nhóm, gửi qua FCM topic của nhóm
+)
+
+// Constants for user locales
+const (
+	LocaleVI      = "vi"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleVI
+)
+
+// Constants for notifier circuit breaker states
+const (
+	CircuitStateClosed   = "closed"
+	CircuitStateOpen     = "open"
+	CircuitStateHalfOpen = "half_open"
+)
+
 // Constants for recurrence pattern types
 const (
 	RecurrenceTypeDaily               = "daily"
@@ -104,9 +194,73 @@ func (r *Reminder) Validate() error {
 	if r.CalendarType != CalendarTypeSolar && r.CalendarType != CalendarTypeLunar {
 		return &ValidationError{Field: "calendar_type", Message: "Calendar type must be solar or lunar"}
 	}
+	if r.RecurrencePattern != nil && r.RecurrencePattern.IntervalSeconds < 0 {
+		return &ValidationError{Field: "recurrence_pattern.interval_seconds", Message: "Interval cannot be negative"}
+	}
+	if r.RecurrencePattern != nil && r.RecurrencePattern.IntervalSeconds > 0 && r.RecurrencePattern.IntervalSeconds < MinIntervalSeconds {
+		return &ValidationError{Field: "recurrence_pattern.interval_seconds", Message: fmt.Sprintf("Interval must be at least %d seconds", MinIntervalSeconds)}
+	}
+	if r.RepeatStrategy == RepeatStrategyRetryUntilComplete && r.RetryIntervalSec < MinRetryIntervalSec {
+		return &ValidationError{Field: "retry_interval_sec", Message: fmt.Sprintf("Retry interval must be at least %d seconds", MinRetryIntervalSec)}
+	}
+	if r.SeenRetryIntervalSec < 0 || (r.SeenRetryIntervalSec > 0 && r.SeenRetryIntervalSec < MinRetryIntervalSec) {
+		return &ValidationError{Field: "seen_retry_interval_sec", Message: fmt.Sprintf("Seen retry interval must be 0 or at least %d seconds", MinRetryIntervalSec)}
+	}
+	if err := r.DeliveryOptions.Validate(); err != nil {
+		return err
+	}
+	if err := r.EscalationPolicy.Validate(); err != nil {
+		return err
+	}
+	if r.EscalationPolicy.HasSteps() && (r.Type != ReminderTypeOneTime || r.RepeatStrategy != RepeatStrategyRetryUntilComplete) {
+		return &ValidationError{Field: "escalation_policy", Message: "Escalation requires a one_time reminder with repeat_strategy retry_until_complete"}
+	}
+	switch r.AudienceType {
+	case "", AudienceUser:
+	case AudienceUsers:
+		if len(r.AudienceUserIDs) == 0 {
+			return &ValidationError{Field: "audience_user_ids", Message: "At least one user is required for audience users"}
+		}
+	case AudienceGroup:
+		if r.GroupID == "" {
+			return &ValidationError{Field: "group_id", Message: "Group is required for audience group"}
+		}
+	default:
+		return &ValidationError{Field: "audience_type", Message: "Audience type must be user, users or group"}
+	}
 	return nil
 }
 
+// Validate checks delivery options against FCM limits. Nil options are valid.
+func (o *DeliveryOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Priority != "" && o.Priority != PriorityHigh && o.Priority != PriorityNormal {
+		return &ValidationError{Field: "delivery_options.priority", Message: "Priority must be high or normal"}
+	}
+	if o.TTLSeconds < 0 || o.TTLSeconds > MaxTTLSeconds {
+		return &ValidationError{Field: "delivery_options.ttl_seconds", Message: fmt.Sprintf("TTL must be between 0 and %d seconds", MaxTTLSeconds)}
+	}
+	if len(o.CollapseKey) > MaxCollapseKeyLen {
+		return &ValidationError{Field: "delivery_options.collapse_key", Message: fmt.Sprintf("Collapse key cannot exceed %d bytes", MaxCollapseKeyLen)}
+	}
+	if o.Badge != nil && *o.Badge < 0 {
+		return &ValidationError{Field: "delivery_options.badge", Message: "Badge cannot be negative"}
+	}
+	return nil
+}
+
+// IsEscalating reports whether the reminder is running its escalation policy.
+func (r *Reminder) IsEscalating() bool {
+	return r.EscalationStep > 0
+}
+
+// IsBroadcast reports whether the reminder goes to more than its owner.
+func (r *Reminder) IsBroadcast() bool {
+	return r.AudienceType == AudienceUsers || r.AudienceType == AudienceGroup
+}
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field   string
@@ -123,6 +277,14 @@ func (r *Reminder) IsRetryable() bool {
 		r.RetryCount < r.MaxRetries
 }
 
+// TriggerAt returns when the reminder is next due: next_trigger_at, or snooze_until if later.
+func (r *Reminder) TriggerAt() time.Time {
+	if r.SnoozeUntil != nil && r.SnoozeUntil.After(r.NextTriggerAt) {
+		return *r.SnoozeUntil
+	}
+	return r.NextTriggerAt
+}
+
 // ShouldSend checks if reminder should be sent now
 func (r *Reminder) ShouldSend(now time.Time) bool {
 	if r.Status != ReminderStatusActive {
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// SavedQuery is a named, admin-vetted SQL statement run through /api/queries/{name}/run.
+// Its {:name} placeholders are bound from parameters checked against Params.
+type SavedQuery struct {
+	ID          string                     `json:"id" db:"id"`
+	Name        string                     `json:"name" db:"name"`
+	Description string                     `json:"description" db:"description"`
+	SQL         string                     `json:"sql" db:"sql"`
+	Params      map[string]SavedQueryParam `json:"params" db:"params"`
+	Role        string                     `json:"role" db:"role"` // ai được chạy: superuser, write, read
+	Created     time.Time                  `json:"created" db:"created"`
+	Updated     time.Time                  `json:"updated" db:"updated"`
+}
+
+// SavedQueryParam describes one parameter of a saved query
+type SavedQueryParam struct {
+	Type        string `json:"type"` // string, integer, number, boolean, datetime
+	Required    bool   `json:"required,omitempty"`
+	Default     any    `json:"default,omitempty"` // dùng khi không truyền tham số
+	Description string `json:"description,omitempty"`
+}
+
+// Roles allowed to run a saved query
+const (
+	SavedQueryRoleSuperuser = "superuser" // chỉ superuser
+	SavedQueryRoleWrite     = "write"     // superuser hoặc API key có quyền ghi
+	SavedQueryRoleRead      = "read"      // mọi API key
+)
+
+// Saved query parameter types
+const (
+	ParamTypeString   = "string"
+	ParamTypeInteger  = "integer"
+	ParamTypeNumber   = "number"
+	ParamTypeBoolean  = "boolean"
+	ParamTypeDatetime = "datetime" // RFC 3339
+)
+
+// Validate checks the saved query definition
+func (q *SavedQuery) Validate() error {
+	if q.Name == "" {
+		return &ValidationError{Field: "name", Message: "Name is required"}
+	}
+	if q.SQL == "" {
+		return &ValidationError{Field: "sql", Message: "SQL is required"}
+	}
+	switch q.Role {
+	case SavedQueryRoleSuperuser, SavedQueryRoleWrite, SavedQueryRoleRead:
+	default:
+		return &ValidationError{Field: "role", Message: "Role must be superuser, write or read"}
+	}
+	for _, name := range q.paramNames() {
+		param := q.Params[name]
+		switch param.Type {
+		case ParamTypeString, ParamTypeInteger, ParamTypeNumber, ParamTypeBoolean, ParamTypeDatetime:
+		default:
+			return &ValidationError{Field: "params." + name + ".type", Message: "Type must be string, integer, number, boolean or datetime"}
+		}
+		if param.Default != nil {
+			if _, err := param.convert(param.Default); err != nil {
+				return &ValidationError{Field: "params." + name + ".default", Message: err.Error()}
+			}
+		}
+	}
+	return nil
+}
+
+// BindParams checks values against the parameter schema and returns the params to bind:
+// unknown names are rejected, missing ones take their default (or NULL unless required).
+// Values are as decoded from JSON with UseNumber; integers become int64 and numbers float64.
+func (q *SavedQuery) BindParams(values map[string]any) (map[string]any, error) {
+	for name := range values {
+		if _, ok := q.Params[name]; !ok {
+			return nil, &ValidationError{Field: "params." + name, Message: "Unknown parameter"}
+		}
+	}
+
+	bound := make(map[string]any, len(q.Params))
+	for _, name := range q.paramNames() {
+		param := q.Params[name]
+		value, ok := values[name]
+		if !ok || value == nil {
+			if param.Required {
+				return nil, &ValidationError{Field: "params." + name, Message: "Parameter is required"}
+			}
+			value = param.Default
+		}
+		converted, err := param.convert(value)
+		if err != nil {
+			return nil, &ValidationError{Field: "params." + name, Message: err.Error()}
+		}
+		bound[name] = converted
+	}
+	return bound, nil
+}
+
+// paramNames returns the parameter names in order, for stable error messages
+func (q *SavedQuery) paramNames() []string {
+	names := make([]string, 0, len(q.Params))
+	for name := range q.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// convert checks value against the parameter type. nil stays nil.
+func (p SavedQueryParam) convert(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch p.Type {
+	case ParamTypeString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case ParamTypeBoolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case ParamTypeInteger:
+		// Default đọc từ cột JSON là float64, tham số từ request là json.Number
+		switch v := value.(type) {
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return int64(v), nil
+			}
+		}
+	case ParamTypeNumber:
+		switch v := value.(type) {
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f, nil
+			}
+		case float64:
+			return v, nil
+		}
+	case ParamTypeDatetime:
+		if s, ok := value.(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, errors.New("Must be an RFC 3339 datetime")
+			}
+			return t.UTC().Format(time.RFC3339Nano), nil
+		}
+	}
+	return nil, fmt.Errorf("Must be of type %s", p.Type)
+}
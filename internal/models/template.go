@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationTemplate is a localized title/body pair with {placeholders}.
+// RetryTitle/RetryBody (optional) are used for retry_until_complete re-sends.
+type NotificationTemplate struct {
+	ID         string    `json:"id" db:"id"`
+	Key        string    `json:"key" db:"key"`
+	Locale     string    `json:"locale" db:"locale"` // vi, en
+	Title      string    `json:"title" db:"title"`
+	Body       string    `json:"body" db:"body"`
+	RetryTitle string    `json:"retry_title" db:"retry_title"`
+	RetryBody  string    `json:"retry_body" db:"retry_body"`
+	Created    time.Time `json:"created" db:"created"`
+	Updated    time.Time `json:"updated" db:"updated"`
+}
+
+// Built-in template keys
+const (
+	TemplateDefault     = "default"
+	TemplatePreReminder = "pre_reminder"
+	TemplateLunar       = "lunar"
+	TemplateDigest      = "digest"     // gộp nhiều reminder của một user trong một thông báo
+	TemplateEscalation  = "escalation" // báo người/kênh dự phòng khi hết lượt nhắc mà chưa hoàn thành
+)
@@ -7,6 +7,12 @@ import (
 	"remiaq/internal/models"
 )
 
+// Transactor runs repository calls in one database transaction: calls made with the ctx
+// passed to fn join it. It commits when fn returns nil and rolls back otherwise.
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 // ReminderRepository defines operations for reminder data access
 type ReminderRepository interface {
 	// CRUD operations
@@ -17,15 +23,34 @@ type ReminderRepository interface {
 
 	// Query operations
 	GetDueReminders(ctx context.Context, beforeTime time.Time) ([]*models.Reminder, error)
+	GetDueRemindersByUser(ctx context.Context, userID string, beforeTime time.Time) ([]*models.Reminder, error)
 	GetByUserID(ctx context.Context, userID string) ([]*models.Reminder, error)
+	// GetUpcomingTriggers returns up to limit active reminders due before beforeTime, earliest first
+	GetUpcomingTriggers(ctx context.Context, beforeTime time.Time, limit int) ([]models.ScheduledTrigger, error)
 
-	// Specific updates
+	// Leasing (multiple worker instances). ClaimDueReminders atomically claims up to limit
+	// due reminders that are unclaimed or whose lease expired, for owner until leaseUntil,
+	// and returns them. ClaimDueRemindersByUser does the same for one user's reminders,
+	// also returning those owner already holds. ReleaseClaims drops every claim held by owner.
+	ClaimDueReminders(ctx context.Context, owner string, beforeTime, leaseUntil time.Time, limit int) ([]*models.Reminder, error)
+	ClaimDueRemindersByUser(ctx context.Context, owner, userID string, beforeTime, leaseUntil time.Time) ([]*models.Reminder, error)
+	ReleaseClaims(ctx context.Context, owner string) error
+
+	// Specific updates. With a lease owner in ctx (WithLeaseOwner) the worker transitions
+	// (next trigger, retry count, completed, last sent, escalation) skip rows claimed by
+	// another instance.
 	UpdateNextTrigger(ctx context.Context, id string, nextTrigger time.Time) error
 	UpdateStatus(ctx context.Context, id string, status string) error
 	IncrementRetryCount(ctx context.Context, id string) error
 	UpdateSnooze(ctx context.Context, id string, snoozeUntil *time.Time) error
 	MarkCompleted(ctx context.Context, id string, completedAt time.Time) error
 	UpdateLastSent(ctx context.Context, id string, sentAt time.Time) error
+	UpdateEscalation(ctx context.Context, id string, step int, nextTrigger time.Time) error
+
+	// ApplyTransition applies a worker transition in one transaction. It returns false without
+	// error when the reminder's next_trigger_at no longer equals t.PrevTriggerAt (already
+	// applied, or changed meanwhile) or the row is claimed by another instance.
+	ApplyTransition(ctx context.Context, t *models.ReminderTransition) (bool, error)
 }
 
 // UserRepository defines operations for user data access
@@ -33,6 +58,7 @@ type UserRepository interface {
 	// CRUD operations
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*models.User, error) // IDs without a user are skipped
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
 
@@ -58,13 +84,113 @@ type SystemStatusRepository interface {
 	// Error tracking
 	UpdateError(ctx context.Context, errorMsg string) error
 	ClearError(ctx context.Context) error
+
+	// Notifier circuit breaker
+	UpdateCircuitState(ctx context.Context, state, reason string, changedAt time.Time) error
+	SetCircuitOverride(ctx context.Context, override string) error
+}
+
+// DeliveryRepository defines operations for the delivery log
+type DeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.Delivery) error
+
+	// Paginated history, newest first. page starts at 1. Returns items and total count.
+	ListByReminder(ctx context.Context, reminderID string, page, perPage int) ([]*models.Delivery, int, error)
+	ListByUser(ctx context.Context, userID string, page, perPage int) ([]*models.Delivery, int, error)
+
+	// Acknowledge sets received_at (and opened_at for "opened") on the sent deliveries of a
+	// dispatch, optionally only those of userID. Existing timestamps are kept. Returns the
+	// acknowledged deliveries.
+	Acknowledge(ctx context.Context, dispatchID, userID, event string, at time.Time) ([]*models.Delivery, error)
+}
+
+// GroupRepository defines operations for broadcast groups and their members
+type GroupRepository interface {
+	Create(ctx context.Context, group *models.Group) error
+	GetByID(ctx context.Context, id string) (*models.Group, error)
+	Delete(ctx context.Context, id string) error
+
+	// Membership
+	AddMember(ctx context.Context, member *models.GroupMember) error
+	RemoveMember(ctx context.Context, groupID, userID string) error
+	IsMember(ctx context.Context, groupID, userID string) (bool, error)
+	ListMembers(ctx context.Context, groupID string) ([]*models.User, error)
+}
+
+// TemplateRepository defines read access to admin-managed notification templates
+type TemplateRepository interface {
+	// GetByKey returns the template for key and locale, or sql.ErrNoRows if none exists
+	GetByKey(ctx context.Context, key, locale string) (*models.NotificationTemplate, error)
 }
 
-// QueryRepository defines operations for raw SQL queries (existing functionality)
+// QueryAuditRepository defines operations for the raw query audit log
+type QueryAuditRepository interface {
+	Create(ctx context.Context, entry *models.QueryAudit) error
+
+	// List returns a page of entries, newest first, optionally only those of caller.
+	// page starts at 1. Returns items and total count.
+	List(ctx context.Context, caller string, page, perPage int) ([]*models.QueryAudit, int, error)
+}
+
+// SavedQueryRepository defines read access to admin-managed saved queries
+type SavedQueryRepository interface {
+	// GetByName returns the saved query named name, or sql.ErrNoRows if none exists
+	GetByName(ctx context.Context, name string) (*models.SavedQuery, error)
+	List(ctx context.Context) ([]*models.SavedQuery, error) // ordered by name
+}
+
+// QueryRepository defines operations for raw SQL queries (existing functionality).
+// params bind the {:name} placeholders of query; values are as decoded from JSON
+// (json.Number, bool, string, nil).
 type QueryRepository interface {
-	// Raw query operations
-	ExecuteSelect(ctx context.Context, query string) ([]map[string]interface{}, error)
-	ExecuteInsert(ctx context.Context, query string) (rowsAffected int64, lastInsertId int64, err error)
-	ExecuteUpdate(ctx context.Context, query string) (rowsAffected int64, err error)
-	ExecuteDelete(ctx context.Context, query string) (rowsAffected int64, err error)
+	// Raw mutations
+	ExecuteInsert(ctx context.Context, query string, params map[string]any) (rowsAffected int64, lastInsertId int64, err error)
+	ExecuteUpdate(ctx context.Context, query string, params map[string]any) (rowsAffected int64, err error)
+	ExecuteDelete(ctx context.Context, query string, params map[string]any) (rowsAffected int64, err error)
+
+	// ExecuteSelectPage executes a SELECT wrapped in a subquery to return one page of it and,
+	// unless page.SkipTotal, the number of rows of the whole query. SELECT values are typed by
+	// the declared column types: integers, numbers, booleans, NULL, RFC 3339 datetimes and
+	// raw JSON instead of strings.
+	ExecuteSelectPage(ctx context.Context, query string, params map[string]any, page SelectPage) (*SelectResult, error)
+
+	// ExecuteDryRun executes an INSERT/UPDATE/DELETE in a transaction that is rolled back,
+	// returning how many rows it would affect.
+	ExecuteDryRun(ctx context.Context, query string, params map[string]any) (rowsAffected int64, err error)
+
+	// StreamSelect executes a SELECT and writes its typed rows to w as they are read from the
+	// DB cursor, for exports too large to hold in memory. It runs until ctx is done, without
+	// the default per-query timeout.
+	StreamSelect(ctx context.Context, query string, params map[string]any, w RowWriter) error
+}
+
+// RowWriter receives the rows of StreamSelect one at a time
+type RowWriter interface {
+	Columns(names []string) error // once, before the first row; an error aborts the query
+	Row(values []any) error       // see ExecuteSelectPage for the value types; reused between rows
+}
+
+// SortField is a result column to sort a raw SELECT page by
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// SelectPage selects a page of a raw SELECT. With After set the page is the keyset page
+// following the row whose Sort values are After: Offset is ignored and the Sort columns
+// should be non-null and unique together.
+type SelectPage struct {
+	Limit     int
+	Offset    int
+	Sort      []SortField
+	After     []any
+	SkipTotal bool
+	Schema    bool // describe the columns even when no row matches
+}
+
+// SelectResult is a page of a raw SELECT
+type SelectResult struct {
+	Items   []map[string]interface{}
+	Columns []models.QueryColumn // nil when no row matched and the schema wasn't asked for
+	Total   int                  // rows of the whole query, -1 if not counted
 }
@@ -0,0 +1,19 @@
+package repository
+
+import "context"
+
+type leaseOwnerKey struct{}
+
+// WithLeaseOwner marks ctx as belonging to the worker instance owner. Reminder state
+// transitions made with this context skip rows claimed by another instance, so an
+// instance whose lease expired and was reclaimed can't overwrite the new holder's work.
+func WithLeaseOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, leaseOwnerKey{}, owner)
+}
+
+// LeaseOwner returns the worker instance set by WithLeaseOwner. API requests carry no
+// owner, so user actions (complete, snooze) always apply.
+func LeaseOwner(ctx context.Context) (string, bool) {
+	owner, ok := ctx.Value(leaseOwnerKey{}).(string)
+	return owner, ok && owner != ""
+}
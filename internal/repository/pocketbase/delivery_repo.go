@@ -0,0 +1,136 @@
+package pocketbase
+
+import (
+	"context"
+	"time"
+
+	"remiaq/internal/db"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// DeliveryRepo implements repository.DeliveryRepository
+type DeliveryRepo struct {
+	helper db.DBHelperInterface
+}
+
+// Ensure implementation
+var _ repository.DeliveryRepository = (*DeliveryRepo)(nil)
+
+// NewDeliveryRepo creates a new delivery repository
+func NewDeliveryRepo(app *pocketbase.PocketBase) repository.DeliveryRepository {
+	return &DeliveryRepo{helper: db.NewDBHelper(app)}
+}
+
+// Create inserts a delivery record
+func (r *DeliveryRepo) Create(ctx context.Context, delivery *models.Delivery) error {
+	if delivery.Created.IsZero() {
+		delivery.Created = time.Now().UTC()
+	}
+	return r.helper.Exec(ctx,
+		`INSERT INTO deliveries (
+			id, reminder_id, user_id, channel, device, scheduled_for, sent_at,
+			provider_message_id, outcome, error_class, error_message, attempt, dispatch_id, created
+		) VALUES (
+			{:id}, {:reminder_id}, {:user_id}, {:channel}, {:device}, {:scheduled_for}, {:sent_at},
+			{:provider_message_id}, {:outcome}, {:error_class}, {:error_message}, {:attempt}, {:dispatch_id}, {:created}
+		)`,
+		dbx.Params{
+			"id":                  delivery.ID,
+			"reminder_id":         delivery.ReminderID,
+			"user_id":             delivery.UserID,
+			"channel":             delivery.Channel,
+			"device":              delivery.Device,
+			"scheduled_for":       delivery.ScheduledFor,
+			"sent_at":             delivery.SentAt,
+			"provider_message_id": delivery.ProviderMessageID,
+			"outcome":             delivery.Outcome,
+			"error_class":         delivery.ErrorClass,
+			"error_message":       delivery.ErrorMessage,
+			"attempt":             delivery.Attempt,
+			"dispatch_id":         delivery.DispatchID,
+			"created":             delivery.Created,
+		},
+	)
+}
+
+// ListByReminder returns a page of deliveries for a reminder, newest first
+func (r *DeliveryRepo) ListByReminder(ctx context.Context, reminderID string, page, perPage int) ([]*models.Delivery, int, error) {
+	return r.list(ctx, "reminder_id = {:id}", reminderID, page, perPage)
+}
+
+// ListByUser returns a page of deliveries for a user, newest first
+func (r *DeliveryRepo) ListByUser(ctx context.Context, userID string, page, perPage int) ([]*models.Delivery, int, error) {
+	return r.list(ctx, "user_id = {:id}", userID, page, perPage)
+}
+
+// Acknowledge records a received/opened acknowledgement for the sent deliveries of a dispatch
+func (r *DeliveryRepo) Acknowledge(ctx context.Context, dispatchID, userID, event string, at time.Time) ([]*models.Delivery, error) {
+	where := "dispatch_id = {:dispatch_id} AND outcome = 'sent'"
+	params := dbx.Params{"dispatch_id": dispatchID, "at": at}
+	if userID != "" {
+		where += " AND user_id = {:user_id}"
+		params["user_id"] = userID
+	}
+
+	// Mở thông báo thì chắc chắn đã nhận; giữ mốc thời gian đầu tiên nếu client gửi lại
+	set := "received_at = COALESCE(received_at, {:at})"
+	if event == models.AckEventOpened {
+		set += ", opened_at = COALESCE(opened_at, {:at})"
+	}
+	if err := r.helper.Exec(ctx, "UPDATE deliveries SET "+set+" WHERE "+where, params); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := db.GetAll[models.Delivery](ctx, r.helper,
+		"SELECT * FROM deliveries WHERE "+where+" ORDER BY created ASC, id ASC", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert []models.Delivery to []*models.Delivery
+	result := make([]*models.Delivery, len(deliveries))
+	for i := range deliveries {
+		result[i] = &deliveries[i]
+	}
+	return result, nil
+}
+
+// list runs the count and page queries for a single-column filter
+func (r *DeliveryRepo) list(ctx context.Context, where, id string, page, perPage int) ([]*models.Delivery, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 1
+	}
+
+	total, err := r.helper.Count(ctx, "SELECT COUNT(*) AS count FROM deliveries WHERE "+where, dbx.Params{"id": id})
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.Delivery{}, 0, nil
+	}
+
+	deliveries, err := db.GetAll[models.Delivery](ctx, r.helper,
+		"SELECT * FROM deliveries WHERE "+where+" ORDER BY created DESC, id DESC LIMIT {:limit} OFFSET {:offset}",
+		dbx.Params{
+			"id":     id,
+			"limit":  perPage,
+			"offset": (page - 1) * perPage,
+		})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Convert []models.Delivery to []*models.Delivery
+	result := make([]*models.Delivery, len(deliveries))
+	for i := range deliveries {
+		result[i] = &deliveries[i]
+	}
+	return result, total, nil
+}
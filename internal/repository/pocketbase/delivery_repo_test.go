@@ -0,0 +1,228 @@
+package pocketbase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"remiaq/internal/models"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryRepo_Create(t *testing.T) {
+	t.Run("should insert all fields", func(t *testing.T) {
+		sentAt := time.Now().UTC()
+		delivery := &models.Delivery{
+			ID:                "d1",
+			ReminderID:        "rem1",
+			UserID:            "user1",
+			Channel:           models.DeliveryChannelFCM,
+			Device:            "...abcdefgh",
+			ScheduledFor:      sentAt.Add(-time.Minute),
+			SentAt:            &sentAt,
+			ProviderMessageID: "projects/p/messages/1",
+			Outcome:           models.DeliveryOutcomeSent,
+			Attempt:           1,
+			DispatchID:        "dispatch-1",
+		}
+
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					assert.Contains(t, query, "INSERT INTO deliveries")
+					assert.Equal(t, "d1", params["id"])
+					assert.Equal(t, "rem1", params["reminder_id"])
+					assert.Equal(t, "user1", params["user_id"])
+					assert.Equal(t, "projects/p/messages/1", params["provider_message_id"])
+					assert.Equal(t, "sent", params["outcome"])
+					assert.Equal(t, 1, params["attempt"])
+					assert.Equal(t, "dispatch-1", params["dispatch_id"])
+					assert.False(t, params["created"].(time.Time).IsZero())
+					return nil
+				},
+			},
+		}
+
+		require.NoError(t, repo.Create(context.Background(), delivery))
+	})
+
+	t.Run("should return exec error", func(t *testing.T) {
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					return errors.New("database error")
+				},
+			},
+		}
+
+		require.Error(t, repo.Create(context.Background(), &models.Delivery{ID: "d1"}))
+	})
+}
+
+func TestDeliveryRepo_ListByReminder(t *testing.T) {
+	t.Run("should count and fetch requested page", func(t *testing.T) {
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				CountFn: func(query string, params dbx.Params) (int, error) {
+					assert.Contains(t, query, "FROM deliveries WHERE reminder_id = {:id}")
+					assert.Equal(t, "rem1", params["id"])
+					return 3, nil
+				},
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					assert.Contains(t, query, "ORDER BY created DESC")
+					assert.Equal(t, 2, params["limit"])
+					assert.Equal(t, 2, params["offset"])
+					return []dbx.NullStringMap{
+						{
+							"id":          {String: "d1", Valid: true},
+							"reminder_id": {String: "rem1", Valid: true},
+							"outcome":     {String: "failed", Valid: true},
+							"error_class": {String: "unavailable", Valid: true},
+							"attempt":     {String: "1", Valid: true},
+							"sent_at":     {String: "", Valid: true},
+						},
+					}, nil
+				},
+			},
+		}
+
+		deliveries, total, err := repo.ListByReminder(context.Background(), "rem1", 2, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 3, total)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, "unavailable", deliveries[0].ErrorClass)
+		assert.Equal(t, 1, deliveries[0].Attempt)
+		assert.Nil(t, deliveries[0].SentAt)
+	})
+
+	t.Run("should skip page query when empty", func(t *testing.T) {
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				CountFn: func(query string, params dbx.Params) (int, error) {
+					return 0, nil
+				},
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					t.Fatal("page query should not run")
+					return nil, nil
+				},
+			},
+		}
+
+		deliveries, total, err := repo.ListByReminder(context.Background(), "rem1", 1, 30)
+		require.NoError(t, err)
+		assert.Equal(t, 0, total)
+		assert.Empty(t, deliveries)
+	})
+
+	t.Run("should return count error", func(t *testing.T) {
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				CountFn: func(query string, params dbx.Params) (int, error) {
+					return 0, errors.New("database error")
+				},
+			},
+		}
+
+		_, _, err := repo.ListByReminder(context.Background(), "rem1", 1, 30)
+		require.Error(t, err)
+	})
+}
+
+func TestDeliveryRepo_ListByUser(t *testing.T) {
+	repo := &DeliveryRepo{
+		helper: &MockDBHelper{
+			CountFn: func(query string, params dbx.Params) (int, error) {
+				assert.Contains(t, query, "user_id = {:id}")
+				return 1, nil
+			},
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "user_id = {:id}")
+				assert.Equal(t, 0, params["offset"])
+				return []dbx.NullStringMap{{"id": {String: "d1", Valid: true}}}, nil
+			},
+		},
+	}
+
+	deliveries, total, err := repo.ListByUser(context.Background(), "user1", 1, 30)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, deliveries, 1)
+}
+
+func TestDeliveryRepo_Acknowledge(t *testing.T) {
+	at := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	t.Run("should set received_at only for received", func(t *testing.T) {
+		var updateQuery string
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					updateQuery = query
+					assert.Equal(t, "dispatch-1", params["dispatch_id"])
+					assert.Equal(t, at, params["at"])
+					return nil
+				},
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					assert.Contains(t, query, "dispatch_id = {:dispatch_id} AND outcome = 'sent'")
+					return []dbx.NullStringMap{{
+						"id":          sql.NullString{String: "d1", Valid: true},
+						"reminder_id": sql.NullString{String: "rem1", Valid: true},
+						"dispatch_id": sql.NullString{String: "dispatch-1", Valid: true},
+						"received_at": sql.NullString{String: "2025-10-18 09:00:00.000Z", Valid: true},
+					}}, nil
+				},
+			},
+		}
+
+		deliveries, err := repo.Acknowledge(context.Background(), "dispatch-1", "", models.AckEventReceived, at)
+
+		require.NoError(t, err)
+		assert.Contains(t, updateQuery, "received_at = COALESCE(received_at, {:at})")
+		assert.NotContains(t, updateQuery, "opened_at")
+		assert.NotContains(t, updateQuery, "user_id")
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, "rem1", deliveries[0].ReminderID)
+		require.NotNil(t, deliveries[0].ReceivedAt)
+		assert.Nil(t, deliveries[0].OpenedAt)
+	})
+
+	t.Run("should set opened_at and filter by user", func(t *testing.T) {
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					assert.Contains(t, query, "opened_at = COALESCE(opened_at, {:at})")
+					assert.Contains(t, query, "user_id = {:user_id}")
+					assert.Equal(t, "user2", params["user_id"])
+					return nil
+				},
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					assert.Contains(t, query, "user_id = {:user_id}")
+					return []dbx.NullStringMap{}, nil
+				},
+			},
+		}
+
+		deliveries, err := repo.Acknowledge(context.Background(), "dispatch-1", "user2", models.AckEventOpened, at)
+
+		require.NoError(t, err)
+		assert.Empty(t, deliveries)
+	})
+
+	t.Run("should return update error", func(t *testing.T) {
+		repo := &DeliveryRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					return errors.New("database error")
+				},
+			},
+		}
+
+		_, err := repo.Acknowledge(context.Background(), "dispatch-1", "", models.AckEventOpened, at)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,112 @@
+package pocketbase
+
+import (
+	"context"
+	"time"
+
+	"remiaq/internal/db"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// GroupRepo implements repository.GroupRepository
+type GroupRepo struct {
+	helper db.DBHelperInterface
+}
+
+// Ensure implementation
+var _ repository.GroupRepository = (*GroupRepo)(nil)
+
+// NewGroupRepo creates a new group repository
+func NewGroupRepo(app *pocketbase.PocketBase) repository.GroupRepository {
+	return &GroupRepo{helper: db.NewDBHelper(app)}
+}
+
+// Create inserts a group
+func (r *GroupRepo) Create(ctx context.Context, group *models.Group) error {
+	now := time.Now().UTC()
+	if group.Created.IsZero() {
+		group.Created = now
+	}
+	group.Updated = now
+	return r.helper.Exec(ctx,
+		`INSERT INTO mgroups (id, name, topic, owner_id, created, updated)
+		 VALUES ({:id}, {:name}, {:topic}, {:owner_id}, {:created}, {:updated})`,
+		dbx.Params{
+			"id":       group.ID,
+			"name":     group.Name,
+			"topic":    group.Topic,
+			"owner_id": group.OwnerID,
+			"created":  group.Created,
+			"updated":  group.Updated,
+		},
+	)
+}
+
+// GetByID retrieves a group by ID
+func (r *GroupRepo) GetByID(ctx context.Context, id string) (*models.Group, error) {
+	return db.GetOne[models.Group](ctx, r.helper,
+		"SELECT * FROM mgroups WHERE id = {:id} LIMIT 1",
+		dbx.Params{"id": id})
+}
+
+// Delete removes a group and its memberships
+func (r *GroupRepo) Delete(ctx context.Context, id string) error {
+	if err := r.helper.Exec(ctx, "DELETE FROM mgroup_members WHERE group_id = {:id}", dbx.Params{"id": id}); err != nil {
+		return err
+	}
+	return r.helper.Exec(ctx, "DELETE FROM mgroups WHERE id = {:id}", dbx.Params{"id": id})
+}
+
+// AddMember inserts a membership
+func (r *GroupRepo) AddMember(ctx context.Context, member *models.GroupMember) error {
+	if member.Created.IsZero() {
+		member.Created = time.Now().UTC()
+	}
+	return r.helper.Exec(ctx,
+		`INSERT INTO mgroup_members (id, group_id, user_id, created)
+		 VALUES ({:id}, {:group_id}, {:user_id}, {:created})`,
+		dbx.Params{
+			"id":       member.ID,
+			"group_id": member.GroupID,
+			"user_id":  member.UserID,
+			"created":  member.Created,
+		},
+	)
+}
+
+// RemoveMember deletes a membership
+func (r *GroupRepo) RemoveMember(ctx context.Context, groupID, userID string) error {
+	return r.helper.Exec(ctx,
+		"DELETE FROM mgroup_members WHERE group_id = {:group_id} AND user_id = {:user_id}",
+		dbx.Params{"group_id": groupID, "user_id": userID})
+}
+
+// IsMember checks whether user belongs to group
+func (r *GroupRepo) IsMember(ctx context.Context, groupID, userID string) (bool, error) {
+	return r.helper.Exists(ctx,
+		"SELECT 1 FROM mgroup_members WHERE group_id = {:group_id} AND user_id = {:user_id} LIMIT 1",
+		dbx.Params{"group_id": groupID, "user_id": userID})
+}
+
+// ListMembers returns the users of a group in join order
+func (r *GroupRepo) ListMembers(ctx context.Context, groupID string) ([]*models.User, error) {
+	users, err := db.GetAll[models.User](ctx, r.helper,
+		`SELECT u.* FROM musers u
+		 JOIN mgroup_members m ON m.user_id = u.id
+		 WHERE m.group_id = {:group_id}
+		 ORDER BY m.created ASC`,
+		dbx.Params{"group_id": groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.User, len(users))
+	for i := range users {
+		result[i] = &users[i]
+	}
+	return result, nil
+}
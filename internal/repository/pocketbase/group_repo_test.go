@@ -0,0 +1,86 @@
+package pocketbase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+)
+
+func TestGroupRepo_Create(t *testing.T) {
+	t.Run("should insert group with topic and timestamps", func(t *testing.T) {
+		repo := &GroupRepo{helper: &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.Contains(t, query, "INSERT INTO mgroups")
+				assert.Equal(t, "g1", params["id"])
+				assert.Equal(t, "remiaq-group-g1", params["topic"])
+				assert.NotZero(t, params["created"])
+				return nil
+			},
+		}}
+
+		err := repo.Create(context.Background(), &models.Group{ID: "g1", Name: "Team", Topic: "remiaq-group-g1"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestGroupRepo_Delete(t *testing.T) {
+	t.Run("should delete memberships before the group", func(t *testing.T) {
+		var queries []string
+		repo := &GroupRepo{helper: &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				queries = append(queries, query)
+				assert.Equal(t, "g1", params["id"])
+				return nil
+			},
+		}}
+
+		require.NoError(t, repo.Delete(context.Background(), "g1"))
+		require.Len(t, queries, 2)
+		assert.Contains(t, queries[0], "DELETE FROM mgroup_members")
+		assert.Contains(t, queries[1], "DELETE FROM mgroups")
+	})
+}
+
+func TestGroupRepo_Membership(t *testing.T) {
+	t.Run("should check membership", func(t *testing.T) {
+		repo := &GroupRepo{helper: &MockDBHelper{
+			ExistsFn: func(query string, params dbx.Params) (bool, error) {
+				assert.Contains(t, query, "FROM mgroup_members")
+				assert.Equal(t, "g1", params["group_id"])
+				assert.Equal(t, "u1", params["user_id"])
+				return true, nil
+			},
+		}}
+
+		ok, err := repo.IsMember(context.Background(), "g1", "u1")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("should list member users", func(t *testing.T) {
+		repo := &GroupRepo{helper: &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "JOIN mgroup_members")
+				assert.Equal(t, "g1", params["group_id"])
+				return []dbx.NullStringMap{
+					{
+						"id":            {String: "u1", Valid: true},
+						"fcm_token":     {String: "tok-1", Valid: true},
+						"is_fcm_active": {String: "true", Valid: true},
+					},
+				}, nil
+			},
+		}}
+
+		users, err := repo.ListMembers(context.Background(), "g1")
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "tok-1", users[0].FCMToken)
+		assert.True(t, users[0].IsFCMActive)
+	})
+}
@@ -1,49 +1,87 @@
 package pocketbase
 
 import (
+	"context"
+	"database/sql"
+
+	"remiaq/internal/db"
+
 	"github.com/pocketbase/dbx"
 )
 
-// MockDBHelper is a simple mock for db.DBHelperInterface
+// MockDBHelper is a simple mock for db.DBHelperInterface (ctx is ignored)
 type MockDBHelper struct {
 	GetOneRowFn  func(query string, params dbx.Params) (dbx.NullStringMap, error)
 	GetAllRowsFn func(query string, params dbx.Params) ([]dbx.NullStringMap, error)
 	ExecFn       func(query string, params dbx.Params) error
+	ExecResultFn func(query string, params dbx.Params) (sql.Result, error)
 	CountFn      func(query string, params dbx.Params) (int, error)
 	ExistsFn     func(query string, params dbx.Params) (bool, error)
+	QueryRowsFn  func(query string, params dbx.Params, handler db.RowHandler) error
+
+	// Transactions run fn against the mock itself; count them in Transactions
+	Transactions int
 }
 
-func (m *MockDBHelper) GetOneRow(query string, params dbx.Params) (dbx.NullStringMap, error) {
+func (m *MockDBHelper) GetOneRow(ctx context.Context, query string, params dbx.Params) (dbx.NullStringMap, error) {
 	if m.GetOneRowFn != nil {
 		return m.GetOneRowFn(query, params)
 	}
 	return nil, nil
 }
 
-func (m *MockDBHelper) GetAllRows(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+func (m *MockDBHelper) GetAllRows(ctx context.Context, query string, params dbx.Params) ([]dbx.NullStringMap, error) {
 	if m.GetAllRowsFn != nil {
 		return m.GetAllRowsFn(query, params)
 	}
 	return nil, nil
 }
 
-func (m *MockDBHelper) Exec(query string, params dbx.Params) error {
+func (m *MockDBHelper) Exec(ctx context.Context, query string, params dbx.Params) error {
 	if m.ExecFn != nil {
 		return m.ExecFn(query, params)
 	}
 	return nil
 }
 
-func (m *MockDBHelper) Count(query string, params dbx.Params) (int, error) {
+// ExecResult uses ExecResultFn, falling back to ExecFn with an empty result
+func (m *MockDBHelper) ExecResult(ctx context.Context, query string, params dbx.Params) (sql.Result, error) {
+	if m.ExecResultFn != nil {
+		return m.ExecResultFn(query, params)
+	}
+	return mockResult{}, m.Exec(ctx, query, params)
+}
+
+func (m *MockDBHelper) Count(ctx context.Context, query string, params dbx.Params) (int, error) {
 	if m.CountFn != nil {
 		return m.CountFn(query, params)
 	}
 	return 0, nil
 }
 
-func (m *MockDBHelper) Exists(query string, params dbx.Params) (bool, error) {
+func (m *MockDBHelper) Exists(ctx context.Context, query string, params dbx.Params) (bool, error) {
 	if m.ExistsFn != nil {
 		return m.ExistsFn(query, params)
 	}
 	return false, nil
-}
\ No newline at end of file
+}
+
+func (m *MockDBHelper) QueryRows(ctx context.Context, query string, params dbx.Params, handler db.RowHandler) error {
+	if m.QueryRowsFn != nil {
+		return m.QueryRowsFn(query, params, handler)
+	}
+	return handler.Columns(nil, nil)
+}
+
+func (m *MockDBHelper) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.Transactions++
+	return fn(ctx)
+}
+
+// mockResult is a fixed sql.Result
+type mockResult struct {
+	rowsAffected, lastInsertID int64
+}
+
+func (r mockResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r mockResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
@@ -0,0 +1,110 @@
+package pocketbase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"remiaq/internal/db"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// QueryAuditRepo implements repository.QueryAuditRepository
+type QueryAuditRepo struct {
+	helper db.DBHelperInterface
+}
+
+// Ensure implementation
+var _ repository.QueryAuditRepository = (*QueryAuditRepo)(nil)
+
+// NewQueryAuditRepo creates a new query audit repository
+func NewQueryAuditRepo(app *pocketbase.PocketBase) repository.QueryAuditRepository {
+	return &QueryAuditRepo{helper: db.NewDBHelper(app)}
+}
+
+// Create inserts an audit entry
+func (r *QueryAuditRepo) Create(ctx context.Context, entry *models.QueryAudit) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.Created.IsZero() {
+		entry.Created = time.Now().UTC()
+	}
+
+	params := "null"
+	if entry.Params != nil {
+		data, err := json.Marshal(entry.Params)
+		if err != nil {
+			return err
+		}
+		params = string(data)
+	}
+
+	return r.helper.Exec(ctx,
+		`INSERT INTO query_audit (
+			id, caller, statement, query, saved_query, params, dry_run, rows_affected, duration_ms, outcome, status, error, created
+		) VALUES (
+			{:id}, {:caller}, {:statement}, {:query}, {:saved_query}, {:params}, {:dry_run}, {:rows_affected}, {:duration_ms}, {:outcome}, {:status}, {:error}, {:created}
+		)`,
+		dbx.Params{
+			"id":            entry.ID,
+			"caller":        entry.Caller,
+			"statement":     entry.Statement,
+			"query":         entry.Query,
+			"saved_query":   entry.SavedQuery,
+			"params":        params,
+			"dry_run":       entry.DryRun,
+			"rows_affected": entry.RowsAffected,
+			"duration_ms":   entry.DurationMs,
+			"outcome":       entry.Outcome,
+			"status":        entry.Status,
+			"error":         entry.Error,
+			"created":       entry.Created,
+		},
+	)
+}
+
+// List returns a page of audit entries, newest first
+func (r *QueryAuditRepo) List(ctx context.Context, caller string, page, perPage int) ([]*models.QueryAudit, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 1
+	}
+
+	where := "1 = 1"
+	params := dbx.Params{}
+	if caller != "" {
+		where = "caller = {:caller}"
+		params["caller"] = caller
+	}
+
+	total, err := r.helper.Count(ctx, "SELECT COUNT(*) AS count FROM query_audit WHERE "+where, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.QueryAudit{}, 0, nil
+	}
+
+	params["limit"] = perPage
+	params["offset"] = (page - 1) * perPage
+	entries, err := db.GetAll[models.QueryAudit](ctx, r.helper,
+		"SELECT * FROM query_audit WHERE "+where+" ORDER BY created DESC, id DESC LIMIT {:limit} OFFSET {:offset}", params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Convert []models.QueryAudit to []*models.QueryAudit
+	result := make([]*models.QueryAudit, len(entries))
+	for i := range entries {
+		result[i] = &entries[i]
+	}
+	return result, total, nil
+}
@@ -0,0 +1,137 @@
+package pocketbase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"remiaq/internal/models"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryAuditRepo_Create(t *testing.T) {
+	t.Run("should insert all fields with params as JSON", func(t *testing.T) {
+		entry := &models.QueryAudit{
+			Caller:       "apikey:reporting",
+			Statement:    "UPDATE",
+			Query:        "UPDATE reminders SET status = 'paused' WHERE id = {:id}",
+			SavedQuery:   "pause-reminder",
+			Params:       map[string]any{"id": "rem1"},
+			DryRun:       true,
+			RowsAffected: 1,
+			DurationMs:   12,
+			Outcome:      models.QueryOutcomeOK,
+			Status:       200,
+		}
+
+		var got dbx.Params
+		repo := &QueryAuditRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					assert.Contains(t, query, "INSERT INTO query_audit")
+					got = params
+					return nil
+				},
+			},
+		}
+
+		require.NoError(t, repo.Create(context.Background(), entry))
+		assert.NotEmpty(t, entry.ID)
+		assert.False(t, entry.Created.IsZero())
+		assert.Equal(t, entry.ID, got["id"])
+		assert.Equal(t, "apikey:reporting", got["caller"])
+		assert.Equal(t, "UPDATE", got["statement"])
+		assert.Equal(t, "pause-reminder", got["saved_query"])
+		assert.Equal(t, `{"id":"rem1"}`, got["params"])
+		assert.Equal(t, true, got["dry_run"])
+		assert.Equal(t, int64(1), got["rows_affected"])
+		assert.Equal(t, int64(12), got["duration_ms"])
+		assert.Equal(t, "ok", got["outcome"])
+		assert.Equal(t, 200, got["status"])
+		assert.Equal(t, "", got["error"])
+	})
+
+	t.Run("should store missing params as null", func(t *testing.T) {
+		created := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+		repo := &QueryAuditRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					assert.Equal(t, "a1", params["id"])
+					assert.Equal(t, "null", params["params"])
+					assert.Equal(t, created, params["created"])
+					return nil
+				},
+			},
+		}
+
+		require.NoError(t, repo.Create(context.Background(), &models.QueryAudit{ID: "a1", Caller: "superuser:admin@example.com", Statement: "SELECT", Created: created}))
+	})
+}
+
+func TestQueryAuditRepo_List(t *testing.T) {
+	t.Run("should filter by caller and fetch the requested page", func(t *testing.T) {
+		repo := &QueryAuditRepo{
+			helper: &MockDBHelper{
+				CountFn: func(query string, params dbx.Params) (int, error) {
+					assert.Contains(t, query, "FROM query_audit WHERE caller = {:caller}")
+					assert.Equal(t, "apikey:ops", params["caller"])
+					return 3, nil
+				},
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					assert.Contains(t, query, "WHERE caller = {:caller} ORDER BY created DESC, id DESC")
+					assert.Equal(t, 2, params["limit"])
+					assert.Equal(t, 2, params["offset"])
+					return []dbx.NullStringMap{
+						{
+							"id":            {String: "a3", Valid: true},
+							"caller":        {String: "apikey:ops", Valid: true},
+							"statement":     {String: "DELETE", Valid: true},
+							"params":        {String: `{"id":"rem1"}`, Valid: true},
+							"dry_run":       {String: "1", Valid: true},
+							"rows_affected": {String: "2", Valid: true},
+							"duration_ms":   {String: "5", Valid: true},
+							"outcome":       {String: "denied", Valid: true},
+							"status":        {String: "403", Valid: true},
+							"created":       {String: "2025-10-18 09:00:00.000Z", Valid: true},
+						},
+					}, nil
+				},
+			},
+		}
+
+		entries, total, err := repo.List(context.Background(), "apikey:ops", 2, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 3, total)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "DELETE", entries[0].Statement)
+		assert.Equal(t, map[string]any{"id": "rem1"}, entries[0].Params)
+		assert.True(t, entries[0].DryRun)
+		assert.Equal(t, int64(2), entries[0].RowsAffected)
+		assert.Equal(t, models.QueryOutcomeDenied, entries[0].Outcome)
+		assert.Equal(t, 403, entries[0].Status)
+		assert.Equal(t, time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC), entries[0].Created)
+	})
+
+	t.Run("should list every caller and skip the page query when empty", func(t *testing.T) {
+		repo := &QueryAuditRepo{
+			helper: &MockDBHelper{
+				CountFn: func(query string, params dbx.Params) (int, error) {
+					assert.NotContains(t, query, "caller")
+					return 0, nil
+				},
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					t.Fatal("page query should not run")
+					return nil, nil
+				},
+			},
+		}
+
+		entries, total, err := repo.List(context.Background(), "", 1, 30)
+		require.NoError(t, err)
+		assert.Equal(t, 0, total)
+		assert.Empty(t, entries)
+	})
+}
@@ -2,11 +2,20 @@ package pocketbase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"remiaq/internal/db"
 	"remiaq/internal/repository"
 
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
 // QueryRepo implements QueryRepository for raw SQL queries
@@ -22,52 +31,231 @@ func NewQueryRepo(app *pocketbase.PocketBase) repository.QueryRepository {
 	return &QueryRepo{helper: db.NewDBHelper(app)}
 }
 
-// ExecuteSelect executes a SELECT query and returns results
-func (r *QueryRepo) ExecuteSelect(ctx context.Context, query string) ([]map[string]interface{}, error) {
-	rawResult, err := r.helper.GetAllRows(query, nil)
+// ExecuteSelectPage executes a page of a SELECT query and counts the rows of the whole query
+func (r *QueryRepo) ExecuteSelectPage(ctx context.Context, query string, params map[string]any, page repository.SelectPage) (*repository.SelectResult, error) {
+	bound, err := bindParams(params)
 	if err != nil {
 		return nil, err
 	}
+	if bound == nil {
+		bound = dbx.Params{}
+	}
+	if len(page.After) > 0 && len(page.After) != len(page.Sort) {
+		return nil, fmt.Errorf("got %d cursor values for %d sort fields", len(page.After), len(page.Sort))
+	}
 
-	// Convert NullStringMap to regular map
-	result := make([]map[string]interface{}, 0, len(rawResult))
-	for _, row := range rawResult {
-		cleaned := map[string]interface{}{}
-		for key, val := range row {
-			if val.Valid {
-				cleaned[key] = val.String
-			} else {
-				cleaned[key] = nil
+	// Xuống dòng trước ")" để comment cuối query không nuốt mất phần bọc ngoài
+	from := "SELECT * FROM (" + query + "\n)"
+
+	total := -1
+	if !page.SkipTotal {
+		if total, err = r.helper.Count(ctx, "SELECT COUNT(*) AS count FROM ("+query+"\n)", bound); err != nil {
+			return nil, err
+		}
+		// Vẫn chạy truy vấn trang khi cần schema: tên và kiểu cột có cả khi không có dòng nào
+		if total == 0 && !page.Schema {
+			return &repository.SelectResult{Items: []map[string]interface{}{}}, nil
+		}
+	}
+
+	pageParams := dbx.Params{"_limit": page.Limit, "_offset": page.Offset}
+	statement := from
+	if len(page.After) > 0 {
+		statement += " WHERE " + keysetCondition(page.Sort, page.After, pageParams)
+		pageParams["_offset"] = 0
+	}
+	if len(page.Sort) > 0 {
+		order := make([]string, len(page.Sort))
+		for i, field := range page.Sort {
+			order[i] = quoteColumn(field.Column)
+			if field.Desc {
+				order[i] += " DESC"
 			}
 		}
-		result = append(result, cleaned)
+		statement += " ORDER BY " + strings.Join(order, ", ")
+	}
+	statement += " LIMIT {:_limit} OFFSET {:_offset}"
+
+	for name, value := range pageParams {
+		if _, ok := bound[name]; ok {
+			return nil, fmt.Errorf("param %q is reserved", name)
+		}
+		bound[name] = value
+	}
+
+	rows := &typedRows{}
+	if err := r.helper.QueryRows(ctx, statement, bound, rows); err != nil {
+		return nil, err
+	}
+	return &repository.SelectResult{Items: rows.items, Columns: rows.columns, Total: total}, nil
+}
+
+// StreamSelect executes a SELECT and writes each row to w as soon as it is scanned. Only ctx
+// bounds it, not the per-query timeout of the helper.
+func (r *QueryRepo) StreamSelect(ctx context.Context, query string, params map[string]any, w repository.RowWriter) error {
+	bound, err := bindParams(params)
+	if err != nil {
+		return err
+	}
+	return r.helper.QueryRows(db.WithoutTimeout(ctx), query, bound, &typedRows{w: w})
+}
+
+// keysetCondition returns the condition selecting the rows sorted after the after values,
+// binding them into params:
+// (a > {:_after0}) OR (a = {:_after0} AND b < {:_after1}) ... for "a, b DESC".
+func keysetCondition(sort []repository.SortField, after []any, params dbx.Params) string {
+	terms := make([]string, len(sort))
+	for i, field := range sort {
+		name := fmt.Sprintf("_after%d", i)
+		params[name] = cursorValue(after[i])
+
+		var term []string
+		for j, prev := range sort[:i] {
+			term = append(term, fmt.Sprintf("%s = {:_after%d}", quoteColumn(prev.Column), j))
+		}
+		op := ">"
+		if field.Desc {
+			op = "<"
+		}
+		term = append(term, fmt.Sprintf("%s %s {:%s}", quoteColumn(field.Column), op, name))
+		terms[i] = "(" + strings.Join(term, " AND ") + ")"
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+// cursorValue binds numeric-looking cursor values as numbers: a column without affinity
+// (e.g. COUNT(*) AS n) compares integers before any text, while a TEXT column converts
+// the number back to text. RFC 3339 datetimes go back to the layout PocketBase stores.
+func cursorValue(value any) any {
+	if n, ok := value.(json.Number); ok {
+		value = n.String()
+	}
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return value
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC().Format(types.DefaultDateLayout)
+	}
+	if !(isDigit(s[0]) || (s[0] == '-' && len(s) > 1 && isDigit(s[1]))) {
+		return value
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
 	}
+	return value
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
 
-	return result, nil
+// quoteColumn quotes a result column name. Backticks, unlike double quotes, never fall
+// back to a string literal when the column doesn't exist.
+func quoteColumn(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
 }
 
 // ExecuteInsert executes an INSERT query
-func (r *QueryRepo) ExecuteInsert(ctx context.Context, query string) (int64, int64, error) {
-	// For raw queries with return values, we need to use the underlying DB
-	// This is a limitation of the current DBHelper interface
-	// TODO: Consider extending DBHelper interface for this use case
-	return 0, 0, r.helper.Exec(query, nil)
+func (r *QueryRepo) ExecuteInsert(ctx context.Context, query string, params map[string]any) (int64, int64, error) {
+	bound, err := bindParams(params)
+	if err != nil {
+		return 0, 0, err
+	}
+	result, err := r.helper.ExecResult(ctx, query, bound)
+	if err != nil {
+		return 0, 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	lastInsertId, err := result.LastInsertId()
+	if err != nil {
+		return 0, 0, err
+	}
+	return rowsAffected, lastInsertId, nil
 }
 
 // ExecuteUpdate executes an UPDATE query
-func (r *QueryRepo) ExecuteUpdate(ctx context.Context, query string) (int64, error) {
-	// For raw queries with return values, we need to use the underlying DB
-	// This is a limitation of the current DBHelper interface
-	// TODO: Consider extending DBHelper interface for this use case
-	err := r.helper.Exec(query, nil)
-	return 0, err
+func (r *QueryRepo) ExecuteUpdate(ctx context.Context, query string, params map[string]any) (int64, error) {
+	return r.execute(ctx, query, params)
 }
 
 // ExecuteDelete executes a DELETE query
-func (r *QueryRepo) ExecuteDelete(ctx context.Context, query string) (int64, error) {
-	// For raw queries with return values, we need to use the underlying DB
-	// This is a limitation of the current DBHelper interface
-	// TODO: Consider extending DBHelper interface for this use case
-	err := r.helper.Exec(query, nil)
-	return 0, err
+func (r *QueryRepo) ExecuteDelete(ctx context.Context, query string, params map[string]any) (int64, error) {
+	return r.execute(ctx, query, params)
+}
+
+// errDryRun rolls back the dry-run transaction
+var errDryRun = errors.New("dry run")
+
+// ExecuteDryRun executes a mutation in a transaction that is always rolled back
+func (r *QueryRepo) ExecuteDryRun(ctx context.Context, query string, params map[string]any) (int64, error) {
+	var rowsAffected int64
+	err := r.helper.InTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if rowsAffected, err = r.execute(ctx, query, params); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// execute runs a mutation and returns the number of rows it affected
+func (r *QueryRepo) execute(ctx context.Context, query string, params map[string]any) (int64, error) {
+	bound, err := bindParams(params)
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.helper.ExecResult(ctx, query, bound)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// bindParams converts JSON-decoded params to dbx.Params: integral numbers become int64,
+// other numbers float64 and RFC 3339 strings PocketBase datetimes (UTC, types.DefaultDateLayout)
+// so they compare with stored values. Arrays and objects are rejected.
+func bindParams(params map[string]any) (dbx.Params, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	bound := make(dbx.Params, len(params))
+	for name, value := range params {
+		switch v := value.(type) {
+		case nil, bool, int, int64:
+			bound[name] = v
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				bound[name] = i
+			} else if f, err := v.Float64(); err == nil {
+				bound[name] = f
+			} else {
+				return nil, fmt.Errorf("param %q: invalid number %s", name, v)
+			}
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				bound[name] = int64(v)
+			} else {
+				bound[name] = v
+			}
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				bound[name] = t.UTC().Format(types.DefaultDateLayout)
+			} else {
+				bound[name] = v
+			}
+		default:
+			return nil, fmt.Errorf("param %q: unsupported type %T", name, value)
+		}
+	}
+	return bound, nil
 }
@@ -2,198 +2,590 @@ package pocketbase
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 
+	"remiaq/internal/db"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
 	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	_ "github.com/pocketbase/pocketbase/migrations" // system tables needed by Bootstrap
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestQueryRepo_ExecuteSelect(t *testing.T) {
-	t.Run("should execute select query successfully", func(t *testing.T) {
+func TestQueryRepo_ExecuteInsert(t *testing.T) {
+	t.Run("should execute insert query successfully", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
-			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
-				assert.Equal(t, "SELECT * FROM users", query)
+			ExecResultFn: func(query string, params dbx.Params) (sql.Result, error) {
+				assert.Equal(t, "INSERT INTO users (id, email) VALUES ('user1', 'test@example.com')", query)
 				assert.Nil(t, params)
-				
-				return []dbx.NullStringMap{
-					{
-						"id":    {String: "user1", Valid: true},
-						"email": {String: "user1@example.com", Valid: true},
-						"name":  {String: "", Valid: false}, // NULL value
-					},
-					{
-						"id":    {String: "user2", Valid: true},
-						"email": {String: "user2@example.com", Valid: true},
-						"name":  {String: "John Doe", Valid: true},
-					},
-				}, nil
+				return mockResult{rowsAffected: 1, lastInsertID: 42}, nil
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		result, err := repo.ExecuteSelect(context.Background(), "SELECT * FROM users")
+		rowsAffected, lastInsertId, err := repo.ExecuteInsert(context.Background(), "INSERT INTO users (id, email) VALUES ('user1', 'test@example.com')", nil)
 
-		require.NoError(t, err)
-		require.Len(t, result, 2)
-		
-		// Check first row
-		assert.Equal(t, "user1", result[0]["id"])
-		assert.Equal(t, "user1@example.com", result[0]["email"])
-		assert.Nil(t, result[0]["name"]) // NULL value should be nil
-		
-		// Check second row
-		assert.Equal(t, "user2", result[1]["id"])
-		assert.Equal(t, "user2@example.com", result[1]["email"])
-		assert.Equal(t, "John Doe", result[1]["name"])
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), rowsAffected)
+		assert.Equal(t, int64(42), lastInsertId)
 	})
 
-	t.Run("should return error when query fails", func(t *testing.T) {
+	t.Run("should return error when insert fails", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
-			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
-				return nil, errors.New("database error")
+			ExecFn: func(query string, params dbx.Params) error {
+				return errors.New("insert failed")
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		result, err := repo.ExecuteSelect(context.Background(), "SELECT * FROM invalid_table")
+		rowsAffected, lastInsertId, err := repo.ExecuteInsert(context.Background(), "INSERT INTO users (id, email) VALUES ('user1', 'test@example.com')", nil)
 
 		assert.Error(t, err)
-		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "database error")
+		assert.Equal(t, int64(0), rowsAffected)
+		assert.Equal(t, int64(0), lastInsertId)
+		assert.Contains(t, err.Error(), "insert failed")
 	})
+}
 
-	t.Run("should return empty slice for no results", func(t *testing.T) {
+func TestQueryRepo_ExecuteUpdate(t *testing.T) {
+	t.Run("should execute update query successfully", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
-			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
-				return []dbx.NullStringMap{}, nil
+			ExecResultFn: func(query string, params dbx.Params) (sql.Result, error) {
+				assert.Equal(t, "UPDATE users SET email = 'new@example.com' WHERE id = 'user1'", query)
+				assert.Nil(t, params)
+				return mockResult{rowsAffected: 1}, nil
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		result, err := repo.ExecuteSelect(context.Background(), "SELECT * FROM empty_table")
+		rowsAffected, err := repo.ExecuteUpdate(context.Background(), "UPDATE users SET email = 'new@example.com' WHERE id = 'user1'", nil)
 
-		require.NoError(t, err)
-		assert.Len(t, result, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), rowsAffected)
 	})
-}
 
-func TestQueryRepo_ExecuteInsert(t *testing.T) {
-	t.Run("should execute insert query successfully", func(t *testing.T) {
+	t.Run("should return error when update fails", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
 			ExecFn: func(query string, params dbx.Params) error {
-				assert.Equal(t, "INSERT INTO users (id, email) VALUES ('user1', 'test@example.com')", query)
+				return errors.New("update failed")
+			},
+		}
+
+		repo := &QueryRepo{helper: mockHelper}
+		rowsAffected, err := repo.ExecuteUpdate(context.Background(), "UPDATE users SET email = 'new@example.com' WHERE id = 'user1'", nil)
+
+		assert.Error(t, err)
+		assert.Equal(t, int64(0), rowsAffected)
+		assert.Contains(t, err.Error(), "update failed")
+	})
+}
+
+func TestQueryRepo_ExecuteDelete(t *testing.T) {
+	t.Run("should execute delete query successfully", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			ExecResultFn: func(query string, params dbx.Params) (sql.Result, error) {
+				assert.Equal(t, "DELETE FROM users WHERE id = 'user1'", query)
 				assert.Nil(t, params)
-				return nil
+				return mockResult{rowsAffected: 3}, nil
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		rowsAffected, lastInsertId, err := repo.ExecuteInsert(context.Background(), "INSERT INTO users (id, email) VALUES ('user1', 'test@example.com')")
+		rowsAffected, err := repo.ExecuteDelete(context.Background(), "DELETE FROM users WHERE id = 'user1'", nil)
 
 		assert.NoError(t, err)
-		// Note: Current implementation returns 0, 0 due to DBHelper limitation
-		assert.Equal(t, int64(0), rowsAffected)
-		assert.Equal(t, int64(0), lastInsertId)
+		assert.Equal(t, int64(3), rowsAffected)
 	})
 
-	t.Run("should return error when insert fails", func(t *testing.T) {
+	t.Run("should return error when delete fails", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
 			ExecFn: func(query string, params dbx.Params) error {
-				return errors.New("insert failed")
+				return errors.New("delete failed")
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		rowsAffected, lastInsertId, err := repo.ExecuteInsert(context.Background(), "INSERT INTO users (id, email) VALUES ('user1', 'test@example.com')")
+		rowsAffected, err := repo.ExecuteDelete(context.Background(), "DELETE FROM users WHERE id = 'user1'", nil)
 
 		assert.Error(t, err)
 		assert.Equal(t, int64(0), rowsAffected)
-		assert.Equal(t, int64(0), lastInsertId)
-		assert.Contains(t, err.Error(), "insert failed")
+		assert.Contains(t, err.Error(), "delete failed")
 	})
 }
 
-func TestQueryRepo_ExecuteUpdate(t *testing.T) {
-	t.Run("should execute update query successfully", func(t *testing.T) {
+func TestQueryRepo_ExecuteDryRun(t *testing.T) {
+	t.Run("should report rows affected and roll back", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
-			ExecFn: func(query string, params dbx.Params) error {
-				assert.Equal(t, "UPDATE users SET email = 'new@example.com' WHERE id = 'user1'", query)
-				assert.Nil(t, params)
-				return nil
+			ExecResultFn: func(query string, params dbx.Params) (sql.Result, error) {
+				assert.Equal(t, "DELETE FROM users WHERE verified = 0", query)
+				return mockResult{rowsAffected: 5}, nil
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		rowsAffected, err := repo.ExecuteUpdate(context.Background(), "UPDATE users SET email = 'new@example.com' WHERE id = 'user1'")
+		rowsAffected, err := repo.ExecuteDryRun(context.Background(), "DELETE FROM users WHERE verified = 0", nil)
 
-		assert.NoError(t, err)
-		// Note: Current implementation returns 0 due to DBHelper limitation
-		assert.Equal(t, int64(0), rowsAffected)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), rowsAffected)
+		assert.Equal(t, 1, mockHelper.Transactions)
 	})
 
-	t.Run("should return error when update fails", func(t *testing.T) {
+	t.Run("should return error when the query fails", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
-			ExecFn: func(query string, params dbx.Params) error {
-				return errors.New("update failed")
+			ExecResultFn: func(query string, params dbx.Params) (sql.Result, error) {
+				return nil, errors.New("no such table: missing")
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		rowsAffected, err := repo.ExecuteUpdate(context.Background(), "UPDATE users SET email = 'new@example.com' WHERE id = 'user1'")
+		rowsAffected, err := repo.ExecuteDryRun(context.Background(), "DELETE FROM missing", nil)
 
-		assert.Error(t, err)
+		assert.EqualError(t, err, "no such table: missing")
 		assert.Equal(t, int64(0), rowsAffected)
-		assert.Contains(t, err.Error(), "update failed")
 	})
 }
 
-func TestQueryRepo_ExecuteDelete(t *testing.T) {
-	t.Run("should execute delete query successfully", func(t *testing.T) {
+func TestQueryRepo_ExecuteSelectPage(t *testing.T) {
+	t.Run("should wrap the query with count, sort and limit", func(t *testing.T) {
+		var countQuery, pageQuery string
+		var pageParams dbx.Params
 		mockHelper := &MockDBHelper{
-			ExecFn: func(query string, params dbx.Params) error {
-				assert.Equal(t, "DELETE FROM users WHERE id = 'user1'", query)
-				assert.Nil(t, params)
+			CountFn: func(query string, params dbx.Params) (int, error) {
+				countQuery = query
+				assert.Equal(t, dbx.Params{"uid": "u1"}, params)
+				return 42, nil
+			},
+			QueryRowsFn: func(query string, params dbx.Params, handler db.RowHandler) error {
+				pageQuery, pageParams = query, params
+				return serveRows([]string{"id"}, []string{"TEXT"}, []any{"r1"})(query, params, handler)
+			},
+		}
+
+		repo := &QueryRepo{helper: mockHelper}
+		result, err := repo.ExecuteSelectPage(context.Background(), "SELECT * FROM reminders WHERE user_id = {:uid} -- mine",
+			map[string]any{"uid": "u1"}, repository.SelectPage{
+				Limit:  11,
+				Offset: 20,
+				Sort:   []repository.SortField{{Column: "created", Desc: true}, {Column: "id"}},
+			})
+
+		require.NoError(t, err)
+		assert.Equal(t, 42, result.Total)
+		assert.Equal(t, []map[string]interface{}{{"id": "r1"}}, result.Items)
+		assert.Equal(t, []models.QueryColumn{{Name: "id", Type: "text", DeclType: "TEXT"}}, result.Columns)
+		assert.Equal(t, "SELECT COUNT(*) AS count FROM (SELECT * FROM reminders WHERE user_id = {:uid} -- mine\n)", countQuery)
+		assert.Equal(t, "SELECT * FROM (SELECT * FROM reminders WHERE user_id = {:uid} -- mine\n) ORDER BY `created` DESC, `id` LIMIT {:_limit} OFFSET {:_offset}", pageQuery)
+		assert.Equal(t, dbx.Params{"uid": "u1", "_limit": 11, "_offset": 20}, pageParams)
+	})
+
+	t.Run("should skip the page query when nothing matches", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			CountFn: func(query string, params dbx.Params) (int, error) { return 0, nil },
+			QueryRowsFn: func(query string, params dbx.Params, handler db.RowHandler) error {
+				t.Fatal("unexpected page query")
 				return nil
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		rowsAffected, err := repo.ExecuteDelete(context.Background(), "DELETE FROM users WHERE id = 'user1'")
+		result, err := repo.ExecuteSelectPage(context.Background(), "SELECT * FROM reminders", nil, repository.SelectPage{Limit: 10})
 
-		assert.NoError(t, err)
-		// Note: Current implementation returns 0 due to DBHelper limitation
-		assert.Equal(t, int64(0), rowsAffected)
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Total)
+		assert.NotNil(t, result.Items)
+		assert.Empty(t, result.Items)
+		assert.Nil(t, result.Columns)
 	})
 
-	t.Run("should return error when delete fails", func(t *testing.T) {
+	t.Run("should describe the columns of empty results when asked", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
-			ExecFn: func(query string, params dbx.Params) error {
-				return errors.New("delete failed")
+			CountFn:     func(query string, params dbx.Params) (int, error) { return 0, nil },
+			QueryRowsFn: serveRows([]string{"id", "done"}, []string{"TEXT", "BOOLEAN"}),
+		}
+
+		repo := &QueryRepo{helper: mockHelper}
+		result, err := repo.ExecuteSelectPage(context.Background(), "SELECT * FROM reminders", nil, repository.SelectPage{Limit: 10, Schema: true})
+
+		require.NoError(t, err)
+		assert.Empty(t, result.Items)
+		assert.Equal(t, []models.QueryColumn{
+			{Name: "id", Type: "text", DeclType: "TEXT"},
+			{Name: "done", Type: "boolean", DeclType: "BOOLEAN"},
+		}, result.Columns)
+	})
+
+	t.Run("should select the keyset page without counting", func(t *testing.T) {
+		var pageQuery string
+		var pageParams dbx.Params
+		mockHelper := &MockDBHelper{
+			CountFn: func(query string, params dbx.Params) (int, error) {
+				t.Fatal("unexpected count")
+				return 0, nil
+			},
+			QueryRowsFn: func(query string, params dbx.Params, handler db.RowHandler) error {
+				pageQuery, pageParams = query, params
+				return nil
 			},
 		}
 
 		repo := &QueryRepo{helper: mockHelper}
-		rowsAffected, err := repo.ExecuteDelete(context.Background(), "DELETE FROM users WHERE id = 'user1'")
+		result, err := repo.ExecuteSelectPage(context.Background(), "SELECT * FROM reminders", nil, repository.SelectPage{
+			Limit:     5,
+			Offset:    100,
+			Sort:      []repository.SortField{{Column: "priority", Desc: true}, {Column: "id"}},
+			After:     []any{"3", "r9"},
+			SkipTotal: true,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, -1, result.Total)
+		assert.Equal(t, "SELECT * FROM (SELECT * FROM reminders\n) WHERE ((`priority` < {:_after0}) OR (`priority` = {:_after0} AND `id` > {:_after1})) ORDER BY `priority` DESC, `id` LIMIT {:_limit} OFFSET {:_offset}", pageQuery)
+		assert.Equal(t, dbx.Params{"_after0": int64(3), "_after1": "r9", "_limit": 5, "_offset": 0}, pageParams)
+	})
+
+	t.Run("should reject reserved params and mismatched cursors", func(t *testing.T) {
+		repo := &QueryRepo{helper: &MockDBHelper{}}
 
+		_, err := repo.ExecuteSelectPage(context.Background(), "SELECT {:_limit}", map[string]any{"_limit": 1},
+			repository.SelectPage{Limit: 10, SkipTotal: true})
+		assert.EqualError(t, err, `param "_limit" is reserved`)
+
+		_, err = repo.ExecuteSelectPage(context.Background(), "SELECT 1", nil,
+			repository.SelectPage{Limit: 10, Sort: []repository.SortField{{Column: "id"}}, After: []any{"a", "b"}})
+		assert.EqualError(t, err, "got 2 cursor values for 1 sort fields")
+	})
+
+	t.Run("should walk every row once with keyset pages", func(t *testing.T) {
+		app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+		require.NoError(t, app.Bootstrap())
+		t.Cleanup(func() { _ = app.ResetBootstrapState() })
+		helper := db.NewDBHelper(app)
+
+		ctx := context.Background()
+		require.NoError(t, helper.Exec(ctx, "CREATE TABLE items (id TEXT PRIMARY KEY, priority INTEGER)", nil))
+		for i := 0; i < 25; i++ {
+			require.NoError(t, helper.Exec(ctx, "INSERT INTO items (id, priority) VALUES ({:id}, {:p})",
+				dbx.Params{"id": fmt.Sprintf("i%02d", i), "p": i % 4}))
+		}
+
+		repo := &QueryRepo{helper: helper}
+		// n has no affinity: cursor values must compare as numbers
+		query := "SELECT id, priority * 10 AS n FROM items"
+		sort := []repository.SortField{{Column: "n", Desc: true}, {Column: "id"}}
+
+		var seen []string
+		var after []any
+		for page := 0; page < 10; page++ {
+			result, err := repo.ExecuteSelectPage(ctx, query, nil, repository.SelectPage{Limit: 7, Sort: sort, After: after, SkipTotal: true})
+			require.NoError(t, err)
+			items := result.Items
+			for _, item := range items {
+				seen = append(seen, item["id"].(string))
+			}
+			if len(items) < 7 {
+				break
+			}
+			last := items[len(items)-1]
+			after = []any{last["n"], last["id"]}
+		}
+
+		require.Len(t, seen, 25)
+		assert.Equal(t, []string{"i03", "i07", "i11"}, seen[:3])
+		assert.Equal(t, "i24", seen[24])
+		all, err := repo.ExecuteSelectPage(ctx, query, nil, repository.SelectPage{Limit: 100, Sort: sort})
+		require.NoError(t, err)
+		assert.Equal(t, 25, all.Total)
+		for i, item := range all.Items {
+			assert.Equal(t, item["id"], seen[i])
+		}
+	})
+}
+
+func TestQueryRepo_TypedValues(t *testing.T) {
+	newRepo := func(t *testing.T) *QueryRepo {
+		app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+		require.NoError(t, app.Bootstrap())
+		t.Cleanup(func() { _ = app.ResetBootstrapState() })
+		helper := db.NewDBHelper(app)
+
+		// Kiểu cột như PocketBase tạo cho text, bool, number, json, date/autodate
+		ctx := context.Background()
+		require.NoError(t, helper.Exec(ctx, `CREATE TABLE items (
+			id TEXT PRIMARY KEY, done BOOLEAN DEFAULT FALSE NOT NULL, amount NUMERIC DEFAULT 0 NOT NULL,
+			meta JSON DEFAULT NULL, created TEXT DEFAULT '' NOT NULL, hits INTEGER, ratio REAL)`, nil))
+		for i := 0; i < 5; i++ {
+			require.NoError(t, helper.Exec(ctx, "INSERT INTO items VALUES ({:id}, {:done}, {:amount}, {:meta}, {:created}, {:hits}, {:ratio})",
+				dbx.Params{
+					"id": fmt.Sprintf("i%d", i), "done": i%2 == 0, "amount": 1.5 * float64(i), "meta": `{"tags":["a"]}`,
+					"created": fmt.Sprintf("2025-10-18 0%d:30:00.000Z", i), "hits": i, "ratio": nil,
+				}))
+		}
+		return &QueryRepo{helper: helper}
+	}
+
+	t.Run("should type values by the declared column types", func(t *testing.T) {
+		repo := newRepo(t)
+
+		result, err := repo.ExecuteSelectPage(context.Background(), "SELECT *, hits * 2 AS double, 'x' AS label FROM items WHERE id = 'i1'", nil,
+			repository.SelectPage{Limit: 10})
+
+		require.NoError(t, err)
+		require.Len(t, result.Items, 1)
+		assert.Equal(t, map[string]interface{}{
+			"id":      "i1",
+			"done":    false,
+			"amount":  1.5,
+			"meta":    json.RawMessage(`{"tags":["a"]}`),
+			"created": "2025-10-18T01:30:00Z",
+			"hits":    int64(1),
+			"ratio":   nil,
+			"double":  int64(2),
+			"label":   "x",
+		}, result.Items[0])
+		assert.Equal(t, []models.QueryColumn{
+			{Name: "id", Type: "text", DeclType: "TEXT"},
+			{Name: "done", Type: "boolean", DeclType: "BOOLEAN"},
+			{Name: "amount", Type: "number", DeclType: "NUMERIC"},
+			{Name: "meta", Type: "json", DeclType: "JSON"},
+			{Name: "created", Type: "datetime", DeclType: "TEXT"},
+			{Name: "hits", Type: "integer", DeclType: "INTEGER"},
+			{Name: "ratio", Type: "number", DeclType: "REAL"},
+			{Name: "double", Type: "integer"},
+			{Name: "label", Type: "text"},
+		}, result.Columns)
+
+		data, err := json.Marshal(result.Items[0])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"i1","done":false,"amount":1.5,"meta":{"tags":["a"]},"created":"2025-10-18T01:30:00Z",
+			"hits":1,"ratio":null,"double":2,"label":"x"}`, string(data))
+	})
+
+	t.Run("should type streamed rows", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var c rowCollector
+		require.NoError(t, repo.StreamSelect(context.Background(), "SELECT done, created FROM items WHERE id = 'i2'", nil, &c))
+
+		assert.Equal(t, [][]any{{true, "2025-10-18T02:30:00Z"}}, c.rows)
+	})
+
+	t.Run("should page through datetime cursors", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		sort := []repository.SortField{{Column: "created", Desc: true}}
+
+		first, err := repo.ExecuteSelectPage(ctx, "SELECT id, created FROM items", nil, repository.SelectPage{Limit: 2, Sort: sort, SkipTotal: true})
+		require.NoError(t, err)
+		last := first.Items[1]
+		assert.Equal(t, "2025-10-18T03:30:00Z", last["created"])
+
+		next, err := repo.ExecuteSelectPage(ctx, "SELECT id, created FROM items", nil,
+			repository.SelectPage{Limit: 2, Sort: sort, After: []any{last["created"]}, SkipTotal: true})
+		require.NoError(t, err)
+		require.Len(t, next.Items, 2)
+		assert.Equal(t, "i2", next.Items[0]["id"])
+	})
+}
+
+// serveRows returns a QueryRowsFn handing the columns and rows to the handler
+func serveRows(columns, declTypes []string, rows ...[]any) func(string, dbx.Params, db.RowHandler) error {
+	return func(query string, params dbx.Params, handler db.RowHandler) error {
+		if err := handler.Columns(columns, declTypes); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := handler.Row(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// rowCollector is a RowWriter keeping copies of the rows
+type rowCollector struct {
+	columns []string
+	rows    [][]any
+}
+
+func (c *rowCollector) Columns(names []string) error {
+	c.columns = names
+	return nil
+}
+
+func (c *rowCollector) Row(values []any) error {
+	c.rows = append(c.rows, append([]any(nil), values...))
+	return nil
+}
+
+func TestQueryRepo_StreamSelect(t *testing.T) {
+	t.Run("should pass the bound query to the helper", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			QueryRowsFn: func(query string, params dbx.Params, handler db.RowHandler) error {
+				assert.Equal(t, "SELECT * FROM users WHERE age > {:age}", query)
+				assert.Equal(t, dbx.Params{"age": int64(18)}, params)
+				return serveRows([]string{"id"}, []string{"TEXT"}, []any{"u1"})(query, params, handler)
+			},
+		}
+		repo := &QueryRepo{helper: mockHelper}
+
+		var c rowCollector
+		err := repo.StreamSelect(context.Background(), "SELECT * FROM users WHERE age > {:age}", map[string]any{"age": json.Number("18")}, &c)
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{"u1"}}, c.rows)
+	})
+
+	t.Run("should pass NULL values as nil", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			QueryRowsFn: serveRows([]string{"id", "email", "name"}, []string{"TEXT", "TEXT", "TEXT"},
+				[]any{"user1", "user1@example.com", nil},
+				[]any{"user2", "user2@example.com", "John Doe"},
+			),
+		}
+		repo := &QueryRepo{helper: mockHelper}
+
+		var c rowCollector
+		require.NoError(t, repo.StreamSelect(context.Background(), "SELECT * FROM users", nil, &c))
+
+		assert.Equal(t, []string{"id", "email", "name"}, c.columns)
+		assert.Equal(t, [][]any{{"user1", "user1@example.com", nil}, {"user2", "user2@example.com", "John Doe"}}, c.rows)
+	})
+
+	t.Run("should report columns of empty results", func(t *testing.T) {
+		repo := &QueryRepo{helper: &MockDBHelper{QueryRowsFn: serveRows([]string{"id"}, []string{"TEXT"})}}
+
+		var c rowCollector
+		require.NoError(t, repo.StreamSelect(context.Background(), "SELECT * FROM empty_table", nil, &c))
+
+		assert.Equal(t, []string{"id"}, c.columns)
+		assert.Empty(t, c.rows)
+	})
+
+	t.Run("should return query errors", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			QueryRowsFn: func(query string, params dbx.Params, handler db.RowHandler) error {
+				return errors.New("database error")
+			},
+		}
+		repo := &QueryRepo{helper: mockHelper}
+
+		err := repo.StreamSelect(context.Background(), "SELECT * FROM invalid_table", nil, &rowCollector{})
+		assert.EqualError(t, err, "database error")
+	})
+
+	t.Run("should reject invalid params before querying", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			QueryRowsFn: func(query string, params dbx.Params, handler db.RowHandler) error {
+				t.Fatal("query must not run")
+				return nil
+			},
+		}
+		repo := &QueryRepo{helper: mockHelper}
+
+		err := repo.StreamSelect(context.Background(), "SELECT {:a}", map[string]any{"a": []any{1}}, &rowCollector{})
+		assert.EqualError(t, err, `param "a": unsupported type []interface {}`)
+	})
+
+	t.Run("should keep column types from SQLite", func(t *testing.T) {
+		app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+		require.NoError(t, app.Bootstrap())
+		t.Cleanup(func() { _ = app.ResetBootstrapState() })
+		helper := db.NewDBHelper(app)
+
+		ctx := context.Background()
+		require.NoError(t, helper.Exec(ctx, "CREATE TABLE items (id TEXT PRIMARY KEY, priority INTEGER, score REAL)", nil))
+		require.NoError(t, helper.Exec(ctx, "INSERT INTO items VALUES ('a', 2, 0.5), ('b', NULL, NULL)", nil))
+
+		repo := &QueryRepo{helper: helper}
+		var c rowCollector
+		require.NoError(t, repo.StreamSelect(ctx, "SELECT * FROM items WHERE id >= {:from} ORDER BY id", map[string]any{"from": "a"}, &c))
+
+		assert.Equal(t, []string{"id", "priority", "score"}, c.columns)
+		assert.Equal(t, [][]any{{"a", int64(2), 0.5}, {"b", nil, nil}}, c.rows)
+	})
+}
+
+func TestQueryRepo_Params(t *testing.T) {
+	t.Run("should bind params with JSON type coercion", func(t *testing.T) {
+		var got dbx.Params
+		mockHelper := &MockDBHelper{
+			QueryRowsFn: func(query string, params dbx.Params, handler db.RowHandler) error {
+				got = params
+				return nil
+			},
+		}
+
+		repo := &QueryRepo{helper: mockHelper}
+		err := repo.StreamSelect(context.Background(), "SELECT * FROM reminders WHERE user_id = {:uid}", map[string]any{
+			"uid":    "u1",
+			"count":  json.Number("3"),
+			"big":    json.Number("12345678901234567"),
+			"ratio":  json.Number("0.5"),
+			"float":  float64(7),
+			"active": true,
+			"none":   nil,
+			"after":  "2025-10-18T09:30:00+07:00",
+		}, &rowCollector{})
+
+		require.NoError(t, err)
+		assert.Equal(t, dbx.Params{
+			"uid":    "u1",
+			"count":  int64(3),
+			"big":    int64(12345678901234567),
+			"ratio":  0.5,
+			"float":  int64(7),
+			"active": true,
+			"none":   nil,
+			"after":  "2025-10-18 02:30:00.000Z", // UTC, PocketBase layout
+		}, got)
+	})
+
+	t.Run("should pass params to mutations and dry runs", func(t *testing.T) {
+		var calls []dbx.Params
+		mockHelper := &MockDBHelper{
+			ExecResultFn: func(query string, params dbx.Params) (sql.Result, error) {
+				calls = append(calls, params)
+				return mockResult{rowsAffected: 1}, nil
+			},
+		}
+		repo := &QueryRepo{helper: mockHelper}
+		params := map[string]any{"id": "r1"}
+
+		_, _, err := repo.ExecuteInsert(context.Background(), "INSERT INTO reminders (id) VALUES ({:id})", params)
+		require.NoError(t, err)
+		_, err = repo.ExecuteUpdate(context.Background(), "UPDATE reminders SET title = 'x' WHERE id = {:id}", params)
+		require.NoError(t, err)
+		_, err = repo.ExecuteDelete(context.Background(), "DELETE FROM reminders WHERE id = {:id}", params)
+		require.NoError(t, err)
+		_, err = repo.ExecuteDryRun(context.Background(), "DELETE FROM reminders WHERE id = {:id}", params)
+		require.NoError(t, err)
+
+		assert.Equal(t, []dbx.Params{{"id": "r1"}, {"id": "r1"}, {"id": "r1"}, {"id": "r1"}}, calls)
+	})
+
+	t.Run("should reject arrays and objects", func(t *testing.T) {
+		repo := &QueryRepo{helper: &MockDBHelper{}}
+
+		_, err := repo.ExecuteSelectPage(context.Background(), "SELECT 1", map[string]any{"ids": []any{"a", "b"}}, repository.SelectPage{Limit: 1})
+		assert.EqualError(t, err, `param "ids": unsupported type []interface {}`)
+
+		_, err = repo.ExecuteDelete(context.Background(), "DELETE FROM reminders WHERE id = {:id}", map[string]any{"id": map[string]any{}})
 		assert.Error(t, err)
-		assert.Equal(t, int64(0), rowsAffected)
-		assert.Contains(t, err.Error(), "delete failed")
 	})
 }
 
 // Benchmark tests
-func BenchmarkQueryRepo_ExecuteSelect(b *testing.B) {
+func BenchmarkQueryRepo_StreamSelect(b *testing.B) {
 	mockHelper := &MockDBHelper{
-		GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
-			return []dbx.NullStringMap{
-				{
-					"id":    {String: "user1", Valid: true},
-					"email": {String: "user1@example.com", Valid: true},
-				},
-			}, nil
-		},
+		QueryRowsFn: serveRows([]string{"id", "email"}, []string{"TEXT", "TEXT"}, []any{"user1", "user1@example.com"}),
 	}
 
 	repo := &QueryRepo{helper: mockHelper}
@@ -201,6 +593,6 @@ func BenchmarkQueryRepo_ExecuteSelect(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = repo.ExecuteSelect(ctx, "SELECT * FROM users")
+		_ = repo.StreamSelect(ctx, "SELECT * FROM users", nil, &rowCollector{})
 	}
 }
\ No newline at end of file
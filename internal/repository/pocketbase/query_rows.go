@@ -0,0 +1,151 @@
+package pocketbase
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// typedRows is a db.RowHandler turning the driver values of a raw SELECT into JSON types
+// chosen from the declared column types. It collects the rows as maps, or hands them to w
+// when set.
+type typedRows struct {
+	columns []models.QueryColumn
+	infer   []bool // type still to be decided from the first non-empty value
+	items   []map[string]interface{}
+	w       repository.RowWriter
+	values  []any // row passed to w, reused
+}
+
+func (t *typedRows) Columns(names, declTypes []string) error {
+	t.columns = make([]models.QueryColumn, len(names))
+	t.infer = make([]bool, len(names))
+	for i, name := range names {
+		columnType := declaredType(declTypes[i])
+		t.columns[i] = models.QueryColumn{Name: name, Type: columnType, DeclType: declTypes[i]}
+		// Biểu thức không có kiểu khai báo; cột TEXT của PocketBase có thể chứa ngày giờ
+		t.infer[i] = columnType == "" || columnType == models.ColumnTypeText
+	}
+
+	if t.w == nil {
+		t.items = []map[string]interface{}{}
+		return nil
+	}
+	t.values = make([]any, len(names))
+	return t.w.Columns(names)
+}
+
+func (t *typedRows) Row(values []any) error {
+	if t.w != nil {
+		for i, value := range values {
+			t.values[i] = t.value(i, value)
+		}
+		return t.w.Row(t.values)
+	}
+
+	item := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		item[t.columns[i].Name] = t.value(i, value)
+	}
+	t.items = append(t.items, item)
+	return nil
+}
+
+// value converts a driver value of column i. A column without a telling declared type
+// takes the type of its first non-empty value.
+func (t *typedRows) value(i int, value any) any {
+	column := &t.columns[i]
+	if t.infer[i] && value != nil && value != "" {
+		t.infer[i] = false
+		if inferred := valueType(value); column.Type == "" || inferred == models.ColumnTypeDatetime {
+			column.Type = inferred
+		}
+	}
+	return typedValue(column.Type, value)
+}
+
+// declaredType maps a declared SQL type to a column type, following SQLite's affinity rules
+// after the names PocketBase uses (BOOLEAN, JSON). "" = no declared type.
+func declaredType(declType string) string {
+	decl := strings.ToUpper(declType)
+	switch {
+	case decl == "":
+		return ""
+	case strings.Contains(decl, "BOOL"):
+		return models.ColumnTypeBoolean
+	case strings.Contains(decl, "JSON"):
+		return models.ColumnTypeJSON
+	case strings.Contains(decl, "DATE"), strings.Contains(decl, "TIME"):
+		return models.ColumnTypeDatetime
+	case strings.Contains(decl, "INT"):
+		return models.ColumnTypeInteger
+	case strings.Contains(decl, "CHAR"), strings.Contains(decl, "CLOB"), strings.Contains(decl, "TEXT"):
+		return models.ColumnTypeText
+	case strings.Contains(decl, "BLOB"):
+		return models.ColumnTypeBlob
+	default:
+		return models.ColumnTypeNumber // REAL, DOUBLE, NUMERIC, DECIMAL...
+	}
+}
+
+// valueType returns the column type of a driver value; PocketBase datetimes are stored as text
+func valueType(value any) string {
+	switch v := value.(type) {
+	case int64:
+		return models.ColumnTypeInteger
+	case float64:
+		return models.ColumnTypeNumber
+	case bool:
+		return models.ColumnTypeBoolean
+	case []byte:
+		return models.ColumnTypeBlob
+	case time.Time:
+		return models.ColumnTypeDatetime
+	case string:
+		if _, err := time.Parse(types.DefaultDateLayout, v); err == nil {
+			return models.ColumnTypeDatetime
+		}
+		return models.ColumnTypeText
+	}
+	return ""
+}
+
+// typedValue converts a driver value to the JSON type of its column. Values that don't fit
+// the column (SQLite doesn't enforce types) are left as they are.
+func typedValue(columnType string, value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		if columnType == models.ColumnTypeBlob {
+			return v
+		}
+		value = string(v)
+	}
+
+	switch columnType {
+	case models.ColumnTypeBoolean:
+		switch v := value.(type) {
+		case int64:
+			return v != 0
+		case float64:
+			return v != 0
+		}
+	case models.ColumnTypeJSON:
+		if s, ok := value.(string); ok && json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	case models.ColumnTypeDatetime:
+		if s, ok := value.(string); ok {
+			if t, err := time.Parse(types.DefaultDateLayout, s); err == nil {
+				return t.UTC().Format(time.RFC3339Nano)
+			}
+		}
+	}
+	return value
+}
@@ -3,7 +3,11 @@ package pocketbase
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
 	"time"
 
 	"remiaq/internal/db"
@@ -26,54 +30,71 @@ func NewReminderRepo(app *pocketbase.PocketBase) repository.ReminderRepository {
 
 func (r *ReminderRepo) Create(ctx context.Context, reminder *models.Reminder) error {
 	patternJSON, _ := json.Marshal(reminder.RecurrencePattern)
+	audienceJSON, _ := json.Marshal(reminder.AudienceUserIDs)
+	optionsJSON, _ := json.Marshal(reminder.DeliveryOptions)
+	escalationJSON, _ := json.Marshal(reminder.EscalationPolicy)
 
 	query := `
         INSERT INTO reminders (
             id, user_id, title, description, type, calendar_type,
             next_trigger_at, trigger_time_of_day, recurrence_pattern,
-            repeat_strategy, retry_interval_sec, max_retries, status,
+            repeat_strategy, retry_interval_sec, seen_retry_interval_sec, max_retries, status,
             snooze_until, last_completed_at, last_sent_at,
+            template, due_at,
+            audience_type, audience_user_ids, group_id,
+            delivery_options, escalation_policy,
             created, updated
         ) VALUES (
             {:id}, {:user_id}, {:title}, {:description}, {:type}, {:calendar_type},
             {:next_trigger_at}, {:trigger_time_of_day}, {:recurrence_pattern},
-            {:repeat_strategy}, {:retry_interval_sec}, {:max_retries}, {:status},
+            {:repeat_strategy}, {:retry_interval_sec}, {:seen_retry_interval_sec}, {:max_retries}, {:status},
             {:snooze_until}, {:last_completed_at}, {:last_sent_at},
+            {:template}, {:due_at},
+            {:audience_type}, {:audience_user_ids}, {:group_id},
+            {:delivery_options}, {:escalation_policy},
             {:created}, {:updated}
         )
     `
 
-	return r.helper.Exec(query, dbx.Params{
-		"id":                reminder.ID,
-		"user_id":           reminder.UserID,
-		"title":             reminder.Title,
-		"description":       reminder.Description,
-		"type":              reminder.Type,
-		"calendar_type":     reminder.CalendarType,
-		"next_trigger_at":   reminder.NextTriggerAt,
-		"trigger_time_of_day": reminder.TriggerTimeOfDay,
-		"recurrence_pattern": string(patternJSON),
-		"repeat_strategy":    reminder.RepeatStrategy,
-		"retry_interval_sec": reminder.RetryIntervalSec,
-		"max_retries":       reminder.MaxRetries,
-		"status":            reminder.Status,
-		"snooze_until":      reminder.SnoozeUntil,
-		"last_completed_at": reminder.LastCompletedAt,
-		"last_sent_at":      reminder.LastSentAt,
-		"created":           reminder.Created,
-		"updated":           reminder.Updated,
+	return r.helper.Exec(ctx, query, dbx.Params{
+		"id":                      reminder.ID,
+		"user_id":                 reminder.UserID,
+		"title":                   reminder.Title,
+		"description":             reminder.Description,
+		"type":                    reminder.Type,
+		"calendar_type":           reminder.CalendarType,
+		"next_trigger_at":         reminder.NextTriggerAt.UTC(),
+		"trigger_time_of_day":     reminder.TriggerTimeOfDay,
+		"recurrence_pattern":      string(patternJSON),
+		"repeat_strategy":         reminder.RepeatStrategy,
+		"retry_interval_sec":      reminder.RetryIntervalSec,
+		"seen_retry_interval_sec": reminder.SeenRetryIntervalSec,
+		"max_retries":             reminder.MaxRetries,
+		"status":                  reminder.Status,
+		"snooze_until":            utcTime(reminder.SnoozeUntil),
+		"last_completed_at":       utcTime(reminder.LastCompletedAt),
+		"last_sent_at":            utcTime(reminder.LastSentAt),
+		"template":                reminder.Template,
+		"due_at":                  utcTime(reminder.DueAt),
+		"audience_type":           reminder.AudienceType,
+		"audience_user_ids":       string(audienceJSON),
+		"group_id":                reminder.GroupID,
+		"delivery_options":        string(optionsJSON),
+		"escalation_policy":       string(escalationJSON),
+		"created":                 reminder.Created.UTC(),
+		"updated":                 reminder.Updated.UTC(),
 	})
 }
 
 func (r *ReminderRepo) GetByID(ctx context.Context, id string) (*models.Reminder, error) {
-	return db.GetOne[models.Reminder](r.helper,
+	return db.GetOne[models.Reminder](ctx, r.helper,
 		"SELECT * FROM reminders WHERE id = {:id} LIMIT 1",
 		dbx.Params{"id": id})
 }
 
 // GetByUserID retrieves all reminders for a specific user
 func (r *ReminderRepo) GetByUserID(ctx context.Context, userID string) ([]*models.Reminder, error) {
-	reminders, err := db.GetAll[models.Reminder](r.helper,
+	reminders, err := db.GetAll[models.Reminder](ctx, r.helper,
 		"SELECT * FROM reminders WHERE user_id = {:user_id} ORDER BY next_trigger_at ASC",
 		dbx.Params{"user_id": userID})
 	if err != nil {
@@ -90,6 +111,9 @@ func (r *ReminderRepo) GetByUserID(ctx context.Context, userID string) ([]*model
 
 func (r *ReminderRepo) Update(ctx context.Context, reminder *models.Reminder) error {
 	patternJSON, _ := json.Marshal(reminder.RecurrencePattern)
+	audienceJSON, _ := json.Marshal(reminder.AudienceUserIDs)
+	optionsJSON, _ := json.Marshal(reminder.DeliveryOptions)
+	escalationJSON, _ := json.Marshal(reminder.EscalationPolicy)
 
 	query := `
         UPDATE reminders SET
@@ -97,37 +121,49 @@ func (r *ReminderRepo) Update(ctx context.Context, reminder *models.Reminder) er
             type = {:type}, calendar_type = {:calendar_type},
             next_trigger_at = {:next_trigger_at}, trigger_time_of_day = {:trigger_time_of_day}, 
             recurrence_pattern = {:recurrence_pattern},
-            repeat_strategy = {:repeat_strategy}, retry_interval_sec = {:retry_interval_sec}, 
+            repeat_strategy = {:repeat_strategy}, retry_interval_sec = {:retry_interval_sec},
+            seen_retry_interval_sec = {:seen_retry_interval_sec},
             max_retries = {:max_retries}, status = {:status},
             snooze_until = {:snooze_until}, last_completed_at = {:last_completed_at}, 
             last_sent_at = {:last_sent_at},
+            template = {:template}, due_at = {:due_at},
+            audience_type = {:audience_type}, audience_user_ids = {:audience_user_ids}, group_id = {:group_id},
+            delivery_options = {:delivery_options}, escalation_policy = {:escalation_policy},
             updated = {:updated}
         WHERE id = {:id}
     `
 
-	return r.helper.Exec(query, dbx.Params{
-		"user_id":           reminder.UserID,
-		"title":             reminder.Title,
-		"description":       reminder.Description,
-		"type":              reminder.Type,
-		"calendar_type":     reminder.CalendarType,
-		"next_trigger_at":   reminder.NextTriggerAt,
-		"trigger_time_of_day": reminder.TriggerTimeOfDay,
-		"recurrence_pattern": string(patternJSON),
-		"repeat_strategy":    reminder.RepeatStrategy,
-		"retry_interval_sec": reminder.RetryIntervalSec,
-		"max_retries":       reminder.MaxRetries,
-		"status":            reminder.Status,
-		"snooze_until":      reminder.SnoozeUntil,
-		"last_completed_at": reminder.LastCompletedAt,
-		"last_sent_at":      reminder.LastSentAt,
-		"updated":           time.Now(),
-		"id":                reminder.ID,
+	return r.helper.Exec(ctx, query, dbx.Params{
+		"user_id":                 reminder.UserID,
+		"title":                   reminder.Title,
+		"description":             reminder.Description,
+		"type":                    reminder.Type,
+		"calendar_type":           reminder.CalendarType,
+		"next_trigger_at":         reminder.NextTriggerAt.UTC(),
+		"trigger_time_of_day":     reminder.TriggerTimeOfDay,
+		"recurrence_pattern":      string(patternJSON),
+		"repeat_strategy":         reminder.RepeatStrategy,
+		"retry_interval_sec":      reminder.RetryIntervalSec,
+		"seen_retry_interval_sec": reminder.SeenRetryIntervalSec,
+		"max_retries":             reminder.MaxRetries,
+		"status":                  reminder.Status,
+		"snooze_until":            utcTime(reminder.SnoozeUntil),
+		"last_completed_at":       utcTime(reminder.LastCompletedAt),
+		"last_sent_at":            utcTime(reminder.LastSentAt),
+		"template":                reminder.Template,
+		"due_at":                  utcTime(reminder.DueAt),
+		"audience_type":           reminder.AudienceType,
+		"audience_user_ids":       string(audienceJSON),
+		"group_id":                reminder.GroupID,
+		"delivery_options":        string(optionsJSON),
+		"escalation_policy":       string(escalationJSON),
+		"updated":                 time.Now().UTC(),
+		"id":                      reminder.ID,
 	})
 }
 
 func (r *ReminderRepo) Delete(ctx context.Context, id string) error {
-	return r.helper.Exec("DELETE FROM reminders WHERE id = {:id}",
+	return r.helper.Exec(ctx, "DELETE FROM reminders WHERE id = {:id}",
 		dbx.Params{"id": id})
 }
 
@@ -138,9 +174,9 @@ func (r *ReminderRepo) GetDueReminders(ctx context.Context, beforeTime time.Time
           AND status = 'active'
           AND (snooze_until IS NULL OR snooze_until <= {:before_time})
     `
-	
-	reminders, err := db.GetAll[models.Reminder](r.helper, query,
-		dbx.Params{"before_time": beforeTime})
+
+	reminders, err := db.GetAll[models.Reminder](ctx, r.helper, query,
+		dbx.Params{"before_time": beforeTime.UTC()})
 	if err != nil {
 		return nil, err
 	}
@@ -153,62 +189,299 @@ func (r *ReminderRepo) GetDueReminders(ctx context.Context, beforeTime time.Time
 	return result, nil
 }
 
+// GetDueRemindersByUser returns a user's active reminders due before beforeTime (used by digest windows).
+// With a lease owner in ctx, reminders claimed by another instance are skipped.
+func (r *ReminderRepo) GetDueRemindersByUser(ctx context.Context, userID string, beforeTime time.Time) ([]*models.Reminder, error) {
+	params := dbx.Params{"user_id": userID, "before_time": beforeTime.UTC()}
+	query := `
+        SELECT * FROM reminders
+        WHERE user_id = {:user_id}
+          AND next_trigger_at <= {:before_time}
+          AND status = 'active'
+          AND (snooze_until IS NULL OR snooze_until <= {:before_time})` + leaseCondition(ctx, params) + `
+        ORDER BY next_trigger_at ASC
+    `
+
+	reminders, err := db.GetAll[models.Reminder](ctx, r.helper, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert []models.Reminder to []*models.Reminder
+	result := make([]*models.Reminder, len(reminders))
+	for i := range reminders {
+		result[i] = &reminders[i]
+	}
+	return result, nil
+}
+
+// GetUpcomingTriggers returns when active reminders are next due (the later of next_trigger_at
+// and snooze_until), earliest first. Only id and time are read, so the scheduler can load many.
+func (r *ReminderRepo) GetUpcomingTriggers(ctx context.Context, beforeTime time.Time, limit int) ([]models.ScheduledTrigger, error) {
+	query := `
+        SELECT id, trigger_at FROM (
+            SELECT id, MAX(next_trigger_at, COALESCE(NULLIF(snooze_until, ''), next_trigger_at)) AS trigger_at
+            FROM reminders
+            WHERE status = 'active'
+        )
+        WHERE trigger_at <= {:before_time}
+        ORDER BY trigger_at ASC
+        LIMIT {:limit}
+    `
+
+	return db.GetAll[models.ScheduledTrigger](ctx, r.helper, query,
+		dbx.Params{"before_time": beforeTime.UTC(), "limit": limit})
+}
+
 func (r *ReminderRepo) UpdateNextTrigger(ctx context.Context, id string, nextTrigger time.Time) error {
-	return r.helper.Exec(
-		"UPDATE reminders SET next_trigger_at = {:next_trigger}, updated = {:updated} WHERE id = {:id}",
-		dbx.Params{
-			"next_trigger": nextTrigger,
-			"updated":      time.Now(),
-			"id":           id,
-		})
+	params := dbx.Params{
+		"next_trigger": nextTrigger.UTC(),
+		"updated":      time.Now().UTC(),
+		"id":           id,
+	}
+	return r.helper.Exec(ctx,
+		"UPDATE reminders SET next_trigger_at = {:next_trigger}, updated = {:updated} WHERE id = {:id}"+leaseCondition(ctx, params),
+		params)
+}
+
+// UpdateEscalation moves a reminder to escalation step (1-based), due at nextTrigger
+func (r *ReminderRepo) UpdateEscalation(ctx context.Context, id string, step int, nextTrigger time.Time) error {
+	params := dbx.Params{
+		"step":         step,
+		"next_trigger": nextTrigger.UTC(),
+		"updated":      time.Now().UTC(),
+		"id":           id,
+	}
+	return r.helper.Exec(ctx,
+		"UPDATE reminders SET escalation_step = {:step}, next_trigger_at = {:next_trigger}, updated = {:updated} WHERE id = {:id}"+leaseCondition(ctx, params),
+		params)
 }
 
 func (r *ReminderRepo) IncrementRetryCount(ctx context.Context, id string) error {
-	return r.helper.Exec(
-		"UPDATE reminders SET retry_count = retry_count + 1, updated = {:updated} WHERE id = {:id}",
-		dbx.Params{
-			"updated": time.Now(),
-			"id":      id,
-		})
+	params := dbx.Params{
+		"updated": time.Now().UTC(),
+		"id":      id,
+	}
+	return r.helper.Exec(ctx,
+		"UPDATE reminders SET retry_count = retry_count + 1, updated = {:updated} WHERE id = {:id}"+leaseCondition(ctx, params),
+		params)
 }
 
 func (r *ReminderRepo) MarkCompleted(ctx context.Context, id string, completedAt time.Time) error {
-	return r.helper.Exec(
-		"UPDATE reminders SET status = {:status}, last_completed_at = {:completed_at}, updated = {:updated} WHERE id = {:id}",
-		dbx.Params{
-			"status":       "completed",
-			"completed_at": completedAt,
-			"updated":      time.Now(),
-			"id":           id,
-		})
+	params := dbx.Params{
+		"status":       "completed",
+		"completed_at": completedAt.UTC(),
+		"updated":      time.Now().UTC(),
+		"id":           id,
+	}
+	return r.helper.Exec(ctx,
+		"UPDATE reminders SET status = {:status}, last_completed_at = {:completed_at}, updated = {:updated} WHERE id = {:id}"+leaseCondition(ctx, params),
+		params)
 }
 
 func (r *ReminderRepo) UpdateSnooze(ctx context.Context, id string, snoozeUntil *time.Time) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		"UPDATE reminders SET snooze_until = {:snooze_until}, updated = {:updated} WHERE id = {:id}",
 		dbx.Params{
-			"snooze_until": snoozeUntil,
-			"updated":      time.Now(),
+			"snooze_until": utcTime(snoozeUntil),
+			"updated":      time.Now().UTC(),
 			"id":           id,
 		})
 }
 
 func (r *ReminderRepo) UpdateLastSent(ctx context.Context, id string, sentAt time.Time) error {
-	return r.helper.Exec(
-		"UPDATE reminders SET last_sent_at = {:sent_at}, updated = {:updated} WHERE id = {:id}",
-		dbx.Params{
-			"sent_at": sentAt,
-			"updated": time.Now(),
-			"id":      id,
-		})
+	params := dbx.Params{
+		"sent_at": sentAt.UTC(),
+		"updated": time.Now().UTC(),
+		"id":      id,
+	}
+	return r.helper.Exec(ctx,
+		// Mỗi lần gửi thành công cũng tăng số lần xuất hiện (dùng cho {occurrence} trong template)
+		"UPDATE reminders SET last_sent_at = {:sent_at}, occurrence_count = COALESCE(occurrence_count, 0) + 1, updated = {:updated} WHERE id = {:id}"+leaseCondition(ctx, params),
+		params)
 }
 
 func (r *ReminderRepo) UpdateStatus(ctx context.Context, id string, status string) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		"UPDATE reminders SET status = {:status}, updated = {:updated} WHERE id = {:id}",
 		dbx.Params{
 			"status":  status,
-			"updated": time.Now(),
+			"updated": time.Now().UTC(),
 			"id":      id,
 		})
 }
+
+// ApplyTransition applies t as one UPDATE inside a transaction, after checking that the
+// stored next_trigger_at is still the time t was computed from. The UPDATE matches the stored
+// value exactly, so a concurrent writer in between makes it change nothing.
+func (r *ReminderRepo) ApplyTransition(ctx context.Context, t *models.ReminderTransition) (bool, error) {
+	params := dbx.Params{
+		"id":      t.ReminderID,
+		"updated": time.Now().UTC(),
+	}
+	sets := []string{"updated = {:updated}"}
+	if t.SentAt != nil {
+		sets = append(sets, "last_sent_at = {:sent_at}", "occurrence_count = COALESCE(occurrence_count, 0) + 1")
+		params["sent_at"] = t.SentAt.UTC()
+	}
+	if t.IncrementRetry {
+		sets = append(sets, "retry_count = retry_count + 1")
+	}
+	if !t.NextTriggerAt.IsZero() {
+		sets = append(sets, "next_trigger_at = {:next_trigger}")
+		params["next_trigger"] = t.NextTriggerAt.UTC()
+	}
+	if t.EscalationStep != nil {
+		sets = append(sets, "escalation_step = {:step}")
+		params["step"] = *t.EscalationStep
+	}
+	if t.CompletedAt != nil {
+		sets = append(sets, "status = 'completed'", "last_completed_at = {:completed_at}")
+		params["completed_at"] = t.CompletedAt.UTC()
+	}
+	query := "UPDATE reminders SET " + strings.Join(sets, ", ") +
+		" WHERE id = {:id} AND next_trigger_at = {:prev_trigger}" + leaseCondition(ctx, params) + " RETURNING id"
+
+	applied := false
+	err := r.helper.InTransaction(ctx, func(ctx context.Context) error {
+		row, err := r.helper.GetOneRow(ctx, "SELECT next_trigger_at FROM reminders WHERE id = {:id}", dbx.Params{"id": t.ReminderID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil // reminder đã bị xóa
+		}
+		if err != nil {
+			return err
+		}
+		// Giá trị lưu có thể ở nhiều định dạng: so sánh thời điểm, rồi UPDATE đúng chuỗi đã đọc
+		current, err := db.MapNullStringMapToStruct[struct {
+			NextTriggerAt time.Time `db:"next_trigger_at"`
+		}](row)
+		if err != nil {
+			return err
+		}
+		if !current.NextTriggerAt.Equal(t.PrevTriggerAt) {
+			return nil
+		}
+
+		params["prev_trigger"] = row["next_trigger_at"].String
+		rows, err := r.helper.GetAllRows(ctx, query, params)
+		applied = len(rows) > 0
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+// ClaimDueReminders claims up to limit due reminders for owner in a single UPDATE, so two
+// instances claiming at the same time always get disjoint rows. Rows whose lease expired
+// (the holder crashed or stalled) are claimed again. limit <= 0 claims every due reminder.
+func (r *ReminderRepo) ClaimDueReminders(ctx context.Context, owner string, beforeTime, leaseUntil time.Time, limit int) ([]*models.Reminder, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite: LIMIT -1 = không giới hạn
+	}
+	query := `
+        UPDATE reminders
+        SET claimed_by = {:owner}, claimed_until = {:lease_until}
+        WHERE id IN (
+            SELECT id FROM reminders
+            WHERE next_trigger_at <= {:before_time}
+              AND status = 'active'
+              AND (snooze_until IS NULL OR snooze_until <= {:before_time})
+              AND (claimed_until IS NULL OR claimed_until = '' OR claimed_until < {:before_time})
+            ORDER BY next_trigger_at ASC
+            LIMIT {:limit}
+        )
+        RETURNING *
+    `
+
+	reminders, err := db.GetAll[models.Reminder](ctx, r.helper, query, dbx.Params{
+		"owner":       owner,
+		"lease_until": leaseUntil.UTC(),
+		"before_time": beforeTime.UTC(),
+		"limit":       limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING không giữ thứ tự của truy vấn con
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].NextTriggerAt.Before(reminders[j].NextTriggerAt)
+	})
+	result := make([]*models.Reminder, len(reminders))
+	for i := range reminders {
+		result[i] = &reminders[i]
+	}
+	return result, nil
+}
+
+// ClaimDueRemindersByUser claims for owner until leaseUntil the active reminders of userID
+// due before beforeTime that are unclaimed, whose lease expired or that owner already holds,
+// and returns them. The worker uses it for reminders pulled early into a digest.
+func (r *ReminderRepo) ClaimDueRemindersByUser(ctx context.Context, owner, userID string, beforeTime, leaseUntil time.Time) ([]*models.Reminder, error) {
+	query := `
+        UPDATE reminders
+        SET claimed_by = {:owner}, claimed_until = {:lease_until}
+        WHERE id IN (
+            SELECT id FROM reminders
+            WHERE user_id = {:user_id}
+              AND next_trigger_at <= {:before_time}
+              AND status = 'active'
+              AND (snooze_until IS NULL OR snooze_until <= {:before_time})
+              AND (claimed_until IS NULL OR claimed_until = '' OR claimed_until < {:now} OR claimed_by = {:owner})
+        )
+        RETURNING *
+    `
+
+	reminders, err := db.GetAll[models.Reminder](ctx, r.helper, query, dbx.Params{
+		"owner":       owner,
+		"user_id":     userID,
+		"lease_until": leaseUntil.UTC(),
+		"before_time": beforeTime.UTC(),
+		"now":         time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING không giữ thứ tự của truy vấn con
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].NextTriggerAt.Before(reminders[j].NextTriggerAt)
+	})
+	result := make([]*models.Reminder, len(reminders))
+	for i := range reminders {
+		result[i] = &reminders[i]
+	}
+	return result, nil
+}
+
+// ReleaseClaims clears the leases held by owner, e.g. at the end of a worker tick, so
+// reminders left due are not blocked until their lease expires.
+func (r *ReminderRepo) ReleaseClaims(ctx context.Context, owner string) error {
+	return r.helper.Exec(ctx,
+		"UPDATE reminders SET claimed_by = '', claimed_until = '' WHERE claimed_by = {:owner}",
+		dbx.Params{"owner": owner})
+}
+
+// utcTime returns t in UTC. Times are bound in UTC everywhere: the driver stores a
+// time.Time as text and SQLite compares it as text, so mixed offsets would compare wrong.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// leaseCondition restricts a worker transition to rows not claimed by another instance,
+// adding the lease owner of ctx to params. Without an owner it returns "".
+// Reminder chưa ai nhận vẫn được cập nhật: digest kéo sớm reminder chưa due (chưa claim).
+func leaseCondition(ctx context.Context, params dbx.Params) string {
+	owner, ok := repository.LeaseOwner(ctx)
+	if !ok {
+		return ""
+	}
+	params["lease_owner"] = owner
+	return " AND (claimed_by IS NULL OR claimed_by = '' OR claimed_by = {:lease_owner})"
+}
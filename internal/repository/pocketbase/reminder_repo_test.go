@@ -2,14 +2,19 @@ package pocketbase
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"remiaq/internal/db"
 	"remiaq/internal/models"
+	"remiaq/internal/repository"
 
 	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -67,6 +72,66 @@ func TestReminderRepo_Create(t *testing.T) {
 		err := repo.Create(context.Background(), reminder)
 		assert.NoError(t, err)
 	})
+
+	t.Run("should store audience user IDs as JSON", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.Equal(t, models.AudienceUsers, params["audience_type"])
+				assert.Equal(t, `["u1","u2"]`, params["audience_user_ids"])
+				return nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminder := &models.Reminder{
+			ID:              "test-id",
+			UserID:          "user-123",
+			AudienceType:    models.AudienceUsers,
+			AudienceUserIDs: []string{"u1", "u2"},
+		}
+
+		assert.NoError(t, repo.Create(context.Background(), reminder))
+	})
+
+	t.Run("should store delivery options as JSON", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.Contains(t, query, "delivery_options")
+				assert.JSONEq(t, `{"priority":"normal","ttl_seconds":600}`, params["delivery_options"].(string))
+				return nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminder := &models.Reminder{
+			ID:              "test-id",
+			UserID:          "user-123",
+			DeliveryOptions: &models.DeliveryOptions{Priority: models.PriorityNormal, TTLSeconds: 600},
+		}
+
+		assert.NoError(t, repo.Create(context.Background(), reminder))
+	})
+
+	t.Run("should store escalation policy as JSON", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.Contains(t, query, "escalation_policy")
+				assert.JSONEq(t, `{"steps":[{"delay_seconds":600,"channel":"email","email":"son@example.com"}]}`, params["escalation_policy"].(string))
+				return nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminder := &models.Reminder{
+			ID:     "test-id",
+			UserID: "user-123",
+			EscalationPolicy: &models.EscalationPolicy{Steps: []models.EscalationStep{
+				{DelaySeconds: 600, Channel: models.EscalationChannelEmail, Email: "son@example.com"},
+			}},
+		}
+
+		assert.NoError(t, repo.Create(context.Background(), reminder))
+	})
 }
 
 func TestReminderRepo_GetByID(t *testing.T) {
@@ -88,6 +153,63 @@ func TestReminderRepo_GetByID(t *testing.T) {
 		assert.Equal(t, "Test Reminder", reminder.Title)
 	})
 
+	t.Run("should map audience fields", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+				row := mockReminderRow("test-id", "user-123", "Standup", "active")
+				row["audience_type"] = sql.NullString{String: "users", Valid: true}
+				row["audience_user_ids"] = sql.NullString{String: `["u1","u2"]`, Valid: true}
+				row["group_id"] = sql.NullString{String: "", Valid: true}
+				return row, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminder, err := repo.GetByID(context.Background(), "test-id")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"u1", "u2"}, reminder.AudienceUserIDs)
+		assert.True(t, reminder.IsBroadcast())
+	})
+
+	t.Run("should map delivery options", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+				row := mockReminderRow("test-id", "user-123", "Uống thuốc", "active")
+				row["delivery_options"] = sql.NullString{String: `{"collapse_key":"pill","badge":1}`, Valid: true}
+				return row, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminder, err := repo.GetByID(context.Background(), "test-id")
+
+		require.NoError(t, err)
+		require.NotNil(t, reminder.DeliveryOptions)
+		assert.Equal(t, "pill", reminder.DeliveryOptions.CollapseKey)
+		require.NotNil(t, reminder.DeliveryOptions.Badge)
+		assert.Equal(t, 1, *reminder.DeliveryOptions.Badge)
+	})
+
+	t.Run("should map escalation policy and step", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+				row := mockReminderRow("test-id", "user-123", "Uống thuốc", "active")
+				row["escalation_policy"] = sql.NullString{String: `{"steps":[{"delay_seconds":0,"channel":"push","user_id":"family-1"}]}`, Valid: true}
+				row["escalation_step"] = sql.NullString{String: "1", Valid: true}
+				return row, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminder, err := repo.GetByID(context.Background(), "test-id")
+
+		require.NoError(t, err)
+		require.True(t, reminder.EscalationPolicy.HasSteps())
+		assert.Equal(t, "family-1", reminder.EscalationPolicy.Steps[0].UserID)
+		assert.True(t, reminder.IsEscalating())
+	})
+
 	t.Run("should return error when query fails", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
 			GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
@@ -185,7 +307,7 @@ func TestReminderRepo_GetDueReminders(t *testing.T) {
 			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
 				assert.Contains(t, query, "next_trigger_at <= {:before_time}")
 				assert.Contains(t, query, "status = 'active'")
-				assert.Equal(t, beforeTime, params["before_time"])
+				assert.Equal(t, beforeTime.UTC(), params["before_time"])
 				return []dbx.NullStringMap{
 					mockReminderRow("rem-1", "user-123", "Due Reminder", "active"),
 				}, nil
@@ -201,6 +323,175 @@ func TestReminderRepo_GetDueReminders(t *testing.T) {
 	})
 }
 
+func TestReminderRepo_GetDueRemindersByUser(t *testing.T) {
+	t.Run("should filter due reminders by user", func(t *testing.T) {
+		beforeTime := time.Now().Add(10 * time.Minute)
+		mockHelper := &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "user_id = {:user_id}")
+				assert.Contains(t, query, "next_trigger_at <= {:before_time}")
+				assert.Equal(t, "user-123", params["user_id"])
+				assert.Equal(t, beforeTime.UTC(), params["before_time"])
+				return []dbx.NullStringMap{
+					mockReminderRow("rem-1", "user-123", "Upcoming", "active"),
+				}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminders, err := repo.GetDueRemindersByUser(context.Background(), "user-123", beforeTime)
+
+		require.NoError(t, err)
+		assert.Len(t, reminders, 1)
+		assert.Equal(t, "rem-1", reminders[0].ID)
+	})
+
+	t.Run("should skip reminders claimed by another instance", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "claimed_by = {:lease_owner}")
+				assert.Less(t, strings.Index(query, "claimed_by"), strings.Index(query, "ORDER BY"))
+				assert.Equal(t, "worker-a", params["lease_owner"])
+				return []dbx.NullStringMap{}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		ctx := repository.WithLeaseOwner(context.Background(), "worker-a")
+		_, err := repo.GetDueRemindersByUser(ctx, "user-123", time.Now())
+
+		require.NoError(t, err)
+	})
+}
+
+func TestReminderRepo_GetUpcomingTriggers(t *testing.T) {
+	t.Run("should map reminder IDs and trigger times", func(t *testing.T) {
+		beforeTime := time.Now().Add(5 * time.Minute)
+		at := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+		mockHelper := &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "MAX(next_trigger_at, COALESCE(NULLIF(snooze_until, ''), next_trigger_at)) AS trigger_at")
+				assert.Contains(t, query, "status = 'active'")
+				assert.Contains(t, query, "ORDER BY trigger_at ASC")
+				assert.Equal(t, beforeTime.UTC(), params["before_time"])
+				assert.Equal(t, 100, params["limit"])
+				return []dbx.NullStringMap{{
+					"id":         {String: "rem-1", Valid: true},
+					"trigger_at": {String: at.Format(time.RFC3339), Valid: true},
+				}}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		triggers, err := repo.GetUpcomingTriggers(context.Background(), beforeTime, 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, []models.ScheduledTrigger{{ReminderID: "rem-1", TriggerAt: at}}, triggers)
+	})
+}
+
+func TestReminderRepo_ClaimDueReminders(t *testing.T) {
+	t.Run("should claim due reminders atomically and return them in trigger order", func(t *testing.T) {
+		now := time.Now()
+		leaseUntil := now.Add(5 * time.Minute)
+		later := mockReminderRow("rem-2", "user-1", "Later", "active")
+		later["next_trigger_at"] = sql.NullString{String: now.Add(-time.Minute).Format(time.RFC3339), Valid: true}
+		earlier := mockReminderRow("rem-1", "user-1", "Earlier", "active")
+		earlier["next_trigger_at"] = sql.NullString{String: now.Add(-time.Hour).Format(time.RFC3339), Valid: true}
+
+		mockHelper := &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "UPDATE reminders")
+				assert.Contains(t, query, "SET claimed_by = {:owner}, claimed_until = {:lease_until}")
+				assert.Contains(t, query, "claimed_until < {:before_time}")
+				assert.Contains(t, query, "LIMIT {:limit}")
+				assert.Contains(t, query, "RETURNING *")
+				assert.Equal(t, "worker-a", params["owner"])
+				assert.Equal(t, leaseUntil.UTC(), params["lease_until"])
+				assert.Equal(t, now.UTC(), params["before_time"])
+				assert.Equal(t, 100, params["limit"])
+				return []dbx.NullStringMap{later, earlier}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminders, err := repo.ClaimDueReminders(context.Background(), "worker-a", now, leaseUntil, 100)
+
+		require.NoError(t, err)
+		require.Len(t, reminders, 2)
+		assert.Equal(t, "rem-1", reminders[0].ID)
+		assert.Equal(t, "rem-2", reminders[1].ID)
+	})
+
+	t.Run("should not limit the batch when limit is zero", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Equal(t, -1, params["limit"])
+				return []dbx.NullStringMap{}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminders, err := repo.ClaimDueReminders(context.Background(), "worker-a", time.Now(), time.Now().Add(time.Minute), 0)
+
+		require.NoError(t, err)
+		assert.Empty(t, reminders)
+	})
+}
+
+func TestReminderRepo_ClaimDueRemindersByUser(t *testing.T) {
+	t.Run("should claim the user's due reminders including those already held", func(t *testing.T) {
+		now := time.Now()
+		before := now.Add(10 * time.Minute)
+		leaseUntil := now.Add(5 * time.Minute)
+		later := mockReminderRow("rem-2", "user-1", "Later", "active")
+		later["next_trigger_at"] = sql.NullString{String: now.Add(5 * time.Minute).UTC().Format(time.RFC3339), Valid: true}
+		earlier := mockReminderRow("rem-1", "user-1", "Earlier", "active")
+		earlier["next_trigger_at"] = sql.NullString{String: now.Add(-time.Minute).UTC().Format(time.RFC3339), Valid: true}
+
+		mockHelper := &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "UPDATE reminders")
+				assert.Contains(t, query, "SET claimed_by = {:owner}, claimed_until = {:lease_until}")
+				assert.Contains(t, query, "user_id = {:user_id}")
+				assert.Contains(t, query, "claimed_until < {:now} OR claimed_by = {:owner}")
+				assert.Contains(t, query, "RETURNING *")
+				assert.Equal(t, "worker-a", params["owner"])
+				assert.Equal(t, "user-1", params["user_id"])
+				assert.Equal(t, leaseUntil.UTC(), params["lease_until"])
+				assert.Equal(t, before.UTC(), params["before_time"])
+				assert.WithinDuration(t, now, params["now"].(time.Time), time.Second)
+				return []dbx.NullStringMap{later, earlier}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		reminders, err := repo.ClaimDueRemindersByUser(context.Background(), "worker-a", "user-1", before, leaseUntil)
+
+		require.NoError(t, err)
+		require.Len(t, reminders, 2)
+		assert.Equal(t, "rem-1", reminders[0].ID)
+		assert.Equal(t, "rem-2", reminders[1].ID)
+	})
+}
+
+func TestReminderRepo_ReleaseClaims(t *testing.T) {
+	t.Run("should clear the claims of the owner", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.Contains(t, query, "claimed_by = '', claimed_until = ''")
+				assert.Contains(t, query, "WHERE claimed_by = {:owner}")
+				assert.Equal(t, "worker-a", params["owner"])
+				return nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		err := repo.ReleaseClaims(context.Background(), "worker-a")
+		assert.NoError(t, err)
+	})
+}
+
 func TestReminderRepo_UpdateNextTrigger(t *testing.T) {
 	t.Run("should update next trigger successfully", func(t *testing.T) {
 		nextTrigger := time.Now().Add(24 * time.Hour)
@@ -208,7 +499,7 @@ func TestReminderRepo_UpdateNextTrigger(t *testing.T) {
 			ExecFn: func(query string, params dbx.Params) error {
 				assert.Contains(t, query, "UPDATE reminders SET next_trigger_at")
 				assert.Equal(t, "test-id", params["id"])
-				assert.Equal(t, nextTrigger, params["next_trigger"])
+				assert.Equal(t, nextTrigger.UTC(), params["next_trigger"])
 				return nil
 			},
 		}
@@ -219,6 +510,25 @@ func TestReminderRepo_UpdateNextTrigger(t *testing.T) {
 	})
 }
 
+func TestReminderRepo_UpdateEscalation(t *testing.T) {
+	t.Run("should update escalation step and next trigger", func(t *testing.T) {
+		nextTrigger := time.Now().Add(10 * time.Minute)
+		mockHelper := &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.Contains(t, query, "escalation_step = {:step}")
+				assert.Equal(t, "test-id", params["id"])
+				assert.Equal(t, 2, params["step"])
+				assert.Equal(t, nextTrigger.UTC(), params["next_trigger"])
+				return nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		err := repo.UpdateEscalation(context.Background(), "test-id", 2, nextTrigger)
+		assert.NoError(t, err)
+	})
+}
+
 func TestReminderRepo_IncrementRetryCount(t *testing.T) {
 	t.Run("should increment retry count successfully", func(t *testing.T) {
 		mockHelper := &MockDBHelper{
@@ -242,7 +552,7 @@ func TestReminderRepo_MarkCompleted(t *testing.T) {
 			ExecFn: func(query string, params dbx.Params) error {
 				assert.Contains(t, query, "status = {:status}")
 				assert.Equal(t, "completed", params["status"])
-				assert.Equal(t, completedAt, params["completed_at"])
+				assert.Equal(t, completedAt.UTC(), params["completed_at"])
 				return nil
 			},
 		}
@@ -251,6 +561,168 @@ func TestReminderRepo_MarkCompleted(t *testing.T) {
 		err := repo.MarkCompleted(context.Background(), "test-id", completedAt)
 		assert.NoError(t, err)
 	})
+
+	t.Run("should not require a lease for user actions", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.NotContains(t, query, "claimed_by")
+				assert.NotContains(t, params, "lease_owner")
+				return nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		err := repo.MarkCompleted(context.Background(), "test-id", time.Now())
+		assert.NoError(t, err)
+	})
+
+	t.Run("should skip reminders claimed by another instance", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			ExecFn: func(query string, params dbx.Params) error {
+				assert.Contains(t, query, "WHERE id = {:id} AND (claimed_by IS NULL OR claimed_by = '' OR claimed_by = {:lease_owner})")
+				assert.Equal(t, "worker-a", params["lease_owner"])
+				return nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		ctx := repository.WithLeaseOwner(context.Background(), "worker-a")
+		err := repo.MarkCompleted(ctx, "test-id", time.Now())
+		assert.NoError(t, err)
+	})
+}
+
+func TestReminderRepo_ApplyTransition(t *testing.T) {
+	storedTrigger := func(value string) func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+		return func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+			assert.Contains(t, query, "SELECT next_trigger_at FROM reminders WHERE id = {:id}")
+			return dbx.NullStringMap{"next_trigger_at": sql.NullString{String: value, Valid: true}}, nil
+		}
+	}
+
+	t.Run("should apply the whole transition in one conditional update", func(t *testing.T) {
+		prev := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+		sentAt := time.Now()
+		next := sentAt.Add(5 * time.Minute)
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: storedTrigger("2025-10-18 09:00:00.000Z"),
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "last_sent_at = {:sent_at}, occurrence_count = COALESCE(occurrence_count, 0) + 1")
+				assert.Contains(t, query, "retry_count = retry_count + 1")
+				assert.Contains(t, query, "next_trigger_at = {:next_trigger}")
+				assert.Contains(t, query, "WHERE id = {:id} AND next_trigger_at = {:prev_trigger}")
+				assert.Contains(t, query, "RETURNING id")
+				assert.NotContains(t, query, "status")
+				assert.NotContains(t, query, "escalation_step")
+				// Khớp đúng chuỗi đã lưu, không phải định dạng của PrevTriggerAt
+				assert.Equal(t, "2025-10-18 09:00:00.000Z", params["prev_trigger"])
+				assert.Equal(t, sentAt.UTC(), params["sent_at"])
+				assert.Equal(t, next.UTC(), params["next_trigger"])
+				return []dbx.NullStringMap{{"id": sql.NullString{String: "rem-1", Valid: true}}}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		applied, err := repo.ApplyTransition(context.Background(), &models.ReminderTransition{
+			ReminderID:     "rem-1",
+			PrevTriggerAt:  prev,
+			SentAt:         &sentAt,
+			IncrementRetry: true,
+			NextTriggerAt:  next,
+		})
+
+		require.NoError(t, err)
+		assert.True(t, applied)
+		assert.Equal(t, 1, mockHelper.Transactions)
+	})
+
+	t.Run("should skip a transition computed from another trigger time", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: storedTrigger("2025-10-18 09:05:00.000Z"),
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				t.Fatal("reminder already advanced, nothing should be updated")
+				return nil, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		completedAt := time.Now()
+		applied, err := repo.ApplyTransition(context.Background(), &models.ReminderTransition{
+			ReminderID:    "rem-1",
+			PrevTriggerAt: time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC),
+			CompletedAt:   &completedAt,
+		})
+
+		require.NoError(t, err)
+		assert.False(t, applied)
+	})
+
+	t.Run("should report an update that changed nothing", func(t *testing.T) {
+		completedAt := time.Now()
+		step := 2
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: storedTrigger("2025-10-18 09:00:00.000Z"),
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "status = 'completed', last_completed_at = {:completed_at}")
+				assert.Contains(t, query, "escalation_step = {:step}")
+				assert.NotContains(t, query, "next_trigger_at = {:next_trigger}")
+				assert.Equal(t, 2, params["step"])
+				return []dbx.NullStringMap{}, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		applied, err := repo.ApplyTransition(context.Background(), &models.ReminderTransition{
+			ReminderID:     "rem-1",
+			PrevTriggerAt:  time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC),
+			EscalationStep: &step,
+			CompletedAt:    &completedAt,
+		})
+
+		require.NoError(t, err)
+		assert.False(t, applied)
+	})
+
+	t.Run("should skip reminders claimed by another instance", func(t *testing.T) {
+		mockHelper := &MockDBHelper{
+			GetOneRowFn: storedTrigger("2025-10-18 09:00:00.000Z"),
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Contains(t, query, "claimed_by = {:lease_owner}")
+				assert.Equal(t, "worker-a", params["lease_owner"])
+				return nil, nil
+			},
+		}
+
+		repo := &ReminderRepo{helper: mockHelper}
+		ctx := repository.WithLeaseOwner(context.Background(), "worker-a")
+		applied, err := repo.ApplyTransition(ctx, &models.ReminderTransition{
+			ReminderID:    "rem-1",
+			PrevTriggerAt: time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC),
+		})
+
+		require.NoError(t, err)
+		assert.False(t, applied)
+	})
+
+	t.Run("should skip deleted reminders and return database errors", func(t *testing.T) {
+		repo := &ReminderRepo{helper: &MockDBHelper{
+			GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+				return nil, sql.ErrNoRows
+			},
+		}}
+		applied, err := repo.ApplyTransition(context.Background(), &models.ReminderTransition{ReminderID: "rem-1"})
+		require.NoError(t, err)
+		assert.False(t, applied)
+
+		repo = &ReminderRepo{helper: &MockDBHelper{
+			GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+				return nil, errors.New("database is locked")
+			},
+		}}
+		applied, err = repo.ApplyTransition(context.Background(), &models.ReminderTransition{ReminderID: "rem-1"})
+		assert.Error(t, err)
+		assert.False(t, applied)
+	})
 }
 
 func TestReminderRepo_UpdateSnooze(t *testing.T) {
@@ -259,7 +731,7 @@ func TestReminderRepo_UpdateSnooze(t *testing.T) {
 		mockHelper := &MockDBHelper{
 			ExecFn: func(query string, params dbx.Params) error {
 				assert.Contains(t, query, "snooze_until = {:snooze_until}")
-				assert.Equal(t, &snoozeUntil, params["snooze_until"])
+				assert.Equal(t, utcTime(&snoozeUntil), params["snooze_until"])
 				return nil
 			},
 		}
@@ -276,7 +748,8 @@ func TestReminderRepo_UpdateLastSent(t *testing.T) {
 		mockHelper := &MockDBHelper{
 			ExecFn: func(query string, params dbx.Params) error {
 				assert.Contains(t, query, "last_sent_at = {:sent_at}")
-				assert.Equal(t, sentAt, params["sent_at"])
+				assert.Contains(t, query, "occurrence_count = COALESCE(occurrence_count, 0) + 1")
+				assert.Equal(t, sentAt.UTC(), params["sent_at"])
 				return nil
 			},
 		}
@@ -337,4 +810,64 @@ func BenchmarkReminderRepo_GetByUserID(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = repo.GetByUserID(ctx, "user-123")
 	}
-}
\ No newline at end of file
+}
+func TestReminderRepo_UTCTimes(t *testing.T) {
+	// Server ở múi giờ dương: giờ local được so sánh dạng chuỗi sẽ sai lệch 7 tiếng
+	local := time.Local
+	time.Local = time.FixedZone("ICT", 7*3600)
+	t.Cleanup(func() { time.Local = local })
+
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	require.NoError(t, app.Bootstrap())
+	t.Cleanup(func() { _ = app.ResetBootstrapState() })
+	helper := db.NewDBHelper(app)
+	ctx := context.Background()
+	require.NoError(t, helper.Exec(ctx, `CREATE TABLE reminders (
+		id TEXT PRIMARY KEY, user_id TEXT, title TEXT, description TEXT, type TEXT, calendar_type TEXT,
+		next_trigger_at TEXT DEFAULT '', trigger_time_of_day TEXT, recurrence_pattern TEXT,
+		repeat_strategy TEXT, retry_interval_sec INTEGER, seen_retry_interval_sec INTEGER,
+		max_retries INTEGER, retry_count INTEGER DEFAULT 0, occurrence_count INTEGER DEFAULT 0,
+		status TEXT, snooze_until TEXT DEFAULT '', last_completed_at TEXT DEFAULT '',
+		last_sent_at TEXT DEFAULT '', template TEXT, due_at TEXT DEFAULT '',
+		audience_type TEXT, audience_user_ids TEXT, group_id TEXT,
+		delivery_options TEXT, escalation_policy TEXT, escalation_step INTEGER DEFAULT 0,
+		claimed_by TEXT DEFAULT '', claimed_until TEXT DEFAULT '',
+		created TEXT DEFAULT '', updated TEXT DEFAULT '')`, nil))
+	repo := &ReminderRepo{helper: helper}
+
+	now := time.Now()
+	for id, next := range map[string]time.Time{"due": now.Add(-time.Minute), "later": now.Add(time.Hour)} {
+		require.NoError(t, repo.Create(ctx, &models.Reminder{
+			ID: id, UserID: "user-1", Title: id, Type: models.ReminderTypeOneTime,
+			Status: models.ReminderStatusActive, NextTriggerAt: next, Created: now, Updated: now,
+		}))
+	}
+
+	due, err := repo.GetDueReminders(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "due", due[0].ID)
+
+	// Transition ghi lại giờ nhắc sau 2 tiếng: không được coi là due ngay
+	next := now.Add(2 * time.Hour)
+	applied, err := repo.ApplyTransition(ctx, &models.ReminderTransition{
+		ReminderID: "due", PrevTriggerAt: due[0].NextTriggerAt, SentAt: &now, NextTriggerAt: next,
+	})
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	due, err = repo.GetDueReminders(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	claimed, err := repo.ClaimDueReminders(ctx, "worker-a", time.Now(), time.Now().Add(time.Minute), 0)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	reminder, err := repo.GetByID(ctx, "due")
+	require.NoError(t, err)
+	assert.True(t, next.Equal(reminder.NextTriggerAt), "got %s", reminder.NextTriggerAt)
+
+	claimed, err = repo.ClaimDueReminders(ctx, "worker-a", now.Add(3*time.Hour), now.Add(4*time.Hour), 0)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+}
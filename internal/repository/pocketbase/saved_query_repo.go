@@ -0,0 +1,49 @@
+package pocketbase
+
+import (
+	"context"
+
+	"remiaq/internal/db"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// SavedQueryRepo implements repository.SavedQueryRepository
+type SavedQueryRepo struct {
+	helper db.DBHelperInterface
+}
+
+// Ensure implementation
+var _ repository.SavedQueryRepository = (*SavedQueryRepo)(nil)
+
+// NewSavedQueryRepo creates a new saved query repository
+func NewSavedQueryRepo(app *pocketbase.PocketBase) repository.SavedQueryRepository {
+	return &SavedQueryRepo{helper: db.NewDBHelper(app)}
+}
+
+// GetByName retrieves a saved query by name
+func (r *SavedQueryRepo) GetByName(ctx context.Context, name string) (*models.SavedQuery, error) {
+	return db.GetOne[models.SavedQuery](ctx,
+		r.helper,
+		"SELECT * FROM saved_queries WHERE name = {:name} LIMIT 1",
+		dbx.Params{"name": name},
+	)
+}
+
+// List returns every saved query ordered by name
+func (r *SavedQueryRepo) List(ctx context.Context) ([]*models.SavedQuery, error) {
+	queries, err := db.GetAll[models.SavedQuery](ctx, r.helper, "SELECT * FROM saved_queries ORDER BY name", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert []models.SavedQuery to []*models.SavedQuery
+	result := make([]*models.SavedQuery, len(queries))
+	for i := range queries {
+		result[i] = &queries[i]
+	}
+	return result, nil
+}
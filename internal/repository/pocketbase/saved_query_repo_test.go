@@ -0,0 +1,78 @@
+package pocketbase
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"remiaq/internal/models"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedQueryRepo_GetByName(t *testing.T) {
+	t.Run("should map saved query row with its parameter schema", func(t *testing.T) {
+		repo := &SavedQueryRepo{
+			helper: &MockDBHelper{
+				GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+					assert.Contains(t, query, "FROM saved_queries WHERE name = {:name}")
+					assert.Equal(t, "due-by-user", params["name"])
+					return dbx.NullStringMap{
+						"id":   {String: "q1", Valid: true},
+						"name": {String: "due-by-user", Valid: true},
+						"sql":  {String: "SELECT * FROM reminders WHERE user_id = {:user_id} LIMIT {:limit}", Valid: true},
+						"params": {
+							String: `{"user_id": {"type": "string", "required": true}, "limit": {"type": "integer", "default": 50}}`,
+							Valid:  true,
+						},
+						"role":    {String: "read", Valid: true},
+						"created": {String: "2025-10-18 09:00:00.000Z", Valid: true},
+					}, nil
+				},
+			},
+		}
+
+		query, err := repo.GetByName(context.Background(), "due-by-user")
+		require.NoError(t, err)
+		assert.Equal(t, models.SavedQueryRoleRead, query.Role)
+		assert.Equal(t, map[string]models.SavedQueryParam{
+			"user_id": {Type: models.ParamTypeString, Required: true},
+			"limit":   {Type: models.ParamTypeInteger, Default: float64(50)},
+		}, query.Params)
+	})
+
+	t.Run("should return not found error", func(t *testing.T) {
+		repo := &SavedQueryRepo{
+			helper: &MockDBHelper{
+				GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+					return nil, sql.ErrNoRows
+				},
+			},
+		}
+
+		_, err := repo.GetByName(context.Background(), "missing")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestSavedQueryRepo_List(t *testing.T) {
+	repo := &SavedQueryRepo{
+		helper: &MockDBHelper{
+			GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+				assert.Equal(t, "SELECT * FROM saved_queries ORDER BY name", query)
+				return []dbx.NullStringMap{
+					{"name": {String: "a", Valid: true}, "params": {String: "", Valid: false}},
+					{"name": {String: "b", Valid: true}, "params": {String: "null", Valid: true}},
+				}, nil
+			},
+		},
+	}
+
+	queries, err := repo.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	assert.Equal(t, "a", queries[0].Name)
+	assert.Nil(t, queries[1].Params)
+}
@@ -2,6 +2,7 @@ package pocketbase
 
 import (
 	"context"
+	"time"
 
 	"remiaq/internal/db"
 	"remiaq/internal/models"
@@ -27,7 +28,7 @@ func NewSystemStatusRepo(app *pocketbase.PocketBase) repository.SystemStatusRepo
 
 // Get retrieves the system status (singleton, id=1)
 func (r *SystemStatusRepo) Get(ctx context.Context) (*models.SystemStatus, error) {
-	return db.GetOne[models.SystemStatus](
+	return db.GetOne[models.SystemStatus](ctx,
 		r.helper,
 		"SELECT * FROM system_status WHERE mid = {:mid}",
 		dbx.Params{"mid": 1},
@@ -45,7 +46,7 @@ func (r *SystemStatusRepo) IsWorkerEnabled(ctx context.Context) (bool, error) {
 
 // EnableWorker enables the worker
 func (r *SystemStatusRepo) EnableWorker(ctx context.Context) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		"UPDATE system_status SET worker_enabled = TRUE, updated = {:updated} WHERE mid = 1",
 		dbx.Params{"updated": types.NowDateTime()},
 	)
@@ -53,30 +54,64 @@ func (r *SystemStatusRepo) EnableWorker(ctx context.Context) error {
 
 // DisableWorker disables the worker with an error message
 func (r *SystemStatusRepo) DisableWorker(ctx context.Context, errorMsg string) error {
-	return r.helper.Exec(
-		"UPDATE system_status SET worker_enabled = FALSE, last_error = {:error_msg}, updated = {:updated} WHERE mid = 1",
+	now := types.NowDateTime()
+	return r.helper.Exec(ctx,
+		"UPDATE system_status SET worker_enabled = FALSE, last_error = {:error_msg}, error_at = {:error_at}, updated = {:updated} WHERE mid = 1",
 		dbx.Params{
 			"error_msg": errorMsg,
-			"updated":   types.NowDateTime(),
+			"error_at":  now,
+			"updated":   now,
 		},
 	)
 }
 
-// UpdateError updates the last error message
+// UpdateError updates the last error message and its timestamp
 func (r *SystemStatusRepo) UpdateError(ctx context.Context, errorMsg string) error {
-	return r.helper.Exec(
-		"UPDATE system_status SET last_error = {:error_msg}, updated = {:updated} WHERE mid = 1",
+	now := types.NowDateTime()
+	return r.helper.Exec(ctx,
+		"UPDATE system_status SET last_error = {:error_msg}, error_at = {:error_at}, updated = {:updated} WHERE mid = 1",
 		dbx.Params{
 			"error_msg": errorMsg,
-			"updated":   types.NowDateTime(),
+			"error_at":  now,
+			"updated":   now,
 		},
 	)
 }
 
 // ClearError clears the error message
 func (r *SystemStatusRepo) ClearError(ctx context.Context) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		"UPDATE system_status SET last_error = '', updated = {:updated} WHERE mid = 1",
 		dbx.Params{"updated": types.NowDateTime()},
 	)
 }
+
+// UpdateCircuitState records a circuit breaker transition with its reason
+func (r *SystemStatusRepo) UpdateCircuitState(ctx context.Context, state, reason string, changedAt time.Time) error {
+	changed, err := types.ParseDateTime(changedAt)
+	if err != nil {
+		return err
+	}
+	return r.helper.Exec(ctx,
+		`UPDATE system_status
+		 SET circuit_state = {:state}, circuit_reason = {:reason}, circuit_changed_at = {:changed_at}, updated = {:updated}
+		 WHERE mid = 1`,
+		dbx.Params{
+			"state":      state,
+			"reason":     reason,
+			"changed_at": changed,
+			"updated":    types.NowDateTime(),
+		},
+	)
+}
+
+// SetCircuitOverride stores the admin override ("", "open" or "closed")
+func (r *SystemStatusRepo) SetCircuitOverride(ctx context.Context, override string) error {
+	return r.helper.Exec(ctx,
+		"UPDATE system_status SET circuit_override = {:override}, updated = {:updated} WHERE mid = 1",
+		dbx.Params{
+			"override": override,
+			"updated":  types.NowDateTime(),
+		},
+	)
+}
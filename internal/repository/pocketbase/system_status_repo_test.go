@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -103,7 +104,7 @@ func TestSystemStatusRepo_IsWorkerEnabled(t *testing.T) {
 func TestSystemStatusRepo_EnableWorker(t *testing.T) {
 	t.Run("should enable worker successfully", func(t *testing.T) {
 		execCalled := false
-		beforeTime := time.Now().UTC()
+		beforeTime := time.Now().UTC().Truncate(time.Millisecond)
 
 		repo := &SystemStatusRepo{
 			helper: &MockDBHelper{
@@ -112,7 +113,7 @@ func TestSystemStatusRepo_EnableWorker(t *testing.T) {
 					assert.Contains(t, query, "UPDATE system_status SET worker_enabled = TRUE")
 					assert.Contains(t, query, "updated = {:updated}")
 
-					updated := params["updated"].(time.Time)
+					updated := params["updated"].(types.DateTime).Time()
 					assert.True(t, updated.After(beforeTime) || updated.Equal(beforeTime))
 					return nil
 				},
@@ -149,7 +150,9 @@ func TestSystemStatusRepo_DisableWorker(t *testing.T) {
 					execCalled = true
 					assert.Contains(t, query, "UPDATE system_status SET worker_enabled = FALSE")
 					assert.Contains(t, query, "last_error = {:error_msg}")
+					assert.Contains(t, query, "error_at = {:error_at}")
 					assert.Equal(t, errorMsg, params["error_msg"])
+					assert.NotNil(t, params["error_at"])
 					assert.NotNil(t, params["updated"])
 					return nil
 				},
@@ -186,6 +189,7 @@ func TestSystemStatusRepo_UpdateError(t *testing.T) {
 				ExecFn: func(query string, params dbx.Params) error {
 					execCalled = true
 					assert.Contains(t, query, "UPDATE system_status SET last_error = {:error_msg}")
+					assert.Contains(t, query, "error_at = {:error_at}")
 					assert.Equal(t, errorMsg, params["error_msg"])
 					assert.NotNil(t, params["updated"])
 					return nil
@@ -248,6 +252,62 @@ func TestSystemStatusRepo_ClearError(t *testing.T) {
 	})
 }
 
+func TestSystemStatusRepo_UpdateCircuitState(t *testing.T) {
+	t.Run("should record state, reason and timestamp", func(t *testing.T) {
+		changedAt := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+		execCalled := false
+
+		repo := &SystemStatusRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					execCalled = true
+					assert.Contains(t, query, "circuit_state = {:state}")
+					assert.Contains(t, query, "circuit_reason = {:reason}")
+					assert.Contains(t, query, "circuit_changed_at = {:changed_at}")
+					assert.Equal(t, "open", params["state"])
+					assert.Equal(t, "5 consecutive failures", params["reason"])
+					assert.Equal(t, changedAt, params["changed_at"].(types.DateTime).Time())
+					return nil
+				},
+			},
+		}
+
+		err := repo.UpdateCircuitState(context.Background(), "open", "5 consecutive failures", changedAt)
+		require.NoError(t, err)
+		assert.True(t, execCalled)
+	})
+
+	t.Run("should return error if exec fails", func(t *testing.T) {
+		repo := &SystemStatusRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					return errors.New("database error")
+				},
+			},
+		}
+
+		err := repo.UpdateCircuitState(context.Background(), "closed", "", time.Now())
+		require.Error(t, err)
+	})
+}
+
+func TestSystemStatusRepo_SetCircuitOverride(t *testing.T) {
+	t.Run("should store override", func(t *testing.T) {
+		repo := &SystemStatusRepo{
+			helper: &MockDBHelper{
+				ExecFn: func(query string, params dbx.Params) error {
+					assert.Contains(t, query, "circuit_override = {:override}")
+					assert.Equal(t, "closed", params["override"])
+					return nil
+				},
+			},
+		}
+
+		err := repo.SetCircuitOverride(context.Background(), "closed")
+		require.NoError(t, err)
+	})
+}
+
 // Benchmark tests
 func BenchmarkSystemStatusRepo_Get(b *testing.B) {
 	repo := &SystemStatusRepo{
@@ -0,0 +1,34 @@
+package pocketbase
+
+import (
+	"context"
+
+	"remiaq/internal/db"
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// TemplateRepo implements repository.TemplateRepository
+type TemplateRepo struct {
+	helper db.DBHelperInterface
+}
+
+// Ensure implementation
+var _ repository.TemplateRepository = (*TemplateRepo)(nil)
+
+// NewTemplateRepo creates a new notification template repository
+func NewTemplateRepo(app *pocketbase.PocketBase) repository.TemplateRepository {
+	return &TemplateRepo{helper: db.NewDBHelper(app)}
+}
+
+// GetByKey retrieves a template by key and locale
+func (r *TemplateRepo) GetByKey(ctx context.Context, key, locale string) (*models.NotificationTemplate, error) {
+	return db.GetOne[models.NotificationTemplate](ctx,
+		r.helper,
+		"SELECT * FROM notification_templates WHERE key = {:key} AND locale = {:locale} LIMIT 1",
+		dbx.Params{"key": key, "locale": locale},
+	)
+}
@@ -0,0 +1,52 @@
+package pocketbase
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRepo_GetByKey(t *testing.T) {
+	t.Run("should map template row", func(t *testing.T) {
+		repo := &TemplateRepo{
+			helper: &MockDBHelper{
+				GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+					assert.Contains(t, query, "FROM notification_templates")
+					assert.Equal(t, "pre_reminder", params["key"])
+					assert.Equal(t, "en", params["locale"])
+					return dbx.NullStringMap{
+						"id":          {String: "t1", Valid: true},
+						"key":         {String: "pre_reminder", Valid: true},
+						"locale":      {String: "en", Valid: true},
+						"title":       {String: "{title} {until_due}", Valid: true},
+						"body":        {String: "{description}", Valid: true},
+						"retry_title": {String: "", Valid: false},
+					}, nil
+				},
+			},
+		}
+
+		tmpl, err := repo.GetByKey(context.Background(), "pre_reminder", "en")
+		require.NoError(t, err)
+		assert.Equal(t, "{title} {until_due}", tmpl.Title)
+		assert.Equal(t, "{description}", tmpl.Body)
+		assert.Empty(t, tmpl.RetryTitle)
+	})
+
+	t.Run("should return not found error", func(t *testing.T) {
+		repo := &TemplateRepo{
+			helper: &MockDBHelper{
+				GetOneRowFn: func(query string, params dbx.Params) (dbx.NullStringMap, error) {
+					return nil, sql.ErrNoRows
+				},
+			},
+		}
+
+		_, err := repo.GetByKey(context.Background(), "missing", "vi")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
@@ -0,0 +1,14 @@
+package pocketbase
+
+import (
+	"remiaq/internal/db"
+	"remiaq/internal/repository"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+// NewTransactor returns a Transactor on the app's database. Repositories built on the same
+// app run inside the transaction when called with the ctx it passes to fn.
+func NewTransactor(app *pocketbase.PocketBase) repository.Transactor {
+	return db.NewDBHelper(app)
+}
@@ -2,6 +2,8 @@ package pocketbase
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"time"
 
 	"remiaq/internal/db"
@@ -27,32 +29,54 @@ func NewUserRepo(app *pocketbase.PocketBase) repository.UserRepository {
 
 // Create inserts a new user
 func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
-	return r.helper.Exec(
-		`INSERT INTO musers (id, email, fcm_token, is_fcm_active, created, updated)
-		 VALUES ({:id}, {:email}, {:fcm_token}, {:is_fcm_active}, {:created}, {:updated})`,
+	return r.helper.Exec(ctx,
+		`INSERT INTO musers (id, email, fcm_token, is_fcm_active, locale, digest_enabled, digest_window_sec, created, updated)
+		 VALUES ({:id}, {:email}, {:fcm_token}, {:is_fcm_active}, {:locale}, {:digest_enabled}, {:digest_window_sec}, {:created}, {:updated})`,
 		dbx.Params{
-			"id":            user.ID,
-			"email":         user.Email,
-			"fcm_token":     user.FCMToken,
-			"is_fcm_active": user.IsFCMActive,
-			"created":       time.Now().UTC(),
-			"updated":       time.Now().UTC(),
+			"id":                user.ID,
+			"email":             user.Email,
+			"fcm_token":         user.FCMToken,
+			"is_fcm_active":     user.IsFCMActive,
+			"locale":            user.Locale,
+			"digest_enabled":    user.DigestEnabled,
+			"digest_window_sec": user.DigestWindowSec,
+			"created":           time.Now().UTC(),
+			"updated":           time.Now().UTC(),
 		},
 	)
 }
 
 // GetByID retrieves a user by ID
 func (r *UserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
-	return db.GetOne[models.User](
+	return db.GetOne[models.User](ctx,
 		r.helper,
 		"SELECT * FROM musers WHERE id = {:id}",
 		dbx.Params{"id": id},
 	)
 }
 
+// GetByIDs retrieves the users with the given IDs, querying at most maxIDsPerQuery IDs at a time.
+// IDs without a user are skipped; the result is in no particular order.
+func (r *UserRepo) GetByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	result := make([]*models.User, 0, len(ids))
+	for start := 0; start < len(ids); start += maxIDsPerQuery {
+		end := min(start+maxIDsPerQuery, len(ids))
+
+		query, params := inClause("id", ids[start:end])
+		users, err := db.GetAll[models.User](ctx, r.helper, "SELECT * FROM musers WHERE "+query, params)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			result = append(result, &users[i])
+		}
+	}
+	return result, nil
+}
+
 // GetByEmail retrieves a user by email
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	return db.GetOne[models.User](
+	return db.GetOne[models.User](ctx,
 		r.helper,
 		"SELECT * FROM musers WHERE email = {:email}",
 		dbx.Params{"email": email},
@@ -61,23 +85,27 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User,
 
 // Update updates user information
 func (r *UserRepo) Update(ctx context.Context, user *models.User) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		`UPDATE musers 
-		 SET email = {:email}, fcm_token = {:fcm_token}, is_fcm_active = {:is_fcm_active}, updated = {:updated}
+		 SET email = {:email}, fcm_token = {:fcm_token}, is_fcm_active = {:is_fcm_active}, locale = {:locale},
+		     digest_enabled = {:digest_enabled}, digest_window_sec = {:digest_window_sec}, updated = {:updated}
 		 WHERE id = {:id}`,
 		dbx.Params{
-			"email":         user.Email,
-			"fcm_token":     user.FCMToken,
-			"is_fcm_active": user.IsFCMActive,
-			"updated":       time.Now().UTC(),
-			"id":            user.ID,
+			"email":             user.Email,
+			"fcm_token":         user.FCMToken,
+			"is_fcm_active":     user.IsFCMActive,
+			"locale":            user.Locale,
+			"digest_enabled":    user.DigestEnabled,
+			"digest_window_sec": user.DigestWindowSec,
+			"updated":           time.Now().UTC(),
+			"id":                user.ID,
 		},
 	)
 }
 
 // UpdateFCMToken updates only the FCM token
 func (r *UserRepo) UpdateFCMToken(ctx context.Context, userID, token string) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		"UPDATE musers SET fcm_token = {:token}, is_fcm_active = TRUE, updated = {:updated} WHERE id = {:id}",
 		dbx.Params{
 			"token":   token,
@@ -89,7 +117,7 @@ func (r *UserRepo) UpdateFCMToken(ctx context.Context, userID, token string) err
 
 // DisableFCM disables FCM for a user (token invalid)
 func (r *UserRepo) DisableFCM(ctx context.Context, userID string) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		"UPDATE musers SET is_fcm_active = FALSE, fcm_token = NULL, updated = {:updated} WHERE id = {:id}",
 		dbx.Params{
 			"updated": time.Now().UTC(),
@@ -100,7 +128,7 @@ func (r *UserRepo) DisableFCM(ctx context.Context, userID string) error {
 
 // EnableFCM re-enables FCM with a new token
 func (r *UserRepo) EnableFCM(ctx context.Context, userID string, token string) error {
-	return r.helper.Exec(
+	return r.helper.Exec(ctx,
 		"UPDATE musers SET fcm_token = {:token}, is_fcm_active = TRUE, updated = {:updated} WHERE id = {:id}",
 		dbx.Params{
 			"token":   token,
@@ -112,7 +140,7 @@ func (r *UserRepo) EnableFCM(ctx context.Context, userID string, token string) e
 
 // GetActiveUsers retrieves all users with active FCM
 func (r *UserRepo) GetActiveUsers(ctx context.Context) ([]*models.User, error) {
-	users, err := db.GetAll[models.User](
+	users, err := db.GetAll[models.User](ctx,
 		r.helper,
 		`SELECT * FROM musers 
 		 WHERE is_fcm_active = TRUE 
@@ -131,3 +159,18 @@ func (r *UserRepo) GetActiveUsers(ctx context.Context) ([]*models.User, error) {
 	}
 	return result, nil
 }
+
+// maxIDsPerQuery bounds the number of bound parameters in one IN (...) query.
+const maxIDsPerQuery = 500
+
+// inClause builds "column IN ({:in0}, {:in1}, ...)" with one bound parameter per value.
+func inClause(column string, values []string) (string, dbx.Params) {
+	placeholders := make([]string, len(values))
+	params := make(dbx.Params, len(values))
+	for i, value := range values {
+		key := "in" + strconv.Itoa(i)
+		placeholders[i] = "{:" + key + "}"
+		params[key] = value
+	}
+	return column + " IN (" + strings.Join(placeholders, ", ") + ")", params
+}
@@ -3,6 +3,7 @@ package pocketbase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -142,6 +143,7 @@ func TestUserRepo_Update(t *testing.T) {
 			Email:       "updated@example.com",
 			FCMToken:    "new_fcm_token",
 			IsFCMActive: false,
+			Locale:      "en",
 		}
 
 		repo := &UserRepo{
@@ -153,6 +155,7 @@ func TestUserRepo_Update(t *testing.T) {
 					assert.Equal(t, user.Email, params["email"])
 					assert.Equal(t, user.FCMToken, params["fcm_token"])
 					assert.Equal(t, user.IsFCMActive, params["is_fcm_active"])
+					assert.Equal(t, "en", params["locale"])
 					assert.Equal(t, user.ID, params["id"])
 					return nil
 				},
@@ -273,6 +276,59 @@ func TestUserRepo_GetActiveUsers(t *testing.T) {
 	})
 }
 
+func TestUserRepo_GetByIDs(t *testing.T) {
+	t.Run("should load users in one query with bound IDs", func(t *testing.T) {
+		calls := 0
+		repo := &UserRepo{
+			helper: &MockDBHelper{
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					calls++
+					assert.Contains(t, query, "WHERE id IN ({:in0}, {:in1})")
+					assert.Equal(t, "user1", params["in0"])
+					assert.Equal(t, "user2", params["in1"])
+					return []dbx.NullStringMap{
+						mockUserRow("user1", "user1@example.com", "token1", true),
+					}, nil
+				},
+			},
+		}
+
+		result, err := repo.GetByIDs(context.Background(), []string{"user1", "user2"})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, "user1", result[0].ID)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should split large ID lists into chunks", func(t *testing.T) {
+		var sizes []int
+		repo := &UserRepo{
+			helper: &MockDBHelper{
+				GetAllRowsFn: func(query string, params dbx.Params) ([]dbx.NullStringMap, error) {
+					sizes = append(sizes, len(params))
+					return []dbx.NullStringMap{}, nil
+				},
+			},
+		}
+
+		ids := make([]string, maxIDsPerQuery+1)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("user%d", i)
+		}
+		_, err := repo.GetByIDs(context.Background(), ids)
+		require.NoError(t, err)
+		assert.Equal(t, []int{maxIDsPerQuery, 1}, sizes)
+	})
+
+	t.Run("should not query without IDs", func(t *testing.T) {
+		repo := &UserRepo{helper: &MockDBHelper{}}
+
+		result, err := repo.GetByIDs(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+}
+
 // Benchmark tests
 func BenchmarkUserRepo_GetByID(b *testing.B) {
 	repo := &UserRepo{
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+)
+
+// ErrInvalidAckEvent is returned for acknowledgement events other than received/opened.
+var ErrInvalidAckEvent = errors.New("ack event must be received or opened")
+
+// ErrDeliveryLogDisabled is returned when acknowledging without a delivery repository.
+var ErrDeliveryLogDisabled = errors.New("delivery log is not enabled")
+
+// AcknowledgeDelivery records that the device displayed ("received") or the user opened
+// ("opened") the notification tagged with deliveryID. userID (optional) limits the ack to
+// that recipient of a multicast. When opened, retry_until_complete reminders with
+// seen_retry_interval_sec back off their next retry. Returns sql.ErrNoRows if no sent
+// delivery matches.
+func (s *ReminderService) AcknowledgeDelivery(ctx context.Context, deliveryID, userID, event string) ([]*models.Delivery, error) {
+	if event != models.AckEventReceived && event != models.AckEventOpened {
+		return nil, ErrInvalidAckEvent
+	}
+	if s.deliveryRepo == nil {
+		return nil, ErrDeliveryLogDisabled
+	}
+
+	now := time.Now().UTC()
+	deliveries, err := s.deliveryRepo.Acknowledge(ctx, deliveryID, userID, event, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if event == models.AckEventOpened {
+		seen := make(map[string]bool)
+		for _, delivery := range deliveries {
+			if seen[delivery.ReminderID] {
+				continue
+			}
+			seen[delivery.ReminderID] = true
+			if err := s.backOffSeenRetry(ctx, delivery.ReminderID, now); err != nil {
+				log.Printf("ReminderService: failed to back off retry of reminder %s: %v", delivery.ReminderID, err)
+			}
+		}
+	}
+	return deliveries, nil
+}
+
+// backOffSeenRetry moves the next retry of an opened but not completed reminder to
+// seen_retry_interval_sec after it was opened. The retry is never brought forward.
+// The retry moves only while next_trigger_at is still the one read and no other instance
+// holds the lease, so a worker sending it concurrently wins. The read and the update run in
+// one transaction when a transactor is configured; the schedule observer learns the new
+// trigger time once it is committed.
+func (s *ReminderService) backOffSeenRetry(ctx context.Context, reminderID string, openedAt time.Time) error {
+	if s.leaseOwner != "" {
+		ctx = repository.WithLeaseOwner(ctx, s.leaseOwner)
+	}
+	var backedOff *models.Reminder
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		reminder, err := s.reminderRepo.GetByID(ctx, reminderID)
+		if err != nil {
+			return err
+		}
+		// User đã thấy thông báo: giãn nhịp nhắc lại thay vì nhắc dồn như khi chưa nhận được
+		if reminder.RepeatStrategy != models.RepeatStrategyRetryUntilComplete ||
+			reminder.Status != models.ReminderStatusActive ||
+			reminder.IsEscalating() ||
+			reminder.SeenRetryIntervalSec <= 0 {
+			return nil
+		}
+
+		next := openedAt.Add(time.Duration(reminder.SeenRetryIntervalSec) * time.Second)
+		if !next.After(reminder.NextTriggerAt) {
+			return nil
+		}
+		// Chỉ đổi lịch nếu worker chưa xử lý lần nhắc này và không có instance khác đang giữ lease
+		applied, err := s.reminderRepo.ApplyTransition(ctx, &models.ReminderTransition{
+			ReminderID:    reminder.ID,
+			PrevTriggerAt: reminder.NextTriggerAt,
+			NextTriggerAt: next,
+		})
+		if err != nil {
+			return err
+		}
+		if !applied {
+			log.Printf("ReminderService: reminder %s changed since %s, seen back off skipped",
+				reminder.ID, reminder.NextTriggerAt.Format(time.RFC3339))
+			return nil
+		}
+		reminder.NextTriggerAt = next
+		backedOff = reminder
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Chỉ báo scheduler sau khi commit, nếu không scheduler vẫn đánh thức theo giờ cũ
+	if backedOff != nil {
+		s.notifySchedule(backedOff)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+)
+
+func TestReminderService_AcknowledgeDelivery(t *testing.T) {
+	newService := func(opts ...ReminderServiceOption) (*ReminderService, *MockReminderRepository, *MockDeliveryRepository) {
+		reminderRepo := &MockReminderRepository{}
+		deliveryRepo := &MockDeliveryRepository{}
+		service := NewReminderService(reminderRepo, &MockUserRepository{}, &stubNotifier{},
+			NewScheduleCalculator(NewLunarCalendar()), append(opts, WithDeliveryRepo(deliveryRepo))...)
+		return service, reminderRepo, deliveryRepo
+	}
+	retryReminder := func(nextTrigger time.Time) *models.Reminder {
+		reminder := createTestReminder()
+		reminder.RepeatStrategy = models.RepeatStrategyRetryUntilComplete
+		reminder.RetryIntervalSec = 300
+		reminder.SeenRetryIntervalSec = 3600
+		reminder.NextTriggerAt = nextTrigger
+		return reminder
+	}
+
+	t.Run("should reject unknown events", func(t *testing.T) {
+		service, _, _ := newService()
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "", "clicked")
+
+		assert.ErrorIs(t, err, ErrInvalidAckEvent)
+	})
+
+	t.Run("should return ErrNoRows when nothing matches", func(t *testing.T) {
+		service, _, deliveryRepo := newService()
+		deliveryRepo.On("Acknowledge", mock.Anything, "missing", "", models.AckEventReceived, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{}, nil)
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "missing", "", models.AckEventReceived)
+
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("should not touch the schedule when only received", func(t *testing.T) {
+		service, reminderRepo, deliveryRepo := newService()
+		deliveryRepo.On("Acknowledge", mock.Anything, "dispatch-1", "", models.AckEventReceived, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{{ID: "d1", ReminderID: "test-id"}}, nil)
+
+		deliveries, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "", models.AckEventReceived)
+
+		require.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		reminderRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should back off retry once opened", func(t *testing.T) {
+		service, reminderRepo, deliveryRepo := newService()
+		reminder := retryReminder(time.Now().Add(5 * time.Minute))
+		deliveryRepo.On("Acknowledge", mock.Anything, "dispatch-1", "user-1", models.AckEventOpened, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{{ID: "d1", ReminderID: "test-id"}, {ID: "d2", ReminderID: "test-id"}}, nil)
+		reminderRepo.On("GetByID", mock.Anything, "test-id").Return(reminder, nil).Once()
+		reminderRepo.On("ApplyTransition", mock.Anything, mock.MatchedBy(func(tr *models.ReminderTransition) bool {
+			return tr.ReminderID == "test-id" && tr.PrevTriggerAt.Equal(reminder.NextTriggerAt) &&
+				tr.NextTriggerAt.Sub(time.Now()) > 59*time.Minute && tr.SentAt == nil && tr.CompletedAt == nil
+		})).Return(true, nil)
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "user-1", models.AckEventOpened)
+
+		require.NoError(t, err)
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should report the backed off retry to the schedule observer", func(t *testing.T) {
+		observer := &recordingObserver{}
+		service, reminderRepo, deliveryRepo := newService(WithScheduleObserver(observer))
+		reminder := retryReminder(time.Now().Add(5 * time.Minute))
+		deliveryRepo.On("Acknowledge", mock.Anything, "dispatch-1", "", models.AckEventOpened, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{{ID: "d1", ReminderID: "test-id"}}, nil)
+		reminderRepo.On("GetByID", mock.Anything, "test-id").Return(reminder, nil)
+		var next time.Time
+		reminderRepo.On("ApplyTransition", mock.Anything, mock.AnythingOfType("*models.ReminderTransition")).
+			Run(func(args mock.Arguments) { next = args.Get(1).(*models.ReminderTransition).NextTriggerAt }).Return(true, nil)
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "", models.AckEventOpened)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]time.Time{"test-id": next}, observer.events)
+	})
+
+	t.Run("should not report a failed back off", func(t *testing.T) {
+		observer := &recordingObserver{}
+		service, reminderRepo, deliveryRepo := newService(WithScheduleObserver(observer), WithTransactor(&stubTransactor{}))
+		deliveryRepo.On("Acknowledge", mock.Anything, "dispatch-1", "", models.AckEventOpened, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{{ID: "d1", ReminderID: "test-id"}}, nil)
+		reminderRepo.On("GetByID", mock.Anything, "test-id").Return(retryReminder(time.Now().Add(5*time.Minute)), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, mock.Anything).Return(false, errors.New("database is locked"))
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "", models.AckEventOpened)
+
+		require.NoError(t, err)
+		assert.Empty(t, observer.events)
+	})
+
+	t.Run("should not report a back off that lost the race", func(t *testing.T) {
+		observer := &recordingObserver{}
+		service, reminderRepo, deliveryRepo := newService(WithScheduleObserver(observer), WithLeasing("instance-1", time.Minute, 0))
+		deliveryRepo.On("Acknowledge", mock.Anything, "dispatch-1", "", models.AckEventOpened, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{{ID: "d1", ReminderID: "test-id"}}, nil)
+		reminderRepo.On("GetByID", mock.Anything, "test-id").Return(retryReminder(time.Now().Add(5*time.Minute)), nil)
+		reminderRepo.On("ApplyTransition", mock.MatchedBy(func(ctx context.Context) bool {
+			owner, ok := repository.LeaseOwner(ctx)
+			return ok && owner == "instance-1"
+		}), mock.AnythingOfType("*models.ReminderTransition")).Return(false, nil)
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "", models.AckEventOpened)
+
+		require.NoError(t, err)
+		reminderRepo.AssertExpectations(t)
+		assert.Empty(t, observer.events)
+	})
+
+	t.Run("should never bring a retry forward", func(t *testing.T) {
+		service, reminderRepo, deliveryRepo := newService()
+		reminder := retryReminder(time.Now().Add(2 * time.Hour))
+		deliveryRepo.On("Acknowledge", mock.Anything, "dispatch-1", "", models.AckEventOpened, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{{ID: "d1", ReminderID: "test-id"}}, nil)
+		reminderRepo.On("GetByID", mock.Anything, "test-id").Return(reminder, nil)
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "", models.AckEventOpened)
+
+		require.NoError(t, err)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should keep retry spacing without seen interval", func(t *testing.T) {
+		service, reminderRepo, deliveryRepo := newService()
+		reminder := retryReminder(time.Now().Add(5 * time.Minute))
+		reminder.SeenRetryIntervalSec = 0
+		deliveryRepo.On("Acknowledge", mock.Anything, "dispatch-1", "", models.AckEventOpened, mock.AnythingOfType("time.Time")).
+			Return([]*models.Delivery{{ID: "d1", ReminderID: "test-id"}}, nil)
+		reminderRepo.On("GetByID", mock.Anything, "test-id").Return(reminder, nil)
+
+		_, err := service.AcknowledgeDelivery(context.Background(), "dispatch-1", "", models.AckEventOpened)
+
+		require.NoError(t, err)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"remiaq/internal/models"
+)
+
+// ErrGroupsDisabled is returned for group reminders when no group repository is configured.
+var ErrGroupsDisabled = errors.New("group reminders are not enabled")
+
+// processBroadcast sends a reminder addressed to a list of users (multicast)
+// or a group (FCM topic), then advances it once for everyone.
+// Title/body are rendered in the owner's locale.
+func (s *ReminderService) processBroadcast(ctx context.Context, reminder *models.Reminder, owner *models.User, now time.Time) error {
+	if s.notifier != nil {
+		msg := &PushMessage{Data: map[string]string{"reminder_id": reminder.ID}}
+		if !applyDeliveryOptions(msg, reminder, now) {
+			return s.skipExpired(ctx, reminder, owner.ID, "", now)
+		}
+
+		// Giới hạn theo user tính cho chủ sở hữu; giới hạn chung tính theo số tin FCM
+		messages := 1
+		if reminder.AudienceType == models.AudienceUsers {
+			messages = len(reminder.AudienceUserIDs)
+		}
+		if err := s.throttle(ctx, owner.ID, "", []*models.Reminder{reminder}, messages); err != nil {
+			return err
+		}
+
+		msg.Title, msg.Body = reminder.Title, reminder.Description
+		if s.templates != nil {
+			msg.Title, msg.Body = s.templates.Render(ctx, reminder, owner, now)
+		}
+
+		var err error
+		switch reminder.AudienceType {
+		case models.AudienceGroup:
+			err = s.sendToGroup(ctx, reminder, owner, msg)
+		default:
+			err = s.sendToUsers(ctx, reminder, msg)
+		}
+		if err != nil {
+			return err
+		}
+
+		return s.advance(ctx, reminder, now, &now)
+	}
+
+	return s.advance(ctx, reminder, now, nil)
+}
+
+// sendToGroup publishes msg to the group's topic. The delivery is logged against the owner.
+func (s *ReminderService) sendToGroup(ctx context.Context, reminder *models.Reminder, owner *models.User, msg *PushMessage) error {
+	if s.groupRepo == nil {
+		return ErrGroupsDisabled
+	}
+	group, err := s.groupRepo.GetByID(ctx, reminder.GroupID)
+	if err != nil {
+		return fmt.Errorf("load group %s: %w", reminder.GroupID, err)
+	}
+
+	msg.Topic = group.Topic
+	msg.Data["group_id"] = group.ID
+	return s.send(ctx, owner, msg, []*models.Reminder{reminder})
+}
+
+// sendToUsers multicasts msg to the active tokens of the audience users.
+// Invalid tokens are disabled; the reminder stays due only if nobody received it
+// because of a provider error.
+func (s *ReminderService) sendToUsers(ctx context.Context, reminder *models.Reminder, msg *PushMessage) error {
+	users, err := s.userRepo.GetByIDs(ctx, reminder.AudienceUserIDs)
+	if err != nil {
+		return fmt.Errorf("load audience of reminder %s: %w", reminder.ID, err)
+	}
+	byID := make(map[string]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	recipients := make(map[string]string) // token -> user ID
+	var tokens []string
+	// Theo thứ tự audience_user_ids để multicast chia lô ổn định
+	for _, userID := range reminder.AudienceUserIDs {
+		user, ok := byID[userID]
+		if !ok {
+			log.Printf("ReminderService: skipping audience user %s of reminder %s: not found", userID, reminder.ID)
+			continue
+		}
+		if !user.IsFCMActive || user.FCMToken == "" {
+			continue
+		}
+		if _, dup := recipients[user.FCMToken]; dup {
+			continue
+		}
+		recipients[user.FCMToken] = user.ID
+		tokens = append(tokens, user.FCMToken)
+	}
+	if len(tokens) == 0 {
+		// Không ai nhận được: vẫn tiến lịch để không lặp lại mỗi tick
+		log.Printf("ReminderService: reminder %s has no active recipients", reminder.ID)
+		return nil
+	}
+
+	sent := 0
+	var lastErr error
+	for start := 0; start < len(tokens); start += maxMulticastTokens {
+		end := start + maxMulticastTokens
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+
+		dispatchID := newDispatchID(msg)
+		attempt := 0
+		var results []SendResult
+		// Chỉ gửi lại khi cả request lỗi tạm thời; lỗi từng token xử lý bên dưới
+		_, err := s.retryPolicy.Do(ctx, func(ctx context.Context) error {
+			attempt++
+			var sendErr error
+			results, sendErr = multicast(ctx, s.notifier, msg, tokens[start:end])
+			return sendErr
+		})
+		if err != nil {
+			for _, token := range tokens[start:end] {
+				s.recordDelivery(ctx, reminder, recipients[token], maskToken(token), dispatchID, attempt, "", err)
+			}
+			lastErr = err
+			continue
+		}
+
+		for _, result := range results {
+			userID := recipients[result.Token]
+			s.recordDelivery(ctx, reminder, userID, maskToken(result.Token), dispatchID, attempt, result.MessageID, result.Err)
+			if result.Err == nil {
+				sent++
+				continue
+			}
+			if isTokenInvalidError(result.Err) {
+				if disableErr := s.userRepo.DisableFCM(ctx, userID); disableErr != nil {
+					log.Printf("ReminderService: failed to disable FCM for user %s: %v", userID, disableErr)
+				}
+				continue
+			}
+			lastErr = result.Err
+		}
+	}
+
+	if sent == 0 && lastErr != nil {
+		return lastErr
+	}
+	return nil
+}
+
+// multicast sends msg to tokens with n's multicast support, or one Send per token otherwise.
+func multicast(ctx context.Context, n Notifier, msg *PushMessage, tokens []string) ([]SendResult, error) {
+	if sender, ok := n.(MulticastSender); ok {
+		return sender.Multicast(ctx, msg, tokens)
+	}
+
+	results := make([]SendResult, len(tokens))
+	for i, token := range tokens {
+		single := *msg
+		single.Token, single.Topic = token, ""
+		id, err := n.Send(ctx, &single)
+		results[i] = SendResult{Token: token, MessageID: id, Err: err}
+	}
+	return results, nil
+}
+
+// batchError returns the first error when every result failed, nil otherwise.
+func batchError(results []SendResult) error {
+	for _, result := range results {
+		if result.Err == nil {
+			return nil
+		}
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	return results[0].Err
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+)
+
+// stubMulticaster returns a fixed error per token from Multicast
+type stubMulticaster struct {
+	stubNotifier
+	tokenErrs map[string]error
+	batches   [][]string
+}
+
+func (s *stubMulticaster) Multicast(ctx context.Context, msg *PushMessage, tokens []string) ([]SendResult, error) {
+	s.batches = append(s.batches, tokens)
+	results := make([]SendResult, len(tokens))
+	for i, token := range tokens {
+		results[i] = SendResult{Token: token, Err: s.tokenErrs[token]}
+		if results[i].Err == nil {
+			results[i].MessageID = "msg-" + token
+		}
+	}
+	return results, nil
+}
+
+func broadcastReminder() *models.Reminder {
+	reminder := createTestReminder()
+	reminder.NextTriggerAt = time.Now().Add(-time.Minute)
+	reminder.AudienceType = models.AudienceUsers
+	reminder.AudienceUserIDs = []string{"user-2", "user-3"}
+	return reminder
+}
+
+func audienceUser(id, token string) *models.User {
+	return &models.User{ID: id, FCMToken: token, IsFCMActive: true}
+}
+
+func TestReminderService_ProcessDueReminders_Broadcast(t *testing.T) {
+	t.Run("should send to every active audience user", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminder := broadcastReminder()
+		reminder.AudienceUserIDs = []string{"user-2", "user-3", "user-4"}
+		inactive := audienceUser("user-4", "token-4")
+		inactive.IsFCMActive = false
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("GetByID", mock.Anything, "user-2").Return(audienceUser("user-2", "token-2"), nil)
+		userRepo.On("GetByID", mock.Anything, "user-3").Return(audienceUser("user-3", "token-3"), nil)
+		userRepo.On("GetByID", mock.Anything, "user-4").Return(inactive, nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition(reminder.ID, sentAndCompleted)).Return(true, nil)
+
+		require.NoError(t, service.ProcessDueReminders(context.Background()))
+
+		// Không có Multicast: gửi lần lượt từng token
+		assert.Equal(t, 2, notifier.calls)
+		assert.Equal(t, "token-3", notifier.last.Token)
+		assert.Equal(t, reminder.ID, notifier.last.Data["reminder_id"])
+		reminderRepo.AssertNumberOfCalls(t, "ApplyTransition", 1)
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should disable invalid tokens and still advance", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubMulticaster{tokenErrs: map[string]error{
+			"token-2": &FCMError{Class: FCMErrorTokenInvalid, Err: errors.New("UNREGISTERED")},
+		}}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminder := broadcastReminder()
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("GetByID", mock.Anything, "user-2").Return(audienceUser("user-2", "token-2"), nil)
+		userRepo.On("GetByID", mock.Anything, "user-3").Return(audienceUser("user-3", "token-3"), nil)
+		userRepo.On("DisableFCM", mock.Anything, "user-2").Return(nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition(reminder.ID, sentAndCompleted)).Return(true, nil)
+
+		require.NoError(t, service.ProcessDueReminders(context.Background()))
+
+		require.Len(t, notifier.batches, 1)
+		assert.Equal(t, []string{"token-2", "token-3"}, notifier.batches[0])
+		userRepo.AssertCalled(t, "DisableFCM", mock.Anything, "user-2")
+		userRepo.AssertNotCalled(t, "DisableFCM", mock.Anything, "user-3")
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should keep reminder due when nobody received it", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		quota := &FCMError{Class: FCMErrorQuotaExceeded, Err: errors.New("QUOTA_EXCEEDED")}
+		notifier := &stubMulticaster{tokenErrs: map[string]error{"token-2": quota, "token-3": quota}}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminder := broadcastReminder()
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("GetByID", mock.Anything, "user-2").Return(audienceUser("user-2", "token-2"), nil)
+		userRepo.On("GetByID", mock.Anything, "user-3").Return(audienceUser("user-3", "token-3"), nil)
+
+		_ = service.ProcessDueReminders(context.Background())
+
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should publish group reminders to the group topic", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		groupRepo := &MockGroupRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithGroupRepo(groupRepo))
+
+		reminder := broadcastReminder()
+		reminder.AudienceType = models.AudienceGroup
+		reminder.AudienceUserIDs = nil
+		reminder.GroupID = "g1"
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		groupRepo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition(reminder.ID, sentAndCompleted)).Return(true, nil)
+
+		require.NoError(t, service.ProcessDueReminders(context.Background()))
+
+		assert.Equal(t, 1, notifier.calls)
+		assert.Empty(t, notifier.last.Token)
+		assert.Equal(t, testGroup().Topic, notifier.last.Topic)
+		assert.Equal(t, "g1", notifier.last.Data["group_id"])
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should fail group reminders without group repository", func(t *testing.T) {
+		service := NewReminderService(&MockReminderRepository{}, &MockUserRepository{}, &stubNotifier{},
+			NewScheduleCalculator(NewLunarCalendar()))
+
+		reminder := broadcastReminder()
+		reminder.AudienceType = models.AudienceGroup
+		reminder.GroupID = "g1"
+
+		err := service.processBroadcast(context.Background(), reminder, createTestUser(), time.Now())
+
+		assert.ErrorIs(t, err, ErrGroupsDisabled)
+	})
+}
+
+func TestCircuitBreaker_Multicast(t *testing.T) {
+	systemErr := &FCMError{Class: FCMErrorSystem, Err: errors.New("THIRD_PARTY_AUTH_ERROR")}
+	msg := &PushMessage{Title: "t"}
+
+	t.Run("should count a batch where every token failed", func(t *testing.T) {
+		stub := &stubMulticaster{tokenErrs: map[string]error{"a": systemErr, "b": systemErr}}
+		cb, _, _ := newTestBreaker(stub, 1)
+
+		results, err := cb.Multicast(context.Background(), msg, []string{"a", "b"})
+
+		require.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, models.CircuitStateOpen, cb.State())
+
+		_, err = cb.Multicast(context.Background(), msg, []string{"a"})
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+	})
+
+	t.Run("should stay closed on partial success", func(t *testing.T) {
+		stub := &stubMulticaster{tokenErrs: map[string]error{"a": systemErr}}
+		cb, _, _ := newTestBreaker(stub, 1)
+
+		_, err := cb.Multicast(context.Background(), msg, []string{"a", "b"})
+
+		require.NoError(t, err)
+		assert.Equal(t, models.CircuitStateClosed, cb.State())
+	})
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"remiaq/internal/models"
+)
+
+// CircuitBreakerConfig configures when the breaker trips and how often it probes.
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // Số lỗi liên tiếp (system/unavailable) để mở mạch
+	OpenTimeout      time.Duration // Thời gian chờ trước mỗi lần probe khi mạch mở
+}
+
+// DefaultCircuitBreakerConfig returns the breaker settings used when none are configured.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      time.Minute,
+	}
+}
+
+// CircuitTransition describes a breaker state change.
+type CircuitTransition struct {
+	From   string
+	To     string
+	Reason string
+	At     time.Time
+}
+
+// CircuitBreaker wraps a Notifier with closed, open and half-open states.
+// While open, sends fail fast with ErrCircuitOpen and the provider is probed periodically.
+type CircuitBreaker struct {
+	notifier Notifier
+	cfg      CircuitBreakerConfig
+	onChange func(CircuitTransition)
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	override string // "", open, closed
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// Ensure implementation
+var (
+	_ Notifier        = (*CircuitBreaker)(nil)
+	_ MulticastSender = (*CircuitBreaker)(nil)
+)
+
+// NewCircuitBreaker creates a closed breaker around notifier.
+// onChange (optional) is called after every state transition, outside the breaker lock.
+func NewCircuitBreaker(notifier Notifier, cfg CircuitBreakerConfig, onChange func(CircuitTransition)) *CircuitBreaker {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultCircuitBreakerConfig().OpenTimeout
+	}
+	return &CircuitBreaker{
+		notifier: notifier,
+		cfg:      cfg,
+		onChange: onChange,
+		now:      time.Now,
+		state:    models.CircuitStateClosed,
+	}
+}
+
+// State returns the current breaker state.
+func (cb *CircuitBreaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// Send delivers msg through the wrapped notifier unless the circuit is open.
+func (cb *CircuitBreaker) Send(ctx context.Context, msg *PushMessage) (string, error) {
+	probe, err := cb.acquire()
+	if err != nil {
+		return "", err
+	}
+
+	id, err := cb.notifier.Send(ctx, msg)
+	cb.record(err, probe)
+	return id, err
+}
+
+// Multicast sends msg to tokens through the breaker. Only the request-level error
+// and a batch where every token failed count towards opening the circuit.
+func (cb *CircuitBreaker) Multicast(ctx context.Context, msg *PushMessage, tokens []string) ([]SendResult, error) {
+	probe, err := cb.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := multicast(ctx, cb.notifier, msg, tokens)
+	if err == nil {
+		err = batchError(results)
+	}
+	cb.record(err, probe)
+	if err != nil && results == nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Run probes the provider every OpenTimeout while the circuit is open so that it
+// closes automatically even when there is no traffic. It returns when ctx is cancelled.
+func (cb *CircuitBreaker) Run(ctx context.Context) {
+	prober, ok := cb.notifier.(Prober)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(cb.cfg.OpenTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !cb.startProbe() {
+				continue
+			}
+			cb.record(prober.Probe(ctx), true)
+		}
+	}
+}
+
+// ForceOpen blocks all sends until ForceClose or ResetOverride is called.
+func (cb *CircuitBreaker) ForceOpen(reason string) {
+	if reason == "" {
+		reason = "forced open by admin"
+	}
+	cb.mu.Lock()
+	cb.override = models.CircuitStateOpen
+	tr := cb.transitionLocked(models.CircuitStateOpen, reason)
+	cb.mu.Unlock()
+	cb.notify(tr)
+}
+
+// ForceClose lets all sends through regardless of failures until ResetOverride is called.
+func (cb *CircuitBreaker) ForceClose() {
+	cb.mu.Lock()
+	cb.override = models.CircuitStateClosed
+	cb.failures = 0
+	tr := cb.transitionLocked(models.CircuitStateClosed, "forced closed by admin")
+	cb.mu.Unlock()
+	cb.notify(tr)
+}
+
+// ResetOverride returns the breaker to automatic mode, starting closed.
+func (cb *CircuitBreaker) ResetOverride() {
+	cb.mu.Lock()
+	cb.override = ""
+	cb.failures = 0
+	tr := cb.transitionLocked(models.CircuitStateClosed, "override cleared by admin")
+	cb.mu.Unlock()
+	cb.notify(tr)
+}
+
+// acquire checks whether a send may proceed and whether it acts as the half-open probe.
+func (cb *CircuitBreaker) acquire() (bool, error) {
+	cb.mu.Lock()
+	probe, tr, err := cb.acquireLocked()
+	cb.mu.Unlock()
+	cb.notify(tr)
+	return probe, err
+}
+
+// acquireLocked implements acquire. Caller holds mu.
+func (cb *CircuitBreaker) acquireLocked() (bool, *CircuitTransition, error) {
+	blocked := &FCMError{Class: FCMErrorCircuitOpen, Err: ErrCircuitOpen}
+
+	switch cb.override {
+	case models.CircuitStateOpen:
+		return false, nil, blocked
+	case models.CircuitStateClosed:
+		return false, nil, nil
+	}
+
+	switch cb.state {
+	case models.CircuitStateClosed:
+		return false, nil, nil
+	case models.CircuitStateOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cfg.OpenTimeout {
+			return false, nil, blocked
+		}
+		// Hết thời gian chờ: cho đúng một request đi qua làm probe
+		tr := cb.transitionLocked(models.CircuitStateHalfOpen, "probing after open timeout")
+		cb.probing = true
+		return true, tr, nil
+	default: // half_open
+		if cb.probing {
+			return false, nil, blocked
+		}
+		cb.probing = true
+		return true, nil, nil
+	}
+}
+
+// startProbe moves an open breaker to half-open for a background probe. Returns false if no probe is due.
+func (cb *CircuitBreaker) startProbe() bool {
+	cb.mu.Lock()
+	if cb.override != "" || cb.state != models.CircuitStateOpen ||
+		cb.now().Sub(cb.openedAt) < cb.cfg.OpenTimeout {
+		cb.mu.Unlock()
+		return false
+	}
+	tr := cb.transitionLocked(models.CircuitStateHalfOpen, "probing after open timeout")
+	cb.probing = true
+	cb.mu.Unlock()
+	cb.notify(tr)
+	return true
+}
+
+// record updates failure counters and state after a send or probe.
+func (cb *CircuitBreaker) record(err error, probe bool) {
+	cb.mu.Lock()
+	var tr *CircuitTransition
+
+	if probe {
+		cb.probing = false
+	}
+
+	switch {
+	case err == nil || !countsAsFailure(err):
+		cb.failures = 0
+		if cb.override == "" && cb.state == models.CircuitStateHalfOpen {
+			tr = cb.transitionLocked(models.CircuitStateClosed, "probe succeeded")
+		}
+	case cb.override == "":
+		cb.failures++
+		if cb.state == models.CircuitStateHalfOpen {
+			tr = cb.transitionLocked(models.CircuitStateOpen, fmt.Sprintf("probe failed: %v", err))
+		} else if cb.state == models.CircuitStateClosed && cb.failures >= cb.cfg.FailureThreshold {
+			tr = cb.transitionLocked(models.CircuitStateOpen,
+				fmt.Sprintf("%d consecutive failures, last: %v", cb.failures, err))
+		}
+	}
+
+	cb.mu.Unlock()
+	cb.notify(tr)
+}
+
+// transitionLocked changes state and returns the transition, or nil if unchanged. Caller holds mu.
+func (cb *CircuitBreaker) transitionLocked(to, reason string) *CircuitTransition {
+	if cb.state == to {
+		return nil
+	}
+	now := cb.now()
+	if to == models.CircuitStateOpen {
+		cb.openedAt = now
+	}
+	tr := &CircuitTransition{From: cb.state, To: to, Reason: reason, At: now}
+	cb.state = to
+	return tr
+}
+
+// notify logs the transition and invokes the onChange hook.
+func (cb *CircuitBreaker) notify(tr *CircuitTransition) {
+	if tr == nil {
+		return
+	}
+	log.Printf("CircuitBreaker: %s -> %s (%s)", tr.From, tr.To, tr.Reason)
+	if cb.onChange != nil {
+		cb.onChange(*tr)
+	}
+}
+
+// countsAsFailure reports whether err indicates an unhealthy provider.
+// Token, message and quota errors mean FCM is reachable, and a cancelled ctx says nothing
+// about it, so they do not trip the breaker.
+func countsAsFailure(err error) bool {
+	switch ClassifyFCMError(err) {
+	case FCMErrorSystem, FCMErrorUnavailable:
+		return true
+	default:
+		return false
+	}
+}
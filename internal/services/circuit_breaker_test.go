@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+)
+
+// stubNotifier returns queued errors in order, then succeeds
+type stubNotifier struct {
+	mu       sync.Mutex
+	errs     []error
+	calls    int
+	probeErr error
+	last     *PushMessage
+}
+
+func (s *stubNotifier) Send(ctx context.Context, msg *PushMessage) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.last = msg
+	if len(s.errs) == 0 {
+		return "msg-id", nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return "", err
+}
+
+func (s *stubNotifier) Probe(ctx context.Context) error {
+	return s.probeErr
+}
+
+// newTestBreaker returns a breaker with a controllable clock and a transition log
+func newTestBreaker(n Notifier, threshold int) (*CircuitBreaker, *time.Time, *[]CircuitTransition) {
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+	var transitions []CircuitTransition
+	cb := NewCircuitBreaker(n, CircuitBreakerConfig{FailureThreshold: threshold, OpenTimeout: time.Minute},
+		func(tr CircuitTransition) { transitions = append(transitions, tr) })
+	cb.now = func() time.Time { return now }
+	return cb, &now, &transitions
+}
+
+func TestCircuitBreaker_Send(t *testing.T) {
+	systemErr := &FCMError{Class: FCMErrorSystem, Err: errors.New("THIRD_PARTY_AUTH_ERROR")}
+	msg := &PushMessage{Token: "token"}
+
+	t.Run("should open after threshold consecutive failures", func(t *testing.T) {
+		stub := &stubNotifier{errs: []error{systemErr, systemErr, systemErr}}
+		cb, _, transitions := newTestBreaker(stub, 3)
+
+		for i := 0; i < 3; i++ {
+			_, err := cb.Send(context.Background(), msg)
+			assert.ErrorIs(t, err, systemErr)
+		}
+
+		assert.Equal(t, models.CircuitStateOpen, cb.State())
+		require.Len(t, *transitions, 1)
+		assert.Equal(t, models.CircuitStateClosed, (*transitions)[0].From)
+		assert.Equal(t, models.CircuitStateOpen, (*transitions)[0].To)
+		assert.Contains(t, (*transitions)[0].Reason, "3 consecutive failures")
+	})
+
+	t.Run("should fail fast while open", func(t *testing.T) {
+		stub := &stubNotifier{errs: []error{systemErr}}
+		cb, _, _ := newTestBreaker(stub, 1)
+
+		_, _ = cb.Send(context.Background(), msg)
+		_, err := cb.Send(context.Background(), msg)
+
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, FCMErrorCircuitOpen, ClassifyFCMError(err))
+		assert.Equal(t, 1, stub.calls)
+	})
+
+	t.Run("should not count token, message, quota or cancellation errors", func(t *testing.T) {
+		tokenErr := &FCMError{Class: FCMErrorTokenInvalid, Err: errors.New("UNREGISTERED")}
+		quotaErr := &FCMError{Class: FCMErrorQuotaExceeded, Err: errors.New("QUOTA_EXCEEDED")}
+		messageErr := &FCMError{Class: FCMErrorInvalidMessage, Err: errors.New("INVALID_ARGUMENT")}
+		stub := &stubNotifier{errs: []error{tokenErr, quotaErr, messageErr, context.Canceled, context.DeadlineExceeded}}
+		cb, _, _ := newTestBreaker(stub, 2)
+
+		for i := 0; i < 5; i++ {
+			_, _ = cb.Send(context.Background(), msg)
+		}
+
+		assert.Equal(t, models.CircuitStateClosed, cb.State())
+	})
+
+	t.Run("should close after successful half-open probe", func(t *testing.T) {
+		stub := &stubNotifier{errs: []error{systemErr}}
+		cb, now, transitions := newTestBreaker(stub, 1)
+
+		_, _ = cb.Send(context.Background(), msg)
+		*now = now.Add(time.Minute)
+
+		id, err := cb.Send(context.Background(), msg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "msg-id", id)
+		assert.Equal(t, models.CircuitStateClosed, cb.State())
+		require.Len(t, *transitions, 3)
+		assert.Equal(t, models.CircuitStateHalfOpen, (*transitions)[1].To)
+		assert.Equal(t, models.CircuitStateClosed, (*transitions)[2].To)
+	})
+
+	t.Run("should reopen after failed half-open probe", func(t *testing.T) {
+		stub := &stubNotifier{errs: []error{systemErr, systemErr}}
+		cb, now, _ := newTestBreaker(stub, 1)
+
+		_, _ = cb.Send(context.Background(), msg)
+		*now = now.Add(time.Minute)
+		_, err := cb.Send(context.Background(), msg)
+
+		assert.ErrorIs(t, err, systemErr)
+		assert.Equal(t, models.CircuitStateOpen, cb.State())
+
+		// openedAt is reset, so the next send fails fast again
+		_, err = cb.Send(context.Background(), msg)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+	})
+}
+
+func TestCircuitBreaker_StartProbe(t *testing.T) {
+	systemErr := &FCMError{Class: FCMErrorSystem, Err: errors.New("internal")}
+
+	t.Run("should probe only after open timeout", func(t *testing.T) {
+		stub := &stubNotifier{errs: []error{systemErr}}
+		cb, now, _ := newTestBreaker(stub, 1)
+		_, _ = cb.Send(context.Background(), &PushMessage{Token: "token"})
+
+		assert.False(t, cb.startProbe())
+
+		*now = now.Add(time.Minute)
+		assert.True(t, cb.startProbe())
+		assert.Equal(t, models.CircuitStateHalfOpen, cb.State())
+
+		cb.record(stub.Probe(context.Background()), true)
+		assert.Equal(t, models.CircuitStateClosed, cb.State())
+	})
+
+	t.Run("should not probe when closed", func(t *testing.T) {
+		cb, _, _ := newTestBreaker(&stubNotifier{}, 1)
+		assert.False(t, cb.startProbe())
+	})
+}
+
+func TestCircuitBreaker_Overrides(t *testing.T) {
+	systemErr := &FCMError{Class: FCMErrorSystem, Err: errors.New("internal")}
+	msg := &PushMessage{Token: "token"}
+
+	t.Run("force open should block sends", func(t *testing.T) {
+		stub := &stubNotifier{}
+		cb, _, transitions := newTestBreaker(stub, 5)
+
+		cb.ForceOpen("maintenance")
+		_, err := cb.Send(context.Background(), msg)
+
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, 0, stub.calls)
+		require.Len(t, *transitions, 1)
+		assert.Equal(t, "maintenance", (*transitions)[0].Reason)
+	})
+
+	t.Run("force closed should ignore failures", func(t *testing.T) {
+		stub := &stubNotifier{errs: []error{systemErr, systemErr, systemErr}}
+		cb, _, _ := newTestBreaker(stub, 1)
+
+		cb.ForceClose()
+		for i := 0; i < 3; i++ {
+			_, err := cb.Send(context.Background(), msg)
+			assert.ErrorIs(t, err, systemErr)
+		}
+
+		assert.Equal(t, models.CircuitStateClosed, cb.State())
+		assert.Equal(t, 3, stub.calls)
+	})
+
+	t.Run("reset override should return to automatic mode", func(t *testing.T) {
+		stub := &stubNotifier{errs: []error{systemErr}}
+		cb, _, _ := newTestBreaker(stub, 1)
+
+		cb.ForceOpen("")
+		cb.ResetOverride()
+		assert.Equal(t, models.CircuitStateClosed, cb.State())
+
+		_, _ = cb.Send(context.Background(), msg)
+		assert.Equal(t, models.CircuitStateOpen, cb.State())
+	})
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"remiaq/internal/models"
+)
+
+// ErrDeliveryExpired is recorded when a reminder is older than its TTL and is not sent.
+var ErrDeliveryExpired = errors.New("reminder expired before it could be sent")
+
+// applyDeliveryOptions copies the reminder's delivery options into msg. The TTL counts
+// from the trigger time (the snooze end when snoozed), so a late send only gets what is
+// left of it. Returns false when nothing is left and the reminder must not be sent.
+func applyDeliveryOptions(msg *PushMessage, reminder *models.Reminder, now time.Time) bool {
+	// Nhắc lại (retry_until_complete) thay thế thông báo trước thay vì xếp chồng
+	if reminder.RepeatStrategy == models.RepeatStrategyRetryUntilComplete {
+		msg.CollapseKey = reminder.ID
+	}
+
+	opts := reminder.DeliveryOptions
+	if opts == nil {
+		return true
+	}
+	msg.Priority = opts.Priority
+	msg.Sound = opts.Sound
+	msg.ChannelID = opts.ChannelID
+	msg.Badge = opts.Badge
+	if opts.CollapseKey != "" {
+		msg.CollapseKey = opts.CollapseKey
+	}
+
+	if opts.TTLSeconds > 0 {
+		ttl := time.Duration(opts.TTLSeconds)*time.Second - now.Sub(reminder.TriggerAt())
+		if ttl <= 0 {
+			return false
+		}
+		ttl = ttl.Truncate(time.Second)
+		msg.TTL = &ttl
+	}
+	return true
+}
+
+// applyDigestOptions sets the delivery options of a digest from those of its reminders and
+// returns the reminders still worth sending next to the expired ones. The digest keeps the
+// longest TTL left (none if a reminder has none) so that no reminder expires early; the
+// other options apply only when every reminder has the same value, otherwise the default.
+func applyDigestOptions(msg *PushMessage, reminders []*models.Reminder, now time.Time) (live, expired []*models.Reminder) {
+	var msgs []*PushMessage
+	for _, reminder := range reminders {
+		m := &PushMessage{}
+		if !applyDeliveryOptions(m, reminder, now) {
+			expired = append(expired, reminder)
+			continue
+		}
+		live = append(live, reminder)
+		msgs = append(msgs, m)
+	}
+	if len(msgs) == 0 {
+		return live, expired
+	}
+
+	first := msgs[0]
+	msg.Priority, msg.TTL, msg.CollapseKey = first.Priority, first.TTL, first.CollapseKey
+	msg.Sound, msg.ChannelID, msg.Badge = first.Sound, first.ChannelID, first.Badge
+	for _, m := range msgs[1:] {
+		// Priority mặc định là high nên khác nhau thì lấy mặc định, không hạ xuống normal
+		if m.Priority != msg.Priority {
+			msg.Priority = ""
+		}
+		if m.TTL == nil || msg.TTL == nil {
+			msg.TTL = nil
+		} else if *m.TTL > *msg.TTL {
+			msg.TTL = m.TTL
+		}
+		if m.CollapseKey != msg.CollapseKey {
+			msg.CollapseKey = ""
+		}
+		if m.Sound != msg.Sound {
+			msg.Sound = ""
+		}
+		if m.ChannelID != msg.ChannelID {
+			msg.ChannelID = ""
+		}
+		if m.Badge == nil || msg.Badge == nil || *m.Badge != *msg.Badge {
+			msg.Badge = nil
+		}
+	}
+	return live, expired
+}
+
+// skipExpired records that an expired reminder was not sent and moves it to its next state.
+func (s *ReminderService) skipExpired(ctx context.Context, reminder *models.Reminder, userID, device string, now time.Time) error {
+	log.Printf("ReminderService: reminder %s triggered at %s expired (ttl %ds), skipping send",
+		reminder.ID, reminder.TriggerAt().Format(time.RFC3339), reminder.DeliveryOptions.TTLSeconds)
+	s.recordDelivery(ctx, reminder, userID, device, "", 0, "", ErrDeliveryExpired)
+	return s.advance(ctx, reminder, now, nil)
+}
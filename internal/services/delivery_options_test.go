@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+)
+
+func TestApplyDeliveryOptions(t *testing.T) {
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	t.Run("should keep defaults without options", func(t *testing.T) {
+		reminder := createTestReminder()
+		msg := &PushMessage{}
+
+		assert.True(t, applyDeliveryOptions(msg, reminder, now))
+		assert.Empty(t, msg.Priority)
+		assert.Empty(t, msg.CollapseKey)
+		assert.Nil(t, msg.TTL)
+	})
+
+	t.Run("should collapse retries of the same reminder", func(t *testing.T) {
+		reminder := createTestReminder()
+		reminder.RepeatStrategy = models.RepeatStrategyRetryUntilComplete
+		msg := &PushMessage{}
+
+		applyDeliveryOptions(msg, reminder, now)
+		assert.Equal(t, reminder.ID, msg.CollapseKey)
+
+		reminder.DeliveryOptions = &models.DeliveryOptions{CollapseKey: "pill"}
+		applyDeliveryOptions(msg, reminder, now)
+		assert.Equal(t, "pill", msg.CollapseKey)
+	})
+
+	t.Run("should copy options and shorten TTL by lateness", func(t *testing.T) {
+		badge := 3
+		reminder := createTestReminder()
+		reminder.NextTriggerAt = now.Add(-10 * time.Minute)
+		reminder.DeliveryOptions = &models.DeliveryOptions{
+			Priority:   models.PriorityNormal,
+			TTLSeconds: 3600,
+			Sound:      "chime.wav",
+			ChannelID:  "pills",
+			Badge:      &badge,
+		}
+		msg := &PushMessage{}
+
+		require.True(t, applyDeliveryOptions(msg, reminder, now))
+		assert.Equal(t, models.PriorityNormal, msg.Priority)
+		assert.Equal(t, "chime.wav", msg.Sound)
+		assert.Equal(t, "pills", msg.ChannelID)
+		assert.Equal(t, &badge, msg.Badge)
+		require.NotNil(t, msg.TTL)
+		assert.Equal(t, 50*time.Minute, *msg.TTL)
+	})
+
+	t.Run("should report expired reminders", func(t *testing.T) {
+		reminder := createTestReminder()
+		reminder.NextTriggerAt = now.Add(-2 * time.Hour)
+		reminder.DeliveryOptions = &models.DeliveryOptions{TTLSeconds: 3600}
+
+		assert.False(t, applyDeliveryOptions(&PushMessage{}, reminder, now))
+	})
+
+	t.Run("should count TTL from the snooze end", func(t *testing.T) {
+		snoozeUntil := now.Add(-5 * time.Minute)
+		reminder := createTestReminder()
+		reminder.NextTriggerAt = now.Add(-2 * time.Hour)
+		reminder.SnoozeUntil = &snoozeUntil
+		reminder.DeliveryOptions = &models.DeliveryOptions{TTLSeconds: 3600}
+		msg := &PushMessage{}
+
+		require.True(t, applyDeliveryOptions(msg, reminder, now))
+		require.NotNil(t, msg.TTL)
+		assert.Equal(t, 55*time.Minute, *msg.TTL)
+	})
+}
+
+func TestApplyDigestOptions(t *testing.T) {
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+	withOptions := func(id string, opts *models.DeliveryOptions) *models.Reminder {
+		r := createTestReminder()
+		r.ID = id
+		r.NextTriggerAt = now.Add(-10 * time.Minute)
+		r.DeliveryOptions = opts
+		return r
+	}
+
+	t.Run("should keep shared options and the longest TTL", func(t *testing.T) {
+		badge := 1
+		first := withOptions("rem-1", &models.DeliveryOptions{Priority: models.PriorityNormal, TTLSeconds: 1800, CollapseKey: "pills", ChannelID: "pills", Badge: &badge})
+		second := withOptions("rem-2", &models.DeliveryOptions{Priority: models.PriorityNormal, TTLSeconds: 3600, CollapseKey: "pills", ChannelID: "pills", Badge: &badge})
+		msg := &PushMessage{}
+
+		live, expired := applyDigestOptions(msg, []*models.Reminder{first, second}, now)
+
+		assert.Equal(t, []*models.Reminder{first, second}, live)
+		assert.Empty(t, expired)
+		assert.Equal(t, models.PriorityNormal, msg.Priority)
+		require.NotNil(t, msg.TTL)
+		assert.Equal(t, 50*time.Minute, *msg.TTL)
+		assert.Equal(t, "pills", msg.CollapseKey)
+		assert.Equal(t, "pills", msg.ChannelID)
+		assert.Equal(t, &badge, msg.Badge)
+	})
+
+	t.Run("should fall back to defaults when options differ", func(t *testing.T) {
+		first := withOptions("rem-1", &models.DeliveryOptions{Priority: models.PriorityNormal, TTLSeconds: 3600, Sound: "chime.wav"})
+		second := withOptions("rem-2", nil)
+		msg := &PushMessage{}
+
+		live, _ := applyDigestOptions(msg, []*models.Reminder{first, second}, now)
+
+		assert.Len(t, live, 2)
+		assert.Empty(t, msg.Priority)
+		assert.Nil(t, msg.TTL)
+		assert.Empty(t, msg.Sound)
+	})
+
+	t.Run("should leave out expired reminders", func(t *testing.T) {
+		fresh := withOptions("rem-1", &models.DeliveryOptions{TTLSeconds: 3600})
+		stale := withOptions("rem-2", &models.DeliveryOptions{TTLSeconds: 60})
+		msg := &PushMessage{}
+
+		live, expired := applyDigestOptions(msg, []*models.Reminder{fresh, stale}, now)
+
+		assert.Equal(t, []*models.Reminder{fresh}, live)
+		assert.Equal(t, []*models.Reminder{stale}, expired)
+		require.NotNil(t, msg.TTL)
+		assert.Equal(t, 50*time.Minute, *msg.TTL)
+	})
+}
+
+func TestBuildMessage_DeliveryOptions(t *testing.T) {
+	t.Run("should use high priority and default sound", func(t *testing.T) {
+		m := buildMessage(&PushMessage{Token: "token"})
+
+		assert.Equal(t, "high", m.Android.Priority)
+		assert.Nil(t, m.Android.TTL)
+		assert.Empty(t, m.Android.CollapseKey)
+		assert.Equal(t, "default", m.Android.Notification.Sound)
+		assert.Equal(t, "10", m.APNS.Headers["apns-priority"])
+		assert.NotContains(t, m.APNS.Headers, "apns-expiration")
+		assert.NotContains(t, m.APNS.Headers, "apns-collapse-id")
+		assert.Equal(t, "default", m.APNS.Payload.Aps.Sound)
+	})
+
+	t.Run("should map options to Android and APNs", func(t *testing.T) {
+		ttl := 10 * time.Minute
+		badge := 2
+		m := buildMessage(&PushMessage{
+			Token:       "token",
+			Priority:    models.PriorityNormal,
+			TTL:         &ttl,
+			CollapseKey: "rem-1",
+			Sound:       "chime.wav",
+			ChannelID:   "pills",
+			Badge:       &badge,
+		})
+
+		assert.Equal(t, "normal", m.Android.Priority)
+		assert.Equal(t, &ttl, m.Android.TTL)
+		assert.Equal(t, "rem-1", m.Android.CollapseKey)
+		assert.Equal(t, "chime.wav", m.Android.Notification.Sound)
+		assert.Equal(t, "pills", m.Android.Notification.ChannelID)
+		assert.Equal(t, &badge, m.Android.Notification.NotificationCount)
+
+		assert.Equal(t, "5", m.APNS.Headers["apns-priority"])
+		assert.Equal(t, "rem-1", m.APNS.Headers["apns-collapse-id"])
+		expiration, err := strconv.ParseInt(m.APNS.Headers["apns-expiration"], 10, 64)
+		require.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(ttl).Unix(), expiration, 2)
+		assert.Equal(t, "chime.wav", m.APNS.Payload.Aps.Sound)
+		assert.Equal(t, &badge, m.APNS.Payload.Aps.Badge)
+	})
+
+	t.Run("should not store APNs message with zero TTL", func(t *testing.T) {
+		ttl := time.Duration(0)
+		m := buildMessage(&PushMessage{Token: "token", TTL: &ttl})
+
+		assert.Equal(t, "0", m.APNS.Headers["apns-expiration"])
+	})
+}
+
+func TestReminderService_ProcessDueReminders_Expired(t *testing.T) {
+	reminderRepo := &MockReminderRepository{}
+	userRepo := &MockUserRepository{}
+	deliveryRepo := &MockDeliveryRepository{}
+	notifier := &stubNotifier{}
+	service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+		WithDeliveryRepo(deliveryRepo))
+
+	reminder := createTestReminder()
+	reminder.NextTriggerAt = time.Now().Add(-3 * time.Hour)
+	reminder.DeliveryOptions = &models.DeliveryOptions{TTLSeconds: 600}
+
+	var deliveries []*models.Delivery
+	reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+	userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+	reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", completedUnsent)).Return(true, nil)
+	deliveryRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Delivery")).
+		Run(func(args mock.Arguments) { deliveries = append(deliveries, args.Get(1).(*models.Delivery)) }).
+		Return(nil)
+
+	err := service.ProcessDueReminders(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, notifier.calls)
+	reminderRepo.AssertExpectations(t)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryOutcomeExpired, deliveries[0].Outcome)
+}
+
+func TestReminderService_ProcessDueReminders_DigestExpired(t *testing.T) {
+	reminderRepo := &MockReminderRepository{}
+	userRepo := &MockUserRepository{}
+	deliveryRepo := &MockDeliveryRepository{}
+	notifier := &stubNotifier{}
+	service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+		WithDeliveryRepo(deliveryRepo))
+
+	now := time.Now()
+	due := func(id string, ttl int) *models.Reminder {
+		r := createTestReminder()
+		r.ID = id
+		r.NextTriggerAt = now.Add(-time.Hour)
+		r.DeliveryOptions = &models.DeliveryOptions{TTLSeconds: ttl, CollapseKey: "pills"}
+		return r
+	}
+	user := createTestUser()
+	user.DigestEnabled = true
+
+	var deliveries []*models.Delivery
+	reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return([]*models.Reminder{due("rem-1", 7200), due("rem-2", 7200), due("rem-3", 600)}, nil)
+	userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil)
+	reminderRepo.On("ApplyTransition", mock.Anything, transition("rem-3", completedUnsent)).Return(true, nil)
+	reminderRepo.On("ApplyTransition", mock.Anything, transition("", sentAndCompleted)).Return(true, nil)
+	deliveryRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Delivery")).
+		Run(func(args mock.Arguments) { deliveries = append(deliveries, args.Get(1).(*models.Delivery)) }).
+		Return(nil)
+
+	err := service.ProcessDueReminders(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, notifier.calls)
+	assert.JSONEq(t, `["rem-1","rem-2"]`, notifier.last.Data["reminder_ids"])
+	assert.Equal(t, "pills", notifier.last.CollapseKey)
+	require.NotNil(t, notifier.last.TTL)
+	assert.InDelta(t, time.Hour, *notifier.last.TTL, float64(2*time.Second))
+	reminderRepo.AssertCalled(t, "ApplyTransition", mock.Anything, transition("rem-3", completedUnsent))
+	reminderRepo.AssertNumberOfCalls(t, "ApplyTransition", 3)
+
+	var expired int
+	for _, d := range deliveries {
+		if d.Outcome == models.DeliveryOutcomeExpired {
+			expired++
+			assert.Equal(t, "rem-3", d.ReminderID)
+		}
+	}
+	assert.Equal(t, 1, expired)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"remiaq/internal/models"
+)
+
+// maxDigestWindow caps how far ahead a digest may pull reminders that are not yet due.
+const maxDigestWindow = time.Hour
+
+// dueBatch is one unit of sending: a single reminder (possibly a broadcast), or several grouped into a digest.
+type dueBatch struct {
+	user      *models.User
+	reminders []*models.Reminder
+}
+
+// String describes the batch for logs.
+func (b dueBatch) String() string {
+	if len(b.reminders) == 1 {
+		return "reminder " + b.reminders[0].ID
+	}
+	return fmt.Sprintf("digest for user %s (%d reminders)", b.user.ID, len(b.reminders))
+}
+
+// groupByUser splits reminders per user, keeping the order in which users first appear.
+func groupByUser(reminders []*models.Reminder) [][]*models.Reminder {
+	index := make(map[string]int)
+	var groups [][]*models.Reminder
+	for _, reminder := range reminders {
+		i, ok := index[reminder.UserID]
+		if !ok {
+			i = len(groups)
+			index[reminder.UserID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], reminder)
+	}
+	return groups
+}
+
+// batchesForUser decides how the due reminders of user are sent.
+// With digest enabled, reminders due within the user's window are pulled in and
+// everything goes out as one notification; otherwise each reminder is its own batch.
+func (s *ReminderService) batchesForUser(ctx context.Context, user *models.User, group []*models.Reminder, now time.Time) []dueBatch {
+	// Reminder broadcast (nhiều user/nhóm) hoặc đang chuyển tiếp luôn gửi riêng, không gộp vào digest của chủ sở hữu
+	var batches []dueBatch
+	personal := group[:0:0]
+	for _, reminder := range group {
+		if reminder.IsBroadcast() || reminder.IsEscalating() {
+			batches = append(batches, dueBatch{user: user, reminders: []*models.Reminder{reminder}})
+		} else {
+			personal = append(personal, reminder)
+		}
+	}
+	group = personal
+
+	if user.DigestEnabled && len(group) > 0 {
+		group = s.withDigestWindow(ctx, user, group, now)
+		if len(group) > 1 {
+			return append(batches, dueBatch{user: user, reminders: group})
+		}
+	}
+
+	for _, reminder := range group {
+		batches = append(batches, dueBatch{user: user, reminders: []*models.Reminder{reminder}})
+	}
+	return batches
+}
+
+// withDigestWindow adds the user's reminders that become due within digest_window_sec.
+// With leasing, they are claimed for this instance first, like the due ones.
+func (s *ReminderService) withDigestWindow(ctx context.Context, user *models.User, group []*models.Reminder, now time.Time) []*models.Reminder {
+	window := time.Duration(user.DigestWindowSec) * time.Second
+	if window <= 0 {
+		return group
+	}
+	if window > maxDigestWindow {
+		window = maxDigestWindow
+	}
+
+	var upcoming []*models.Reminder
+	var err error
+	if s.leaseOwner == "" {
+		upcoming, err = s.reminderRepo.GetDueRemindersByUser(ctx, user.ID, now.Add(window))
+	} else {
+		// Reminder kéo sớm chưa được nhận lease trong tick: nhận trước khi gộp, tránh instance khác gửi trùng
+		upcoming, err = s.reminderRepo.ClaimDueRemindersByUser(ctx, s.leaseOwner, user.ID, now.Add(window), time.Now().Add(s.leaseTTL))
+	}
+	if err != nil {
+		// Không lấy được thì vẫn gửi digest với các reminder đã due
+		log.Printf("ReminderService: failed to load digest window for user %s: %v", user.ID, err)
+		return group
+	}
+
+	seen := make(map[string]bool, len(group))
+	for _, reminder := range group {
+		seen[reminder.ID] = true
+	}
+	for _, reminder := range upcoming {
+		if !seen[reminder.ID] && !reminder.IsBroadcast() && !reminder.IsEscalating() {
+			seen[reminder.ID] = true
+			group = append(group, reminder)
+		}
+	}
+	return group
+}
+
+// processDigest sends one summary notification for reminders and advances each of them.
+// The data payload carries the reminder IDs as a JSON array in "reminder_ids". Reminders
+// past their TTL are skipped as when sent alone and left out of the digest.
+func (s *ReminderService) processDigest(ctx context.Context, user *models.User, reminders []*models.Reminder, now time.Time) error {
+	if !user.IsFCMActive || user.FCMToken == "" {
+		return ErrUserFCMInactive
+	}
+
+	if s.notifier != nil {
+		msg := &PushMessage{Token: user.FCMToken}
+		live, expired := applyDigestOptions(msg, reminders, now)
+		for _, reminder := range expired {
+			if err := s.skipExpired(ctx, reminder, user.ID, maskToken(user.FCMToken), now); err != nil {
+				log.Printf("ReminderService: failed to skip expired reminder %s in digest: %v", reminder.ID, err)
+			}
+		}
+		// Chỉ gửi digest cho các reminder còn hạn
+		reminders = live
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		if err := s.throttle(ctx, user.ID, maskToken(user.FCMToken), reminders, 1); err != nil {
+			return err
+		}
+
+		renderer := s.templates
+		if renderer == nil {
+			renderer = NewTemplateRenderer(nil, nil)
+		}
+		msg.Title, msg.Body = renderer.RenderDigest(ctx, reminders, user)
+
+		ids := make([]string, len(reminders))
+		for i, reminder := range reminders {
+			ids[i] = reminder.ID
+		}
+		idsJSON, _ := json.Marshal(ids)
+
+		msg.Data = map[string]string{
+			"type":         "digest",
+			"reminder_ids": string(idsJSON),
+		}
+		if err := s.send(ctx, user, msg, reminders); err != nil {
+			return err
+		}
+	}
+
+	// Đã gửi: cập nhật từng reminder như khi gửi riêng lẻ
+	for _, reminder := range reminders {
+		// Reminder kéo sớm trong cửa sổ digest được tính như gửi đúng giờ của nó,
+		// tránh lịch lặp tính ra đúng mốc cũ và gửi lại
+		at := now
+		if reminder.NextTriggerAt.After(now) {
+			at = reminder.NextTriggerAt
+		}
+		var sentAt *time.Time
+		if s.notifier != nil {
+			sentAt = &at
+		}
+		if err := s.advance(ctx, reminder, at, sentAt); err != nil {
+			log.Printf("ReminderService: failed to advance reminder %s after digest: %v", reminder.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSMTPMailer(t *testing.T) {
+	t.Run("should accept sender with display name", func(t *testing.T) {
+		mailer, err := NewSMTPMailer("smtp.example.com", 587, "user", "secret", "RemiaQ <noreply@example.com>")
+
+		require.NoError(t, err)
+		assert.Equal(t, "smtp.example.com:587", mailer.addr)
+		assert.Equal(t, "noreply@example.com", mailer.from.Address)
+		assert.NotNil(t, mailer.auth)
+	})
+
+	t.Run("should reject invalid sender", func(t *testing.T) {
+		_, err := NewSMTPMailer("smtp.example.com", 587, "", "", "remiaq")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject invalid recipient before connecting", func(t *testing.T) {
+		mailer, err := NewSMTPMailer("127.0.0.1", 1, "", "", "noreply@example.com")
+		require.NoError(t, err)
+
+		err = mailer.SendEmail(context.Background(), "not an address", "s", "b")
+
+		assert.ErrorContains(t, err, "invalid recipient")
+	})
+}
+
+// fakeSMTPServer accepts one SMTP session on a local port and sends the DATA it receives
+// on the returned channel. With stall set it accepts the connection and never answers.
+func fakeSMTPServer(t *testing.T, stall bool) (host string, port int, received <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if stall {
+			// Giữ kết nối, không trả lời cho đến khi client đóng
+			conn.Read(make([]byte, 1))
+			return
+		}
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO":
+				tp.PrintfLine("250 localhost")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				body, _ := tp.ReadDotBytes()
+				data <- string(body)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, data
+}
+
+func TestSMTPMailer_SendEmail(t *testing.T) {
+	t.Run("should deliver the message", func(t *testing.T) {
+		host, port, received := fakeSMTPServer(t, false)
+		mailer, err := NewSMTPMailer(host, port, "", "", "noreply@example.com")
+		require.NoError(t, err)
+
+		require.NoError(t, mailer.SendEmail(context.Background(), "son@example.com", "Nhắc việc", "Uống thuốc"))
+
+		select {
+		case body := <-received:
+			assert.Contains(t, body, "To: <son@example.com>")
+			assert.Contains(t, body, "Uống thuốc")
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+	})
+
+	t.Run("should stop at the ctx deadline when the server doesn't answer", func(t *testing.T) {
+		host, port, _ := fakeSMTPServer(t, true)
+		mailer, err := NewSMTPMailer(host, port, "", "", "noreply@example.com")
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err = mailer.SendEmail(ctx, "son@example.com", "s", "b")
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("should stop when ctx is cancelled", func(t *testing.T) {
+		host, port, _ := fakeSMTPServer(t, true)
+		mailer, err := NewSMTPMailer(host, port, "", "", "noreply@example.com")
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		err = mailer.SendEmail(ctx, "son@example.com", "s", "b")
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestBuildEmail(t *testing.T) {
+	from := &mail.Address{Name: "RemiaQ", Address: "noreply@example.com"}
+	to := &mail.Address{Address: "son@example.com"}
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	msg := string(buildEmail(from, to, "Chưa hoàn thành: Uống thuốc\r\nBcc: x@example.com", "Dòng 1\nDòng 2", now))
+
+	headers, body, found := strings.Cut(msg, "\r\n\r\n")
+	require.True(t, found)
+	assert.Contains(t, headers, "To: <son@example.com>")
+	assert.Contains(t, headers, "Subject: =?utf-8?q?")
+	assert.NotContains(t, headers, "\r\nBcc:")
+	assert.Contains(t, headers, "Content-Type: text/plain; charset=UTF-8")
+	assert.Equal(t, "Dòng 1\r\nDòng 2\r\n", body)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"remiaq/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrEmailDisabled is returned for email escalation steps when no email sender is configured.
+var ErrEmailDisabled = errors.New("email escalation is not enabled")
+
+// startEscalation hands a reminder whose retries ran out to its escalation policy.
+// The first step becomes due after its delay; the reminder stays active until the
+// chain ends or the owner completes it.
+func (s *ReminderService) startEscalation(reminder *models.Reminder, now time.Time, t *models.ReminderTransition) {
+	log.Printf("ReminderService: reminder %s not completed after %d retries, escalating", reminder.ID, reminder.RetryCount)
+	scheduleEscalationStep(reminder, 1, now, t)
+}
+
+// scheduleEscalationStep sets t to make step (1-based) of the reminder's policy due after
+// its delay, or to complete the reminder when no steps are left.
+func scheduleEscalationStep(reminder *models.Reminder, step int, now time.Time, t *models.ReminderTransition) {
+	if !reminder.EscalationPolicy.HasSteps() || step > len(reminder.EscalationPolicy.Steps) {
+		t.CompletedAt = &now
+		return
+	}
+	delay := time.Duration(reminder.EscalationPolicy.Steps[step-1].DelaySeconds) * time.Second
+	t.EscalationStep = &step
+	t.NextTriggerAt = now.Add(delay)
+}
+
+// processEscalation runs the pending escalation step of reminder and schedules the next one.
+// Transient failures (rate limits, FCM outages) keep the step due for the next tick; any other
+// failure is logged and the chain moves on, so one unreachable contact doesn't stop the rest.
+func (s *ReminderService) processEscalation(ctx context.Context, reminder *models.Reminder, owner *models.User, now time.Time) error {
+	t := &models.ReminderTransition{ReminderID: reminder.ID, PrevTriggerAt: reminder.NextTriggerAt}
+	if !reminder.EscalationPolicy.HasSteps() || reminder.EscalationStep > len(reminder.EscalationPolicy.Steps) {
+		// Policy đã bị sửa ngắn lại trong lúc đang chuyển tiếp: coi như hết chuỗi
+		t.CompletedAt = &now
+		return s.applyTransition(ctx, t)
+	}
+
+	step := reminder.EscalationPolicy.Steps[reminder.EscalationStep-1]
+	var err error
+	switch step.Channel {
+	case models.EscalationChannelPush:
+		err = s.escalatePush(ctx, reminder, owner, step, now)
+	case models.EscalationChannelEmail:
+		err = s.escalateEmail(ctx, reminder, owner, step, now)
+	default:
+		err = fmt.Errorf("unknown escalation channel %q", step.Channel)
+	}
+	if err != nil {
+		if isTransientSendError(err) {
+			return err
+		}
+		log.Printf("ReminderService: escalation step %d of reminder %s failed: %v", reminder.EscalationStep, reminder.ID, err)
+	}
+
+	scheduleEscalationStep(reminder, reminder.EscalationStep+1, now, t)
+	return s.applyTransition(ctx, t)
+}
+
+// escalatePush notifies the step's contact user on their device, in the contact's locale.
+func (s *ReminderService) escalatePush(ctx context.Context, reminder *models.Reminder, owner *models.User, step models.EscalationStep, now time.Time) error {
+	if s.notifier == nil {
+		return nil
+	}
+	contact, err := s.userRepo.GetByID(ctx, step.UserID)
+	if err != nil {
+		return fmt.Errorf("load escalation contact %s: %w", step.UserID, err)
+	}
+	if !contact.IsFCMActive || contact.FCMToken == "" {
+		return ErrUserFCMInactive
+	}
+
+	if err := s.throttle(ctx, contact.ID, maskToken(contact.FCMToken), []*models.Reminder{reminder}, 1); err != nil {
+		return err
+	}
+
+	msg := &PushMessage{
+		Token: contact.FCMToken,
+		Data: map[string]string{
+			"type":        "escalation",
+			"reminder_id": reminder.ID,
+			"owner_id":    owner.ID,
+		},
+	}
+	msg.Title, msg.Body = s.escalationRenderer().RenderEscalation(ctx, reminder, owner, contact.Locale, now)
+	return s.send(ctx, contact, msg, []*models.Reminder{reminder})
+}
+
+// escalateEmail emails the step's address in the owner's locale. The delivery is logged against the owner.
+func (s *ReminderService) escalateEmail(ctx context.Context, reminder *models.Reminder, owner *models.User, step models.EscalationStep, now time.Time) error {
+	if s.mailer == nil {
+		return ErrEmailDisabled
+	}
+
+	subject, body := s.escalationRenderer().RenderEscalation(ctx, reminder, owner, owner.Locale, now)
+	err := s.mailer.SendEmail(ctx, step.Email, subject, body)
+	s.recordEmailDelivery(ctx, reminder, owner.ID, step.Email, err)
+	return err
+}
+
+// escalationRenderer returns the configured renderer, or one with built-in templates only.
+func (s *ReminderService) escalationRenderer() *TemplateRenderer {
+	if s.templates != nil {
+		return s.templates
+	}
+	return NewTemplateRenderer(nil, nil)
+}
+
+// recordEmailDelivery writes the delivery log entry of an escalation email.
+func (s *ReminderService) recordEmailDelivery(ctx context.Context, reminder *models.Reminder, userID, to string, sendErr error) {
+	if s.deliveryRepo == nil {
+		return
+	}
+
+	delivery := &models.Delivery{
+		ID:           uuid.New().String(),
+		ReminderID:   reminder.ID,
+		UserID:       userID,
+		Channel:      models.DeliveryChannelEmail,
+		Device:       maskEmail(to),
+		ScheduledFor: reminder.NextTriggerAt,
+		Attempt:      1,
+	}
+	if sendErr == nil {
+		sentAt := time.Now().UTC()
+		delivery.SentAt = &sentAt
+		delivery.Outcome = models.DeliveryOutcomeSent
+	} else {
+		delivery.Outcome = models.DeliveryOutcomeFailed
+		delivery.ErrorClass = models.DeliveryErrorEmail
+		delivery.ErrorMessage = sendErr.Error()
+	}
+
+	// Email đã gửi thì phải ghi nhận, kể cả khi tick bị hủy (tắt server)
+	if err := s.deliveryRepo.Create(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("ReminderService: failed to record email delivery for reminder %s: %v", reminder.ID, err)
+	}
+}
+
+// isTransientSendError reports whether a failed send is worth repeating on the next tick.
+func isTransientSendError(err error) bool {
+	var limitErr *RateLimitError
+	if errors.As(err, &limitErr) {
+		return true
+	}
+	var fcmErr *FCMError
+	return errors.As(err, &fcmErr) && fcmErr.Class != FCMErrorTokenInvalid && fcmErr.Class != FCMErrorInvalidMessage
+}
+
+// maskEmail keeps the first characters of the local part and the domain, e.g. "jo***@example.com".
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return maskToken(email)
+	}
+	local := email[:at]
+	if len(local) > 2 {
+		local = local[:2]
+	}
+	return local + "***" + email[at:]
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+)
+
+// stubMailer records sent emails and fails with err when set
+type stubMailer struct {
+	sent []string // địa chỉ nhận theo thứ tự gửi
+	subj string
+	body string
+	err  error
+}
+
+func (m *stubMailer) SendEmail(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, to)
+	m.subj, m.body = subject, body
+	return m.err
+}
+
+func TestReminderService_Escalation(t *testing.T) {
+	policy := &models.EscalationPolicy{Steps: []models.EscalationStep{
+		{DelaySeconds: 600, Channel: models.EscalationChannelPush, UserID: "family-1"},
+		{DelaySeconds: 1800, Channel: models.EscalationChannelEmail, Email: "son@example.com"},
+	}}
+	escalatingReminder := func(step int) *models.Reminder {
+		reminder := createTestReminder()
+		reminder.NextTriggerAt = time.Now().Add(-time.Minute)
+		reminder.RepeatStrategy = models.RepeatStrategyRetryUntilComplete
+		reminder.RetryIntervalSec = 300
+		reminder.MaxRetries = 3
+		reminder.RetryCount = 3
+		reminder.EscalationPolicy = policy
+		reminder.EscalationStep = step
+		return reminder
+	}
+	familyUser := &models.User{ID: "family-1", Email: "family@example.com", FCMToken: "family-token", IsFCMActive: true, Locale: models.LocaleEN}
+
+	t.Run("should start escalation when retries run out", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		service := NewReminderService(reminderRepo, userRepo, &stubNotifier{}, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{escalatingReminder(0)}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", func(tr *models.ReminderTransition) bool {
+			return tr.SentAt != nil && escalatesTo(tr, 1) && tr.NextTriggerAt.Sub(time.Now()) > 9*time.Minute
+		})).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should push to contact and schedule next step", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{escalatingReminder(1)}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("GetByID", mock.Anything, "family-1").Return(familyUser, nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", func(tr *models.ReminderTransition) bool {
+			return escalatesTo(tr, 2) && tr.CompletedAt == nil
+		})).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		require.NotNil(t, notifier.last)
+		assert.Equal(t, "family-token", notifier.last.Token)
+		assert.Equal(t, "Not completed: Test Reminder", notifier.last.Title)
+		assert.Contains(t, notifier.last.Body, "test@example.com")
+		assert.Equal(t, "escalation", notifier.last.Data["type"])
+		assert.Equal(t, "user-1", notifier.last.Data["owner_id"])
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should email on last step and complete the reminder", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		deliveryRepo := &MockDeliveryRepository{}
+		mailer := &stubMailer{}
+		service := NewReminderService(reminderRepo, userRepo, &stubNotifier{}, NewScheduleCalculator(NewLunarCalendar()),
+			WithEmailSender(mailer), WithDeliveryRepo(deliveryRepo))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{escalatingReminder(2)}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", completedUnsent)).Return(true, nil)
+		deliveryRepo.On("Create", mock.Anything, mock.MatchedBy(func(d *models.Delivery) bool {
+			return d.Channel == models.DeliveryChannelEmail && d.Outcome == models.DeliveryOutcomeSent && d.Device == "so***@example.com"
+		})).Return(nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"son@example.com"}, mailer.sent)
+		assert.Equal(t, "Chưa hoàn thành: Test Reminder", mailer.subj)
+		reminderRepo.AssertExpectations(t)
+		deliveryRepo.AssertExpectations(t)
+	})
+
+	t.Run("should record the email with a live context after shutdown", func(t *testing.T) {
+		deliveryRepo := &MockDeliveryRepository{}
+		service := NewReminderService(&MockReminderRepository{}, &MockUserRepository{}, &stubNotifier{},
+			NewScheduleCalculator(NewLunarCalendar()), WithEmailSender(&stubMailer{}), WithDeliveryRepo(deliveryRepo))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		deliveryRepo.On("Create", mock.MatchedBy(func(ctx context.Context) bool {
+			return ctx.Err() == nil
+		}), mock.AnythingOfType("*models.Delivery")).Return(nil)
+
+		service.recordEmailDelivery(ctx, escalatingReminder(2), "user-1", "son@example.com", nil)
+
+		deliveryRepo.AssertExpectations(t)
+	})
+
+	t.Run("should skip unreachable contact", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		inactive := *familyUser
+		inactive.IsFCMActive = false
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{escalatingReminder(1)}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("GetByID", mock.Anything, "family-1").Return(&inactive, nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", func(tr *models.ReminderTransition) bool {
+			return escalatesTo(tr, 2) && tr.CompletedAt == nil
+		})).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, notifier.calls)
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should keep step due on transient FCM error", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{errs: []error{&FCMError{Class: FCMErrorUnavailable, Err: errors.New("503")}}}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{escalatingReminder(1)}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("GetByID", mock.Anything, "family-1").Return(familyUser, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should complete when email is not configured", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		service := NewReminderService(reminderRepo, userRepo, &stubNotifier{}, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{escalatingReminder(2)}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", completedUnsent)).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		reminderRepo.AssertExpectations(t)
+	})
+}
+
+func escalatesTo(tr *models.ReminderTransition, step int) bool {
+	return tr.EscalationStep != nil && *tr.EscalationStep == step
+}
+
+func TestMaskEmail(t *testing.T) {
+	assert.Equal(t, "jo***@example.com", maskEmail("john@example.com"))
+	assert.Equal(t, "a***@example.com", maskEmail("a@example.com"))
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/messaging"
+)
+
+// FCMErrorClass categorizes an FCM send failure by how the caller should react.
+type FCMErrorClass string
+
+const (
+	// FCMErrorTokenInvalid: token hết hạn/không hợp lệ → vô hiệu hóa token của user.
+	FCMErrorTokenInvalid FCMErrorClass = "token_invalid"
+	// FCMErrorInvalidMessage: FCM từ chối nội dung message (INVALID_ARGUMENT) → không gửi lại,
+	// token vẫn giữ nguyên.
+	FCMErrorInvalidMessage FCMErrorClass = "invalid_message"
+	// FCMErrorQuotaExceeded: vượt quota FCM → lùi lại (back off), thử ở tick sau.
+	FCMErrorQuotaExceeded FCMErrorClass = "quota_exceeded"
+	// FCMErrorUnavailable: lỗi tạm thời (503, 500, timeout, mạng) → gửi lại.
+	FCMErrorUnavailable FCMErrorClass = "unavailable"
+	// FCMErrorSystem: lỗi cấu hình/xác thực hoặc không xác định → ngắt mạch (circuit breaker).
+	FCMErrorSystem FCMErrorClass = "system"
+	// FCMErrorCircuitOpen: circuit breaker đang mở, không gửi → giữ reminder cho tick sau.
+	FCMErrorCircuitOpen FCMErrorClass = "circuit_open"
+	// FCMErrorCanceled: ctx của lần gửi bị hủy/hết hạn (shutdown, tick quá lâu) → không phải
+	// lỗi của FCM, giữ reminder cho tick sau.
+	FCMErrorCanceled FCMErrorClass = "canceled"
+)
+
+// ErrUserFCMInactive is returned when the target user has no active FCM token.
+var ErrUserFCMInactive = errors.New("user FCM not active")
+
+// ErrCircuitOpen is returned by CircuitBreaker while sends are blocked.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrSystemFCM is returned by ProcessDueReminders when a system-level FCM failure occurred.
+var ErrSystemFCM = errors.New("system_fcm_error")
+
+// FCMError wraps an FCM send error together with its class.
+type FCMError struct {
+	Class FCMErrorClass
+	Err   error
+}
+
+func (e *FCMError) Error() string {
+	return string(e.Class) + ": " + e.Err.Error()
+}
+
+func (e *FCMError) Unwrap() error {
+	return e.Err
+}
+
+// newFCMError classifies err and wraps it as *FCMError. Returns nil for nil err.
+func newFCMError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var fcmErr *FCMError
+	if errors.As(err, &fcmErr) {
+		return err
+	}
+	return &FCMError{Class: ClassifyFCMError(err), Err: err}
+}
+
+// ClassifyFCMError maps an error returned by the messaging SDK to an FCMErrorClass.
+func ClassifyFCMError(err error) FCMErrorClass {
+	var fcmErr *FCMError
+	if errors.As(err, &fcmErr) {
+		return fcmErr.Class
+	}
+
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return FCMErrorCircuitOpen
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return FCMErrorCanceled
+	case errors.Is(err, ErrUserFCMInactive),
+		messaging.IsUnregistered(err),
+		messaging.IsSenderIDMismatch(err),
+		errorutils.IsNotFound(err):
+		return FCMErrorTokenInvalid
+	case messaging.IsInvalidArgument(err):
+		return FCMErrorInvalidMessage
+	case messaging.IsQuotaExceeded(err),
+		errorutils.IsResourceExhausted(err):
+		return FCMErrorQuotaExceeded
+	case messaging.IsUnavailable(err),
+		messaging.IsInternal(err),
+		errorutils.IsUnavailable(err),
+		errorutils.IsInternal(err),
+		errorutils.IsDeadlineExceeded(err),
+		isNetworkError(err):
+		return FCMErrorUnavailable
+	default:
+		// THIRD_PARTY_AUTH_ERROR, 401/403 và lỗi lạ đều là lỗi hệ thống
+		return FCMErrorSystem
+	}
+}
+
+// isNetworkError reports whether err is a transport-level network failure.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isTokenInvalidError checks if the error means the device token must be disabled.
+func isTokenInvalidError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return ClassifyFCMError(err) == FCMErrorTokenInvalid
+}
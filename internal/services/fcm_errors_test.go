@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+// fcmErrorBody builds an FCM HTTP v1 error response body.
+func fcmErrorBody(code int, status, fcmCode string) []byte {
+	body := map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": fmt.Sprintf("%s error", status),
+			"status":  status,
+			"details": []map[string]string{
+				{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": fcmCode},
+			},
+		},
+	}
+	data, _ := json.Marshal(body)
+	return data
+}
+
+// newTestFCMService creates an FCMService backed by an httptest server.
+func newTestFCMService(t *testing.T, handler http.HandlerFunc) *FCMService {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: "test-project"},
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	require.NoError(t, err)
+	client, err := app.Messaging(ctx)
+	require.NoError(t, err)
+	return &FCMService{client: client}
+}
+
+// fcmErrorHandler always responds with the given FCM error.
+func fcmErrorHandler(code int, status, fcmCode string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Retry-After vượt MaxDelay của SDK để SDK không tự retry 503
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(code)
+		_, _ = w.Write(fcmErrorBody(code, status, fcmCode))
+	}
+}
+
+func TestClassifyFCMError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     int
+		status   string
+		fcmCode  string
+		expected FCMErrorClass
+	}{
+		{"UNREGISTERED", 404, "NOT_FOUND", "UNREGISTERED", FCMErrorTokenInvalid},
+		{"INVALID_ARGUMENT", 400, "INVALID_ARGUMENT", "INVALID_ARGUMENT", FCMErrorInvalidMessage},
+		{"QUOTA_EXCEEDED", 429, "RESOURCE_EXHAUSTED", "QUOTA_EXCEEDED", FCMErrorQuotaExceeded},
+		{"UNAVAILABLE", 503, "UNAVAILABLE", "UNAVAILABLE", FCMErrorUnavailable},
+		{"INTERNAL", 500, "INTERNAL", "INTERNAL", FCMErrorUnavailable},
+		{"THIRD_PARTY_AUTH_ERROR", 401, "UNAUTHENTICATED", "THIRD_PARTY_AUTH_ERROR", FCMErrorSystem},
+		{"SENDER_ID_MISMATCH", 403, "PERMISSION_DENIED", "SENDER_ID_MISMATCH", FCMErrorTokenInvalid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := newTestFCMService(t, fcmErrorHandler(tc.code, tc.status, tc.fcmCode))
+
+			err := svc.SendNotification(context.Background(), "device-token", "Title", "Body")
+
+			require.Error(t, err)
+			var fcmErr *FCMError
+			require.True(t, errors.As(err, &fcmErr))
+			assert.Equal(t, tc.expected, fcmErr.Class)
+			assert.Equal(t, tc.expected, ClassifyFCMError(err))
+		})
+	}
+
+	t.Run("should classify plain errors", func(t *testing.T) {
+		assert.Equal(t, FCMErrorTokenInvalid, ClassifyFCMError(ErrUserFCMInactive))
+		assert.Equal(t, FCMErrorCanceled, ClassifyFCMError(context.DeadlineExceeded))
+		assert.Equal(t, FCMErrorCanceled, ClassifyFCMError(fmt.Errorf("send: %w", context.Canceled)))
+		assert.Equal(t, FCMErrorSystem, ClassifyFCMError(errors.New("something odd")))
+	})
+
+	t.Run("should classify a cancelled send", func(t *testing.T) {
+		svc := newTestFCMService(t, fcmErrorHandler(503, "UNAVAILABLE", "UNAVAILABLE"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := svc.SendNotification(ctx, "device-token", "Title", "Body")
+
+		assert.Equal(t, FCMErrorCanceled, ClassifyFCMError(err))
+	})
+
+	t.Run("should classify empty token as invalid", func(t *testing.T) {
+		svc := &FCMService{}
+		err := svc.SendNotification(context.Background(), "", "Title", "Body")
+		assert.Equal(t, FCMErrorTokenInvalid, ClassifyFCMError(err))
+	})
+}
+
+func TestFCMService_SendNotification_Success(t *testing.T) {
+	svc := newTestFCMService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"projects/test-project/messages/1"}`))
+	})
+
+	err := svc.SendNotification(context.Background(), "device-token", "Title", "Body")
+	assert.NoError(t, err)
+}
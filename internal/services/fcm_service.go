@@ -3,24 +3,131 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"remiaq/internal/models"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
 	"google.golang.org/api/option"
 )
 
+// probeTopic is the topic used for dry-run health probes; nothing is delivered.
+const probeTopic = "remiaq-healthcheck"
+
+// PushMessage is a single notification addressed to one device token or an FCM topic.
+type PushMessage struct {
+	Token string
+	Topic string // gửi tới topic (nhóm) khi Token rỗng
+	Title string
+	Body  string
+	Data  map[string]string
+
+	// Delivery options; zero values keep the defaults (high priority, default sound)
+	Priority    string         // high, normal
+	TTL         *time.Duration // nil = FCM default, 0 = deliver now or drop
+	CollapseKey string         // also sent as apns-collapse-id
+	Sound       string
+	ChannelID   string // Android notification channel
+	Badge       *int
+}
+
+// SendResult is the outcome of a multicast send for one token.
+type SendResult struct {
+	Token     string
+	MessageID string
+	Err       error // *FCMError, nil when sent
+}
+
+// MulticastSender is implemented by notifiers that send one message to many tokens in one call.
+type MulticastSender interface {
+	Multicast(ctx context.Context, msg *PushMessage, tokens []string) ([]SendResult, error)
+}
+
+// TopicManager subscribes device tokens to FCM topics.
+type TopicManager interface {
+	Subscribe(ctx context.Context, topic string, tokens []string) error
+	Unsubscribe(ctx context.Context, topic string, tokens []string) error
+}
+
+// Notifier delivers push messages and returns the provider message ID.
+// Implemented by FCMService and wrapped by CircuitBreaker.
+type Notifier interface {
+	Send(ctx context.Context, msg *PushMessage) (string, error)
+}
+
+// Prober is implemented by notifiers that can check provider health without delivering anything.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
 // FCMService handles Firebase Cloud Messaging
 type FCMService struct {
 	client *messaging.Client
 }
 
-// NewFCMService creates a new FCM service
-func NewFCMService(credentialsPath string) (*FCMService, error) {
+// Ensure implementation
+var (
+	_ Notifier        = (*FCMService)(nil)
+	_ Prober          = (*FCMService)(nil)
+	_ MulticastSender = (*FCMService)(nil)
+	_ TopicManager    = (*FCMService)(nil)
+)
+
+// maxMulticastTokens is the FCM limit of tokens per multicast request.
+const maxMulticastTokens = 500
+
+// FCMOption configures how FCMService connects to FCM.
+type FCMOption func(*fcmOptions)
+
+type fcmOptions struct {
+	endpoint  string
+	projectID string
+}
+
+// WithFCMEndpoint overrides the FCM HTTP v1 endpoint (e.g. the fakefcm server, "http://127.0.0.1:9099/v1").
+func WithFCMEndpoint(endpoint string) FCMOption {
+	return func(o *fcmOptions) {
+		o.endpoint = endpoint
+	}
+}
+
+// WithFCMProjectID sets the Firebase project instead of reading it from the credentials.
+func WithFCMProjectID(projectID string) FCMOption {
+	return func(o *fcmOptions) {
+		o.projectID = projectID
+	}
+}
+
+// NewFCMService creates a new FCM service.
+// An empty credentialsPath sends unauthenticated requests, which only a local endpoint accepts.
+func NewFCMService(credentialsPath string, opts ...FCMOption) (*FCMService, error) {
 	ctx := context.Background()
 
+	var o fcmOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var clientOpts []option.ClientOption
+	if credentialsPath != "" {
+		clientOpts = append(clientOpts, option.WithCredentialsFile(credentialsPath))
+	} else {
+		clientOpts = append(clientOpts, option.WithoutAuthentication())
+	}
+	if o.endpoint != "" {
+		clientOpts = append(clientOpts, option.WithEndpoint(o.endpoint))
+	}
+	var conf *firebase.Config
+	if o.projectID != "" {
+		conf = &firebase.Config{ProjectID: o.projectID}
+	}
+
 	// Initialize Firebase app
-	opt := option.WithCredentialsFile(credentialsPath)
-	app, err := firebase.NewApp(ctx, nil, opt)
+	app, err := firebase.NewApp(ctx, conf, clientOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,51 +141,120 @@ func NewFCMService(credentialsPath string) (*FCMService, error) {
 	return &FCMService{client: client}, nil
 }
 
-// SendNotification sends a notification to a device
-func (s *FCMService) SendNotification(token, title, body string) error {
-	if token == "" {
-		return errors.New("token is empty")
+// Send sends a push message to a single device or topic and returns the FCM message ID.
+// Errors are returned as *FCMError.
+func (s *FCMService) Send(ctx context.Context, msg *PushMessage) (string, error) {
+	if msg == nil || (msg.Token == "" && msg.Topic == "") {
+		return "", &FCMError{Class: FCMErrorTokenInvalid, Err: errors.New("token is empty")}
 	}
 
-	message := &messaging.Message{
-		Token: token,
-		Notification: &messaging.Notification{
-			Title: title,
-			Body:  body,
-		},
-		Android: &messaging.AndroidConfig{
-			Priority: "high",
-			Notification: &messaging.AndroidNotification{
-				Sound: "default",
-			},
-		},
-		APNS: &messaging.APNSConfig{
-			Payload: &messaging.APNSPayload{
-				Aps: &messaging.Aps{
-					Sound: "default",
-				},
-			},
-		},
+	id, err := s.client.Send(ctx, buildMessage(msg))
+	if err != nil {
+		return "", newFCMError(err)
 	}
+	return id, nil
+}
+
+// Probe checks that FCM is reachable and credentials are valid using a dry-run send.
+func (s *FCMService) Probe(ctx context.Context) error {
+	_, err := s.client.SendDryRun(ctx, &messaging.Message{
+		Topic: probeTopic,
+		Data:  map[string]string{"probe": "1"},
+	})
+	return newFCMError(err)
+}
+
+// Multicast sends msg to up to 500 tokens, ignoring msg.Token and msg.Topic.
+// The error covers the whole request; per-token failures are in the results as *FCMError.
+func (s *FCMService) Multicast(ctx context.Context, msg *PushMessage, tokens []string) ([]SendResult, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	if len(tokens) > maxMulticastTokens {
+		return nil, &FCMError{Class: FCMErrorSystem, Err: fmt.Errorf("multicast supports at most %d tokens, got %d", maxMulticastTokens, len(tokens))}
+	}
+
+	single := buildMessage(msg)
+	resp, err := s.client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+		Tokens:       tokens,
+		Notification: single.Notification,
+		Data:         single.Data,
+		Android:      single.Android,
+		APNS:         single.APNS,
+	})
+	if err != nil {
+		return nil, newFCMError(err)
+	}
+
+	results := make([]SendResult, len(tokens))
+	for i, token := range tokens {
+		results[i] = SendResult{Token: token}
+		if i >= len(resp.Responses) {
+			continue
+		}
+		results[i].MessageID = resp.Responses[i].MessageID
+		results[i].Err = newFCMError(resp.Responses[i].Error)
+	}
+	return results, nil
+}
+
+// Subscribe adds tokens to an FCM topic.
+func (s *FCMService) Subscribe(ctx context.Context, topic string, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	resp, err := s.client.SubscribeToTopic(ctx, tokens, topic)
+	return topicError("subscribe", resp, err)
+}
+
+// Unsubscribe removes tokens from an FCM topic.
+func (s *FCMService) Unsubscribe(ctx context.Context, topic string, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	resp, err := s.client.UnsubscribeFromTopic(ctx, tokens, topic)
+	return topicError("unsubscribe", resp, err)
+}
+
+// topicError summarizes a topic management response as a single error.
+func topicError(op string, resp *messaging.TopicManagementResponse, err error) error {
+	if err != nil {
+		return newFCMError(err)
+	}
+	if resp == nil || resp.FailureCount == 0 {
+		return nil
+	}
+	reasons := make([]string, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		reasons = append(reasons, fmt.Sprintf("#%d: %s", e.Index, e.Reason))
+	}
+	return fmt.Errorf("%s failed for %d token(s): %s", op, resp.FailureCount, strings.Join(reasons, ", "))
+}
 
-	// Send message
-	_, err := s.client.Send(context.Background(), message)
+// SendNotification sends a notification to a device
+func (s *FCMService) SendNotification(ctx context.Context, token, title, body string) error {
+	_, err := s.Send(ctx, &PushMessage{Token: token, Title: title, Body: body})
 	return err
 }
 
 // SendNotificationWithData sends a notification with custom data
-func (s *FCMService) SendNotificationWithData(token, title, body string, data map[string]string) error {
-	if token == "" {
-		return errors.New("token is empty")
+func (s *FCMService) SendNotificationWithData(ctx context.Context, token, title, body string, data map[string]string) error {
+	_, err := s.Send(ctx, &PushMessage{Token: token, Title: title, Body: body, Data: data})
+	return err
+}
+
+// SendMulticast sends the same notification to multiple devices
+func (s *FCMService) SendMulticast(ctx context.Context, tokens []string, title, body string) (*messaging.BatchResponse, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("no tokens provided")
 	}
 
-	message := &messaging.Message{
-		Token: token,
+	message := &messaging.MulticastMessage{
+		Tokens: tokens,
 		Notification: &messaging.Notification{
 			Title: title,
 			Body:  body,
 		},
-		Data: data,
 		Android: &messaging.AndroidConfig{
 			Priority: "high",
 			Notification: &messaging.AndroidNotification{
@@ -94,36 +270,59 @@ func (s *FCMService) SendNotificationWithData(token, title, body string, data ma
 		},
 	}
 
-	_, err := s.client.Send(context.Background(), message)
-	return err
+	return s.client.SendEachForMulticast(ctx, message)
 }
 
-// SendMulticast sends the same notification to multiple devices
-func (s *FCMService) SendMulticast(tokens []string, title, body string) (*messaging.BatchResponse, error) {
-	if len(tokens) == 0 {
-		return nil, errors.New("no tokens provided")
+// buildMessage converts a PushMessage into an FCM message, mapping delivery options to Android and APNs config.
+func buildMessage(msg *PushMessage) *messaging.Message {
+	priority, apnsPriority := models.PriorityHigh, "10"
+	if msg.Priority == models.PriorityNormal {
+		priority, apnsPriority = models.PriorityNormal, "5"
+	}
+	sound := msg.Sound
+	if sound == "" {
+		sound = "default"
 	}
 
-	message := &messaging.MulticastMessage{
-		Tokens: tokens,
+	headers := map[string]string{"apns-priority": apnsPriority}
+	if msg.CollapseKey != "" {
+		headers["apns-collapse-id"] = msg.CollapseKey
+	}
+	if msg.TTL != nil {
+		// APNs dùng thời điểm hết hạn tuyệt đối; "0" = chỉ thử giao một lần, không lưu lại
+		expiration := "0"
+		if *msg.TTL > 0 {
+			expiration = strconv.FormatInt(time.Now().Add(*msg.TTL).Unix(), 10)
+		}
+		headers["apns-expiration"] = expiration
+	}
+
+	return &messaging.Message{
+		Token: msg.Token,
+		Topic: msg.Topic,
 		Notification: &messaging.Notification{
-			Title: title,
-			Body:  body,
+			Title: msg.Title,
+			Body:  msg.Body,
 		},
+		Data: msg.Data,
 		Android: &messaging.AndroidConfig{
-			Priority: "high",
+			Priority:    priority,
+			TTL:         msg.TTL,
+			CollapseKey: msg.CollapseKey,
 			Notification: &messaging.AndroidNotification{
-				Sound: "default",
+				Sound:             sound,
+				ChannelID:         msg.ChannelID,
+				NotificationCount: msg.Badge,
 			},
 		},
 		APNS: &messaging.APNSConfig{
+			Headers: headers,
 			Payload: &messaging.APNSPayload{
 				Aps: &messaging.Aps{
-					Sound: "default",
+					Sound: sound,
+					Badge: msg.Badge,
 				},
 			},
 		},
 	}
-
-	return s.client.SendEachForMulticast(context.Background(), message)
 }
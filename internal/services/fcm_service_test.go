@@ -3,11 +3,16 @@ package services
 import (
 	"context"
 	"fmt"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"firebase.google.com/go/v4/messaging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/fakefcm"
 )
 
 // MockMessagingClient mocks Firebase messaging client
@@ -186,6 +191,66 @@ func TestNewFCMService(t *testing.T) {
 	})
 }
 
+func TestFCMService_FakeEndpoint(t *testing.T) {
+	fake := fakefcm.NewServer()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	service, err := NewFCMService("", WithFCMEndpoint(srv.URL+"/v1"), WithFCMProjectID("remiaq-test"))
+	require.NoError(t, err)
+
+	t.Run("should deliver message to the endpoint", func(t *testing.T) {
+		id, err := service.Send(context.Background(), &PushMessage{
+			Token: "token-ok",
+			Title: "Title",
+			Body:  "Body",
+			Data:  map[string]string{"reminder_id": "r1"},
+		})
+
+		require.NoError(t, err)
+		msgs := fake.MessagesTo("token-ok")
+		require.Len(t, msgs, 1)
+		assert.Equal(t, msgs[0].ID, id)
+		assert.Equal(t, "remiaq-test", msgs[0].Project)
+		assert.Equal(t, "Title", msgs[0].Title)
+		assert.Equal(t, "r1", msgs[0].Data["reminder_id"])
+	})
+
+	t.Run("should classify scripted errors", func(t *testing.T) {
+		// RetryAfter vượt 2 phút: SDK không tự gửi lại 503
+		unavailable := fakefcm.Unavailable
+		unavailable.RetryAfter = 5 * time.Minute
+
+		testCases := []struct {
+			fault fakefcm.Fault
+			class FCMErrorClass
+		}{
+			{fakefcm.Unregistered, FCMErrorTokenInvalid},
+			{fakefcm.QuotaExceeded, FCMErrorQuotaExceeded},
+			{unavailable, FCMErrorUnavailable},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.fault.ErrorCode, func(t *testing.T) {
+				token := "token-" + tc.fault.ErrorCode
+				fake.Fail(token, tc.fault, 0)
+
+				_, err := service.Send(context.Background(), &PushMessage{Token: token, Title: "T"})
+
+				require.Error(t, err)
+				assert.Equal(t, tc.class, ClassifyFCMError(err))
+				assert.Empty(t, fake.MessagesTo(token))
+			})
+		}
+	})
+
+	t.Run("should probe with a dry run", func(t *testing.T) {
+		require.NoError(t, service.Probe(context.Background()))
+		assert.Equal(t, 1, fake.Attempts(probeTopic))
+		assert.Empty(t, fake.MessagesTo(""))
+	})
+}
+
 // Benchmark tests
 func BenchmarkFCMService_SendNotification(b *testing.B) {
 	service := NewMockFCMService()
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrAlreadyMember is returned when adding a user that is already in the group.
+var ErrAlreadyMember = errors.New("user is already a member of the group")
+
+// ErrNotMember is returned when removing a user that is not in the group.
+var ErrNotMember = errors.New("user is not a member of the group")
+
+// GroupService manages broadcast groups and keeps FCM topic subscriptions in sync.
+type GroupService struct {
+	groupRepo repository.GroupRepository
+	userRepo  repository.UserRepository
+	topics    TopicManager // optional, nil when FCM is not configured
+}
+
+// NewGroupService creates a new group service. topics may be nil to skip FCM synchronization.
+func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository, topics TopicManager) *GroupService {
+	return &GroupService{
+		groupRepo: groupRepo,
+		userRepo:  userRepo,
+		topics:    topics,
+	}
+}
+
+// CreateGroup creates a group and assigns its FCM topic
+func (s *GroupService) CreateGroup(ctx context.Context, group *models.Group) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	if group.ID == "" {
+		group.ID = uuid.New().String()
+	}
+	// Topic cố định theo ID để đổi tên nhóm không ảnh hưởng subscription
+	group.Topic = models.GroupTopicPrefix + group.ID
+	return s.groupRepo.Create(ctx, group)
+}
+
+// GetGroup retrieves a group by ID
+func (s *GroupService) GetGroup(ctx context.Context, id string) (*models.Group, error) {
+	return s.groupRepo.GetByID(ctx, id)
+}
+
+// ListMembers returns the users of a group
+func (s *GroupService) ListMembers(ctx context.Context, groupID string) ([]*models.User, error) {
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.ListMembers(ctx, groupID)
+}
+
+// DeleteGroup unsubscribes all members from the topic and deletes the group
+func (s *GroupService) DeleteGroup(ctx context.Context, id string) error {
+	group, err := s.groupRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if s.topics != nil {
+		members, err := s.groupRepo.ListMembers(ctx, id)
+		if err != nil {
+			return err
+		}
+		if tokens := activeTokens(members); len(tokens) > 0 {
+			// Topic không còn được dùng sau khi xóa nhóm: lỗi chỉ cần ghi log
+			if err := s.topics.Unsubscribe(ctx, group.Topic, tokens); err != nil {
+				log.Printf("GroupService: failed to unsubscribe members of group %s: %v", id, err)
+			}
+		}
+	}
+
+	return s.groupRepo.Delete(ctx, id)
+}
+
+// AddMember adds a user to a group and subscribes their token to the group topic
+func (s *GroupService) AddMember(ctx context.Context, groupID, userID string) error {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return ErrAlreadyMember
+	}
+
+	// Subscribe trước: nếu FCM lỗi thì chưa ghi membership, client có thể thử lại
+	if err := s.subscribe(ctx, group, user); err != nil {
+		return err
+	}
+
+	member := &models.GroupMember{ID: uuid.New().String(), GroupID: groupID, UserID: userID}
+	if err := s.groupRepo.AddMember(ctx, member); err != nil {
+		s.unsubscribe(ctx, group, user)
+		return err
+	}
+	return nil
+}
+
+// RemoveMember removes a user from a group and unsubscribes their token from the topic
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, userID string) error {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotMember
+	}
+
+	if err := s.groupRepo.RemoveMember(ctx, groupID, userID); err != nil {
+		return err
+	}
+
+	if user, err := s.userRepo.GetByID(ctx, userID); err == nil {
+		s.unsubscribe(ctx, group, user)
+	}
+	return nil
+}
+
+// subscribe adds user's active token to the group topic
+func (s *GroupService) subscribe(ctx context.Context, group *models.Group, user *models.User) error {
+	if s.topics == nil || !user.IsFCMActive || user.FCMToken == "" {
+		return nil
+	}
+	if err := s.topics.Subscribe(ctx, group.Topic, []string{user.FCMToken}); err != nil {
+		return fmt.Errorf("subscribe user %s to group %s: %w", user.ID, group.ID, err)
+	}
+	return nil
+}
+
+// unsubscribe removes user's token from the group topic; failures are only logged
+func (s *GroupService) unsubscribe(ctx context.Context, group *models.Group, user *models.User) {
+	if s.topics == nil || user.FCMToken == "" {
+		return
+	}
+	if err := s.topics.Unsubscribe(ctx, group.Topic, []string{user.FCMToken}); err != nil {
+		log.Printf("GroupService: failed to unsubscribe user %s from group %s: %v", user.ID, group.ID, err)
+	}
+}
+
+// activeTokens returns the FCM tokens of users that can receive notifications
+func activeTokens(users []*models.User) []string {
+	var tokens []string
+	for _, user := range users {
+		if user.IsFCMActive && user.FCMToken != "" {
+			tokens = append(tokens, user.FCMToken)
+		}
+	}
+	return tokens
+}
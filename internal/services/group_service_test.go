@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"remiaq/internal/models"
+)
+
+// MockGroupRepository mocks repository.GroupRepository
+type MockGroupRepository struct {
+	mock.Mock
+}
+
+func (m *MockGroupRepository) Create(ctx context.Context, group *models.Group) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) GetByID(ctx context.Context, id string) (*models.Group, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) AddMember(ctx context.Context, member *models.GroupMember) error {
+	args := m.Called(ctx, member)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) RemoveMember(ctx context.Context, groupID, userID string) error {
+	args := m.Called(ctx, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) IsMember(ctx context.Context, groupID, userID string) (bool, error) {
+	args := m.Called(ctx, groupID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockGroupRepository) ListMembers(ctx context.Context, groupID string) ([]*models.User, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+// stubTopics records topic subscription calls
+type stubTopics struct {
+	subscribed   map[string][]string
+	unsubscribed map[string][]string
+	err          error
+}
+
+func newStubTopics() *stubTopics {
+	return &stubTopics{subscribed: map[string][]string{}, unsubscribed: map[string][]string{}}
+}
+
+func (s *stubTopics) Subscribe(ctx context.Context, topic string, tokens []string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.subscribed[topic] = append(s.subscribed[topic], tokens...)
+	return nil
+}
+
+func (s *stubTopics) Unsubscribe(ctx context.Context, topic string, tokens []string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.unsubscribed[topic] = append(s.unsubscribed[topic], tokens...)
+	return nil
+}
+
+func testGroup() *models.Group {
+	return &models.Group{ID: "g1", Name: "Team", Topic: models.GroupTopicPrefix + "g1"}
+}
+
+func TestGroupService_CreateGroup(t *testing.T) {
+	t.Run("should assign ID and topic", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		repo.On("Create", mock.Anything, mock.AnythingOfType("*models.Group")).Return(nil)
+		service := NewGroupService(repo, &MockUserRepository{}, nil)
+
+		group := &models.Group{Name: "Standup"}
+		require.NoError(t, service.CreateGroup(context.Background(), group))
+
+		assert.NotEmpty(t, group.ID)
+		assert.Equal(t, models.GroupTopicPrefix+group.ID, group.Topic)
+	})
+
+	t.Run("should reject group without name", func(t *testing.T) {
+		service := NewGroupService(&MockGroupRepository{}, &MockUserRepository{}, nil)
+
+		err := service.CreateGroup(context.Background(), &models.Group{})
+
+		var validationErr *models.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
+}
+
+func TestGroupService_AddMember(t *testing.T) {
+	t.Run("should subscribe token and store membership", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		users := &MockUserRepository{}
+		topics := newStubTopics()
+		service := NewGroupService(repo, users, topics)
+
+		repo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		users.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		repo.On("IsMember", mock.Anything, "g1", "user-1").Return(false, nil)
+		repo.On("AddMember", mock.Anything, mock.MatchedBy(func(m *models.GroupMember) bool {
+			return m.GroupID == "g1" && m.UserID == "user-1" && m.ID != ""
+		})).Return(nil)
+
+		require.NoError(t, service.AddMember(context.Background(), "g1", "user-1"))
+		assert.Equal(t, []string{"test-token"}, topics.subscribed[testGroup().Topic])
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should not store membership when subscription fails", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		users := &MockUserRepository{}
+		topics := newStubTopics()
+		topics.err = errors.New("fcm down")
+		service := NewGroupService(repo, users, topics)
+
+		repo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		users.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		repo.On("IsMember", mock.Anything, "g1", "user-1").Return(false, nil)
+
+		err := service.AddMember(context.Background(), "g1", "user-1")
+
+		assert.ErrorContains(t, err, "fcm down")
+		repo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject duplicate members", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		users := &MockUserRepository{}
+		service := NewGroupService(repo, users, newStubTopics())
+
+		repo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		users.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		repo.On("IsMember", mock.Anything, "g1", "user-1").Return(true, nil)
+
+		assert.ErrorIs(t, service.AddMember(context.Background(), "g1", "user-1"), ErrAlreadyMember)
+	})
+
+	t.Run("should skip subscription for users without active token", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		users := &MockUserRepository{}
+		topics := newStubTopics()
+		service := NewGroupService(repo, users, topics)
+
+		user := createTestUser()
+		user.IsFCMActive = false
+		repo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		users.On("GetByID", mock.Anything, "user-1").Return(user, nil)
+		repo.On("IsMember", mock.Anything, "g1", "user-1").Return(false, nil)
+		repo.On("AddMember", mock.Anything, mock.Anything).Return(nil)
+
+		require.NoError(t, service.AddMember(context.Background(), "g1", "user-1"))
+		assert.Empty(t, topics.subscribed)
+	})
+}
+
+func TestGroupService_RemoveMember(t *testing.T) {
+	t.Run("should remove membership and unsubscribe token", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		users := &MockUserRepository{}
+		topics := newStubTopics()
+		service := NewGroupService(repo, users, topics)
+
+		repo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		repo.On("IsMember", mock.Anything, "g1", "user-1").Return(true, nil)
+		repo.On("RemoveMember", mock.Anything, "g1", "user-1").Return(nil)
+		users.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+
+		require.NoError(t, service.RemoveMember(context.Background(), "g1", "user-1"))
+		assert.Equal(t, []string{"test-token"}, topics.unsubscribed[testGroup().Topic])
+	})
+
+	t.Run("should return ErrNotMember", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		service := NewGroupService(repo, &MockUserRepository{}, newStubTopics())
+
+		repo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		repo.On("IsMember", mock.Anything, "g1", "user-1").Return(false, nil)
+
+		assert.ErrorIs(t, service.RemoveMember(context.Background(), "g1", "user-1"), ErrNotMember)
+	})
+}
+
+func TestGroupService_DeleteGroup(t *testing.T) {
+	t.Run("should unsubscribe active members and delete", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		topics := newStubTopics()
+		service := NewGroupService(repo, &MockUserRepository{}, topics)
+
+		inactive := &models.User{ID: "u2", FCMToken: "old", IsFCMActive: false}
+		repo.On("GetByID", mock.Anything, "g1").Return(testGroup(), nil)
+		repo.On("ListMembers", mock.Anything, "g1").Return([]*models.User{createTestUser(), inactive}, nil)
+		repo.On("Delete", mock.Anything, "g1").Return(nil)
+
+		require.NoError(t, service.DeleteGroup(context.Background(), "g1"))
+		assert.Equal(t, []string{"test-token"}, topics.unsubscribed[testGroup().Topic])
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should return not found", func(t *testing.T) {
+		repo := &MockGroupRepository{}
+		service := NewGroupService(repo, &MockUserRepository{}, nil)
+		repo.On("GetByID", mock.Anything, "missing").Return(nil, sql.ErrNoRows)
+
+		assert.ErrorIs(t, service.DeleteGroup(context.Background(), "missing"), sql.ErrNoRows)
+	})
+}
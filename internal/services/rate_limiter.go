@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned (wrapped in *RateLimitError) when a send exceeds a rate limit.
+var ErrRateLimited = errors.New("send rate limit exceeded")
+
+// Rate limit scopes
+const (
+	RateLimitScopeGlobal   = "global"
+	RateLimitScopeUser     = "user"
+	RateLimitScopeReminder = "reminder"
+)
+
+// maxIdleBuckets is the bucket count above which full (idle) buckets are dropped.
+const maxIdleBuckets = 10000
+
+// RateLimit allows Limit sends per Window, with bursts of up to Limit.
+// A zero Limit disables the limit.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitConfig holds the limits applied to every send.
+type RateLimitConfig struct {
+	Global      RateLimit // all notifications, counted per FCM message
+	PerUser     RateLimit // notifications to (or sent on behalf of) one user
+	PerReminder RateLimit // notifications for one reminder
+}
+
+// DefaultRateLimitConfig returns the default send limits.
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Global:      RateLimit{Limit: 1000, Window: time.Minute},
+		PerUser:     RateLimit{Limit: 30, Window: time.Minute},
+		PerReminder: RateLimit{Limit: 10, Window: time.Hour},
+	}
+}
+
+// RateLimitError describes which limit blocked a send and when it may succeed.
+type RateLimitError struct {
+	Scope      string // global, user, reminder
+	Key        string // user or reminder ID, empty for global
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s rate limit exceeded, retry after %s", e.Scope, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s %s rate limit exceeded, retry after %s", e.Scope, e.Key, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// tokenBucket holds the remaining tokens at the time of the last refill.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter applies token-bucket limits globally, per user and per reminder.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	cfg RateLimitConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter creates a limiter with cfg.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Allow takes messages tokens from the global bucket and one token from the bucket of
+// userID and of each reminder. Tokens are taken only if every bucket has enough,
+// otherwise a *RateLimitError for the first exhausted bucket is returned.
+func (l *RateLimiter) Allow(userID string, reminderIDs []string, messages int) error {
+	type take struct {
+		scope, key string
+		limit      RateLimit
+		cost       float64
+	}
+	takes := []take{{RateLimitScopeGlobal, "", l.cfg.Global, float64(messages)}}
+	if userID != "" {
+		takes = append(takes, take{RateLimitScopeUser, userID, l.cfg.PerUser, 1})
+	}
+	for _, id := range reminderIDs {
+		takes = append(takes, take{RateLimitScopeReminder, id, l.cfg.PerReminder, 1})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.pruneLocked(now)
+
+	// Kiểm tra tất cả trước, chỉ trừ token khi mọi bucket đều đủ
+	buckets := make([]*tokenBucket, len(takes))
+	for i, t := range takes {
+		if t.limit.Limit <= 0 || t.limit.Window <= 0 {
+			continue
+		}
+		bucket := l.bucketLocked(t.scope+":"+t.key, t.limit, now)
+		// Một lần gửi lớn hơn cả burst vẫn được phép khi bucket đầy, tránh hoãn mãi mãi
+		cost := math.Min(t.cost, float64(t.limit.Limit))
+		if bucket.tokens < cost {
+			return &RateLimitError{Scope: t.scope, Key: t.key, RetryAfter: refillTime(t.limit, cost-bucket.tokens)}
+		}
+		buckets[i] = bucket
+	}
+	for i, bucket := range buckets {
+		if bucket != nil {
+			bucket.tokens -= math.Min(takes[i].cost, float64(takes[i].limit.Limit))
+		}
+	}
+	return nil
+}
+
+// bucketLocked returns the refilled bucket for key, creating a full one if needed. Caller holds mu.
+func (l *RateLimiter) bucketLocked(key string, limit RateLimit, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Limit), last: now}
+		l.buckets[key] = bucket
+		return bucket
+	}
+	elapsed := now.Sub(bucket.last)
+	if elapsed > 0 {
+		rate := float64(limit.Limit) / limit.Window.Seconds()
+		bucket.tokens = math.Min(float64(limit.Limit), bucket.tokens+elapsed.Seconds()*rate)
+		bucket.last = now
+	}
+	return bucket
+}
+
+// pruneLocked drops buckets that have refilled completely once there are too many. Caller holds mu.
+func (l *RateLimiter) pruneLocked(now time.Time) {
+	if len(l.buckets) <= maxIdleBuckets {
+		return
+	}
+	// Window dài nhất: sau khoảng này mọi bucket chắc chắn đã đầy lại
+	longest := l.cfg.PerUser.Window
+	if l.cfg.PerReminder.Window > longest {
+		longest = l.cfg.PerReminder.Window
+	}
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= longest {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// refillTime returns how long limit takes to refill missing tokens.
+func refillTime(limit RateLimit, missing float64) time.Duration {
+	perToken := limit.Window.Seconds() / float64(limit.Limit)
+	return time.Duration(math.Ceil(missing*perToken*1000)) * time.Millisecond
+}
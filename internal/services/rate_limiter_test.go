@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLimiter returns a limiter with a controllable clock
+func newTestLimiter(cfg RateLimitConfig) (*RateLimiter, *time.Time) {
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(cfg)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	t.Run("should allow burst then defer with retry after", func(t *testing.T) {
+		l, _ := newTestLimiter(RateLimitConfig{PerUser: RateLimit{Limit: 2, Window: time.Minute}})
+
+		require.NoError(t, l.Allow("user-1", nil, 1))
+		require.NoError(t, l.Allow("user-1", nil, 1))
+		err := l.Allow("user-1", nil, 1)
+
+		var limitErr *RateLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.Equal(t, RateLimitScopeUser, limitErr.Scope)
+		assert.Equal(t, "user-1", limitErr.Key)
+		assert.Equal(t, 30*time.Second, limitErr.RetryAfter)
+
+		// User khác dùng bucket riêng
+		assert.NoError(t, l.Allow("user-2", nil, 1))
+	})
+
+	t.Run("should refill over time", func(t *testing.T) {
+		l, now := newTestLimiter(RateLimitConfig{PerReminder: RateLimit{Limit: 1, Window: time.Hour}})
+
+		require.NoError(t, l.Allow("", []string{"rem-1"}, 1))
+		assert.Error(t, l.Allow("", []string{"rem-1"}, 1))
+
+		*now = now.Add(30 * time.Minute)
+		assert.Error(t, l.Allow("", []string{"rem-1"}, 1))
+
+		*now = now.Add(30 * time.Minute)
+		assert.NoError(t, l.Allow("", []string{"rem-1"}, 1))
+	})
+
+	t.Run("should not take tokens when another bucket is exhausted", func(t *testing.T) {
+		l, _ := newTestLimiter(RateLimitConfig{
+			PerUser:     RateLimit{Limit: 2, Window: time.Minute},
+			PerReminder: RateLimit{Limit: 1, Window: time.Hour},
+		})
+
+		require.NoError(t, l.Allow("user-1", []string{"rem-1"}, 1))
+		err := l.Allow("user-1", []string{"rem-1"}, 1)
+
+		var limitErr *RateLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, RateLimitScopeReminder, limitErr.Scope)
+		// Lần bị chặn không trừ token của user
+		assert.NoError(t, l.Allow("user-1", []string{"rem-2"}, 1))
+	})
+
+	t.Run("should count global limit per message", func(t *testing.T) {
+		l, _ := newTestLimiter(RateLimitConfig{Global: RateLimit{Limit: 10, Window: time.Minute}})
+
+		require.NoError(t, l.Allow("user-1", nil, 8))
+		err := l.Allow("user-2", nil, 3)
+
+		var limitErr *RateLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, RateLimitScopeGlobal, limitErr.Scope)
+		assert.NoError(t, l.Allow("user-2", nil, 2))
+	})
+
+	t.Run("should allow a send larger than the burst when the bucket is full", func(t *testing.T) {
+		l, _ := newTestLimiter(RateLimitConfig{Global: RateLimit{Limit: 10, Window: time.Minute}})
+
+		assert.NoError(t, l.Allow("user-1", nil, 50))
+		assert.Error(t, l.Allow("user-1", nil, 1))
+	})
+
+	t.Run("should not limit scopes with zero limit", func(t *testing.T) {
+		l, _ := newTestLimiter(RateLimitConfig{})
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, l.Allow("user-1", []string{"rem-1"}, 1))
+		}
+	})
+}
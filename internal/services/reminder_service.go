@@ -3,6 +3,9 @@ package services
 import (
 	"context"
 	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"remiaq/internal/models"
@@ -15,23 +18,134 @@ import (
 type ReminderService struct {
 	reminderRepo    repository.ReminderRepository
 	userRepo        repository.UserRepository
-	fcmService      *FCMService
+	notifier        Notifier
 	schedCalculator *ScheduleCalculator
+	retryPolicy     RetryPolicy
+	deliveryRepo    repository.DeliveryRepository
+	groupRepo       repository.GroupRepository
+	templates       *TemplateRenderer
+	limiter         *RateLimiter
+	mailer          EmailSender
+	concurrency     int
+	leaseOwner      string // rỗng = không dùng lease (chỉ một instance chạy worker)
+	leaseTTL        time.Duration
+	claimBatchSize  int
+	schedule        ScheduleObserver
+	transactor      repository.Transactor
+	deferrals       deferralLog
+}
+
+// ScheduleObserver is told when a user action changes when a reminder is next due, so an
+// in-memory scheduler can wake up without polling. A zero at means the reminder is no longer
+// scheduled (completed, paused or deleted). Calls must not block.
+type ScheduleObserver interface {
+	ReminderScheduled(id string, at time.Time)
+}
+
+// ReminderServiceOption configures optional ReminderService dependencies.
+type ReminderServiceOption func(*ReminderService)
+
+// WithRetryPolicy sets the per-send retry policy for transient FCM failures.
+func WithRetryPolicy(policy RetryPolicy) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.retryPolicy = policy
+	}
+}
+
+// WithDeliveryRepo enables the delivery log: every FCM send attempt is recorded.
+func WithDeliveryRepo(repo repository.DeliveryRepository) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.deliveryRepo = repo
+	}
+}
+
+// WithGroupRepo enables reminders addressed to a group (sent to the group's FCM topic).
+func WithGroupRepo(repo repository.GroupRepository) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.groupRepo = repo
+	}
+}
+
+// WithRateLimiter defers sends that exceed the global, per-user or per-reminder limits.
+func WithRateLimiter(l *RateLimiter) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.limiter = l
+	}
+}
+
+// DefaultConcurrency is the number of users processed in parallel when none is configured.
+const DefaultConcurrency = 8
+
+// WithConcurrency sets how many users are processed in parallel per tick. n <= 0 keeps the default.
+func WithConcurrency(n int) ReminderServiceOption {
+	return func(s *ReminderService) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithLeasing lets several server instances run the worker against the same database.
+// Each tick claims due reminders for owner in batches of batchSize (<= 0 = all at once),
+// holding them for ttl; reminders claimed by another instance are left alone and a lease
+// that expires (the holder crashed) is claimed again. ttl must exceed the longest tick.
+func WithLeasing(owner string, ttl time.Duration, batchSize int) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.leaseOwner = owner
+		s.leaseTTL = ttl
+		s.claimBatchSize = batchSize
+	}
+}
+
+// WithScheduleObserver reports create, update, snooze, complete and delete events to observer.
+func WithScheduleObserver(observer ScheduleObserver) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.schedule = observer
+	}
+}
+
+// WithTransactor runs multi-step repository changes (read, then write) in one transaction.
+func WithTransactor(transactor repository.Transactor) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.transactor = transactor
+	}
+}
+
+// WithEmailSender enables the email channel of escalation policies.
+func WithEmailSender(mailer EmailSender) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.mailer = mailer
+	}
+}
+
+// WithTemplateRenderer renders title/body from notification templates
+// instead of sending the reminder title and description verbatim.
+func WithTemplateRenderer(r *TemplateRenderer) ReminderServiceOption {
+	return func(s *ReminderService) {
+		s.templates = r
+	}
 }
 
 // NewReminderService creates a new reminder service
 func NewReminderService(
 	reminderRepo repository.ReminderRepository,
 	userRepo repository.UserRepository,
-	fcmService *FCMService,
+	notifier Notifier,
 	schedCalculator *ScheduleCalculator,
+	opts ...ReminderServiceOption,
 ) *ReminderService {
-	return &ReminderService{
+	s := &ReminderService{
 		reminderRepo:    reminderRepo,
 		userRepo:        userRepo,
-		fcmService:      fcmService,
+		notifier:        notifier,
 		schedCalculator: schedCalculator,
+		retryPolicy:     DefaultRetryPolicy(),
+		concurrency:     DefaultConcurrency,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // CreateReminder creates a new reminder
@@ -66,7 +180,11 @@ func (s *ReminderService) CreateReminder(ctx context.Context, reminder *models.R
 		reminder.NextTriggerAt = nextTrigger
 	}
 
-	return s.reminderRepo.Create(ctx, reminder)
+	if err := s.reminderRepo.Create(ctx, reminder); err != nil {
+		return err
+	}
+	s.notifySchedule(reminder)
+	return nil
 }
 
 // GetReminder retrieves a reminder by ID
@@ -80,12 +198,20 @@ func (s *ReminderService) UpdateReminder(ctx context.Context, reminder *models.R
 		return err
 	}
 
-	return s.reminderRepo.Update(ctx, reminder)
+	if err := s.reminderRepo.Update(ctx, reminder); err != nil {
+		return err
+	}
+	s.notifySchedule(reminder)
+	return nil
 }
 
 // DeleteReminder deletes a reminder
 func (s *ReminderService) DeleteReminder(ctx context.Context, id string) error {
-	return s.reminderRepo.Delete(ctx, id)
+	if err := s.reminderRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.notifyUnscheduled(id)
+	return nil
 }
 
 // GetUserReminders retrieves all reminders for a user
@@ -96,21 +222,49 @@ func (s *ReminderService) GetUserReminders(ctx context.Context, userID string) (
 // SnoozeReminder postpones a reminder
 func (s *ReminderService) SnoozeReminder(ctx context.Context, id string, duration time.Duration) error {
 	snoozeUntil := time.Now().Add(duration)
-	return s.reminderRepo.UpdateSnooze(ctx, id, &snoozeUntil)
+	if err := s.reminderRepo.UpdateSnooze(ctx, id, &snoozeUntil); err != nil {
+		return err
+	}
+	if s.schedule != nil {
+		s.schedule.ReminderScheduled(id, snoozeUntil)
+	}
+	return nil
 }
 
 // CompleteReminder marks a reminder as completed
 func (s *ReminderService) CompleteReminder(ctx context.Context, id string) error {
-	reminder, err := s.reminderRepo.GetByID(ctx, id)
+	var notify func()
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		notify, err = s.completeReminder(ctx, id)
+		return err
+	})
 	if err != nil {
 		return err
 	}
+	// Chỉ báo scheduler sau khi commit
+	if notify != nil {
+		notify()
+	}
+	return nil
+}
+
+// completeReminder applies CompleteReminder and returns the schedule notification to send
+// once the change is committed (nil if the schedule didn't change).
+func (s *ReminderService) completeReminder(ctx context.Context, id string) (func(), error) {
+	reminder, err := s.reminderRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 
 	// For one-time reminders, mark as completed
 	if reminder.Type == models.ReminderTypeOneTime {
-		return s.reminderRepo.MarkCompleted(ctx, id, now)
+		if err := s.reminderRepo.MarkCompleted(ctx, id, now); err != nil {
+			return nil, err
+		}
+		return func() { s.notifyUnscheduled(id) }, nil
 	}
 
 	// For recurring reminders with base_on=completion
@@ -119,124 +273,517 @@ func (s *ReminderService) CompleteReminder(ctx context.Context, id string) error
 		// Calculate next trigger from completion time
 		nextTrigger, err := s.schedCalculator.CalculateNextTrigger(reminder, now)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Update last_completed_at and next_trigger_at
 		reminder.LastCompletedAt = &now
 		reminder.NextTriggerAt = nextTrigger
-		return s.reminderRepo.Update(ctx, reminder)
+		if err := s.reminderRepo.Update(ctx, reminder); err != nil {
+			return nil, err
+		}
+		return func() { s.notifySchedule(reminder) }, nil
 	}
 
 	// For other recurring reminders, just update last_completed_at
 	reminder.LastCompletedAt = &now
-	return s.reminderRepo.Update(ctx, reminder)
+	return nil, s.reminderRepo.Update(ctx, reminder)
+}
+
+// inTransaction runs fn in one transaction when a transactor is configured, otherwise directly.
+func (s *ReminderService) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.transactor == nil {
+		return fn(ctx)
+	}
+	return s.transactor.InTransaction(ctx, fn)
+}
+
+// notifySchedule reports the reminder's next due time to the schedule observer.
+func (s *ReminderService) notifySchedule(reminder *models.Reminder) {
+	if s.schedule == nil {
+		return
+	}
+	if reminder.Status != models.ReminderStatusActive {
+		s.schedule.ReminderScheduled(reminder.ID, time.Time{})
+		return
+	}
+	s.schedule.ReminderScheduled(reminder.ID, reminder.TriggerAt())
 }
 
-// ProcessDueReminders processes all reminders that are due (called by worker)
+// notifyUnscheduled tells the schedule observer the reminder won't be due again.
+func (s *ReminderService) notifyUnscheduled(id string) {
+	if s.schedule != nil {
+		s.schedule.ReminderScheduled(id, time.Time{})
+	}
+}
+
+// ProcessDueReminders processes all reminders that are due (called by worker).
+// Users are processed in parallel by up to concurrency goroutines; the reminders of one
+// user are handled in order by a single goroutine. Users with digest enabled get one summary push.
+// With leasing, due reminders are claimed batch by batch and released at the end of the tick.
+// Returns ErrSystemFCM only when a system-level FCM failure occurred, or ctx's error when cancelled.
 func (s *ReminderService) ProcessDueReminders(ctx context.Context) error {
-    now := time.Now()
-
-    // Get all due reminders
-    reminders, err := s.reminderRepo.GetDueReminders(ctx, now)
-    if err != nil {
-        return err
-    }
-
-    // Track if any system-level errors occurred during processing
-    systemErrorOccurred := false
-
-    for _, reminder := range reminders {
-        // Process each reminder
-        if err := s.processReminder(ctx, reminder, now); err != nil {
-            // Distinguish device token errors from system-level errors
-            if !isTokenInvalidError(err) {
-                systemErrorOccurred = true
-            }
-            // Continue with other reminders regardless
-            continue
-        }
-    }
-
-    if systemErrorOccurred {
-        return errors.New("system_fcm_error")
-    }
-    return nil
+	now := time.Now()
+	if s.leaseOwner != "" {
+		ctx = repository.WithLeaseOwner(ctx, s.leaseOwner)
+		defer s.releaseClaims(ctx)
+	}
+
+	var tick tickState
+	for {
+		reminders, err := s.dueReminders(ctx, now)
+		if err != nil {
+			return err
+		}
+		if err := s.processDue(ctx, reminders, now, &tick); err != nil {
+			return err
+		}
+
+		// Lô đầy: có thể còn reminder due chưa ai nhận, nhận tiếp lô sau
+		if s.leaseOwner == "" || s.claimBatchSize <= 0 || len(reminders) < s.claimBatchSize ||
+			tick.stop.Load() || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tick.systemError.Load() {
+		return ErrSystemFCM
+	}
+	return nil
 }
 
-// processReminder processes a single reminder
-func (s *ReminderService) processReminder(ctx context.Context, reminder *models.Reminder, now time.Time) error {
-    // Get user
-    user, err := s.userRepo.GetByID(ctx, reminder.UserID)
-    if err != nil {
-        return err
-    }
+// dueReminders returns the reminders to process: all due ones, or the next batch claimed
+// for this instance when leasing is enabled. Reminders claimed earlier in the tick stay
+// claimed until the tick ends, so a batch never repeats them.
+func (s *ReminderService) dueReminders(ctx context.Context, now time.Time) ([]*models.Reminder, error) {
+	if s.leaseOwner == "" {
+		return s.reminderRepo.GetDueReminders(ctx, now)
+	}
+	return s.reminderRepo.ClaimDueReminders(ctx, s.leaseOwner, now, time.Now().Add(s.leaseTTL), s.claimBatchSize)
+}
+
+// releaseClaims drops this instance's leases, also when the tick was cancelled by shutdown.
+func (s *ReminderService) releaseClaims(ctx context.Context) {
+	if err := s.reminderRepo.ReleaseClaims(context.WithoutCancel(ctx), s.leaseOwner); err != nil {
+		log.Printf("ReminderService: failed to release claims of %s: %v", s.leaseOwner, err)
+	}
+}
+
+// processDue sends reminders with the worker pool, updating tick.
+func (s *ReminderService) processDue(ctx context.Context, reminders []*models.Reminder, now time.Time, tick *tickState) error {
+	groups := groupByUser(reminders)
+	if len(groups) == 0 {
+		return nil
+	}
+
+	// Một truy vấn cho mọi user của lô thay vì một lần GetByID cho mỗi reminder
+	users, err := s.loadUsers(ctx, groups)
+	if err != nil {
+		return err
+	}
+
+	work := make(chan []*models.Reminder)
+	var wg sync.WaitGroup
+	for i := 0; i < min(s.concurrency, len(groups)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				s.processUserGroup(ctx, users[group[0].UserID], group, now, tick)
+			}
+		}()
+	}
+
+dispatch:
+	for _, group := range groups {
+		if tick.stop.Load() {
+			break
+		}
+		select {
+		case work <- group:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(work)
+	wg.Wait()
+	return nil
+}
 
+// tickState is shared by the goroutines processing one tick.
+type tickState struct {
+	stop        atomic.Bool // dừng gửi trong tick này (circuit mở, quota, rate limit chung)
+	systemError atomic.Bool
+}
+
+// loadUsers loads the users owning groups, keyed by ID.
+func (s *ReminderService) loadUsers(ctx context.Context, groups [][]*models.Reminder) (map[string]*models.User, error) {
+	ids := make([]string, len(groups))
+	for i, group := range groups {
+		ids[i] = group[0].UserID
+	}
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
+
+// processUserGroup sends the due reminders of one user in order. Errors that should stop
+// the whole tick set tick.stop; the remaining batches of every user are then left due.
+func (s *ReminderService) processUserGroup(ctx context.Context, user *models.User, group []*models.Reminder, now time.Time, tick *tickState) {
+	if user == nil {
+		log.Printf("ReminderService: user %s of %d due reminders not found", group[0].UserID, len(group))
+		return
+	}
+
+	for _, batch := range s.batchesForUser(ctx, user, group, now) {
+		if tick.stop.Load() || ctx.Err() != nil {
+			return
+		}
+
+		err := s.processBatch(ctx, batch, now)
+		if err == nil {
+			continue
+		}
+
+		var limitErr *RateLimitError
+		if errors.As(err, &limitErr) {
+			// Đã ghi nhận deferred; reminder vẫn due và được gửi ở tick sau
+			if limitErr.Scope == RateLimitScopeGlobal {
+				tick.stop.Store(true)
+			}
+			continue
+		}
+
+		var fcmErr *FCMError
+		if !errors.As(err, &fcmErr) {
+			if !errors.Is(err, ErrUserFCMInactive) {
+				log.Printf("ReminderService: failed to process %s: %v", batch, err)
+			}
+			continue
+		}
+
+		switch fcmErr.Class {
+		case FCMErrorTokenInvalid:
+			// Token đã bị vô hiệu hóa khi gửi, không ảnh hưởng user khác
+		case FCMErrorInvalidMessage:
+			// FCM sẽ từ chối lại đúng message này: bỏ qua lần nhắc, không gửi lại ở tick sau
+			log.Printf("ReminderService: FCM rejected the message for %s, skipping it: %v", batch, err)
+			for _, reminder := range batch.reminders {
+				if advanceErr := s.advance(ctx, reminder, now, nil); advanceErr != nil {
+					log.Printf("ReminderService: failed to advance reminder %s: %v", reminder.ID, advanceErr)
+				}
+			}
+		case FCMErrorCanceled:
+			// Tick bị hủy giữa chừng: các reminder còn lại vẫn due
+			return
+		case FCMErrorUnavailable:
+			// Lỗi tạm thời: giữ nguyên reminder để gửi lại ở tick sau
+			log.Printf("ReminderService: transient FCM error for %s: %v", batch, err)
+		case FCMErrorCircuitOpen:
+			// Mạch đang mở: không gửi thêm trong tick này, chờ breaker probe lại
+			tick.stop.Store(true)
+		case FCMErrorQuotaExceeded:
+			// Vượt quota: dừng gửi trong tick này, các reminder còn lại vẫn due
+			log.Printf("ReminderService: FCM quota exceeded, backing off until next tick: %v", err)
+			tick.stop.Store(true)
+		default:
+			log.Printf("ReminderService: system FCM error for %s: %v", batch, err)
+			tick.systemError.Store(true)
+		}
+	}
+}
+
+// processBatch sends a single reminder, a broadcast or a digest depending on the batch
+func (s *ReminderService) processBatch(ctx context.Context, batch dueBatch, now time.Time) error {
+	if len(batch.reminders) == 1 {
+		if batch.reminders[0].IsEscalating() {
+			return s.processEscalation(ctx, batch.reminders[0], batch.user, now)
+		}
+		if batch.reminders[0].IsBroadcast() {
+			return s.processBroadcast(ctx, batch.reminders[0], batch.user, now)
+		}
+		return s.processReminder(ctx, batch.reminders[0], batch.user, now)
+	}
+	return s.processDigest(ctx, batch.user, batch.reminders, now)
+}
+
+// processReminder processes a single reminder
+func (s *ReminderService) processReminder(ctx context.Context, reminder *models.Reminder, user *models.User, now time.Time) error {
 	// Check if user has active FCM
 	if !user.IsFCMActive || user.FCMToken == "" {
-		return errors.New("user FCM not active")
-	}
-
-    // Send notification (no-op if FCM service is not configured)
-    if s.fcmService != nil {
-        err = s.fcmService.SendNotification(user.FCMToken, reminder.Title, reminder.Description)
-        if err != nil {
-            // Handle FCM errors
-            if isTokenInvalidError(err) {
-                // Disable FCM for this user
-                s.userRepo.DisableFCM(ctx, user.ID)
-            }
-            return err
-        }
-
-        // Update last_sent_at only when we actually sent something
-        s.reminderRepo.UpdateLastSent(ctx, reminder.ID, now)
-    }
-
-	// Handle based on type
+		return ErrUserFCMInactive
+	}
+
+	// Send notification (no-op if no notifier is configured)
+	if s.notifier != nil {
+		msg := &PushMessage{
+			Token: user.FCMToken,
+			Data:  map[string]string{"reminder_id": reminder.ID},
+		}
+		if !applyDeliveryOptions(msg, reminder, now) {
+			return s.skipExpired(ctx, reminder, user.ID, maskToken(user.FCMToken), now)
+		}
+		if err := s.throttle(ctx, user.ID, maskToken(user.FCMToken), []*models.Reminder{reminder}, 1); err != nil {
+			return err
+		}
+
+		msg.Title, msg.Body = reminder.Title, reminder.Description
+		if s.templates != nil {
+			msg.Title, msg.Body = s.templates.Render(ctx, reminder, user, now)
+		}
+		if err := s.send(ctx, user, msg, []*models.Reminder{reminder}); err != nil {
+			return err
+		}
+
+		// Update last_sent_at only when we actually sent something
+		return s.advance(ctx, reminder, now, &now)
+	}
+
+	return s.advance(ctx, reminder, now, nil)
+}
+
+// throttle takes messages from the send rate limits. Over the limit, a *RateLimitError is
+// returned and the reminders stay due; a deferred delivery is recorded for each reminder
+// once per throttle window, not on every tick that finds it still throttled.
+func (s *ReminderService) throttle(ctx context.Context, userID, device string, reminders []*models.Reminder, messages int) error {
+	if s.limiter == nil {
+		return nil
+	}
+	ids := make([]string, len(reminders))
+	for i, reminder := range reminders {
+		ids[i] = reminder.ID
+	}
+
+	err := s.limiter.Allow(userID, ids, messages)
+	if err == nil {
+		s.deferrals.clear(ids)
+		return nil
+	}
+	log.Printf("ReminderService: deferring reminders %v: %v", ids, err)
+	var limitErr *RateLimitError
+	errors.As(err, &limitErr)
+	now := s.limiter.now()
+	for _, reminder := range reminders {
+		if s.deferrals.start(reminder.ID, now, limitErr.RetryAfter) {
+			s.recordDelivery(ctx, reminder, userID, device, "", 0, "", err)
+		}
+	}
+	return err
+}
+
+// deferralLog remembers until when each throttled reminder already has a deferred delivery.
+// The zero value is ready to use.
+type deferralLog struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// start reports whether a new throttle window begins for reminder id at now, i.e. whether
+// its deferral must be recorded; the window then lasts retryAfter.
+func (d *deferralLog) start(id string, now time.Time, retryAfter time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Before(d.until[id]) {
+		return false
+	}
+	if d.until == nil {
+		d.until = make(map[string]time.Time)
+	}
+	// Dọn các cửa sổ đã hết hạn (reminder đã bị xóa hoặc không còn due)
+	for key, until := range d.until {
+		if !now.Before(until) {
+			delete(d.until, key)
+		}
+	}
+	d.until[id] = now.Add(retryAfter)
+	return true
+}
+
+// clear forgets the windows of reminders that got through the limits
+func (d *deferralLog) clear(ids []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range ids {
+		delete(d.until, id)
+	}
+}
+
+// send delivers msg with the retry policy and records a delivery per reminder and attempt.
+// Disables the user's token when FCM reports it invalid.
+func (s *ReminderService) send(ctx context.Context, user *models.User, msg *PushMessage, reminders []*models.Reminder) error {
+	dispatchID := newDispatchID(msg)
+
+	// Lỗi tạm thời được gửi lại với backoff; hết lượt thì reminder vẫn due cho tick sau
+	attempt := 0
+	_, err := s.retryPolicy.Do(ctx, func(ctx context.Context) error {
+		attempt++
+		messageID, sendErr := s.notifier.Send(ctx, msg)
+		for _, reminder := range reminders {
+			s.recordDelivery(ctx, reminder, user.ID, deviceLabel(msg), dispatchID, attempt, messageID, sendErr)
+		}
+		return sendErr
+	})
+	if err != nil {
+		// Token không còn hợp lệ: tắt FCM cho user này (gửi topic không gắn với token của user)
+		if msg.Token != "" && isTokenInvalidError(err) {
+			if disableErr := s.userRepo.DisableFCM(ctx, user.ID); disableErr != nil {
+				log.Printf("ReminderService: failed to disable FCM for user %s: %v", user.ID, disableErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// advance moves a processed reminder to its next state based on its type. The whole change
+// (last sent, retry count, next trigger, escalation step, completion) is applied atomically
+// and only once per occurrence. sentAt is nil when nothing was sent.
+func (s *ReminderService) advance(ctx context.Context, reminder *models.Reminder, now time.Time, sentAt *time.Time) error {
+	t := &models.ReminderTransition{
+		ReminderID:    reminder.ID,
+		PrevTriggerAt: reminder.NextTriggerAt,
+		SentAt:        sentAt,
+	}
 	if reminder.Type == models.ReminderTypeOneTime {
-		return s.handleOneTimeReminder(ctx, reminder, now)
-	} else {
-		return s.handleRecurringReminder(ctx, reminder, now)
+		s.handleOneTimeReminder(reminder, now, t)
+		return s.applyTransition(ctx, t)
+	}
+	if err := s.handleRecurringReminder(reminder, now, t); err != nil {
+		// Không tính được lần kế tiếp: vẫn ghi nhận lần gửi, reminder giữ nguyên lịch
+		if sentAt != nil {
+			if applyErr := s.applyTransition(ctx, t); applyErr != nil {
+				log.Printf("ReminderService: failed to record send of reminder %s: %v", reminder.ID, applyErr)
+			}
+		}
+		return err
 	}
+	return s.applyTransition(ctx, t)
 }
 
-// handleOneTimeReminder handles one-time reminder logic
-func (s *ReminderService) handleOneTimeReminder(ctx context.Context, reminder *models.Reminder, now time.Time) error {
+// handleOneTimeReminder sets the next state of a one-time reminder on t
+func (s *ReminderService) handleOneTimeReminder(reminder *models.Reminder, now time.Time, t *models.ReminderTransition) {
 	// Check if should retry
 	if reminder.RepeatStrategy == models.RepeatStrategyRetryUntilComplete && reminder.IsRetryable() {
-		// Increment retry count
-		s.reminderRepo.IncrementRetryCount(ctx, reminder.ID)
+		t.IncrementRetry = true
+		t.NextTriggerAt = now.Add(time.Duration(reminder.RetryIntervalSec) * time.Second)
+		return
+	}
 
-		// Calculate next retry time
-		nextRetry := now.Add(time.Duration(reminder.RetryIntervalSec) * time.Second)
-		return s.reminderRepo.UpdateNextTrigger(ctx, reminder.ID, nextRetry)
+	// Hết lượt nhắc mà chưa hoàn thành: chuyển cho người/kênh dự phòng theo escalation policy
+	if reminder.RepeatStrategy == models.RepeatStrategyRetryUntilComplete && reminder.EscalationPolicy.HasSteps() {
+		s.startEscalation(reminder, now, t)
+		return
 	}
 
 	// Otherwise, mark as completed
-	return s.reminderRepo.MarkCompleted(ctx, reminder.ID, now)
+	t.CompletedAt = &now
 }
 
-// handleRecurringReminder handles recurring reminder logic
-func (s *ReminderService) handleRecurringReminder(ctx context.Context, reminder *models.Reminder, now time.Time) error {
-	// Calculate next trigger
+// handleRecurringReminder sets the next trigger of a recurring reminder on t
+func (s *ReminderService) handleRecurringReminder(reminder *models.Reminder, now time.Time, t *models.ReminderTransition) error {
 	nextTrigger, err := s.schedCalculator.CalculateNextTrigger(reminder, now)
 	if err != nil {
 		return err
 	}
+	t.NextTriggerAt = nextTrigger
+	return nil
+}
 
-	// Update next trigger time
-	return s.reminderRepo.UpdateNextTrigger(ctx, reminder.ID, nextTrigger)
+// applyTransition applies t. A transition that no longer matches the reminder (it was already
+// applied, or the reminder changed or was claimed by another instance meanwhile) is skipped.
+func (s *ReminderService) applyTransition(ctx context.Context, t *models.ReminderTransition) error {
+	// Đã gửi thì phải ghi nhận, kể cả khi tick bị hủy (tắt server): không để gửi lặp lần sau
+	applied, err := s.reminderRepo.ApplyTransition(context.WithoutCancel(ctx), t)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		log.Printf("ReminderService: reminder %s changed since %s, transition skipped",
+			t.ReminderID, t.PrevTriggerAt.Format(time.RFC3339))
+	}
+	return nil
 }
 
-// Helper function to check if FCM error is due to invalid token
-func isTokenInvalidError(err error) bool {
-	if err == nil {
-		return false
+// recordDelivery writes one delivery log entry. Failures are logged, never returned,
+// so the delivery log can't block sending. Rate-limited sends are recorded as deferred and
+// reminders past their TTL as expired; circuit-open rejections are not recorded because nothing reached the provider.
+func (s *ReminderService) recordDelivery(ctx context.Context, reminder *models.Reminder, userID, device, dispatchID string, attempt int, messageID string, sendErr error) {
+	if s.deliveryRepo == nil {
+		return
+	}
+
+	delivery := &models.Delivery{
+		ID:                uuid.New().String(),
+		ReminderID:        reminder.ID,
+		UserID:            userID,
+		Channel:           models.DeliveryChannelFCM,
+		Device:            device,
+		ScheduledFor:      reminder.NextTriggerAt,
+		ProviderMessageID: messageID,
+		Attempt:           attempt,
+		DispatchID:        dispatchID,
+	}
+
+	switch {
+	case sendErr == nil:
+		sentAt := time.Now().UTC()
+		delivery.SentAt = &sentAt
+		delivery.Outcome = models.DeliveryOutcomeSent
+	case errors.Is(sendErr, ErrRateLimited):
+		delivery.Outcome = models.DeliveryOutcomeDeferred
+		delivery.ErrorClass = models.DeliveryErrorRateLimited
+		delivery.ErrorMessage = sendErr.Error()
+	case errors.Is(sendErr, ErrDeliveryExpired):
+		delivery.Outcome = models.DeliveryOutcomeExpired
+		delivery.ErrorMessage = sendErr.Error()
+	default:
+		class := ClassifyFCMError(sendErr)
+		if class == FCMErrorCircuitOpen {
+			return
+		}
+		delivery.Outcome = models.DeliveryOutcomeFailed
+		delivery.ErrorClass = string(class)
+		delivery.ErrorMessage = sendErr.Error()
+	}
+
+	if err := s.deliveryRepo.Create(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("ReminderService: failed to record delivery for reminder %s: %v", reminder.ID, err)
+	}
+}
+
+// newDispatchID tags msg with a new "delivery_id" that the client echoes back in acknowledgements.
+func newDispatchID(msg *PushMessage) string {
+	id := uuid.New().String()
+	if msg.Data == nil {
+		msg.Data = make(map[string]string)
+	}
+	msg.Data["delivery_id"] = id
+	return id
+}
+
+// deviceLabel identifies the target of msg in the delivery log.
+func deviceLabel(msg *PushMessage) string {
+	if msg.Token == "" && msg.Topic != "" {
+		return "topic:" + msg.Topic
+	}
+	return maskToken(msg.Token)
+}
+
+// maskToken keeps only the last characters of a device token for the delivery log.
+func maskToken(token string) string {
+	const visible = 8
+	if len(token) <= visible {
+		return token
 	}
-	errStr := err.Error()
-	return errStr == "UNREGISTERED" ||
-		errStr == "INVALID_ARGUMENT" ||
-		errStr == "NOT_FOUND" ||
-		errStr == "user FCM not active"
+	return "..." + token[len(token)-visible:]
 }
@@ -3,13 +3,18 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 
 	"remiaq/internal/models"
+	"remiaq/internal/repository"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock repositories
@@ -47,6 +52,31 @@ func (m *MockReminderRepository) GetDueReminders(ctx context.Context, now time.T
 	return args.Get(0).([]*models.Reminder), args.Error(1)
 }
 
+func (m *MockReminderRepository) GetDueRemindersByUser(ctx context.Context, userID string, before time.Time) ([]*models.Reminder, error) {
+	args := m.Called(ctx, userID, before)
+	return args.Get(0).([]*models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepository) GetUpcomingTriggers(ctx context.Context, before time.Time, limit int) ([]models.ScheduledTrigger, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).([]models.ScheduledTrigger), args.Error(1)
+}
+
+func (m *MockReminderRepository) ClaimDueReminders(ctx context.Context, owner string, before, leaseUntil time.Time, limit int) ([]*models.Reminder, error) {
+	args := m.Called(ctx, owner, before, leaseUntil, limit)
+	return args.Get(0).([]*models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepository) ClaimDueRemindersByUser(ctx context.Context, owner, userID string, before, leaseUntil time.Time) ([]*models.Reminder, error) {
+	args := m.Called(ctx, owner, userID, before, leaseUntil)
+	return args.Get(0).([]*models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepository) ReleaseClaims(ctx context.Context, owner string) error {
+	args := m.Called(ctx, owner)
+	return args.Error(0)
+}
+
 func (m *MockReminderRepository) UpdateSnooze(ctx context.Context, id string, snoozeUntil *time.Time) error {
 	args := m.Called(ctx, id, snoozeUntil)
 	return args.Error(0)
@@ -62,6 +92,11 @@ func (m *MockReminderRepository) UpdateLastSent(ctx context.Context, id string,
 	return args.Error(0)
 }
 
+func (m *MockReminderRepository) UpdateEscalation(ctx context.Context, id string, step int, nextTrigger time.Time) error {
+	args := m.Called(ctx, id, step, nextTrigger)
+	return args.Error(0)
+}
+
 func (m *MockReminderRepository) IncrementRetryCount(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -77,6 +112,47 @@ func (m *MockReminderRepository) UpdateStatus(ctx context.Context, id string, st
 	return args.Error(0)
 }
 
+func (m *MockReminderRepository) ApplyTransition(ctx context.Context, tr *models.ReminderTransition) (bool, error) {
+	args := m.Called(ctx, tr)
+	return args.Bool(0), args.Error(1)
+}
+
+// transition matches a transition of reminder id ("" = any reminder) that satisfies check (nil = any)
+type txMarker struct{}
+
+// stubTransactor marks the ctx it passes to fn, so mocks can check calls ran in the transaction.
+type stubTransactor struct {
+	calls, rollbacks int
+}
+
+func (s *stubTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	s.calls++
+	err := fn(context.WithValue(ctx, txMarker{}, true))
+	if err != nil {
+		s.rollbacks++
+	}
+	return err
+}
+
+// inTx matches a ctx inside a stubTransactor transaction
+var inTx = mock.MatchedBy(func(ctx context.Context) bool {
+	return ctx.Value(txMarker{}) != nil
+})
+
+func transition(id string, check func(*models.ReminderTransition) bool) interface{} {
+	return mock.MatchedBy(func(tr *models.ReminderTransition) bool {
+		return (id == "" || tr.ReminderID == id) && (check == nil || check(tr))
+	})
+}
+
+func sentAndCompleted(tr *models.ReminderTransition) bool {
+	return tr.SentAt != nil && tr.CompletedAt != nil
+}
+
+func completedUnsent(tr *models.ReminderTransition) bool {
+	return tr.SentAt == nil && tr.CompletedAt != nil
+}
+
 type MockUserRepository struct {
 	mock.Mock
 }
@@ -86,6 +162,20 @@ func (m *MockUserRepository) GetByID(ctx context.Context, id string) (*models.Us
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+// GetByIDs resolves each ID through the GetByID expectations so tests set up users once
+// for both lookups; IDs whose lookup fails are skipped like missing rows.
+func (m *MockUserRepository) GetByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		user, err := m.GetByID(ctx, id)
+		if err != nil || user == nil {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
 func (m *MockUserRepository) DisableFCM(ctx context.Context, userID string) error {
 	args := m.Called(ctx, userID)
 	return args.Error(0)
@@ -163,7 +253,7 @@ func TestNewReminderService(t *testing.T) {
 	assert.NotNil(t, service)
 	assert.Equal(t, reminderRepo, service.reminderRepo)
 	assert.Equal(t, userRepo, service.userRepo)
-	assert.Equal(t, fcmService, service.fcmService)
+	assert.Equal(t, fcmService, service.notifier)
 	assert.Equal(t, schedCalculator, service.schedCalculator)
 }
 
@@ -262,6 +352,35 @@ func TestReminderService_CompleteReminder(t *testing.T) {
 		assert.NotNil(t, reminder.LastCompletedAt)
 		reminderRepo.AssertExpectations(t)
 	})
+
+	t.Run("should read and complete in one transaction", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		transactor := &stubTransactor{}
+		service := NewReminderService(reminderRepo, &MockUserRepository{}, nil, NewScheduleCalculator(NewLunarCalendar()),
+			WithTransactor(transactor))
+
+		reminderRepo.On("GetByID", inTx, "test-id").Return(createTestReminder(), nil)
+		reminderRepo.On("MarkCompleted", inTx, "test-id", mock.AnythingOfType("time.Time")).Return(nil)
+
+		require.NoError(t, service.CompleteReminder(context.Background(), "test-id"))
+		assert.Equal(t, 1, transactor.calls)
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should roll back when the update fails", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		transactor := &stubTransactor{}
+		observer := &recordingObserver{}
+		service := NewReminderService(reminderRepo, &MockUserRepository{}, nil, NewScheduleCalculator(NewLunarCalendar()),
+			WithTransactor(transactor), WithScheduleObserver(observer))
+
+		reminderRepo.On("GetByID", inTx, "test-id").Return(createTestReminder(), nil)
+		reminderRepo.On("MarkCompleted", inTx, "test-id", mock.Anything).Return(errors.New("database is locked"))
+
+		assert.Error(t, service.CompleteReminder(context.Background(), "test-id"))
+		assert.Equal(t, 1, transactor.rollbacks)
+		assert.Empty(t, observer.events)
+	})
 }
 
 func TestReminderService_SnoozeReminder(t *testing.T) {
@@ -279,6 +398,98 @@ func TestReminderService_SnoozeReminder(t *testing.T) {
 	})
 }
 
+// recordingObserver records schedule events as reminder ID -> trigger time
+type recordingObserver struct {
+	events map[string]time.Time
+}
+
+func (o *recordingObserver) ReminderScheduled(id string, at time.Time) {
+	if o.events == nil {
+		o.events = make(map[string]time.Time)
+	}
+	o.events[id] = at
+}
+
+func TestReminderService_ScheduleObserver(t *testing.T) {
+	newService := func(reminderRepo *MockReminderRepository) (*ReminderService, *recordingObserver) {
+		observer := &recordingObserver{}
+		return NewReminderService(reminderRepo, &MockUserRepository{}, nil, NewScheduleCalculator(NewLunarCalendar()),
+			WithScheduleObserver(observer)), observer
+	}
+
+	t.Run("should report created reminder", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service, observer := newService(reminderRepo)
+		reminder := createTestReminder()
+
+		reminderRepo.On("Create", mock.Anything, reminder).Return(nil)
+
+		require.NoError(t, service.CreateReminder(context.Background(), reminder))
+		assert.Equal(t, reminder.NextTriggerAt, observer.events["test-id"])
+	})
+
+	t.Run("should report snooze on update", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service, observer := newService(reminderRepo)
+		reminder := createTestReminder()
+		snoozeUntil := reminder.NextTriggerAt.Add(time.Hour)
+		reminder.SnoozeUntil = &snoozeUntil
+
+		reminderRepo.On("Update", mock.Anything, reminder).Return(nil)
+
+		require.NoError(t, service.UpdateReminder(context.Background(), reminder))
+		assert.Equal(t, snoozeUntil, observer.events["test-id"])
+	})
+
+	t.Run("should unschedule paused reminder", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service, observer := newService(reminderRepo)
+		reminder := createTestReminder()
+		reminder.Status = models.ReminderStatusPaused
+
+		reminderRepo.On("Update", mock.Anything, reminder).Return(nil)
+
+		require.NoError(t, service.UpdateReminder(context.Background(), reminder))
+		assert.Contains(t, observer.events, "test-id")
+		assert.True(t, observer.events["test-id"].IsZero())
+	})
+
+	t.Run("should report snoozed reminder", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service, observer := newService(reminderRepo)
+
+		reminderRepo.On("UpdateSnooze", mock.Anything, "test-id", mock.AnythingOfType("*time.Time")).Return(nil)
+
+		require.NoError(t, service.SnoozeReminder(context.Background(), "test-id", 30*time.Minute))
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), observer.events["test-id"], time.Second)
+	})
+
+	t.Run("should unschedule completed and deleted reminders", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service, observer := newService(reminderRepo)
+
+		reminderRepo.On("GetByID", mock.Anything, "test-id").Return(createTestReminder(), nil)
+		reminderRepo.On("MarkCompleted", mock.Anything, "test-id", mock.AnythingOfType("time.Time")).Return(nil)
+		reminderRepo.On("Delete", mock.Anything, "other-id").Return(nil)
+
+		require.NoError(t, service.CompleteReminder(context.Background(), "test-id"))
+		require.NoError(t, service.DeleteReminder(context.Background(), "other-id"))
+		assert.True(t, observer.events["test-id"].IsZero())
+		assert.Contains(t, observer.events, "other-id")
+		assert.True(t, observer.events["other-id"].IsZero())
+	})
+
+	t.Run("should not report failed changes", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service, observer := newService(reminderRepo)
+
+		reminderRepo.On("UpdateSnooze", mock.Anything, "test-id", mock.Anything).Return(errors.New("db down"))
+
+		assert.Error(t, service.SnoozeReminder(context.Background(), "test-id", time.Minute))
+		assert.Empty(t, observer.events)
+	})
+}
+
 func TestReminderService_ProcessDueReminders(t *testing.T) {
 	t.Run("should process due reminders successfully", func(t *testing.T) {
 		reminderRepo := &MockReminderRepository{}
@@ -292,8 +503,8 @@ func TestReminderService_ProcessDueReminders(t *testing.T) {
 
 		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
 		userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil)
-		// Only expect MarkCompleted for one-time reminder (no FCM service configured)
-		reminderRepo.On("MarkCompleted", mock.Anything, "test-id", mock.AnythingOfType("time.Time")).Return(nil)
+		// Only expect completion for one-time reminder (no FCM service configured)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", completedUnsent)).Return(true, nil)
 
 		err := service.ProcessDueReminders(context.Background())
 
@@ -307,12 +518,11 @@ func TestReminderService_ProcessDueReminders(t *testing.T) {
 		userRepo := &MockUserRepository{}
 		service := NewReminderService(reminderRepo, userRepo, nil, NewScheduleCalculator(NewLunarCalendar()))
 
-		now := time.Now()
 		reminder := createTestReminder()
 		user := createTestUser()
 		user.FCMToken = "" // Invalid token
 
-		reminderRepo.On("GetDueReminders", mock.Anything, now).Return([]*models.Reminder{reminder}, nil)
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
 		userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil)
 		// No other expectations since FCM is not active
 
@@ -325,6 +535,768 @@ func TestReminderService_ProcessDueReminders(t *testing.T) {
 	})
 }
 
+func TestReminderService_ProcessDueReminders_Transitions(t *testing.T) {
+	t.Run("should advance recurring reminder from the occurrence it sent", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		service := NewReminderService(reminderRepo, userRepo, &stubNotifier{}, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminder := createTestReminder()
+		reminder.Type = models.ReminderTypeRecurring
+		reminder.RecurrencePattern = &models.RecurrencePattern{Type: models.RecurrenceTypeDaily}
+		reminder.TriggerTimeOfDay = "09:00"
+		reminder.NextTriggerAt = time.Now().Add(-time.Minute)
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", func(tr *models.ReminderTransition) bool {
+			return tr.PrevTriggerAt.Equal(reminder.NextTriggerAt) && tr.SentAt != nil &&
+				tr.NextTriggerAt.After(time.Now()) && !tr.IncrementRetry && tr.CompletedAt == nil
+		})).Return(true, nil)
+
+		require.NoError(t, service.ProcessDueReminders(context.Background()))
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should treat a transition already applied as done", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", sentAndCompleted)).Return(false, nil).Once()
+
+		require.NoError(t, service.ProcessDueReminders(context.Background()))
+		assert.Equal(t, 1, notifier.calls)
+		reminderRepo.AssertExpectations(t)
+	})
+}
+
+func TestReminderService_ProcessDueReminders_FCMErrorClasses(t *testing.T) {
+	setup := func(t *testing.T, code int, status, fcmCode string) (*ReminderService, *MockReminderRepository, *MockUserRepository) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		fcm := newTestFCMService(t, fcmErrorHandler(code, status, fcmCode))
+		service := NewReminderService(reminderRepo, userRepo, fcm, NewScheduleCalculator(NewLunarCalendar()),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+		return service, reminderRepo, userRepo
+	}
+
+	t.Run("should disable token on UNREGISTERED without system error", func(t *testing.T) {
+		service, reminderRepo, userRepo := setup(t, 404, "NOT_FOUND", "UNREGISTERED")
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("DisableFCM", mock.Anything, "user-1").Return(nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.NoError(t, err)
+		userRepo.AssertExpectations(t)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should skip the reminder without disabling the token on INVALID_ARGUMENT", func(t *testing.T) {
+		service, reminderRepo, userRepo := setup(t, 400, "INVALID_ARGUMENT", "INVALID_ARGUMENT")
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, mock.MatchedBy(func(t *models.ReminderTransition) bool {
+			return t.ReminderID == "test-id" && t.SentAt == nil
+		})).Return(true, nil).Once()
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.NoError(t, err)
+		userRepo.AssertNotCalled(t, "DisableFCM", mock.Anything, mock.Anything)
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should leave reminder due on UNAVAILABLE", func(t *testing.T) {
+		service, reminderRepo, userRepo := setup(t, 503, "UNAVAILABLE", "UNAVAILABLE")
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.NoError(t, err)
+		userRepo.AssertNotCalled(t, "DisableFCM", mock.Anything, mock.Anything)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should back off on QUOTA_EXCEEDED", func(t *testing.T) {
+		service, reminderRepo, userRepo := setup(t, 429, "RESOURCE_EXHAUSTED", "QUOTA_EXCEEDED")
+
+		second := createTestReminder()
+		second.ID = "test-id-2"
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder(), second}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil).Once()
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.NoError(t, err)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("should report system error on THIRD_PARTY_AUTH_ERROR", func(t *testing.T) {
+		service, reminderRepo, userRepo := setup(t, 401, "UNAUTHENTICATED", "THIRD_PARTY_AUTH_ERROR")
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.ErrorIs(t, err, ErrSystemFCM)
+		userRepo.AssertNotCalled(t, "DisableFCM", mock.Anything, mock.Anything)
+	})
+}
+
+func TestReminderService_ProcessDueReminders_Retry(t *testing.T) {
+	t.Run("should retry transient failures and advance reminder state", func(t *testing.T) {
+		calls := 0
+		fcm := newTestFCMService(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				fcmErrorHandler(503, "UNAVAILABLE", "UNAVAILABLE")(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"projects/test-project/messages/1"}`))
+		})
+
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		service := NewReminderService(reminderRepo, userRepo, fcm, NewScheduleCalculator(NewLunarCalendar()),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", sentAndCompleted)).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should leave reminder due after exhausting retries", func(t *testing.T) {
+		calls := 0
+		fcm := newTestFCMService(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			fcmErrorHandler(503, "UNAVAILABLE", "UNAVAILABLE")(w, r)
+		})
+
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		service := NewReminderService(reminderRepo, userRepo, fcm, NewScheduleCalculator(NewLunarCalendar()),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, mock.Anything)
+	})
+}
+
+// MockDeliveryRepository captures delivery log writes
+type MockDeliveryRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryRepository) Create(ctx context.Context, delivery *models.Delivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func (m *MockDeliveryRepository) ListByReminder(ctx context.Context, reminderID string, page, perPage int) ([]*models.Delivery, int, error) {
+	args := m.Called(ctx, reminderID, page, perPage)
+	return args.Get(0).([]*models.Delivery), args.Int(1), args.Error(2)
+}
+
+func (m *MockDeliveryRepository) ListByUser(ctx context.Context, userID string, page, perPage int) ([]*models.Delivery, int, error) {
+	args := m.Called(ctx, userID, page, perPage)
+	return args.Get(0).([]*models.Delivery), args.Int(1), args.Error(2)
+}
+
+func (m *MockDeliveryRepository) Acknowledge(ctx context.Context, dispatchID, userID, event string, at time.Time) ([]*models.Delivery, error) {
+	args := m.Called(ctx, dispatchID, userID, event, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Delivery), args.Error(1)
+}
+
+func TestReminderService_ProcessDueReminders_DeliveryLog(t *testing.T) {
+	transient := &FCMError{Class: FCMErrorUnavailable, Err: errors.New("UNAVAILABLE")}
+
+	t.Run("should record every send attempt", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		deliveryRepo := &MockDeliveryRepository{}
+		notifier := &stubNotifier{errs: []error{transient}}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+			WithDeliveryRepo(deliveryRepo))
+
+		reminder := createTestReminder()
+		var deliveries []*models.Delivery
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", sentAndCompleted)).Return(true, nil)
+		deliveryRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Delivery")).
+			Run(func(args mock.Arguments) { deliveries = append(deliveries, args.Get(1).(*models.Delivery)) }).
+			Return(nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+
+		failed := deliveries[0]
+		assert.Equal(t, models.DeliveryOutcomeFailed, failed.Outcome)
+		assert.Equal(t, string(FCMErrorUnavailable), failed.ErrorClass)
+		assert.Equal(t, 1, failed.Attempt)
+		assert.Nil(t, failed.SentAt)
+
+		sent := deliveries[1]
+		assert.Equal(t, models.DeliveryOutcomeSent, sent.Outcome)
+		assert.Equal(t, "msg-id", sent.ProviderMessageID)
+		assert.Equal(t, 2, sent.Attempt)
+		// Cùng một tin qua các lần gửi lại: client ack bằng delivery_id trong payload
+		assert.NotEmpty(t, sent.DispatchID)
+		assert.Equal(t, sent.DispatchID, failed.DispatchID)
+		assert.Equal(t, sent.DispatchID, notifier.last.Data["delivery_id"])
+		assert.Equal(t, "test-id", sent.ReminderID)
+		assert.Equal(t, "user-1", sent.UserID)
+		assert.Equal(t, models.DeliveryChannelFCM, sent.Channel)
+		assert.Equal(t, reminder.NextTriggerAt, sent.ScheduledFor)
+		assert.NotNil(t, sent.SentAt)
+		assert.NotEqual(t, sent.ID, failed.ID)
+	})
+
+	t.Run("should keep sending when the delivery log fails", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		deliveryRepo := &MockDeliveryRepository{}
+		service := NewReminderService(reminderRepo, userRepo, &stubNotifier{}, NewScheduleCalculator(NewLunarCalendar()),
+			WithDeliveryRepo(deliveryRepo))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{createTestReminder()}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", sentAndCompleted)).Return(true, nil)
+		deliveryRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		reminderRepo.AssertExpectations(t)
+	})
+}
+
+func TestReminderService_ProcessDueReminders_Templates(t *testing.T) {
+	reminderRepo := &MockReminderRepository{}
+	userRepo := &MockUserRepository{}
+	notifier := &stubNotifier{}
+	service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+		WithTemplateRenderer(NewTemplateRenderer(NewLunarCalendar(), nil)))
+
+	reminder := createTestReminder()
+	reminder.RepeatStrategy = models.RepeatStrategyRetryUntilComplete
+	reminder.RetryCount = 1
+	reminder.MaxRetries = 3
+	reminder.RetryIntervalSec = 60
+	user := createTestUser()
+	user.Locale = models.LocaleEN
+
+	reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{reminder}, nil)
+	userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil)
+	reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", func(tr *models.ReminderTransition) bool {
+		return tr.SentAt != nil && tr.IncrementRetry && !tr.NextTriggerAt.IsZero()
+	})).Return(true, nil)
+
+	err := service.ProcessDueReminders(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, notifier.last)
+	assert.Equal(t, "Reminder again: "+reminder.Title, notifier.last.Title)
+	assert.Contains(t, notifier.last.Body, "Reminder 1/3.")
+}
+
+// gatedNotifier blocks each send until want sends are in flight at once (or a timeout),
+// recording the peak concurrency and the order of reminder IDs per token.
+type gatedNotifier struct {
+	want    int
+	mu      sync.Mutex
+	open    chan struct{}
+	once    sync.Once
+	flying  int
+	peak    int
+	byToken map[string][]string
+}
+
+func newGatedNotifier(want int) *gatedNotifier {
+	return &gatedNotifier{want: want, open: make(chan struct{}), byToken: make(map[string][]string)}
+}
+
+func (n *gatedNotifier) Send(ctx context.Context, msg *PushMessage) (string, error) {
+	n.mu.Lock()
+	n.flying++
+	n.peak = max(n.peak, n.flying)
+	n.byToken[msg.Token] = append(n.byToken[msg.Token], msg.Data["reminder_id"])
+	if n.flying >= n.want {
+		n.once.Do(func() { close(n.open) })
+	}
+	n.mu.Unlock()
+
+	select {
+	case <-n.open:
+	case <-time.After(time.Second):
+	}
+
+	n.mu.Lock()
+	n.flying--
+	n.mu.Unlock()
+	return "msg-id", nil
+}
+
+func TestReminderService_ProcessDueReminders_Concurrency(t *testing.T) {
+	// setup returns reminders for users user-0..user-(users-1), perUser each, with matching users
+	setup := func(users, perUser int) (*MockReminderRepository, *MockUserRepository) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		var reminders []*models.Reminder
+		for u := 0; u < users; u++ {
+			user := createTestUser()
+			user.ID = fmt.Sprintf("user-%d", u)
+			user.FCMToken = "token-" + user.ID
+			userRepo.On("GetByID", mock.Anything, user.ID).Return(user, nil)
+			for i := 0; i < perUser; i++ {
+				reminder := createTestReminder()
+				reminder.ID = fmt.Sprintf("rem-%d-%d", u, i)
+				reminder.UserID = user.ID
+				reminder.NextTriggerAt = time.Now().Add(-time.Minute)
+				reminders = append(reminders, reminder)
+			}
+		}
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return(reminders, nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("", sentAndCompleted)).Return(true, nil)
+		return reminderRepo, userRepo
+	}
+
+	t.Run("should process users in parallel up to the limit", func(t *testing.T) {
+		reminderRepo, userRepo := setup(6, 1)
+		notifier := newGatedNotifier(3)
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithConcurrency(3))
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, notifier.peak)
+		assert.Len(t, notifier.byToken, 6)
+		reminderRepo.AssertNumberOfCalls(t, "ApplyTransition", 6)
+	})
+
+	t.Run("should keep the order of one user's reminders", func(t *testing.T) {
+		reminderRepo, userRepo := setup(2, 3)
+		notifier := newGatedNotifier(2)
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithConcurrency(4))
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, notifier.peak)
+		assert.Equal(t, []string{"rem-0-0", "rem-0-1", "rem-0-2"}, notifier.byToken["token-user-0"])
+		assert.Equal(t, []string{"rem-1-0", "rem-1-1", "rem-1-2"}, notifier.byToken["token-user-1"])
+	})
+
+	t.Run("should stop on context cancellation", func(t *testing.T) {
+		reminderRepo, userRepo := setup(4, 1)
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := service.ProcessDueReminders(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, notifier.calls)
+	})
+
+	t.Run("should skip reminders of missing users", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		orphan := createTestReminder()
+		orphan.ID, orphan.UserID = "orphan", "deleted-user"
+		orphan.NextTriggerAt = time.Now().Add(-time.Minute)
+		owned := createTestReminder()
+		owned.NextTriggerAt = time.Now().Add(-time.Minute)
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{orphan, owned}, nil)
+		userRepo.On("GetByID", mock.Anything, "deleted-user").Return((*models.User)(nil), errors.New("not found"))
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("test-id", sentAndCompleted)).Return(true, nil)
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, transition("orphan", nil))
+	})
+}
+
+func TestReminderService_ProcessDueReminders_Digest(t *testing.T) {
+	dueReminder := func(id, title string, at time.Time) *models.Reminder {
+		r := createTestReminder()
+		r.ID = id
+		r.Title = title
+		r.NextTriggerAt = at
+		return r
+	}
+
+	t.Run("should group due reminders of a digest user into one push", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		now := time.Now()
+		first := dueReminder("rem-1", "Uống thuốc", now.Add(-time.Minute))
+		second := dueReminder("rem-2", "Tập thể dục", now.Add(-time.Minute))
+		user := createTestUser()
+		user.DigestEnabled = true
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{first, second}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil).Once()
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("", sentAndCompleted)).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		require.NotNil(t, notifier.last)
+		assert.Equal(t, "Bạn có 2 lời nhắc", notifier.last.Title)
+		assert.Equal(t, "Uống thuốc, Tập thể dục", notifier.last.Body)
+		assert.Equal(t, "digest", notifier.last.Data["type"])
+		assert.JSONEq(t, `["rem-1","rem-2"]`, notifier.last.Data["reminder_ids"])
+		reminderRepo.AssertNumberOfCalls(t, "ApplyTransition", 2)
+		reminderRepo.AssertCalled(t, "ApplyTransition", mock.Anything, transition("rem-1", sentAndCompleted))
+		reminderRepo.AssertCalled(t, "ApplyTransition", mock.Anything, transition("rem-2", sentAndCompleted))
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("should pull in reminders due within the window", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		now := time.Now()
+		due := dueReminder("rem-1", "A", now.Add(-time.Minute))
+		upcoming := dueReminder("rem-2", "B", now.Add(5*time.Minute))
+		user := createTestUser()
+		user.DigestEnabled = true
+		user.DigestWindowSec = 600
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{due}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil)
+		reminderRepo.On("GetDueRemindersByUser", mock.Anything, "user-1", mock.AnythingOfType("time.Time")).
+			Return([]*models.Reminder{due, upcoming}, nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("", sentAndCompleted)).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		assert.JSONEq(t, `["rem-1","rem-2"]`, notifier.last.Data["reminder_ids"])
+		// Reminder kéo sớm được tính như gửi đúng giờ của nó
+		reminderRepo.AssertCalled(t, "ApplyTransition", mock.Anything, transition("rem-2", func(tr *models.ReminderTransition) bool {
+			return sentAndCompleted(tr) && tr.SentAt.Equal(upcoming.NextTriggerAt) && tr.CompletedAt.Equal(upcoming.NextTriggerAt)
+		}))
+	})
+
+	t.Run("should send separately when digest is disabled", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()))
+
+		now := time.Now()
+		first := dueReminder("rem-1", "A", now.Add(-time.Minute))
+		second := dueReminder("rem-2", "B", now.Add(-time.Minute))
+
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).Return([]*models.Reminder{first, second}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("", sentAndCompleted)).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, notifier.calls)
+		assert.Equal(t, "rem-2", notifier.last.Data["reminder_id"])
+		reminderRepo.AssertNotCalled(t, "GetDueRemindersByUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestReminderService_ProcessDueReminders_RateLimit(t *testing.T) {
+	dueReminder := func(id, userID string) *models.Reminder {
+		r := createTestReminder()
+		r.ID = id
+		r.UserID = userID
+		r.NextTriggerAt = time.Now().Add(-time.Minute)
+		return r
+	}
+
+	t.Run("should defer over-limit sends and record them", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		deliveryRepo := &MockDeliveryRepository{}
+		notifier := &stubNotifier{}
+		limiter := NewRateLimiter(RateLimitConfig{PerUser: RateLimit{Limit: 1, Window: time.Minute}})
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithDeliveryRepo(deliveryRepo), WithRateLimiter(limiter))
+
+		var deliveries []*models.Delivery
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).
+			Return([]*models.Reminder{dueReminder("rem-1", "user-1"), dueReminder("rem-2", "user-1")}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("rem-1", sentAndCompleted)).Return(true, nil)
+		deliveryRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Delivery")).
+			Run(func(args mock.Arguments) { deliveries = append(deliveries, args.Get(1).(*models.Delivery)) }).
+			Return(nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		// rem-2 không bị bỏ: vẫn due cho tick sau
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, transition("rem-2", nil))
+
+		require.Len(t, deliveries, 2)
+		deferred := deliveries[1]
+		assert.Equal(t, "rem-2", deferred.ReminderID)
+		assert.Equal(t, models.DeliveryOutcomeDeferred, deferred.Outcome)
+		assert.Equal(t, models.DeliveryErrorRateLimited, deferred.ErrorClass)
+		assert.Contains(t, deferred.ErrorMessage, "user user-1")
+		assert.Nil(t, deferred.SentAt)
+	})
+
+	t.Run("should record a deferral once per throttle window", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		deliveryRepo := &MockDeliveryRepository{}
+		notifier := &stubNotifier{}
+		limiter := NewRateLimiter(RateLimitConfig{PerUser: RateLimit{Limit: 1, Window: time.Minute}})
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithDeliveryRepo(deliveryRepo), WithRateLimiter(limiter))
+		require.NoError(t, limiter.Allow("user-1", nil, 1))
+
+		var deliveries []*models.Delivery
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).
+			Return([]*models.Reminder{dueReminder("rem-1", "user-1")}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("rem-1", sentAndCompleted)).Return(true, nil)
+		deliveryRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Delivery")).
+			Run(func(args mock.Arguments) { deliveries = append(deliveries, args.Get(1).(*models.Delivery)) }).
+			Return(nil)
+
+		// Nhiều tick trong cùng cửa sổ chỉ ghi một dòng deferred
+		for tick := 0; tick < 3; tick++ {
+			require.NoError(t, service.ProcessDueReminders(context.Background()))
+			now = now.Add(10 * time.Second)
+		}
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, models.DeliveryOutcomeDeferred, deliveries[0].Outcome)
+		assert.Equal(t, 0, notifier.calls)
+
+		now = now.Add(time.Minute)
+		require.NoError(t, service.ProcessDueReminders(context.Background()))
+
+		assert.Equal(t, 1, notifier.calls)
+		require.Len(t, deliveries, 2)
+		assert.Equal(t, models.DeliveryOutcomeSent, deliveries[1].Outcome)
+	})
+
+	t.Run("should stop the tick when the global limit is reached", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		limiter := NewRateLimiter(RateLimitConfig{Global: RateLimit{Limit: 1, Window: time.Minute}})
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithRateLimiter(limiter), WithConcurrency(1))
+
+		user2 := createTestUser()
+		user2.ID = "user-2"
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).
+			Return([]*models.Reminder{dueReminder("rem-1", "user-1"), dueReminder("rem-2", "user-1"), dueReminder("rem-3", "user-2")}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		userRepo.On("GetByID", mock.Anything, "user-2").Return(user2, nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("rem-1", sentAndCompleted)).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		reminderRepo.AssertNotCalled(t, "ApplyTransition", mock.Anything, transition("rem-3", nil))
+	})
+
+	t.Run("should count a digest once per user and each reminder", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		limiter := NewRateLimiter(RateLimitConfig{PerUser: RateLimit{Limit: 1, Window: time.Minute}})
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithRateLimiter(limiter))
+
+		user := createTestUser()
+		user.DigestEnabled = true
+		reminderRepo.On("GetDueReminders", mock.Anything, mock.AnythingOfType("time.Time")).
+			Return([]*models.Reminder{dueReminder("rem-1", "user-1"), dueReminder("rem-2", "user-1")}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil)
+		reminderRepo.On("ApplyTransition", mock.Anything, transition("", sentAndCompleted)).Return(true, nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		reminderRepo.AssertNumberOfCalls(t, "ApplyTransition", 2)
+	})
+}
+
+func TestReminderService_ProcessDueReminders_Leasing(t *testing.T) {
+	dueReminder := func(id string) *models.Reminder {
+		reminder := createTestReminder()
+		reminder.ID = id
+		reminder.NextTriggerAt = time.Now().Add(-time.Minute)
+		return reminder
+	}
+	holdsLease := mock.MatchedBy(func(ctx context.Context) bool {
+		owner, ok := repository.LeaseOwner(ctx)
+		return ok && owner == "worker-a"
+	})
+
+	t.Run("should claim batches until one is short and release the claims", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		service := NewReminderService(reminderRepo, userRepo, nil, NewScheduleCalculator(NewLunarCalendar()),
+			WithLeasing("worker-a", time.Minute, 2))
+
+		leaseUntil := mock.MatchedBy(func(until time.Time) bool {
+			return until.Sub(time.Now()) > 50*time.Second
+		})
+		reminderRepo.On("ClaimDueReminders", holdsLease, "worker-a", mock.AnythingOfType("time.Time"), leaseUntil, 2).
+			Return([]*models.Reminder{dueReminder("rem-1"), dueReminder("rem-2")}, nil).Once()
+		reminderRepo.On("ClaimDueReminders", holdsLease, "worker-a", mock.AnythingOfType("time.Time"), leaseUntil, 2).
+			Return([]*models.Reminder{dueReminder("rem-3")}, nil).Once()
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(createTestUser(), nil)
+		for _, id := range []string{"rem-1", "rem-2", "rem-3"} {
+			reminderRepo.On("ApplyTransition", holdsLease, transition(id, completedUnsent)).Return(true, nil).Once()
+		}
+		reminderRepo.On("ReleaseClaims", mock.Anything, "worker-a").Return(nil).Once()
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		reminderRepo.AssertExpectations(t)
+		reminderRepo.AssertNotCalled(t, "GetDueReminders", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should claim reminders pulled into a digest", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		userRepo := &MockUserRepository{}
+		notifier := &stubNotifier{}
+		service := NewReminderService(reminderRepo, userRepo, notifier, NewScheduleCalculator(NewLunarCalendar()),
+			WithLeasing("worker-a", time.Minute, 0))
+		user := createTestUser()
+		user.DigestEnabled = true
+		user.DigestWindowSec = 600
+		upcoming := dueReminder("rem-2")
+		upcoming.NextTriggerAt = time.Now().Add(5 * time.Minute)
+
+		reminderRepo.On("ClaimDueReminders", holdsLease, "worker-a", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 0).
+			Return([]*models.Reminder{dueReminder("rem-1")}, nil)
+		userRepo.On("GetByID", mock.Anything, "user-1").Return(user, nil)
+		reminderRepo.On("ClaimDueRemindersByUser", holdsLease, "worker-a", "user-1", mock.AnythingOfType("time.Time"),
+			mock.MatchedBy(func(until time.Time) bool { return until.Sub(time.Now()) > 50*time.Second })).
+			Return([]*models.Reminder{dueReminder("rem-1"), upcoming}, nil).Once()
+		reminderRepo.On("ApplyTransition", holdsLease, transition("", sentAndCompleted)).Return(true, nil).Twice()
+		reminderRepo.On("ReleaseClaims", mock.Anything, "worker-a").Return(nil).Once()
+
+		err := service.ProcessDueReminders(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, notifier.calls)
+		assert.JSONEq(t, `["rem-1","rem-2"]`, notifier.last.Data["reminder_ids"])
+		reminderRepo.AssertExpectations(t)
+		reminderRepo.AssertNotCalled(t, "GetDueRemindersByUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should release claims when claiming fails", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service := NewReminderService(reminderRepo, &MockUserRepository{}, nil, NewScheduleCalculator(NewLunarCalendar()),
+			WithLeasing("worker-a", time.Minute, 2))
+
+		reminderRepo.On("ClaimDueReminders", mock.Anything, "worker-a", mock.Anything, mock.Anything, 2).
+			Return([]*models.Reminder(nil), errors.New("database is locked"))
+		reminderRepo.On("ReleaseClaims", mock.Anything, "worker-a").Return(nil)
+
+		err := service.ProcessDueReminders(context.Background())
+
+		assert.ErrorContains(t, err, "database is locked")
+		reminderRepo.AssertExpectations(t)
+	})
+
+	t.Run("should release claims with a live context after shutdown", func(t *testing.T) {
+		reminderRepo := &MockReminderRepository{}
+		service := NewReminderService(reminderRepo, &MockUserRepository{}, nil, NewScheduleCalculator(NewLunarCalendar()),
+			WithLeasing("worker-a", time.Minute, 0))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		reminderRepo.On("ClaimDueReminders", mock.Anything, "worker-a", mock.Anything, mock.Anything, 0).
+			Return([]*models.Reminder{}, nil)
+		reminderRepo.On("ReleaseClaims", mock.MatchedBy(func(ctx context.Context) bool {
+			return ctx.Err() == nil
+		}), "worker-a").Return(nil)
+
+		err := service.ProcessDueReminders(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		reminderRepo.AssertExpectations(t)
+	})
+}
+
+func TestGroupByUser(t *testing.T) {
+	reminders := []*models.Reminder{
+		{ID: "a", UserID: "u2"},
+		{ID: "b", UserID: "u1"},
+		{ID: "c", UserID: "u2"},
+	}
+
+	groups := groupByUser(reminders)
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, []*models.Reminder{reminders[0], reminders[2]}, groups[0])
+	assert.Equal(t, []*models.Reminder{reminders[1]}, groups[1])
+}
+
+func TestMaskToken(t *testing.T) {
+	assert.Equal(t, "short", maskToken("short"))
+	assert.Equal(t, "...34567890", maskToken("abcdefghij1234567890"))
+}
+
 func TestIsTokenInvalidError(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -332,9 +1304,10 @@ func TestIsTokenInvalidError(t *testing.T) {
 		expected bool
 	}{
 		{"nil error", nil, false},
-		{"UNREGISTERED error", errors.New("UNREGISTERED"), true},
-		{"INVALID_ARGUMENT error", errors.New("INVALID_ARGUMENT"), true},
-		{"NOT_FOUND error", errors.New("NOT_FOUND"), true},
+		{"user FCM inactive", ErrUserFCMInactive, true},
+		{"classified token error", &FCMError{Class: FCMErrorTokenInvalid, Err: errors.New("UNREGISTERED")}, true},
+		{"classified unavailable error", &FCMError{Class: FCMErrorUnavailable, Err: errors.New("UNAVAILABLE")}, false},
+		{"classified message error", &FCMError{Class: FCMErrorInvalidMessage, Err: errors.New("INVALID_ARGUMENT")}, false},
 		{"other error", errors.New("network error"), false},
 	}
 
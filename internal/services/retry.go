@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures per-send retries with exponential backoff and jitter.
+type RetryPolicy struct {
+	MaxAttempts int           // Tổng số lần gửi (kể cả lần đầu), tối thiểu 1
+	BaseDelay   time.Duration // Độ trễ trước lần thử lại đầu tiên
+	MaxDelay    time.Duration // Độ trễ tối đa giữa hai lần thử
+	Jitter      float64       // Tỷ lệ ngẫu nhiên cộng/trừ vào độ trễ (0..1)
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// Backoff returns the delay before the given retry (1 = first retry).
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	if retry < 1 || p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			delay = p.MaxDelay
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		// Jitter trong khoảng [-Jitter, +Jitter] để tránh các lần retry dồn cùng lúc
+		factor := 1 + p.Jitter*(2*rand.Float64()-1)
+		delay = time.Duration(float64(delay) * factor)
+	}
+	return delay
+}
+
+// Do runs send until it succeeds, fails with a non-transient error, or attempts run out.
+// Only FCMErrorUnavailable is retried. Returns the number of attempts made and the last error.
+func (p RetryPolicy) Do(ctx context.Context, send func(ctx context.Context) error) (int, error) {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = send(ctx)
+		if err == nil {
+			return attempt, nil
+		}
+		if ClassifyFCMError(err) != FCMErrorUnavailable || attempt == maxAttempts {
+			return attempt, err
+		}
+
+		delay := p.Backoff(attempt)
+		log.Printf("RetryPolicy: transient send failure (attempt %d/%d), retrying in %s: %v",
+			attempt, maxAttempts, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+	return maxAttempts, err
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Run("should grow exponentially and cap at MaxDelay", func(t *testing.T) {
+		p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+		assert.Equal(t, time.Duration(0), p.Backoff(0))
+		assert.Equal(t, 100*time.Millisecond, p.Backoff(1))
+		assert.Equal(t, 200*time.Millisecond, p.Backoff(2))
+		assert.Equal(t, 300*time.Millisecond, p.Backoff(3))
+		assert.Equal(t, 300*time.Millisecond, p.Backoff(10))
+	})
+
+	t.Run("should stay within jitter bounds", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+
+		for i := 0; i < 50; i++ {
+			d := p.Backoff(1)
+			assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+			assert.LessOrEqual(t, d, 150*time.Millisecond)
+		}
+	})
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	transient := &FCMError{Class: FCMErrorUnavailable, Err: errors.New("UNAVAILABLE")}
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	t.Run("should retry transient errors until success", func(t *testing.T) {
+		calls := 0
+		attempts, err := p.Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return transient
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("should give up after MaxAttempts", func(t *testing.T) {
+		attempts, err := p.Do(context.Background(), func(ctx context.Context) error {
+			return transient
+		})
+
+		assert.ErrorIs(t, err, transient)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("should not retry non-transient errors", func(t *testing.T) {
+		tokenErr := &FCMError{Class: FCMErrorTokenInvalid, Err: errors.New("UNREGISTERED")}
+		attempts, err := p.Do(context.Background(), func(ctx context.Context) error {
+			return tokenErr
+		})
+
+		assert.ErrorIs(t, err, tokenErr)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("should stop when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		slow := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}
+
+		attempts, err := slow.Do(ctx, func(ctx context.Context) error {
+			return transient
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+)
+
+// builtinTemplates are used when no admin template exists for a key/locale.
+var builtinTemplates = map[string]map[string]models.NotificationTemplate{
+	models.TemplateDefault: {
+		models.LocaleVI: {
+			Title:      "{title}",
+			Body:       "{description}",
+			RetryTitle: "Nhắc lại: {title}",
+			RetryBody:  "Lần nhắc thứ {retry}/{max_retries}. {description}",
+		},
+		models.LocaleEN: {
+			Title:      "{title}",
+			Body:       "{description}",
+			RetryTitle: "Reminder again: {title}",
+			RetryBody:  "Reminder {retry}/{max_retries}. {description}",
+		},
+	},
+	models.TemplatePreReminder: {
+		models.LocaleVI: {
+			Title:      "{title} ({until_due})",
+			Body:       "{description}",
+			RetryTitle: "Nhắc lại: {title} ({until_due})",
+			RetryBody:  "Lần nhắc thứ {retry}/{max_retries}. {description}",
+		},
+		models.LocaleEN: {
+			Title:      "{title} ({until_due})",
+			Body:       "{description}",
+			RetryTitle: "Reminder again: {title} ({until_due})",
+			RetryBody:  "Reminder {retry}/{max_retries}. {description}",
+		},
+	},
+	models.TemplateDigest: {
+		models.LocaleVI: {
+			Title: "Bạn có {count} lời nhắc",
+			Body:  "{titles}",
+		},
+		models.LocaleEN: {
+			Title: "You have {count} reminders",
+			Body:  "{titles}",
+		},
+	},
+	models.TemplateEscalation: {
+		models.LocaleVI: {
+			Title: "Chưa hoàn thành: {title}",
+			Body:  "{owner} chưa hoàn thành lời nhắc \"{title}\" sau {max_retries} lần nhắc. {description}",
+		},
+		models.LocaleEN: {
+			Title: "Not completed: {title}",
+			Body:  "{owner} has not completed \"{title}\" after {max_retries} reminders. {description}",
+		},
+	},
+	models.TemplateLunar: {
+		models.LocaleVI: {
+			Title: "{title}",
+			Body:  "Ngày {lunar_date} âm lịch. {description}",
+		},
+		models.LocaleEN: {
+			Title: "{title}",
+			Body:  "Lunar date {lunar_date}. {description}",
+		},
+	},
+}
+
+// TemplateRenderer renders notification title/body from templates.
+//
+// Placeholders: {title}, {description}, {lunar_date}, {occurrence},
+// {retry}, {max_retries}, {until_due}.
+type TemplateRenderer struct {
+	lunarCalendar *LunarCalendar
+	repo          repository.TemplateRepository // optional, admin templates override built-ins
+}
+
+// NewTemplateRenderer creates a renderer. repo may be nil to use built-in templates only.
+func NewTemplateRenderer(lunarCalendar *LunarCalendar, repo repository.TemplateRepository) *TemplateRenderer {
+	return &TemplateRenderer{
+		lunarCalendar: lunarCalendar,
+		repo:          repo,
+	}
+}
+
+// Render returns the localized title and body for sending reminder to user at now.
+func (r *TemplateRenderer) Render(ctx context.Context, reminder *models.Reminder, user *models.User, now time.Time) (string, string) {
+	locale := normalizeLocale(user.Locale)
+	tmpl := r.resolve(ctx, reminder.Template, locale)
+
+	title, body := tmpl.Title, tmpl.Body
+	// Lần gửi lại của retry_until_complete dùng biến thể "nhắc lại" nếu template có
+	if reminder.RetryCount > 0 {
+		if tmpl.RetryTitle != "" {
+			title = tmpl.RetryTitle
+		}
+		if tmpl.RetryBody != "" {
+			body = tmpl.RetryBody
+		}
+	}
+
+	replacer := r.replacer(reminder, locale, now)
+	// TrimSpace: placeholder rỗng (vd. description) không để lại khoảng trắng thừa
+	return strings.TrimSpace(replacer.Replace(title)), strings.TrimSpace(replacer.Replace(body))
+}
+
+// RenderDigest returns the localized title and body for a digest of reminders.
+// Digest placeholders: {count}, {titles}.
+func (r *TemplateRenderer) RenderDigest(ctx context.Context, reminders []*models.Reminder, user *models.User) (string, string) {
+	locale := normalizeLocale(user.Locale)
+	tmpl := r.resolve(ctx, models.TemplateDigest, locale)
+
+	replacer := strings.NewReplacer(
+		"{count}", strconv.Itoa(len(reminders)),
+		"{titles}", digestTitles(reminders, locale),
+	)
+	return strings.TrimSpace(replacer.Replace(tmpl.Title)), strings.TrimSpace(replacer.Replace(tmpl.Body))
+}
+
+// RenderEscalation returns the title and body telling a contact, in locale, that owner
+// has not completed reminder. Besides the reminder placeholders it supports {owner}.
+func (r *TemplateRenderer) RenderEscalation(ctx context.Context, reminder *models.Reminder, owner *models.User, locale string, now time.Time) (string, string) {
+	locale = normalizeLocale(locale)
+	tmpl := r.resolve(ctx, models.TemplateEscalation, locale)
+
+	// {owner} thay trước, các placeholder còn lại như thông báo thường
+	ownerName := strings.NewReplacer("{owner}", owner.Email)
+	replacer := r.replacer(reminder, locale, now)
+	title := replacer.Replace(ownerName.Replace(tmpl.Title))
+	body := replacer.Replace(ownerName.Replace(tmpl.Body))
+	return strings.TrimSpace(title), strings.TrimSpace(body)
+}
+
+// resolve finds the template for key/locale: admin template, then built-in, then built-in default.
+func (r *TemplateRenderer) resolve(ctx context.Context, key, locale string) models.NotificationTemplate {
+	if key == "" {
+		key = models.TemplateDefault
+	}
+
+	if r.repo != nil {
+		tmpl, err := r.repo.GetByKey(ctx, key, locale)
+		if err == nil && tmpl != nil {
+			return *tmpl
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("TemplateRenderer: failed to load template %s/%s: %v", key, locale, err)
+		}
+	}
+
+	if variants, ok := builtinTemplates[key]; ok {
+		return variants[locale]
+	}
+	return builtinTemplates[models.TemplateDefault][locale]
+}
+
+// replacer builds the placeholder values for a reminder.
+func (r *TemplateRenderer) replacer(reminder *models.Reminder, locale string, now time.Time) *strings.Replacer {
+	due := reminder.NextTriggerAt
+	if reminder.DueAt != nil {
+		due = *reminder.DueAt
+	}
+
+	return strings.NewReplacer(
+		"{title}", reminder.Title,
+		"{description}", reminder.Description,
+		"{lunar_date}", r.formatLunarDate(due),
+		"{occurrence}", strconv.Itoa(reminder.OccurrenceCount+1),
+		"{retry}", strconv.Itoa(reminder.RetryCount),
+		"{max_retries}", strconv.Itoa(reminder.MaxRetries),
+		"{until_due}", humanizeUntil(due.Sub(now), locale),
+	)
+}
+
+// formatLunarDate formats the lunar date of t as d/m/yyyy, marking leap months.
+func (r *TemplateRenderer) formatLunarDate(t time.Time) string {
+	if r.lunarCalendar == nil {
+		return ""
+	}
+	ld := r.lunarCalendar.SolarToLunar(t)
+	s := fmt.Sprintf("%d/%d/%d", ld.Day, ld.Month, ld.Year)
+	if ld.IsLeap {
+		s += "*"
+	}
+	return s
+}
+
+// humanizeUntil renders d as "in 3 days" / "3 ngày nữa", or overdue when negative.
+func humanizeUntil(d time.Duration, locale string) string {
+	overdue := d < 0
+	if overdue {
+		d = -d
+	}
+
+	// Làm tròn tới đơn vị gần nhất: worker chạy trễ vài giây vẫn ra "3 ngày nữa"
+	var n int
+	var unitVI, unitEN string
+	switch {
+	case d.Round(time.Minute) < time.Minute:
+		if locale == models.LocaleEN {
+			return "now"
+		}
+		return "bây giờ"
+	case d.Round(time.Minute) < time.Hour:
+		n, unitVI, unitEN = int(d.Round(time.Minute)/time.Minute), "phút", "minute"
+	case d.Round(time.Hour) < 24*time.Hour:
+		n, unitVI, unitEN = int(d.Round(time.Hour)/time.Hour), "giờ", "hour"
+	default:
+		n, unitVI, unitEN = int(d.Round(24*time.Hour)/(24*time.Hour)), "ngày", "day"
+	}
+
+	if locale == models.LocaleEN {
+		if n != 1 {
+			unitEN += "s"
+		}
+		if overdue {
+			return fmt.Sprintf("%d %s overdue", n, unitEN)
+		}
+		return fmt.Sprintf("in %d %s", n, unitEN)
+	}
+	if overdue {
+		return fmt.Sprintf("quá hạn %d %s", n, unitVI)
+	}
+	return fmt.Sprintf("%d %s nữa", n, unitVI)
+}
+
+// digestTitles lists the first few reminder titles, summarizing the rest.
+func digestTitles(reminders []*models.Reminder, locale string) string {
+	const maxListed = 5
+
+	titles := make([]string, 0, maxListed)
+	for i, reminder := range reminders {
+		if i == maxListed {
+			break
+		}
+		titles = append(titles, reminder.Title)
+	}
+	list := strings.Join(titles, ", ")
+
+	if rest := len(reminders) - len(titles); rest > 0 {
+		if locale == models.LocaleEN {
+			return fmt.Sprintf("%s and %d more", list, rest)
+		}
+		return fmt.Sprintf("%s và %d lời nhắc khác", list, rest)
+	}
+	return list
+}
+
+// normalizeLocale maps unknown or empty locales to the default.
+func normalizeLocale(locale string) string {
+	switch strings.ToLower(locale) {
+	case models.LocaleEN:
+		return models.LocaleEN
+	default:
+		return models.DefaultLocale
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"remiaq/internal/models"
+)
+
+// stubTemplateRepo serves templates from a map keyed by "key/locale"
+type stubTemplateRepo struct {
+	templates map[string]*models.NotificationTemplate
+	err       error
+}
+
+func (s *stubTemplateRepo) GetByKey(ctx context.Context, key, locale string) (*models.NotificationTemplate, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if tmpl, ok := s.templates[key+"/"+locale]; ok {
+		return tmpl, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestTemplateRenderer_Render(t *testing.T) {
+	now := time.Date(2025, 10, 18, 2, 0, 0, 0, time.UTC)
+	renderer := NewTemplateRenderer(NewLunarCalendar(), nil)
+	vi := &models.User{ID: "u1"}
+	en := &models.User{ID: "u2", Locale: "en"}
+
+	t.Run("default template sends title and description verbatim", func(t *testing.T) {
+		reminder := &models.Reminder{Title: "Uống thuốc", Description: "Sau bữa sáng", NextTriggerAt: now}
+
+		title, body := renderer.Render(context.Background(), reminder, vi, now)
+
+		assert.Equal(t, "Uống thuốc", title)
+		assert.Equal(t, "Sau bữa sáng", body)
+	})
+
+	t.Run("retry uses the retry variant with counters", func(t *testing.T) {
+		reminder := &models.Reminder{Title: "Uống thuốc", NextTriggerAt: now, RetryCount: 2, MaxRetries: 3}
+
+		title, body := renderer.Render(context.Background(), reminder, vi, now)
+		assert.Equal(t, "Nhắc lại: Uống thuốc", title)
+		assert.Equal(t, "Lần nhắc thứ 2/3.", body)
+
+		title, body = renderer.Render(context.Background(), reminder, en, now)
+		assert.Equal(t, "Reminder again: Uống thuốc", title)
+		assert.Equal(t, "Reminder 2/3.", body)
+	})
+
+	t.Run("pre-reminder shows time until due in user locale", func(t *testing.T) {
+		due := now.Add(72 * time.Hour)
+		reminder := &models.Reminder{Title: "Giỗ ông", Template: models.TemplatePreReminder, NextTriggerAt: now, DueAt: &due}
+
+		title, _ := renderer.Render(context.Background(), reminder, vi, now.Add(5*time.Second))
+		assert.Equal(t, "Giỗ ông (3 ngày nữa)", title)
+
+		title, _ = renderer.Render(context.Background(), reminder, en, now)
+		assert.Equal(t, "Giỗ ông (in 3 days)", title)
+	})
+
+	t.Run("lunar template includes the lunar date", func(t *testing.T) {
+		// 2025-10-18 (GMT+7) = 27/8/2025 âm lịch
+		reminder := &models.Reminder{Title: "Rằm", Template: models.TemplateLunar, NextTriggerAt: now}
+
+		_, body := renderer.Render(context.Background(), reminder, vi, now)
+
+		assert.Equal(t, "Ngày 27/8/2025 âm lịch.", body)
+	})
+
+	t.Run("unknown key and locale fall back to default vi", func(t *testing.T) {
+		reminder := &models.Reminder{Title: "A", Template: "missing", NextTriggerAt: now, RetryCount: 1, MaxRetries: 2}
+
+		title, _ := renderer.Render(context.Background(), reminder, &models.User{Locale: "fr"}, now)
+
+		assert.Equal(t, "Nhắc lại: A", title)
+	})
+}
+
+func TestTemplateRenderer_AdminTemplates(t *testing.T) {
+	now := time.Date(2025, 10, 18, 2, 0, 0, 0, time.UTC)
+
+	t.Run("admin template overrides built-in", func(t *testing.T) {
+		repo := &stubTemplateRepo{templates: map[string]*models.NotificationTemplate{
+			"default/en": {Title: "[{occurrence}] {title}", Body: "{description}"},
+		}}
+		renderer := NewTemplateRenderer(NewLunarCalendar(), repo)
+		reminder := &models.Reminder{Title: "Pay rent", Description: "Bank transfer", NextTriggerAt: now, OccurrenceCount: 4}
+
+		title, body := renderer.Render(context.Background(), reminder, &models.User{Locale: "en"}, now)
+
+		assert.Equal(t, "[5] Pay rent", title)
+		assert.Equal(t, "Bank transfer", body)
+	})
+
+	t.Run("repository errors fall back to built-in", func(t *testing.T) {
+		renderer := NewTemplateRenderer(NewLunarCalendar(), &stubTemplateRepo{err: errors.New("db down")})
+		reminder := &models.Reminder{Title: "Pay rent", NextTriggerAt: now}
+
+		title, _ := renderer.Render(context.Background(), reminder, &models.User{}, now)
+
+		assert.Equal(t, "Pay rent", title)
+	})
+}
+
+func TestTemplateRenderer_RenderDigest(t *testing.T) {
+	renderer := NewTemplateRenderer(nil, nil)
+	reminders := make([]*models.Reminder, 7)
+	for i := range reminders {
+		reminders[i] = &models.Reminder{Title: string(rune('A' + i))}
+	}
+
+	t.Run("lists titles and counts in user locale", func(t *testing.T) {
+		title, body := renderer.RenderDigest(context.Background(), reminders[:2], &models.User{})
+		assert.Equal(t, "Bạn có 2 lời nhắc", title)
+		assert.Equal(t, "A, B", body)
+
+		title, _ = renderer.RenderDigest(context.Background(), reminders[:2], &models.User{Locale: "en"})
+		assert.Equal(t, "You have 2 reminders", title)
+	})
+
+	t.Run("summarizes titles beyond the first five", func(t *testing.T) {
+		_, body := renderer.RenderDigest(context.Background(), reminders, &models.User{})
+		assert.Equal(t, "A, B, C, D, E và 2 lời nhắc khác", body)
+
+		_, body = renderer.RenderDigest(context.Background(), reminders, &models.User{Locale: "en"})
+		assert.Equal(t, "A, B, C, D, E and 2 more", body)
+	})
+}
+
+func TestTemplateRenderer_RenderEscalation(t *testing.T) {
+	renderer := NewTemplateRenderer(nil, nil)
+	reminder := &models.Reminder{Title: "Uống thuốc", MaxRetries: 3}
+	owner := &models.User{Email: "me@example.com"}
+
+	t.Run("names the owner in the contact locale", func(t *testing.T) {
+		title, body := renderer.RenderEscalation(context.Background(), reminder, owner, "en", time.Now())
+		assert.Equal(t, "Not completed: Uống thuốc", title)
+		assert.Equal(t, `me@example.com has not completed "Uống thuốc" after 3 reminders.`, body)
+
+		title, _ = renderer.RenderEscalation(context.Background(), reminder, owner, "", time.Now())
+		assert.Equal(t, "Chưa hoàn thành: Uống thuốc", title)
+	})
+}
+
+func TestHumanizeUntil(t *testing.T) {
+	testCases := []struct {
+		d        time.Duration
+		locale   string
+		expected string
+	}{
+		{10 * time.Second, "vi", "bây giờ"},
+		{10 * time.Second, "en", "now"},
+		{45 * time.Minute, "vi", "45 phút nữa"},
+		{time.Hour, "en", "in 1 hour"},
+		{59*time.Minute + 50*time.Second, "en", "in 1 hour"},
+		{5 * time.Hour, "vi", "5 giờ nữa"},
+		{72*time.Hour - 10*time.Second, "en", "in 3 days"},
+		{-2 * time.Hour, "vi", "quá hạn 2 giờ"},
+		{-24 * time.Hour, "en", "1 day overdue"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, humanizeUntil(tc.d, tc.locale))
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Pagination defaults (PocketBase style: ?page=1&perPage=30)
+const (
+	DefaultPerPage = 30
+	MaxPerPage     = 500
+)
+
+// PagedResponse is a page of typed items, same shape as QueryResponse
+type PagedResponse struct {
+	Page       int         `json:"page"`
+	PerPage    int         `json:"perPage"`
+	TotalItems int         `json:"totalItems"`
+	TotalPages int         `json:"totalPages"`
+	Items      interface{} `json:"items"`
+}
+
+// ParsePagination reads page and perPage from the query string.
+// Missing values fall back to page 1 and DefaultPerPage; perPage is capped at MaxPerPage.
+func ParsePagination(re *core.RequestEvent) (page, perPage int, err error) {
+	query := re.Request.URL.Query()
+
+	page, err = parsePositiveInt(query.Get("page"), 1)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid page: %w", err)
+	}
+	perPage, err = parsePositiveInt(query.Get("perPage"), DefaultPerPage)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid perPage: %w", err)
+	}
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+	return page, perPage, nil
+}
+
+// SendPagedResponse sends a page of items with totals
+func SendPagedResponse(re *core.RequestEvent, page, perPage, totalItems int, items interface{}) error {
+	totalPages := 0
+	if perPage > 0 {
+		totalPages = (totalItems + perPage - 1) / perPage
+	}
+	return re.JSON(200, PagedResponse{
+		Page:       page,
+		PerPage:    perPage,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Items:      items,
+	})
+}
+
+// parsePositiveInt parses value as an integer >= 1, returning fallback when empty
+func parsePositiveInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("must be at least 1, got %d", n)
+	}
+	return n, nil
+}
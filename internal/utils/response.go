@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"remiaq/internal/models"
+
 	"github.com/pocketbase/pocketbase/core"
 )
 
@@ -13,12 +15,15 @@ type QueryResponse struct {
 	TotalItems int                      `json:"totalItems"`
 	TotalPages int                      `json:"totalPages"`
 	Items      []map[string]interface{} `json:"items"`
+	NextCursor string                   `json:"nextCursor,omitempty"` // keyset cursor of the next page
+	Schema     []models.QueryColumn     `json:"schema,omitempty"`     // result columns, with ?schema=true
 }
 
 // MutationResponse for INSERT/UPDATE/DELETE
 type MutationResponse struct {
 	RowsAffected int64 `json:"rowsAffected"`
 	LastInsertId int64 `json:"lastInsertId,omitempty"`
+	DryRun       bool  `json:"dryRun,omitempty"` // nothing was changed
 }
 
 // ErrorResponse for errors
@@ -61,15 +66,21 @@ func SendSuccess(re *core.RequestEvent, message string, data interface{}) error
 	return re.JSON(200, response)
 }
 
-// SendQueryResponse sends a query response (for SELECT)
-func SendQueryResponse(re *core.RequestEvent, items []map[string]interface{}) error {
-	totalItems := len(items)
+// SendQueryResponse sends a page of a query (for SELECT). totalItems -1 means not counted;
+// schema is omitted when nil.
+func SendQueryResponse(re *core.RequestEvent, page, perPage, totalItems int, items []map[string]interface{}, nextCursor string, schema []models.QueryColumn) error {
+	totalPages := -1
+	if totalItems >= 0 && perPage > 0 {
+		totalPages = (totalItems + perPage - 1) / perPage
+	}
 	response := QueryResponse{
-		Page:       1,
-		PerPage:    totalItems,
+		Page:       page,
+		PerPage:    perPage,
 		TotalItems: totalItems,
-		TotalPages: 1,
+		TotalPages: totalPages,
 		Items:      items,
+		NextCursor: nextCursor,
+		Schema:     schema,
 	}
 	return re.JSON(200, response)
 }
@@ -86,3 +97,11 @@ func SendMutationResponse(re *core.RequestEvent, rowsAffected int64, lastInsertI
 
 	return re.JSON(200, response)
 }
+
+// SendDryRunResponse sends the result of a mutation that was rolled back
+func SendDryRunResponse(re *core.RequestEvent, rowsAffected int64) error {
+	return re.JSON(200, MutationResponse{
+		RowsAffected: rowsAffected,
+		DryRun:       true,
+	})
+}
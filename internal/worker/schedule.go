@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"remiaq/internal/models"
+)
+
+// Schedule is an in-memory min-heap of when reminders are next due. It is filled from
+// the repository by the scheduler loop and kept current by ReminderService events
+// (it implements services.ScheduleObserver), waking the loop when an event arrives.
+type Schedule struct {
+	mu    sync.Mutex
+	items triggerHeap
+	byID  map[string]*triggerItem
+	seq   uint64        // tăng mỗi sự kiện, để resync không ghi đè sự kiện mới hơn
+	wake  chan struct{} // buffer 1: nhiều sự kiện liên tiếp chỉ đánh thức một lần
+}
+
+type triggerItem struct {
+	id    string
+	at    time.Time
+	seq   uint64
+	index int
+}
+
+// NewSchedule creates an empty schedule.
+func NewSchedule() *Schedule {
+	return &Schedule{
+		byID: make(map[string]*triggerItem),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// ReminderScheduled records that reminder id is next due at at; a zero at removes it.
+// It never blocks.
+func (s *Schedule) ReminderScheduled(id string, at time.Time) {
+	s.mu.Lock()
+	s.seq++
+	s.set(id, at, s.seq)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Next returns the earliest trigger time, or false when nothing is scheduled.
+func (s *Schedule) Next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) == 0 {
+		return time.Time{}, false
+	}
+	return s.items[0].at, true
+}
+
+// Len returns the number of scheduled reminders.
+func (s *Schedule) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Wake is signalled after ReminderScheduled changed the schedule.
+func (s *Schedule) Wake() <-chan struct{} {
+	return s.wake
+}
+
+// mark returns the current event sequence, taken before loading triggers for reset.
+func (s *Schedule) mark() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seq
+}
+
+// reset replaces the schedule with triggers loaded from the repository. Events received
+// after mark (while the triggers were being loaded) are newer than the snapshot and kept.
+func (s *Schedule) reset(triggers []models.ScheduledTrigger, mark uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make(map[string]*triggerItem)
+	for id, item := range s.byID {
+		if item.seq > mark {
+			kept[id] = item
+		}
+	}
+
+	s.items = s.items[:0]
+	s.byID = make(map[string]*triggerItem, len(triggers)+len(kept))
+	for _, trigger := range triggers {
+		if _, ok := kept[trigger.ReminderID]; !ok {
+			s.set(trigger.ReminderID, trigger.TriggerAt, 0)
+		}
+	}
+	for id, item := range kept {
+		s.set(id, item.at, item.seq)
+	}
+}
+
+// set adds, moves or (zero at) removes the entry of id. Caller holds mu.
+func (s *Schedule) set(id string, at time.Time, seq uint64) {
+	item, ok := s.byID[id]
+	switch {
+	case at.IsZero() && ok:
+		heap.Remove(&s.items, item.index)
+		delete(s.byID, id)
+	case at.IsZero():
+	case ok:
+		item.at, item.seq = at, seq
+		heap.Fix(&s.items, item.index)
+	default:
+		item = &triggerItem{id: id, at: at, seq: seq}
+		heap.Push(&s.items, item)
+		s.byID[id] = item
+	}
+}
+
+// triggerHeap implements heap.Interface ordered by trigger time.
+type triggerHeap []*triggerItem
+
+func (h triggerHeap) Len() int           { return len(h) }
+func (h triggerHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h triggerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *triggerHeap) Push(x any) {
+	item := x.(*triggerItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *triggerHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"remiaq/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	base := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	t.Run("should return the earliest trigger", func(t *testing.T) {
+		s := NewSchedule()
+		s.ReminderScheduled("rem-2", base.Add(2*time.Minute))
+		s.ReminderScheduled("rem-1", base.Add(time.Minute))
+		s.ReminderScheduled("rem-3", base.Add(3*time.Minute))
+
+		next, ok := s.Next()
+		require.True(t, ok)
+		assert.Equal(t, base.Add(time.Minute), next)
+		assert.Equal(t, 3, s.Len())
+	})
+
+	t.Run("should move and remove reminders", func(t *testing.T) {
+		s := NewSchedule()
+		s.ReminderScheduled("rem-1", base.Add(time.Minute))
+		s.ReminderScheduled("rem-2", base.Add(2*time.Minute))
+
+		s.ReminderScheduled("rem-1", base.Add(time.Hour)) // snooze
+		next, _ := s.Next()
+		assert.Equal(t, base.Add(2*time.Minute), next)
+
+		s.ReminderScheduled("rem-2", time.Time{}) // hoàn thành
+		s.ReminderScheduled("missing", time.Time{})
+		next, _ = s.Next()
+		assert.Equal(t, base.Add(time.Hour), next)
+		assert.Equal(t, 1, s.Len())
+	})
+
+	t.Run("should report nothing scheduled when empty", func(t *testing.T) {
+		_, ok := NewSchedule().Next()
+		assert.False(t, ok)
+	})
+
+	t.Run("should signal wake once for several events", func(t *testing.T) {
+		s := NewSchedule()
+		s.ReminderScheduled("rem-1", base)
+		s.ReminderScheduled("rem-2", base)
+
+		assert.Len(t, s.Wake(), 1)
+		<-s.Wake()
+		assert.Len(t, s.Wake(), 0)
+	})
+
+	t.Run("should replace the schedule on reset but keep newer events", func(t *testing.T) {
+		s := NewSchedule()
+		s.ReminderScheduled("stale", base)
+		mark := s.mark()
+		s.ReminderScheduled("created", base.Add(5*time.Minute)) // đến trong lúc đang nạp từ DB
+
+		s.reset([]models.ScheduledTrigger{
+			{ReminderID: "rem-1", TriggerAt: base.Add(time.Minute)},
+			{ReminderID: "created", TriggerAt: base.Add(time.Hour)}, // ảnh chụp cũ hơn sự kiện
+		}, mark)
+
+		assert.Equal(t, 2, s.Len())
+		next, _ := s.Next()
+		assert.Equal(t, base.Add(time.Minute), next)
+		s.ReminderScheduled("rem-1", time.Time{})
+		next, _ = s.Next()
+		assert.Equal(t, base.Add(5*time.Minute), next)
+	})
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"remiaq/internal/models"
+)
+
+// TriggerSource loads upcoming trigger times (implemented by the reminder repository).
+type TriggerSource interface {
+	GetUpcomingTriggers(ctx context.Context, beforeTime time.Time, limit int) ([]models.ScheduledTrigger, error)
+}
+
+// DefaultResyncInterval is how often the scheduler reloads the schedule when none is configured.
+const DefaultResyncInterval = 5 * time.Minute
+
+// maxScheduled caps the triggers loaded per resync; later ones are loaded by the next resync.
+const maxScheduled = 10000
+
+// Option configures optional Worker behaviour.
+type Option func(*Worker)
+
+// WithSchedule switches the worker from fixed polling to scheduler mode: it sleeps until the
+// earliest trigger in schedule, wakes on schedule events and reloads the schedule from triggers
+// every resync (and after each run) as a safety net for changes it wasn't told about, e.g. by
+// another instance. resync <= 0 uses DefaultResyncInterval. The worker interval then only spaces
+// out retries of reminders a run left due (rate limits, FCM outages).
+func WithSchedule(schedule *Schedule, triggers TriggerSource, resync time.Duration) Option {
+	return func(w *Worker) {
+		w.schedule = schedule
+		w.triggers = triggers
+		w.resync = resync
+		if w.resync <= 0 {
+			w.resync = DefaultResyncInterval
+		}
+	}
+}
+
+// runScheduled is the scheduler mode loop. It stops when ctx is cancelled.
+func (w *Worker) runScheduled(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	var resyncAt, lastRun time.Time
+	for {
+		now := time.Now()
+		if !now.Before(resyncAt) {
+			resyncAt = w.resyncSchedule(ctx, now)
+		}
+
+		wait := resyncAt.Sub(now)
+		if next, ok := w.schedule.Next(); ok {
+			// Vẫn còn due sau lần chạy trước (bị hoãn, FCM lỗi): chờ interval rồi thử lại
+			if !next.After(lastRun) {
+				next = lastRun.Add(w.interval)
+			}
+			if !next.After(now) {
+				lastRun = now
+				w.runOnce(ctx)
+				if ctx.Err() != nil {
+					log.Println("Worker stopped")
+					return
+				}
+				resyncAt = time.Time{} // nạp lại lịch: lần chạy đã đổi next_trigger_at
+				continue
+			}
+			wait = min(wait, next.Sub(now))
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-w.schedule.Wake():
+		case <-ctx.Done():
+			log.Println("Worker stopped")
+			return
+		}
+	}
+}
+
+// resyncSchedule reloads the triggers due within the resync interval and returns when to
+// resync next. When the load was capped, that is the last loaded trigger (but no sooner
+// than the worker interval), so later reminders are never missed.
+func (w *Worker) resyncSchedule(ctx context.Context, now time.Time) time.Time {
+	next := now.Add(w.resync)
+	mark := w.schedule.mark()
+	triggers, err := w.triggers.GetUpcomingTriggers(ctx, next, maxScheduled)
+	if err != nil {
+		log.Printf("Worker: failed to load schedule: %v", err)
+		return now.Add(w.interval)
+	}
+	w.schedule.reset(triggers, mark)
+
+	if len(triggers) == maxScheduled {
+		next = triggers[len(triggers)-1].TriggerAt
+		if earliest := now.Add(w.interval); next.Before(earliest) {
+			next = earliest
+		}
+	}
+	return next
+}
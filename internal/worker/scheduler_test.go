@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"remiaq/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubTriggerSource returns the triggers set by the test and counts loads
+type stubTriggerSource struct {
+	mu       sync.Mutex
+	triggers []models.ScheduledTrigger
+	loads    int
+}
+
+func (s *stubTriggerSource) GetUpcomingTriggers(ctx context.Context, beforeTime time.Time, limit int) ([]models.ScheduledTrigger, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	return s.triggers, nil
+}
+
+func (s *stubTriggerSource) set(triggers ...models.ScheduledTrigger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.triggers = triggers
+}
+
+func (s *stubTriggerSource) loadCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
+
+// newScheduledWorker starts a scheduler-mode worker; runs counts ProcessDueReminders calls.
+// Sau mỗi lần chạy, source trả về rỗng như thể reminder đã được xử lý (trừ khi keepDue).
+func newScheduledWorker(t *testing.T, source *stubTriggerSource, interval time.Duration, keepDue bool) (*Schedule, *atomic.Int32) {
+	t.Helper()
+	mockSysRepo := &MockSystemStatusRepository{}
+	mockReminderService := &MockReminderService{}
+	var runs atomic.Int32
+
+	mockSysRepo.On("IsWorkerEnabled", mock.Anything).Return(true, nil).Maybe()
+	mockSysRepo.On("ClearError", mock.Anything).Return(nil).Maybe()
+	mockReminderService.On("ProcessDueReminders", mock.Anything).Run(func(args mock.Arguments) {
+		runs.Add(1)
+		if !keepDue {
+			source.set()
+		}
+	}).Return(nil).Maybe()
+
+	schedule := NewSchedule()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	NewWorker(mockSysRepo, mockReminderService, interval, WithSchedule(schedule, source, time.Hour)).Start(ctx)
+	return schedule, &runs
+}
+
+func TestWorker_Scheduler(t *testing.T) {
+	t.Run("should run at the earliest trigger, not before", func(t *testing.T) {
+		source := &stubTriggerSource{}
+		source.set(models.ScheduledTrigger{ReminderID: "rem-1", TriggerAt: time.Now().Add(80 * time.Millisecond)})
+
+		_, runs := newScheduledWorker(t, source, time.Hour, false)
+
+		time.Sleep(40 * time.Millisecond)
+		assert.Equal(t, int32(0), runs.Load())
+		assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("should wake up for a reminder scheduled by an event", func(t *testing.T) {
+		source := &stubTriggerSource{}
+
+		schedule, runs := newScheduledWorker(t, source, time.Hour, false)
+		assert.Eventually(t, func() bool { return source.loadCount() == 1 }, time.Second, 5*time.Millisecond)
+		schedule.ReminderScheduled("rem-1", time.Now().Add(20*time.Millisecond))
+
+		assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("should not query while idle", func(t *testing.T) {
+		source := &stubTriggerSource{}
+
+		_, runs := newScheduledWorker(t, source, 10*time.Millisecond, false)
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, int32(0), runs.Load())
+		assert.Equal(t, 1, source.loadCount())
+	})
+
+	t.Run("should retry reminders left due after the interval", func(t *testing.T) {
+		source := &stubTriggerSource{}
+		source.set(models.ScheduledTrigger{ReminderID: "rem-1", TriggerAt: time.Now().Add(-time.Minute)})
+
+		_, runs := newScheduledWorker(t, source, 40*time.Millisecond, true)
+		time.Sleep(150 * time.Millisecond)
+
+		// Chạy ngay rồi cách nhau interval, không chạy liên tục
+		assert.GreaterOrEqual(t, runs.Load(), int32(2))
+		assert.LessOrEqual(t, runs.Load(), int32(5))
+	})
+}
@@ -14,19 +14,29 @@ type ReminderProcessor interface {
 }
 
 // Worker periodically processes due reminders when enabled in system_status.
+// With WithSchedule it runs at the reminders' trigger times instead of polling.
 type Worker struct {
     sysRepo         repository.SystemStatusRepository
     reminderService ReminderProcessor
     interval        time.Duration
+
+    // Scheduler mode (nil = polling)
+    schedule *Schedule
+    triggers TriggerSource
+    resync   time.Duration
 }
 
 // NewWorker creates a new Worker.
-func NewWorker(sysRepo repository.SystemStatusRepository, reminderService ReminderProcessor, interval time.Duration) *Worker {
-    return &Worker{
+func NewWorker(sysRepo repository.SystemStatusRepository, reminderService ReminderProcessor, interval time.Duration, opts ...Option) *Worker {
+    w := &Worker{
         sysRepo:         sysRepo,
         reminderService: reminderService,
         interval:        interval,
     }
+    for _, opt := range opts {
+        opt(w)
+    }
+    return w
 }
 
 // Start launches the background loop. It stops when ctx is cancelled.
@@ -40,6 +50,12 @@ func (w *Worker) Start(ctx context.Context) {
         w.interval = time.Minute
     }
 
+    if w.schedule != nil {
+        log.Printf("Worker started (scheduler, resync=%s, retry=%s)", w.resync.String(), w.interval.String())
+        go w.runScheduled(ctx)
+        return
+    }
+
     ticker := time.NewTicker(w.interval)
     go func() {
         defer ticker.Stop()
@@ -73,9 +89,16 @@ func (w *Worker) runOnce(ctx context.Context) {
 
     // Process reminders
     if err := w.reminderService.ProcessDueReminders(ctx); err != nil {
-        // On system-level errors, disable worker and record error
-        log.Printf("Worker: disabling due to error: %v", err)
-        _ = w.sysRepo.DisableWorker(ctx, err.Error())
+        if ctx.Err() != nil {
+            // Đang tắt: tick bị hủy giữa chừng không phải lỗi hệ thống
+            return
+        }
+        // Record the error but keep the worker enabled: the notifier circuit
+        // breaker stops sending while FCM is down and recovers on its own.
+        log.Printf("Worker: processing error: %v", err)
+        if uerr := w.sysRepo.UpdateError(ctx, err.Error()); uerr != nil {
+            log.Printf("Worker: failed to record error: %v", uerr)
+        }
         return
     }
 
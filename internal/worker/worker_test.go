@@ -51,6 +51,16 @@ func (m *MockSystemStatusRepository) ClearError(ctx context.Context) error {
 	return args.Error(0)
 }
 
+func (m *MockSystemStatusRepository) UpdateCircuitState(ctx context.Context, state, reason string, changedAt time.Time) error {
+	args := m.Called(ctx, state, reason, changedAt)
+	return args.Error(0)
+}
+
+func (m *MockSystemStatusRepository) SetCircuitOverride(ctx context.Context, override string) error {
+	args := m.Called(ctx, override)
+	return args.Error(0)
+}
+
 // Mock ReminderService
 type MockReminderService struct {
 	mock.Mock
@@ -185,8 +195,8 @@ func TestWorker_runOnce_WorkerEnabled_ProcessingError(t *testing.T) {
 	processingError := errors.New("FCM service unavailable")
 	mockReminderService.On("ProcessDueReminders", mock.Anything).Return(processingError)
 	
-	// Mock disable worker
-	mockSysRepo.On("DisableWorker", mock.Anything, "FCM service unavailable").Return(nil)
+	// Error is recorded, worker stays enabled
+	mockSysRepo.On("UpdateError", mock.Anything, "FCM service unavailable").Return(nil)
 	
 	ctx := context.Background()
 	worker.runOnce(ctx)
@@ -194,6 +204,25 @@ func TestWorker_runOnce_WorkerEnabled_ProcessingError(t *testing.T) {
 	// Verify all calls
 	mockSysRepo.AssertExpectations(t)
 	mockReminderService.AssertExpectations(t)
+	mockSysRepo.AssertNotCalled(t, "DisableWorker", mock.Anything, mock.Anything)
+}
+
+func TestWorker_runOnce_CancelledTick(t *testing.T) {
+	mockSysRepo := &MockSystemStatusRepository{}
+	mockReminderService := &MockReminderService{}
+
+	worker := NewWorker(mockSysRepo, mockReminderService, time.Minute)
+
+	mockSysRepo.On("IsWorkerEnabled", mock.Anything).Return(true, nil)
+	mockReminderService.On("ProcessDueReminders", mock.Anything).Return(context.Canceled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	worker.runOnce(ctx)
+
+	// Shutdown is not recorded as a processing error
+	mockSysRepo.AssertNotCalled(t, "UpdateError", mock.Anything, mock.Anything)
+	mockSysRepo.AssertNotCalled(t, "ClearError", mock.Anything)
 }
 
 func TestWorker_runOnce_SystemStatusError(t *testing.T) {
@@ -215,7 +244,7 @@ func TestWorker_runOnce_SystemStatusError(t *testing.T) {
 	mockReminderService.AssertNotCalled(t, "ProcessDueReminders")
 }
 
-func TestWorker_runOnce_DisableWorkerError(t *testing.T) {
+func TestWorker_runOnce_UpdateErrorFails(t *testing.T) {
 	mockSysRepo := &MockSystemStatusRepository{}
 	mockReminderService := &MockReminderService{}
 	
@@ -228,8 +257,8 @@ func TestWorker_runOnce_DisableWorkerError(t *testing.T) {
 	processingError := errors.New("FCM service unavailable")
 	mockReminderService.On("ProcessDueReminders", mock.Anything).Return(processingError)
 	
-	// Mock disable worker error (should not crash)
-	mockSysRepo.On("DisableWorker", mock.Anything, "FCM service unavailable").Return(errors.New("failed to disable"))
+	// Mock update error failure (should not crash)
+	mockSysRepo.On("UpdateError", mock.Anything, "FCM service unavailable").Return(errors.New("failed to update"))
 	
 	ctx := context.Background()
 	
@@ -6,6 +6,9 @@ CREATE TABLE IF NOT EXISTS musers (
     email TEXT UNIQUE NOT NULL,
     fcm_token TEXT,
     is_fcm_active BOOLEAN DEFAULT TRUE,
+    locale TEXT DEFAULT 'vi' CHECK(locale IN ('', 'vi', 'en')),
+    digest_enabled BOOLEAN DEFAULT FALSE,
+    digest_window_sec INTEGER DEFAULT 0,
     created DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -26,12 +29,24 @@ CREATE TABLE IF NOT EXISTS reminders (
     recurrence_pattern TEXT,
     repeat_strategy TEXT DEFAULT 'none' CHECK(repeat_strategy IN ('none', 'retry_until_complete')),
     retry_interval_sec INTEGER,
+    seen_retry_interval_sec INTEGER DEFAULT 0,
     max_retries INTEGER DEFAULT 0,
     retry_count INTEGER DEFAULT 0,
     status TEXT DEFAULT 'active' CHECK(status IN ('active', 'completed', 'paused')),
     snooze_until DATETIME,
     last_completed_at DATETIME NULL,
     last_sent_at DATETIME,
+    template TEXT DEFAULT '',
+    due_at DATETIME,
+    occurrence_count INTEGER DEFAULT 0,
+    audience_type TEXT DEFAULT 'user' CHECK(audience_type IN ('', 'user', 'users', 'group')),
+    audience_user_ids TEXT,
+    group_id TEXT,
+    delivery_options TEXT,
+    escalation_policy TEXT,
+    escalation_step INTEGER DEFAULT 0,
+    claimed_by TEXT DEFAULT '',
+    claimed_until DATETIME,
     created DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES musers(id) ON DELETE CASCADE
@@ -42,12 +57,82 @@ CREATE INDEX IF NOT EXISTS idx_reminders_user_id ON reminders(user_id);
 CREATE INDEX IF NOT EXISTS idx_reminders_status ON reminders(status);
 CREATE INDEX IF NOT EXISTS idx_reminders_user_status ON reminders(user_id, status);
 CREATE INDEX IF NOT EXISTS idx_reminders_status_trigger ON reminders(status, next_trigger_at);
+CREATE INDEX IF NOT EXISTS idx_reminders_claimed_by ON reminders(claimed_by);
+
+-- Table: mgroups (broadcast groups, one FCM topic each)
+CREATE TABLE IF NOT EXISTS mgroups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    topic TEXT UNIQUE NOT NULL,
+    owner_id TEXT,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: mgroup_members
+CREATE TABLE IF NOT EXISTS mgroup_members (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES mgroups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES musers(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mgroup_members_group_user ON mgroup_members(group_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_mgroup_members_user ON mgroup_members(user_id);
+
+-- Table: notification_templates (admin overrides of built-in templates)
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id TEXT PRIMARY KEY,
+    key TEXT NOT NULL,
+    locale TEXT NOT NULL CHECK(locale IN ('vi', 'en')),
+    title TEXT NOT NULL,
+    body TEXT,
+    retry_title TEXT,
+    retry_body TEXT,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_templates_key_locale ON notification_templates(key, locale);
+
+-- Table: deliveries (one row per FCM send attempt)
+CREATE TABLE IF NOT EXISTS deliveries (
+    id TEXT PRIMARY KEY,
+    reminder_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    channel TEXT DEFAULT 'fcm' CHECK(channel IN ('fcm', 'email')),
+    device TEXT,
+    scheduled_for DATETIME,
+    sent_at DATETIME,
+    provider_message_id TEXT,
+    outcome TEXT NOT NULL CHECK(outcome IN ('sent', 'failed', 'deferred', 'expired')),
+    error_class TEXT,
+    error_message TEXT,
+    attempt INTEGER DEFAULT 1,
+    dispatch_id TEXT,
+    received_at DATETIME,
+    opened_at DATETIME,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reminder_id) REFERENCES reminders(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES musers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_reminder ON deliveries(reminder_id, created);
+CREATE INDEX IF NOT EXISTS idx_deliveries_user ON deliveries(user_id, created);
+CREATE INDEX IF NOT EXISTS idx_deliveries_dispatch ON deliveries(dispatch_id);
 
 -- Table: system_status (singleton table)
 CREATE TABLE IF NOT EXISTS system_status (
     mid INTEGER PRIMARY KEY CHECK (mid = 1),
     worker_enabled BOOLEAN DEFAULT TRUE,
     last_error TEXT,
+    error_at DATETIME,
+    circuit_state TEXT DEFAULT 'closed',
+    circuit_reason TEXT,
+    circuit_changed_at DATETIME,
+    circuit_override TEXT DEFAULT '',
     updated DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// circuitBreakerFields are the system_status columns added for the notifier circuit breaker
+var circuitBreakerFields = []string{"error_at", "circuit_state", "circuit_reason", "circuit_changed_at", "circuit_override"}
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("system_status")
+		if err != nil {
+			return err
+		}
+
+		// Thời điểm ghi nhận last_error (có trong SRS nhưng thiếu trong schema)
+		collection.Fields.Add(&core.DateField{
+			Name:     "error_at",
+			Required: false,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "circuit_state",
+			Required:  false,
+			MaxSelect: 1,
+			Values:    []string{"closed", "open", "half_open"},
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "circuit_reason",
+			Required: false,
+		})
+		collection.Fields.Add(&core.DateField{
+			Name:     "circuit_changed_at",
+			Required: false,
+		})
+		// Override của admin: rỗng = tự động, open/closed = ép trạng thái
+		collection.Fields.Add(&core.SelectField{
+			Name:      "circuit_override",
+			Required:  false,
+			MaxSelect: 1,
+			Values:    []string{"open", "closed"},
+		})
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("system_status")
+		if err != nil {
+			return nil
+		}
+
+		for _, name := range circuitBreakerFields {
+			collection.Fields.RemoveByName(name)
+		}
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		musers, err := app.FindCollectionByNameOrId("musers")
+		if err != nil {
+			return err
+		}
+
+		// Nhật ký gửi: mỗi lần gửi tới FCM là một bản ghi
+		collection := core.NewBaseCollection("deliveries")
+
+		collection.Fields.Add(&core.RelationField{
+			Name:          "reminder_id",
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+			CollectionId:  reminders.Id,
+		})
+		collection.Fields.Add(&core.RelationField{
+			Name:          "user_id",
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+			CollectionId:  musers.Id,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "channel",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"fcm"},
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "device",
+			Required: false,
+		})
+		collection.Fields.Add(&core.DateField{
+			Name:     "scheduled_for",
+			Required: false,
+		})
+		collection.Fields.Add(&core.DateField{
+			Name:     "sent_at",
+			Required: false,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "provider_message_id",
+			Required: false,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "outcome",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"sent", "failed"},
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "error_class",
+			Required: false,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "error_message",
+			Required: false,
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:     "attempt",
+			Required: false,
+		})
+		collection.Fields.Add(&core.DateField{
+			Name:     "created",
+			Required: true,
+		})
+
+		// Truy vấn lịch sử theo reminder/user, mới nhất trước
+		collection.AddIndex("idx_deliveries_reminder", false, "reminder_id, created", "")
+		collection.AddIndex("idx_deliveries_user", false, "user_id, created", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		if collection, _ := app.FindCollectionByNameOrId("deliveries"); collection != nil {
+			return app.Delete(collection)
+		}
+		return nil
+	})
+}
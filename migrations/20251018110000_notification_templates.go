@@ -0,0 +1,107 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Template thông báo do admin quản lý (ghi đè template có sẵn cùng key/locale)
+		templates := core.NewBaseCollection("notification_templates")
+		templates.Fields.Add(&core.TextField{
+			Name:     "key",
+			Required: true,
+		})
+		templates.Fields.Add(&core.SelectField{
+			Name:      "locale",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"vi", "en"},
+		})
+		templates.Fields.Add(&core.TextField{
+			Name:     "title",
+			Required: true,
+		})
+		templates.Fields.Add(&core.TextField{
+			Name:     "body",
+			Required: false,
+		})
+		templates.Fields.Add(&core.TextField{
+			Name:     "retry_title",
+			Required: false,
+		})
+		templates.Fields.Add(&core.TextField{
+			Name:     "retry_body",
+			Required: false,
+		})
+		templates.Fields.Add(&core.DateField{
+			Name:     "created",
+			Required: false,
+		})
+		templates.Fields.Add(&core.DateField{
+			Name:     "updated",
+			Required: false,
+		})
+		templates.AddIndex("idx_notification_templates_key_locale", true, "key, locale", "")
+
+		if err := app.Save(templates); err != nil {
+			return err
+		}
+
+		// Reminder chọn template, hạn sự kiện và số lần đã gửi
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		reminders.Fields.Add(&core.TextField{
+			Name:     "template",
+			Required: false,
+		})
+		reminders.Fields.Add(&core.DateField{
+			Name:     "due_at",
+			Required: false,
+		})
+		reminders.Fields.Add(&core.NumberField{
+			Name:     "occurrence_count",
+			Required: false,
+		})
+		if err := app.Save(reminders); err != nil {
+			return err
+		}
+
+		// Ngôn ngữ của user quyết định biến thể template
+		musers, err := app.FindCollectionByNameOrId("musers")
+		if err != nil {
+			return err
+		}
+		musers.Fields.Add(&core.SelectField{
+			Name:      "locale",
+			Required:  false,
+			MaxSelect: 1,
+			Values:    []string{"vi", "en"},
+		})
+		return app.Save(musers)
+	}, func(app core.App) error {
+		if musers, _ := app.FindCollectionByNameOrId("musers"); musers != nil {
+			musers.Fields.RemoveByName("locale")
+			if err := app.Save(musers); err != nil {
+				return err
+			}
+		}
+
+		if reminders, _ := app.FindCollectionByNameOrId("reminders"); reminders != nil {
+			for _, name := range []string{"template", "due_at", "occurrence_count"} {
+				reminders.Fields.RemoveByName(name)
+			}
+			if err := app.Save(reminders); err != nil {
+				return err
+			}
+		}
+
+		if templates, _ := app.FindCollectionByNameOrId("notification_templates"); templates != nil {
+			return app.Delete(templates)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Tùy chọn digest của user: gộp các reminder due gần nhau thành một thông báo
+		musers, err := app.FindCollectionByNameOrId("musers")
+		if err != nil {
+			return err
+		}
+		musers.Fields.Add(&core.BoolField{
+			Name:     "digest_enabled",
+			Required: false,
+		})
+		musers.Fields.Add(&core.NumberField{
+			Name:     "digest_window_sec",
+			Required: false,
+		})
+		return app.Save(musers)
+	}, func(app core.App) error {
+		musers, _ := app.FindCollectionByNameOrId("musers")
+		if musers == nil {
+			return nil
+		}
+		musers.Fields.RemoveByName("digest_enabled")
+		musers.Fields.RemoveByName("digest_window_sec")
+		return app.Save(musers)
+	})
+}
@@ -0,0 +1,110 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		musers, err := app.FindCollectionByNameOrId("musers")
+		if err != nil {
+			return err
+		}
+
+		// Nhóm nhận reminder chung, mỗi nhóm có một FCM topic
+		groups := core.NewBaseCollection("mgroups")
+		groups.Fields.Add(&core.TextField{
+			Name:     "name",
+			Required: true,
+		})
+		groups.Fields.Add(&core.TextField{
+			Name:     "topic",
+			Required: true,
+		})
+		groups.Fields.Add(&core.RelationField{
+			Name:         "owner_id",
+			Required:     false,
+			MaxSelect:    1,
+			CollectionId: musers.Id,
+		})
+		groups.Fields.Add(&core.DateField{
+			Name:     "created",
+			Required: false,
+		})
+		groups.Fields.Add(&core.DateField{
+			Name:     "updated",
+			Required: false,
+		})
+		groups.AddIndex("idx_mgroups_topic", true, "topic", "")
+		if err := app.Save(groups); err != nil {
+			return err
+		}
+
+		members := core.NewBaseCollection("mgroup_members")
+		members.Fields.Add(&core.RelationField{
+			Name:          "group_id",
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+			CollectionId:  groups.Id,
+		})
+		members.Fields.Add(&core.RelationField{
+			Name:          "user_id",
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+			CollectionId:  musers.Id,
+		})
+		members.Fields.Add(&core.DateField{
+			Name:     "created",
+			Required: false,
+		})
+		members.AddIndex("idx_mgroup_members_group_user", true, "group_id, user_id", "")
+		members.AddIndex("idx_mgroup_members_user", false, "user_id", "")
+		if err := app.Save(members); err != nil {
+			return err
+		}
+
+		// Đối tượng nhận của reminder: user (mặc định), danh sách user, hoặc nhóm
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		reminders.Fields.Add(&core.SelectField{
+			Name:      "audience_type",
+			Required:  false,
+			MaxSelect: 1,
+			Values:    []string{"user", "users", "group"},
+		})
+		reminders.Fields.Add(&core.JSONField{
+			Name:     "audience_user_ids",
+			Required: false,
+		})
+		reminders.Fields.Add(&core.RelationField{
+			Name:         "group_id",
+			Required:     false,
+			MaxSelect:    1,
+			CollectionId: groups.Id,
+		})
+		return app.Save(reminders)
+	}, func(app core.App) error {
+		if reminders, _ := app.FindCollectionByNameOrId("reminders"); reminders != nil {
+			for _, name := range []string{"audience_type", "audience_user_ids", "group_id"} {
+				reminders.Fields.RemoveByName(name)
+			}
+			if err := app.Save(reminders); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range []string{"mgroup_members", "mgroups"} {
+			if collection, _ := app.FindCollectionByNameOrId(name); collection != nil {
+				if err := app.Delete(collection); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Lần gửi bị hoãn do rate limit được ghi vào delivery log với outcome = deferred
+		return setDeliveryOutcomes(app, []string{"sent", "failed", "deferred"})
+	}, func(app core.App) error {
+		return setDeliveryOutcomes(app, []string{"sent", "failed"})
+	})
+}
+
+// setDeliveryOutcomes replaces the allowed values of deliveries.outcome
+func setDeliveryOutcomes(app core.App, values []string) error {
+	deliveries, err := app.FindCollectionByNameOrId("deliveries")
+	if err != nil {
+		return err
+	}
+	outcome, ok := deliveries.Fields.GetByName("outcome").(*core.SelectField)
+	if !ok {
+		return nil
+	}
+	outcome.Values = values
+	return app.Save(deliveries)
+}
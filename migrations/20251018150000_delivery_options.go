@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Tùy chọn gửi theo reminder: priority, TTL, collapse key, sound/channel, badge
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		reminders.Fields.Add(&core.JSONField{
+			Name:     "delivery_options",
+			Required: false,
+		})
+		if err := app.Save(reminders); err != nil {
+			return err
+		}
+
+		// Reminder quá TTL không được gửi, ghi delivery outcome = expired
+		return setDeliveryOutcomes(app, []string{"sent", "failed", "deferred", "expired"})
+	}, func(app core.App) error {
+		if reminders, _ := app.FindCollectionByNameOrId("reminders"); reminders != nil {
+			reminders.Fields.RemoveByName("delivery_options")
+			if err := app.Save(reminders); err != nil {
+				return err
+			}
+		}
+		return setDeliveryOutcomes(app, []string{"sent", "failed", "deferred"})
+	})
+}
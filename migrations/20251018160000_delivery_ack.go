@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Xác nhận từ client: delivery_id trong payload, thời điểm nhận/mở thông báo
+		deliveries, err := app.FindCollectionByNameOrId("deliveries")
+		if err != nil {
+			return err
+		}
+		deliveries.Fields.Add(&core.TextField{
+			Name:     "dispatch_id",
+			Required: false,
+		})
+		deliveries.Fields.Add(&core.DateField{
+			Name:     "received_at",
+			Required: false,
+		})
+		deliveries.Fields.Add(&core.DateField{
+			Name:     "opened_at",
+			Required: false,
+		})
+		deliveries.AddIndex("idx_deliveries_dispatch", false, "dispatch_id", "")
+		if err := app.Save(deliveries); err != nil {
+			return err
+		}
+
+		// Nhịp nhắc lại khi user đã mở thông báo mà chưa hoàn thành
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		reminders.Fields.Add(&core.NumberField{
+			Name:     "seen_retry_interval_sec",
+			Required: false,
+		})
+		return app.Save(reminders)
+	}, func(app core.App) error {
+		if deliveries, _ := app.FindCollectionByNameOrId("deliveries"); deliveries != nil {
+			deliveries.RemoveIndex("idx_deliveries_dispatch")
+			for _, name := range []string{"dispatch_id", "received_at", "opened_at"} {
+				deliveries.Fields.RemoveByName(name)
+			}
+			if err := app.Save(deliveries); err != nil {
+				return err
+			}
+		}
+
+		reminders, _ := app.FindCollectionByNameOrId("reminders")
+		if reminders == nil {
+			return nil
+		}
+		reminders.Fields.RemoveByName("seen_retry_interval_sec")
+		return app.Save(reminders)
+	})
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Chuyển tiếp khi hết lượt nhắc: chuỗi bước (push tới người nhà, email) và bước đang chờ
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		reminders.Fields.Add(&core.JSONField{
+			Name:     "escalation_policy",
+			Required: false,
+		})
+		reminders.Fields.Add(&core.NumberField{
+			Name:     "escalation_step",
+			Required: false,
+		})
+		if err := app.Save(reminders); err != nil {
+			return err
+		}
+
+		// Bước email ghi delivery với channel = email
+		return setDeliveryChannels(app, []string{"fcm", "email"})
+	}, func(app core.App) error {
+		if reminders, _ := app.FindCollectionByNameOrId("reminders"); reminders != nil {
+			reminders.Fields.RemoveByName("escalation_policy")
+			reminders.Fields.RemoveByName("escalation_step")
+			if err := app.Save(reminders); err != nil {
+				return err
+			}
+		}
+		return setDeliveryChannels(app, []string{"fcm"})
+	})
+}
+
+// setDeliveryChannels replaces the allowed values of deliveries.channel
+func setDeliveryChannels(app core.App, values []string) error {
+	deliveries, err := app.FindCollectionByNameOrId("deliveries")
+	if err != nil {
+		return err
+	}
+	channel, ok := deliveries.Fields.GetByName("channel").(*core.SelectField)
+	if !ok {
+		return nil
+	}
+	channel.Values = values
+	return app.Save(deliveries)
+}
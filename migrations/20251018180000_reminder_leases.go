@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Lease cho nhiều instance cùng chạy worker: instance nào đang giữ reminder và đến khi nào
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return err
+		}
+		reminders.Fields.Add(&core.TextField{
+			Name:     "claimed_by",
+			Required: false,
+		})
+		reminders.Fields.Add(&core.DateField{
+			Name:     "claimed_until",
+			Required: false,
+		})
+		reminders.AddIndex("idx_reminders_claimed_by", false, "claimed_by", "")
+		return app.Save(reminders)
+	}, func(app core.App) error {
+		reminders, err := app.FindCollectionByNameOrId("reminders")
+		if err != nil {
+			return nil
+		}
+		reminders.RemoveIndex("idx_reminders_claimed_by")
+		reminders.Fields.RemoveByName("claimed_by")
+		reminders.Fields.RemoveByName("claimed_until")
+		return app.Save(reminders)
+	})
+}
@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Nhật ký truy vấn SQL thô: ai chạy gì, bao nhiêu dòng, mất bao lâu, kể cả lần bị từ chối.
+		// Không có rule nên chỉ superuser xem được trên dashboard.
+		collection := core.NewBaseCollection("query_audit")
+
+		// Rỗng khi bị từ chối mà không xác thực được người gọi
+		collection.Fields.Add(&core.TextField{
+			Name:     "caller",
+			Required: false,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "statement",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"SELECT", "INSERT", "UPDATE", "DELETE"},
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "query",
+			Required: false,
+		})
+		collection.Fields.Add(&core.JSONField{
+			Name:     "params",
+			Required: false,
+		})
+		collection.Fields.Add(&core.BoolField{
+			Name:     "dry_run",
+			Required: false,
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:     "rows_affected",
+			Required: false,
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:     "duration_ms",
+			Required: false,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "outcome",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"ok", "failed", "denied"},
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:     "status",
+			Required: false,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "error",
+			Required: false,
+		})
+		collection.Fields.Add(&core.DateField{
+			Name:     "created",
+			Required: true,
+		})
+
+		collection.AddIndex("idx_query_audit_created", false, "created", "")
+		collection.AddIndex("idx_query_audit_caller", false, "caller, created", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		if collection, _ := app.FindCollectionByNameOrId("query_audit"); collection != nil {
+			return app.Delete(collection)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Truy vấn đã duyệt, admin quản lý trên dashboard; chạy qua /api/queries/{name}/run
+		collection := core.NewBaseCollection("saved_queries")
+
+		collection.Fields.Add(&core.TextField{
+			Name:     "name",
+			Required: true,
+			Pattern:  `^[a-z0-9_-]+$`,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "description",
+			Required: false,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "sql",
+			Required: true,
+		})
+		// {"user_id": {"type": "string", "required": true}, "limit": {"type": "integer", "default": 50}}
+		collection.Fields.Add(&core.JSONField{
+			Name:     "params",
+			Required: false,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "role",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"superuser", "write", "read"},
+		})
+		collection.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			OnCreate: true,
+		})
+		collection.Fields.Add(&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+		collection.AddIndex("idx_saved_queries_name", true, "name", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Nhật ký ghi lại tên truy vấn đã lưu được chạy
+		audit, err := app.FindCollectionByNameOrId("query_audit")
+		if err != nil {
+			return err
+		}
+		audit.Fields.Add(&core.TextField{
+			Name:     "saved_query",
+			Required: false,
+		})
+		return app.Save(audit)
+	}, func(app core.App) error {
+		if audit, _ := app.FindCollectionByNameOrId("query_audit"); audit != nil {
+			audit.Fields.RemoveByName("saved_query")
+			if err := app.Save(audit); err != nil {
+				return err
+			}
+		}
+		if collection, _ := app.FindCollectionByNameOrId("saved_queries"); collection != nil {
+			return app.Delete(collection)
+		}
+		return nil
+	})
+}
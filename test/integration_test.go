@@ -1,7 +1,12 @@
 package test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,7 +14,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"remiaq/config"
+	"remiaq/internal/fakefcm"
 	"remiaq/internal/models"
+	"remiaq/internal/repository"
 	"remiaq/internal/services"
 )
 
@@ -172,6 +179,376 @@ func TestIntegration_ServiceInteraction(t *testing.T) {
 	})
 }
 
+// deliveryEnv wires ReminderService to the fake FCM server through the real messaging SDK
+type deliveryEnv struct {
+	fake       *fakefcm.Server
+	reminders  *memReminderRepo
+	users      *memUserRepo
+	deliveries *memDeliveryRepo
+	groups     *memGroupRepo
+	notifier   services.Notifier
+	service    *services.ReminderService
+}
+
+func newDeliveryEnv(t *testing.T) *deliveryEnv {
+	t.Helper()
+
+	fake := fakefcm.NewServer()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	fcmService, err := services.NewFCMService("", services.WithFCMEndpoint(srv.URL+"/v1"), services.WithFCMProjectID("remiaq-test"))
+	require.NoError(t, err)
+
+	env := &deliveryEnv{
+		fake:       fake,
+		reminders:  newMemReminderRepo(),
+		users:      newMemUserRepo(),
+		deliveries: &memDeliveryRepo{},
+		notifier:   fcmService,
+	}
+	env.groups = newMemGroupRepo(env.users)
+	env.service = env.newService()
+
+	env.users.Create(context.Background(), &models.User{ID: "user-1", Email: "a@example.com", FCMToken: "token-1", IsFCMActive: true})
+	env.reminders.Create(context.Background(), &models.Reminder{
+		ID:            "rem-1",
+		UserID:        "user-1",
+		Title:         "Uống thuốc",
+		Description:   "Sau bữa sáng",
+		Type:          models.ReminderTypeOneTime,
+		CalendarType:  models.CalendarTypeSolar,
+		Status:        models.ReminderStatusActive,
+		NextTriggerAt: time.Now().Add(-time.Minute),
+	})
+	return env
+}
+
+// newService creates a reminder service on the env's repositories and FCM server,
+// e.g. another server instance.
+func (env *deliveryEnv) newService(opts ...services.ReminderServiceOption) *services.ReminderService {
+	opts = append([]services.ReminderServiceOption{
+		services.WithRetryPolicy(services.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+		services.WithDeliveryRepo(env.deliveries),
+		services.WithGroupRepo(env.groups),
+	}, opts...)
+	return services.NewReminderService(env.reminders, env.users, env.notifier,
+		services.NewScheduleCalculator(services.NewLunarCalendar()), opts...)
+}
+
+func TestIntegration_BroadcastDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should deliver users audience to every active device", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		env.users.Create(ctx, &models.User{ID: "user-2", FCMToken: "token-2", IsFCMActive: true})
+		env.users.Create(ctx, &models.User{ID: "user-3", FCMToken: "token-3", IsFCMActive: true})
+		env.reminders.modify("rem-1", func(r *models.Reminder) {
+			r.AudienceType = models.AudienceUsers
+			r.AudienceUserIDs = []string{"user-2", "user-3"}
+		})
+		env.fake.Fail("token-3", fakefcm.Unregistered, 1)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		require.Len(t, env.fake.MessagesTo("token-2"), 1)
+		assert.Empty(t, env.fake.MessagesTo("token-1"))
+		assert.True(t, env.users.get("user-2").IsFCMActive)
+		assert.False(t, env.users.get("user-3").IsFCMActive)
+		assert.Equal(t, models.ReminderStatusCompleted, env.reminders.get("rem-1").Status)
+		require.Len(t, env.deliveries.deliveries, 2)
+	})
+
+	t.Run("should publish group reminder to the group topic", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		env.groups.Create(ctx, &models.Group{ID: "g1", Name: "Team", Topic: models.GroupTopicPrefix + "g1", OwnerID: "user-1"})
+		env.reminders.modify("rem-1", func(r *models.Reminder) {
+			r.AudienceType = models.AudienceGroup
+			r.GroupID = "g1"
+		})
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		msgs := env.fake.Messages()
+		require.Len(t, msgs, 1)
+		assert.Equal(t, models.GroupTopicPrefix+"g1", msgs[0].Topic)
+		assert.Equal(t, "g1", msgs[0].Data["group_id"])
+		require.Len(t, env.deliveries.deliveries, 1)
+		assert.Equal(t, "topic:"+models.GroupTopicPrefix+"g1", env.deliveries.deliveries[0].Device)
+	})
+}
+
+func TestIntegration_FCMDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should send reminder delivery options to FCM", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		env.reminders.modify("rem-1", func(r *models.Reminder) {
+			r.DeliveryOptions = &models.DeliveryOptions{
+				Priority:    models.PriorityNormal,
+				TTLSeconds:  3600,
+				CollapseKey: "pill",
+				ChannelID:   "pills",
+			}
+		})
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		msgs := env.fake.MessagesTo("token-1")
+		require.Len(t, msgs, 1)
+		var android struct {
+			Priority     string `json:"priority"`
+			TTL          string `json:"ttl"`
+			CollapseKey  string `json:"collapse_key"`
+			Notification struct {
+				ChannelID string `json:"channel_id"`
+			} `json:"notification"`
+		}
+		require.NoError(t, json.Unmarshal(msgs[0].Android, &android))
+		assert.Equal(t, "normal", android.Priority)
+		assert.Equal(t, "pill", android.CollapseKey)
+		assert.Equal(t, "pills", android.Notification.ChannelID)
+		assert.Regexp(t, `^3[0-9]{3}s$`, android.TTL) // 1 giờ trừ thời gian trễ
+		assert.Contains(t, string(msgs[0].APNS), `"apns-collapse-id":"pill"`)
+	})
+
+	t.Run("should deliver due reminder and complete it", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		msgs := env.fake.MessagesTo("token-1")
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "Uống thuốc", msgs[0].Title)
+		assert.Equal(t, "Sau bữa sáng", msgs[0].Body)
+		assert.Equal(t, "rem-1", msgs[0].Data["reminder_id"])
+
+		assert.Equal(t, models.ReminderStatusCompleted, env.reminders.get("rem-1").Status)
+		require.Len(t, env.deliveries.deliveries, 1)
+		assert.Equal(t, models.DeliveryOutcomeSent, env.deliveries.deliveries[0].Outcome)
+		assert.Equal(t, msgs[0].ID, env.deliveries.deliveries[0].ProviderMessageID)
+	})
+
+	t.Run("should load every due user in one lookup", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		for _, id := range []string{"user-2", "user-3"} {
+			env.users.Create(ctx, &models.User{ID: id, FCMToken: "token-" + id, IsFCMActive: true})
+			env.reminders.Create(ctx, &models.Reminder{
+				ID: "rem-" + id, UserID: id, Title: "Tập thể dục",
+				Type: models.ReminderTypeOneTime, CalendarType: models.CalendarTypeSolar,
+				Status: models.ReminderStatusActive, NextTriggerAt: time.Now().Add(-time.Minute),
+			})
+		}
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		assert.Equal(t, 1, env.users.lookups)
+		assert.Len(t, env.fake.MessagesTo("token-1"), 1)
+		assert.Len(t, env.fake.MessagesTo("token-user-2"), 1)
+		assert.Len(t, env.fake.MessagesTo("token-user-3"), 1)
+	})
+
+	t.Run("should disable user token on UNREGISTERED", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		env.fake.Fail("token-1", fakefcm.Unregistered, 0)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		assert.False(t, env.users.get("user-1").IsFCMActive)
+		assert.Equal(t, models.ReminderStatusActive, env.reminders.get("rem-1").Status)
+		assert.Equal(t, 1, env.fake.Attempts("token-1"))
+		require.Len(t, env.deliveries.deliveries, 1)
+		assert.Equal(t, string(services.FCMErrorTokenInvalid), env.deliveries.deliveries[0].ErrorClass)
+
+		// Token đã tắt: tick sau không gửi nữa
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+		assert.Equal(t, 1, env.fake.Attempts("token-1"))
+	})
+
+	t.Run("should back off on QUOTA_EXCEEDED and keep reminder due", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		env.fake.Fail("token-1", fakefcm.QuotaExceeded, 1)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+		assert.Empty(t, env.fake.Messages())
+		assert.Equal(t, models.ReminderStatusActive, env.reminders.get("rem-1").Status)
+		assert.True(t, env.users.get("user-1").IsFCMActive)
+
+		// Quota hồi phục: tick sau gửi được
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+		assert.Len(t, env.fake.MessagesTo("token-1"), 1)
+		assert.Equal(t, models.ReminderStatusCompleted, env.reminders.get("rem-1").Status)
+	})
+
+	t.Run("should retry 503 and leave reminder due when attempts run out", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		unavailable := fakefcm.Unavailable
+		unavailable.RetryAfter = 5 * time.Minute // SDK không tự retry, chỉ RetryPolicy của service
+		env.fake.Fail("token-1", unavailable, 0)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		assert.Equal(t, 3, env.fake.Attempts("token-1"))
+		assert.Empty(t, env.fake.Messages())
+		assert.Equal(t, models.ReminderStatusActive, env.reminders.get("rem-1").Status)
+		require.Len(t, env.deliveries.deliveries, 3)
+		for _, d := range env.deliveries.deliveries {
+			assert.Equal(t, string(services.FCMErrorUnavailable), d.ErrorClass)
+		}
+	})
+
+	t.Run("should back off retry after the user opens the notification", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		env.reminders.modify("rem-1", func(r *models.Reminder) {
+			r.RepeatStrategy = models.RepeatStrategyRetryUntilComplete
+			r.RetryIntervalSec = 300
+			r.SeenRetryIntervalSec = 3600
+			r.MaxRetries = 5
+		})
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+		msgs := env.fake.MessagesTo("token-1")
+		require.Len(t, msgs, 1)
+		deliveryID := msgs[0].Data["delivery_id"]
+		require.NotEmpty(t, deliveryID)
+		retryAt := env.reminders.get("rem-1").NextTriggerAt
+
+		acked, err := env.service.AcknowledgeDelivery(ctx, deliveryID, "user-1", models.AckEventOpened)
+
+		require.NoError(t, err)
+		require.Len(t, acked, 1)
+		assert.NotNil(t, acked[0].ReceivedAt)
+		assert.NotNil(t, acked[0].OpenedAt)
+		assert.True(t, env.reminders.get("rem-1").NextTriggerAt.After(retryAt.Add(50*time.Minute)))
+	})
+
+	t.Run("should recover from a single 503", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		env.fake.Fail("token-1", fakefcm.Unavailable, 1)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		assert.Len(t, env.fake.MessagesTo("token-1"), 1)
+		assert.Equal(t, models.ReminderStatusCompleted, env.reminders.get("rem-1").Status)
+	})
+}
+
+func TestIntegration_Escalation(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *deliveryEnv {
+		env := newDeliveryEnv(t)
+		env.users.Create(ctx, &models.User{ID: "family-1", Email: "b@example.com", FCMToken: "family-token", IsFCMActive: true})
+		env.reminders.modify("rem-1", func(r *models.Reminder) {
+			r.RepeatStrategy = models.RepeatStrategyRetryUntilComplete
+			r.RetryIntervalSec = 300
+			r.MaxRetries = 1
+			r.RetryCount = 1 // đây là lần nhắc cuối
+			r.EscalationPolicy = &models.EscalationPolicy{Steps: []models.EscalationStep{
+				{DelaySeconds: 0, Channel: models.EscalationChannelPush, UserID: "family-1"},
+			}}
+		})
+		return env
+	}
+
+	t.Run("should notify family member after the last retry", func(t *testing.T) {
+		env := setup(t)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+		require.Len(t, env.fake.MessagesTo("token-1"), 1)
+		assert.Equal(t, 1, env.reminders.get("rem-1").EscalationStep)
+		assert.Equal(t, models.ReminderStatusActive, env.reminders.get("rem-1").Status)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+		msgs := env.fake.MessagesTo("family-token")
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "escalation", msgs[0].Data["type"])
+		assert.Contains(t, msgs[0].Body, "a@example.com")
+		assert.Len(t, env.fake.MessagesTo("token-1"), 1)
+		assert.Equal(t, models.ReminderStatusCompleted, env.reminders.get("rem-1").Status)
+	})
+
+	t.Run("should stop escalating once the owner completes", func(t *testing.T) {
+		env := setup(t)
+
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+		require.NoError(t, env.service.CompleteReminder(ctx, "rem-1"))
+		require.NoError(t, env.service.ProcessDueReminders(ctx))
+
+		assert.Empty(t, env.fake.MessagesTo("family-token"))
+	})
+}
+
+func TestIntegration_Leasing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should deliver each reminder once across instances", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		for i := 0; i < 20; i++ {
+			env.reminders.Create(ctx, &models.Reminder{
+				ID: fmt.Sprintf("rem-batch-%02d", i), UserID: "user-1", Title: "Uống nước",
+				Type: models.ReminderTypeOneTime, CalendarType: models.CalendarTypeSolar,
+				Status: models.ReminderStatusActive, NextTriggerAt: time.Now().Add(-time.Minute),
+			})
+		}
+		instances := []*services.ReminderService{
+			env.newService(services.WithLeasing("worker-a", time.Minute, 3)),
+			env.newService(services.WithLeasing("worker-b", time.Minute, 3)),
+		}
+
+		var wg sync.WaitGroup
+		for _, instance := range instances {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, instance.ProcessDueReminders(ctx))
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, env.fake.MessagesTo("token-1"), 21)
+		for _, reminder := range env.reminders.filter(func(*models.Reminder) bool { return true }) {
+			assert.Equal(t, models.ReminderStatusCompleted, reminder.Status, reminder.ID)
+			assert.Empty(t, reminder.ClaimedBy, reminder.ID)
+		}
+	})
+
+	t.Run("should leave reminders claimed by a live instance", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		until := time.Now().Add(time.Minute)
+		env.reminders.modify("rem-1", func(r *models.Reminder) { r.ClaimedBy, r.ClaimedUntil = "worker-b", &until })
+
+		require.NoError(t, env.newService(services.WithLeasing("worker-a", time.Minute, 0)).ProcessDueReminders(ctx))
+
+		assert.Empty(t, env.fake.MessagesTo("token-1"))
+		assert.Equal(t, "worker-b", env.reminders.get("rem-1").ClaimedBy)
+	})
+
+	t.Run("should reclaim a reminder whose lease expired", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		expired := time.Now().Add(-time.Second)
+		env.reminders.modify("rem-1", func(r *models.Reminder) { r.ClaimedBy, r.ClaimedUntil = "crashed", &expired })
+
+		require.NoError(t, env.newService(services.WithLeasing("worker-a", time.Minute, 0)).ProcessDueReminders(ctx))
+
+		assert.Len(t, env.fake.MessagesTo("token-1"), 1)
+		assert.Equal(t, models.ReminderStatusCompleted, env.reminders.get("rem-1").Status)
+	})
+
+	t.Run("should ignore a stale transition after the lease was reclaimed", func(t *testing.T) {
+		env := newDeliveryEnv(t)
+		until := time.Now().Add(time.Minute)
+		env.reminders.modify("rem-1", func(r *models.Reminder) { r.ClaimedBy, r.ClaimedUntil = "worker-b", &until })
+
+		require.NoError(t, env.reminders.MarkCompleted(repository.WithLeaseOwner(ctx, "worker-a"), "rem-1", time.Now()))
+		assert.Equal(t, models.ReminderStatusActive, env.reminders.get("rem-1").Status)
+
+		// Người dùng tự hoàn thành thì không cần lease
+		require.NoError(t, env.reminders.MarkCompleted(ctx, "rem-1", time.Now()))
+		assert.Equal(t, models.ReminderStatusCompleted, env.reminders.get("rem-1").Status)
+	})
+}
+
 // BenchmarkIntegration_LunarCalculation benchmarks lunar calendar calculations
 func BenchmarkIntegration_LunarCalculation(b *testing.B) {
 	lunarCalendar := services.NewLunarCalendar()
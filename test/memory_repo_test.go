@@ -0,0 +1,534 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"remiaq/internal/models"
+	"remiaq/internal/repository"
+)
+
+// In-memory repositories so integration tests run without PocketBase.
+
+var (
+	_ repository.ReminderRepository = (*memReminderRepo)(nil)
+	_ repository.UserRepository     = (*memUserRepo)(nil)
+	_ repository.DeliveryRepository = (*memDeliveryRepo)(nil)
+	_ repository.GroupRepository    = (*memGroupRepo)(nil)
+)
+
+type memReminderRepo struct {
+	mu        sync.Mutex
+	reminders map[string]*models.Reminder
+}
+
+func newMemReminderRepo(reminders ...*models.Reminder) *memReminderRepo {
+	r := &memReminderRepo{reminders: make(map[string]*models.Reminder)}
+	for _, reminder := range reminders {
+		r.reminders[reminder.ID] = reminder
+	}
+	return r
+}
+
+// get returns a copy of the stored reminder for assertions.
+func (r *memReminderRepo) get(id string) models.Reminder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.reminders[id]
+}
+
+func (r *memReminderRepo) Create(ctx context.Context, reminder *models.Reminder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copy := *reminder
+	r.reminders[reminder.ID] = &copy
+	return nil
+}
+
+func (r *memReminderRepo) GetByID(ctx context.Context, id string) (*models.Reminder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reminder, ok := r.reminders[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copy := *reminder
+	return &copy, nil
+}
+
+func (r *memReminderRepo) Update(ctx context.Context, reminder *models.Reminder) error {
+	return r.Create(ctx, reminder)
+}
+
+func (r *memReminderRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reminders, id)
+	return nil
+}
+
+func (r *memReminderRepo) GetDueReminders(ctx context.Context, beforeTime time.Time) ([]*models.Reminder, error) {
+	return r.filter(func(rem *models.Reminder) bool { return rem.ShouldSend(beforeTime) }), nil
+}
+
+func (r *memReminderRepo) GetDueRemindersByUser(ctx context.Context, userID string, beforeTime time.Time) ([]*models.Reminder, error) {
+	return r.filter(func(rem *models.Reminder) bool {
+		return rem.UserID == userID && rem.ShouldSend(beforeTime) && !claimedByOther(ctx, rem)
+	}), nil
+}
+
+func (r *memReminderRepo) GetUpcomingTriggers(ctx context.Context, beforeTime time.Time, limit int) ([]models.ScheduledTrigger, error) {
+	upcoming := r.filter(func(rem *models.Reminder) bool {
+		return rem.Status == models.ReminderStatusActive && !rem.TriggerAt().After(beforeTime)
+	})
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].TriggerAt().Before(upcoming[j].TriggerAt()) })
+	if limit > 0 && len(upcoming) > limit {
+		upcoming = upcoming[:limit]
+	}
+
+	triggers := make([]models.ScheduledTrigger, len(upcoming))
+	for i, reminder := range upcoming {
+		triggers[i] = models.ScheduledTrigger{ReminderID: reminder.ID, TriggerAt: reminder.TriggerAt()}
+	}
+	return triggers, nil
+}
+
+func (r *memReminderRepo) ClaimDueReminders(ctx context.Context, owner string, beforeTime, leaseUntil time.Time, limit int) ([]*models.Reminder, error) {
+	// Chọn và nhận trong cùng một lần khóa, như UPDATE ... RETURNING của SQL repo
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*models.Reminder
+	for _, reminder := range r.reminders {
+		if reminder.ShouldSend(beforeTime) && (reminder.ClaimedUntil == nil || reminder.ClaimedUntil.Before(beforeTime)) {
+			due = append(due, reminder)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].NextTriggerAt.Equal(due[j].NextTriggerAt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].NextTriggerAt.Before(due[j].NextTriggerAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	out := make([]*models.Reminder, len(due))
+	for i, reminder := range due {
+		until := leaseUntil
+		reminder.ClaimedBy, reminder.ClaimedUntil = owner, &until
+		copy := *reminder
+		out[i] = &copy
+	}
+	return out, nil
+}
+
+func (r *memReminderRepo) ClaimDueRemindersByUser(ctx context.Context, owner, userID string, beforeTime, leaseUntil time.Time) ([]*models.Reminder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var due []*models.Reminder
+	for _, reminder := range r.reminders {
+		if reminder.UserID == userID && reminder.ShouldSend(beforeTime) &&
+			(reminder.ClaimedUntil == nil || reminder.ClaimedUntil.Before(now) || reminder.ClaimedBy == owner) {
+			due = append(due, reminder)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextTriggerAt.Before(due[j].NextTriggerAt) })
+
+	out := make([]*models.Reminder, len(due))
+	for i, reminder := range due {
+		until := leaseUntil
+		reminder.ClaimedBy, reminder.ClaimedUntil = owner, &until
+		copy := *reminder
+		out[i] = &copy
+	}
+	return out, nil
+}
+
+func (r *memReminderRepo) ReleaseClaims(ctx context.Context, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, reminder := range r.reminders {
+		if reminder.ClaimedBy == owner {
+			reminder.ClaimedBy, reminder.ClaimedUntil = "", nil
+		}
+	}
+	return nil
+}
+
+// claimedByOther reports whether reminder is claimed by an instance other than ctx's lease owner.
+func claimedByOther(ctx context.Context, reminder *models.Reminder) bool {
+	owner, ok := repository.LeaseOwner(ctx)
+	return ok && reminder.ClaimedBy != "" && reminder.ClaimedBy != owner
+}
+
+func (r *memReminderRepo) GetByUserID(ctx context.Context, userID string) ([]*models.Reminder, error) {
+	return r.filter(func(rem *models.Reminder) bool { return rem.UserID == userID }), nil
+}
+
+// filter returns copies of matching reminders ordered by next_trigger_at.
+func (r *memReminderRepo) filter(match func(*models.Reminder) bool) []*models.Reminder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*models.Reminder
+	for _, reminder := range r.reminders {
+		if match(reminder) {
+			copy := *reminder
+			out = append(out, &copy)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].NextTriggerAt.Equal(out[j].NextTriggerAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].NextTriggerAt.Before(out[j].NextTriggerAt)
+	})
+	return out
+}
+
+// modify applies fn to the stored reminder.
+func (r *memReminderRepo) modify(id string, fn func(*models.Reminder)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reminder, ok := r.reminders[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	fn(reminder)
+	return nil
+}
+
+// transition applies a worker transition, skipped like the SQL repo when the reminder
+// is claimed by another instance.
+func (r *memReminderRepo) transition(ctx context.Context, id string, fn func(*models.Reminder)) error {
+	return r.modify(id, func(rem *models.Reminder) {
+		if !claimedByOther(ctx, rem) {
+			fn(rem)
+		}
+	})
+}
+
+func (r *memReminderRepo) UpdateNextTrigger(ctx context.Context, id string, nextTrigger time.Time) error {
+	return r.transition(ctx, id, func(rem *models.Reminder) { rem.NextTriggerAt = nextTrigger })
+}
+
+func (r *memReminderRepo) UpdateStatus(ctx context.Context, id string, status string) error {
+	return r.modify(id, func(rem *models.Reminder) { rem.Status = status })
+}
+
+func (r *memReminderRepo) IncrementRetryCount(ctx context.Context, id string) error {
+	return r.transition(ctx, id, func(rem *models.Reminder) { rem.RetryCount++ })
+}
+
+func (r *memReminderRepo) UpdateSnooze(ctx context.Context, id string, snoozeUntil *time.Time) error {
+	return r.modify(id, func(rem *models.Reminder) { rem.SnoozeUntil = snoozeUntil })
+}
+
+func (r *memReminderRepo) MarkCompleted(ctx context.Context, id string, completedAt time.Time) error {
+	return r.transition(ctx, id, func(rem *models.Reminder) {
+		rem.Status = models.ReminderStatusCompleted
+		rem.LastCompletedAt = &completedAt
+	})
+}
+
+func (r *memReminderRepo) UpdateLastSent(ctx context.Context, id string, sentAt time.Time) error {
+	return r.transition(ctx, id, func(rem *models.Reminder) {
+		rem.LastSentAt = &sentAt
+		rem.OccurrenceCount++
+	})
+}
+
+func (r *memReminderRepo) UpdateEscalation(ctx context.Context, id string, step int, nextTrigger time.Time) error {
+	return r.transition(ctx, id, func(rem *models.Reminder) {
+		rem.EscalationStep = step
+		rem.NextTriggerAt = nextTrigger
+	})
+}
+
+func (r *memReminderRepo) ApplyTransition(ctx context.Context, t *models.ReminderTransition) (bool, error) {
+	applied := false
+	err := r.modify(t.ReminderID, func(rem *models.Reminder) {
+		if claimedByOther(ctx, rem) || !rem.NextTriggerAt.Equal(t.PrevTriggerAt) {
+			return
+		}
+		applied = true
+		if t.SentAt != nil {
+			rem.LastSentAt = t.SentAt
+			rem.OccurrenceCount++
+		}
+		if t.IncrementRetry {
+			rem.RetryCount++
+		}
+		if !t.NextTriggerAt.IsZero() {
+			rem.NextTriggerAt = t.NextTriggerAt
+		}
+		if t.EscalationStep != nil {
+			rem.EscalationStep = *t.EscalationStep
+		}
+		if t.CompletedAt != nil {
+			rem.Status = models.ReminderStatusCompleted
+			rem.LastCompletedAt = t.CompletedAt
+		}
+	})
+	return applied, err
+}
+
+type memUserRepo struct {
+	mu      sync.Mutex
+	users   map[string]*models.User
+	lookups int // số lần gọi GetByID/GetByIDs
+}
+
+func newMemUserRepo(users ...*models.User) *memUserRepo {
+	r := &memUserRepo{users: make(map[string]*models.User)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+// get returns a copy of the stored user for assertions.
+func (r *memUserRepo) get(id string) models.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.users[id]
+}
+
+func (r *memUserRepo) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copy := *user
+	r.users[user.ID] = &copy
+	return nil
+}
+
+func (r *memUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	user, ok := r.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copy := *user
+	return &copy, nil
+}
+
+func (r *memUserRepo) GetByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			copy := *user
+			users = append(users, &copy)
+		}
+	}
+	return users, nil
+}
+
+func (r *memUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			copy := *user
+			return &copy, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memUserRepo) Update(ctx context.Context, user *models.User) error {
+	return r.Create(ctx, user)
+}
+
+func (r *memUserRepo) UpdateFCMToken(ctx context.Context, userID, token string) error {
+	return r.EnableFCM(ctx, userID, token)
+}
+
+func (r *memUserRepo) DisableFCM(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.users[userID]; ok {
+		user.IsFCMActive = false
+	}
+	return nil
+}
+
+func (r *memUserRepo) EnableFCM(ctx context.Context, userID string, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.users[userID]; ok {
+		user.FCMToken = token
+		user.IsFCMActive = true
+	}
+	return nil
+}
+
+func (r *memUserRepo) GetActiveUsers(ctx context.Context) ([]*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*models.User
+	for _, user := range r.users {
+		if user.IsFCMActive {
+			copy := *user
+			out = append(out, &copy)
+		}
+	}
+	return out, nil
+}
+
+type memDeliveryRepo struct {
+	mu         sync.Mutex
+	deliveries []*models.Delivery
+}
+
+func (r *memDeliveryRepo) Create(ctx context.Context, delivery *models.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copy := *delivery
+	r.deliveries = append(r.deliveries, &copy)
+	return nil
+}
+
+func (r *memDeliveryRepo) ListByReminder(ctx context.Context, reminderID string, page, perPage int) ([]*models.Delivery, int, error) {
+	return r.list(func(d *models.Delivery) bool { return d.ReminderID == reminderID }, page, perPage)
+}
+
+func (r *memDeliveryRepo) ListByUser(ctx context.Context, userID string, page, perPage int) ([]*models.Delivery, int, error) {
+	return r.list(func(d *models.Delivery) bool { return d.UserID == userID }, page, perPage)
+}
+
+func (r *memDeliveryRepo) Acknowledge(ctx context.Context, dispatchID, userID, event string, at time.Time) ([]*models.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*models.Delivery
+	for _, d := range r.deliveries {
+		if d.DispatchID != dispatchID || d.Outcome != models.DeliveryOutcomeSent || (userID != "" && d.UserID != userID) {
+			continue
+		}
+		if d.ReceivedAt == nil {
+			d.ReceivedAt = &at
+		}
+		if event == models.AckEventOpened && d.OpenedAt == nil {
+			d.OpenedAt = &at
+		}
+		copy := *d
+		out = append(out, &copy)
+	}
+	return out, nil
+}
+
+// list returns matching deliveries newest first.
+func (r *memDeliveryRepo) list(match func(*models.Delivery) bool, page, perPage int) ([]*models.Delivery, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var all []*models.Delivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		if match(r.deliveries[i]) {
+			all = append(all, r.deliveries[i])
+		}
+	}
+	start := (page - 1) * perPage
+	if start >= len(all) {
+		return []*models.Delivery{}, len(all), nil
+	}
+	end := start + perPage
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[start:end], len(all), nil
+}
+
+type memGroupRepo struct {
+	mu      sync.Mutex
+	groups  map[string]*models.Group
+	members map[string][]string // group ID -> user IDs
+	users   *memUserRepo
+}
+
+func newMemGroupRepo(users *memUserRepo) *memGroupRepo {
+	return &memGroupRepo{
+		groups:  make(map[string]*models.Group),
+		members: make(map[string][]string),
+		users:   users,
+	}
+}
+
+func (r *memGroupRepo) Create(ctx context.Context, group *models.Group) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copy := *group
+	r.groups[group.ID] = &copy
+	return nil
+}
+
+func (r *memGroupRepo) GetByID(ctx context.Context, id string) (*models.Group, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	group, ok := r.groups[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copy := *group
+	return &copy, nil
+}
+
+func (r *memGroupRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.groups, id)
+	delete(r.members, id)
+	return nil
+}
+
+func (r *memGroupRepo) AddMember(ctx context.Context, member *models.GroupMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.members[member.GroupID] = append(r.members[member.GroupID], member.UserID)
+	return nil
+}
+
+func (r *memGroupRepo) RemoveMember(ctx context.Context, groupID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := r.members[groupID][:0]
+	for _, id := range r.members[groupID] {
+		if id != userID {
+			ids = append(ids, id)
+		}
+	}
+	r.members[groupID] = ids
+	return nil
+}
+
+func (r *memGroupRepo) IsMember(ctx context.Context, groupID, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range r.members[groupID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memGroupRepo) ListMembers(ctx context.Context, groupID string) ([]*models.User, error) {
+	r.mu.Lock()
+	ids := append([]string(nil), r.members[groupID]...)
+	r.mu.Unlock()
+
+	var out []*models.User
+	for _, id := range ids {
+		if user, err := r.users.GetByID(ctx, id); err == nil {
+			out = append(out, user)
+		}
+	}
+	return out, nil
+}